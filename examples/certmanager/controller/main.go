@@ -34,9 +34,6 @@ func main() {
 	var command = cobra.Command{
 		Use: "controller",
 		Run: func(c *cobra.Command, args []string) {
-			// Optionally set the annotations prefix
-			// subscriptions.SetAnnotationPrefix("example.prefix.io")
-
 			// Get Kubernetes REST Config and current Namespace so we can talk to Kubernetes
 			restConfig, err := clientConfig.ClientConfig()
 			if err != nil {
@@ -82,6 +79,8 @@ func main() {
 				certClient,
 				certsInformer,
 				notificationsFactory,
+				// Optionally set the annotations prefix
+				// controller.WithAnnotationPrefix("example.prefix.io"),
 				// Register a callback to track notification deliveries/errors
 				// May be helpful in use cases such as surfacing metrics/status
 				controller.WithEventCallback(func(eventSequence controller.NotificationEventSequence) {
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
 	"github.com/argoproj/notifications-engine/pkg/services"
 	"github.com/argoproj/notifications-engine/pkg/templates"
@@ -9,10 +11,45 @@ import (
 )
 
 const (
-	serviceTypeVarName = "serviceType"
-	recipientVarName   = "recipient"
+	serviceTypeVarName     = "serviceType"
+	recipientVarName       = "recipient"
+	conditionKeyVarName    = "conditionKey"
+	failureCountVarName    = "failureCount"
+	previousMessageVarName = "previousMessage"
+	digestSeparator        = "\n\n"
+
+	defaultTemplateVarsKey = "context"
 )
 
+// ConditionKeyField is the field of the object passed to Send and FormatNotification which holds the key of the
+// trigger condition that produced the notification. The field is removed from the object and its value is
+// available to the templates as conditionKey.
+const ConditionKeyField = "__notificationsConditionKey"
+
+// IdempotencyKeyField is the field of the object passed to Send and FormatNotification which holds the idempotency
+// key of the delivery, see services.DeliveryIdempotencyKey. The field is removed from the object and its value is
+// passed to the notification services.
+const IdempotencyKeyField = "__notificationsIdempotencyKey"
+
+// FailureCountField is the field of the object passed to Send and FormatNotification which holds the number of
+// consecutive evaluations in which the trigger condition was triggered, including the current one. The field is removed from the
+// object and its value is available to the templates as the failureCount template variable, e.g.
+// {{.context.failureCount}}, so that templates can escalate the severity of conditions which keep firing.
+const FailureCountField = "__notificationsFailureCount"
+
+// PreviousMessageField is the field of the object passed to Send and FormatNotification which holds the message last
+// delivered about the trigger condition to the destination. The field is removed from the object and its value is
+// available to the templates as the previousMessage template variable, e.g. {{.context.previousMessage}}.
+const PreviousMessageField = "__notificationsPreviousMessage"
+
+// controllerFields are the fields of the object which are set by the controller rather than the resource
+var controllerFields = map[string]bool{
+	ConditionKeyField:    true,
+	IdempotencyKeyField:  true,
+	FailureCountField:    true,
+	PreviousMessageField: true,
+}
+
 //go:generate mockgen -destination=../mocks/api.go -package=mocks github.com/argoproj/notifications-engine/pkg/api API
 
 type GetVars func(obj map[string]interface{}, dest services.Destination) map[string]interface{}
@@ -20,9 +57,16 @@ type GetVars func(obj map[string]interface{}, dest services.Destination) map[str
 // API provides high level interface to send notifications and manage notification services
 type API interface {
 	Send(obj map[string]interface{}, templates []string, dest services.Destination) error
+	SendContext(ctx context.Context, obj map[string]interface{}, templates []string, dest services.Destination) error
+	FormatDigest(obj map[string]interface{}, templates [][]string, dest services.Destination) (*services.Notification, error)
+	FormatNotification(obj map[string]interface{}, templates []string, dest services.Destination) (*services.Notification, error)
+	SendNotification(notification services.Notification, dest services.Destination) error
+	SendNotificationContext(ctx context.Context, notification services.Notification, dest services.Destination) error
+	RenderNotification(templateName string, obj map[string]interface{}) (services.Notification, error)
 	RunTrigger(triggerName string, vars map[string]interface{}) ([]triggers.ConditionResult, error)
 	AddNotificationService(name string, service services.NotificationService)
 	GetNotificationServices() map[string]services.NotificationService
+	GetConfiguredDestinations() map[string][]string
 	GetConfig() Config
 }
 
@@ -48,27 +92,183 @@ func (n *api) GetNotificationServices() map[string]services.NotificationService
 	return n.notificationServices
 }
 
+// GetConfiguredDestinations returns the statically configured recipients of every notification service, e.g. to offer
+// them when building subscriptions. Services without enumerable recipients, see services.RecipientLister, have an
+// empty list.
+func (n *api) GetConfiguredDestinations() map[string][]string {
+	res := make(map[string][]string, len(n.notificationServices))
+	for name, service := range n.notificationServices {
+		res[name] = []string{}
+		if lister, ok := service.(services.RecipientLister); ok {
+			res[name] = lister.Recipients()
+		}
+	}
+	return res
+}
+
+// ValidateServices validates the notification services which implement services.Validatable. The result holds the
+// validation error of each validated service keyed by service name, or nil if the service is valid.
+func ValidateServices(ctx context.Context, api API) map[string]error {
+	res := map[string]error{}
+	for name, service := range api.GetNotificationServices() {
+		if validatable, ok := service.(services.Validatable); ok {
+			res[name] = validatable.Validate(ctx)
+		}
+	}
+	return res
+}
+
 // Send sends notification using specified service and template to the specified destination
 func (n *api) Send(obj map[string]interface{}, templates []string, dest services.Destination) error {
+	return n.SendContext(context.Background(), obj, templates, dest)
+}
+
+// SendContext is like Send but aborts the delivery once the context is done
+func (n *api) SendContext(ctx context.Context, obj map[string]interface{}, templates []string, dest services.Destination) error {
+	if _, ok := n.notificationServices[dest.Service]; !ok {
+		return fmt.Errorf("notification service '%s' is not supported", dest.Service)
+	}
+
+	notification, err := n.FormatNotification(obj, templates, dest)
+	if err != nil {
+		return err
+	}
+
+	return n.SendNotificationContext(ctx, *notification, dest)
+}
+
+// SendNotification sends the already formatted notification using the service of the destination
+func (n *api) SendNotification(notification services.Notification, dest services.Destination) error {
+	return n.SendNotificationContext(context.Background(), notification, dest)
+}
+
+// SendNotificationContext is like SendNotification but aborts the delivery once the context is done
+func (n *api) SendNotificationContext(ctx context.Context, notification services.Notification, dest services.Destination) error {
 	notificationService, ok := n.notificationServices[dest.Service]
 	if !ok {
 		return fmt.Errorf("notification service '%s' is not supported", dest.Service)
 	}
+	return notificationService.SendContext(ctx, notification, dest)
+}
+
+// FormatDigest formats a notification for every set of templates and combines them into a single notification for the
+// specified destination, which concatenates the messages and email bodies. The first notification provides all other
+// fields, e.g. the email subject.
+func (n *api) FormatDigest(obj map[string]interface{}, templates [][]string, dest services.Destination) (*services.Notification, error) {
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("digest has no notifications")
+	}
 
+	var messages, bodies []string
+	var digest *services.Notification
+	for i := range templates {
+		notification, err := n.FormatNotification(obj, templates[i], dest)
+		if err != nil {
+			return nil, err
+		}
+		if digest == nil {
+			digest = notification
+		}
+		if notification.Message != "" {
+			messages = append(messages, notification.Message)
+		}
+		if notification.Email != nil && notification.Email.Body != "" {
+			bodies = append(bodies, notification.Email.Body)
+		}
+	}
+
+	digest.Message = strings.Join(messages, digestSeparator)
+	if digest.Email != nil {
+		emailCopy := *digest.Email
+		emailCopy.Body = strings.Join(bodies, digestSeparator)
+		digest.Email = &emailCopy
+	}
+	return digest, nil
+}
+
+// FormatNotification renders the templates for the specified destination. Every call returns a new notification.
+func (n *api) FormatNotification(obj map[string]interface{}, templates []string, dest services.Destination) (*services.Notification, error) {
+	conditionKey, hasConditionKey := obj[ConditionKeyField].(string)
+	idempotencyKey, hasIdempotencyKey := obj[IdempotencyKeyField].(string)
+	failureCount, hasFailureCount := obj[FailureCountField].(int)
+	previousMessage, hasPreviousMessage := obj[PreviousMessageField].(string)
+	if hasConditionKey || hasIdempotencyKey || hasFailureCount || hasPreviousMessage {
+		withoutKeys := make(map[string]interface{}, len(obj))
+		for k, v := range obj {
+			if !controllerFields[k] {
+				withoutKeys[k] = v
+			}
+		}
+		obj = withoutKeys
+	}
 	vars := n.getVars(obj, dest)
 
 	in := make(map[string]interface{})
 	for k := range vars {
 		in[k] = vars[k]
 	}
+	key := n.config.TemplateVarsKey
+	if key == "" {
+		key = defaultTemplateVarsKey
+	}
+	if len(n.config.TemplateVars) > 0 {
+		if templateVars, ok := withTemplateVars(n.config.TemplateVars, in[key]); ok {
+			in[key] = templateVars
+		}
+	}
+	controllerVars := map[string]interface{}{}
+	if hasFailureCount {
+		controllerVars[failureCountVarName] = failureCount
+	}
+	if hasPreviousMessage {
+		controllerVars[previousMessageVarName] = previousMessage
+	}
+	if len(controllerVars) > 0 {
+		if templateVars, ok := withTemplateVars(controllerVars, in[key]); ok {
+			// the variables are computed by the controller and take precedence over the variables of the object
+			for k, v := range controllerVars {
+				templateVars[k] = v
+			}
+			in[key] = templateVars
+		}
+	}
 	in[serviceTypeVarName] = dest.Service
 	in[recipientVarName] = dest.Recipient
+	in[conditionKeyVarName] = conditionKey
 	notification, err := n.templatesService.FormatNotification(in, templates...)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	notification.IdempotencyKey = idempotencyKey
+	return notification, nil
+}
 
-	return notificationService.Send(*notification, dest)
+// withTemplateVars returns the static template variables merged with the variable of the object with the same name,
+// if any. The variables of the object take precedence. Returns false if the object variable isn't a map and therefore
+// replaces the static variables.
+func withTemplateVars(templateVars map[string]interface{}, objVar interface{}) (map[string]interface{}, bool) {
+	objVars, isMap := objVar.(map[string]interface{})
+	if objVar != nil && !isMap {
+		return nil, false
+	}
+	res := make(map[string]interface{}, len(templateVars)+len(objVars))
+	for k, v := range templateVars {
+		res[k] = v
+	}
+	for k, v := range objVars {
+		res[k] = v
+	}
+	return res, true
+}
+
+// RenderNotification renders the template for the object without sending the notification, e.g. to preview it.
+// The template is rendered without destination, so the serviceType and recipient variables are empty.
+func (n *api) RenderNotification(templateName string, obj map[string]interface{}) (services.Notification, error) {
+	notification, err := n.FormatNotification(obj, []string{templateName}, services.Destination{})
+	if err != nil {
+		return services.Notification{}, err
+	}
+	return *notification, nil
 }
 
 func (n *api) RunTrigger(triggerName string, obj map[string]interface{}) ([]triggers.ConditionResult, error) {
@@ -90,7 +290,7 @@ func NewAPI(cfg Config, getVars GetVars) (*api, error) {
 	if err != nil {
 		return nil, err
 	}
-	templatesService, err := templates.NewService(cfg.Templates)
+	templatesService, err := templates.NewServiceWithFuncs(cfg.Templates, cfg.TemplateFuncs)
 	if err != nil {
 		return nil, err
 	}
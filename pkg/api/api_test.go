@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -37,7 +39,7 @@ func TestSend(t *testing.T) {
 	defer ctrl.Finish()
 
 	api, err := NewAPI(getConfig(ctrl, func(service *mocks.MockNotificationService) {
-		service.EXPECT().Send(services.Notification{
+		service.EXPECT().SendContext(gomock.Any(), services.Notification{
 			Message: "hello world slack:my-channel",
 		}, services.Destination{
 			Service:   "slack",
@@ -56,6 +58,219 @@ func TestSend(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestFormatNotification_ConditionKey(t *testing.T) {
+	var receivedObj map[string]interface{}
+	api, err := NewAPI(Config{
+		Templates: map[string]services.Notification{
+			"my-template": {Message: "{{ .foo }} is {{ .conditionKey }}"},
+		},
+	}, func(in map[string]interface{}, _ services.Destination) map[string]interface{} {
+		receivedObj = in
+		return in
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	obj := map[string]interface{}{"foo": "app", ConditionKeyField: "degraded", IdempotencyKeyField: "abc"}
+	notification, err := api.FormatNotification(obj, []string{"my-template"}, services.Destination{Service: "slack", Recipient: "my-channel"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "app is degraded", notification.Message)
+	assert.Equal(t, "abc", notification.IdempotencyKey)
+	assert.Equal(t, map[string]interface{}{"foo": "app"}, receivedObj)
+	// the object of the caller is not modified
+	assert.Contains(t, obj, ConditionKeyField)
+
+	notification, err = api.FormatNotification(map[string]interface{}{"foo": "app"}, []string{"my-template"}, services.Destination{Service: "slack", Recipient: "my-channel"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "app is ", notification.Message)
+}
+
+func TestFormatNotification_TemplateVars(t *testing.T) {
+	api, err := NewAPI(Config{
+		Templates: map[string]services.Notification{
+			"my-template": {
+				Message: "{{ .app }} deployed to {{ .context.cluster }}",
+				Slack:   &services.SlackNotification{Username: "argocd-{{ .context.environment }}"},
+			},
+		},
+		TemplateVars: map[string]interface{}{"cluster": "prod-eu", "environment": "production"},
+	}, getVars)
+	if !assert.NoError(t, err) {
+		return
+	}
+	dest := services.Destination{Service: "slack", Recipient: "my-channel"}
+
+	notification, err := api.FormatNotification(map[string]interface{}{"app": "guestbook"}, []string{"my-template"}, dest)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "guestbook deployed to prod-eu", notification.Message)
+		assert.Equal(t, "argocd-production", notification.Slack.Username)
+	}
+
+	// the variables of the object take precedence
+	obj := map[string]interface{}{"app": "guestbook", "context": map[string]interface{}{"cluster": "staging"}}
+	notification, err = api.FormatNotification(obj, []string{"my-template"}, dest)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "guestbook deployed to staging", notification.Message)
+		assert.Equal(t, "argocd-production", notification.Slack.Username)
+	}
+	assert.Equal(t, map[string]interface{}{"cluster": "staging"}, obj["context"])
+}
+
+func TestFormatNotification_FailureCount(t *testing.T) {
+	api, err := NewAPI(Config{
+		Templates: map[string]services.Notification{
+			"my-template": {Message: "{{ .app }} is {{ if gt .context.failureCount 3 }}critical{{ else }}warning{{ end }} in {{ .context.cluster }}"},
+		},
+		TemplateVars: map[string]interface{}{"cluster": "prod-eu"},
+	}, getVars)
+	if !assert.NoError(t, err) {
+		return
+	}
+	dest := services.Destination{Service: "pagerdutyv2", Recipient: "my-service"}
+
+	obj := map[string]interface{}{"app": "guestbook", ConditionKeyField: "[0].failed", FailureCountField: 4}
+	notification, err := api.FormatNotification(obj, []string{"my-template"}, dest)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "guestbook is critical in prod-eu", notification.Message)
+	}
+
+	// the count takes precedence over the variables of the object
+	obj = map[string]interface{}{"app": "guestbook", FailureCountField: 1, "context": map[string]interface{}{"cluster": "staging", "failureCount": 5}}
+	notification, err = api.FormatNotification(obj, []string{"my-template"}, dest)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "guestbook is warning in staging", notification.Message)
+	}
+	assert.Equal(t, map[string]interface{}{"cluster": "staging", "failureCount": 5}, obj["context"])
+}
+
+func TestFormatNotification_PreviousMessage(t *testing.T) {
+	api, err := NewAPI(Config{
+		Templates: map[string]services.Notification{
+			"my-template": {Message: "{{ .app }} is {{ .status }}{{ with .context.previousMessage }} (was: {{ . }}){{ end }}"},
+		},
+	}, getVars)
+	if !assert.NoError(t, err) {
+		return
+	}
+	dest := services.Destination{Service: "slack", Recipient: "my-channel"}
+
+	obj := map[string]interface{}{"app": "guestbook", "status": "degraded", PreviousMessageField: ""}
+	notification, err := api.FormatNotification(obj, []string{"my-template"}, dest)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "guestbook is degraded", notification.Message)
+	}
+
+	obj = map[string]interface{}{"app": "guestbook", "status": "missing", PreviousMessageField: "guestbook is degraded"}
+	notification, err = api.FormatNotification(obj, []string{"my-template"}, dest)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "guestbook is missing (was: guestbook is degraded)", notification.Message)
+	}
+}
+
+func TestFormatNotification_TemplateVarsKey(t *testing.T) {
+	api, err := NewAPI(Config{
+		Templates: map[string]services.Notification{
+			"my-template": {Message: "{{ .app }} deployed to {{ .static.cluster }}"},
+		},
+		TemplateVars:    map[string]interface{}{"cluster": "prod-eu"},
+		TemplateVarsKey: "static",
+	}, getVars)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	notification, err := api.FormatNotification(map[string]interface{}{"app": "guestbook"}, []string{"my-template"}, services.Destination{Service: "slack"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "guestbook deployed to prod-eu", notification.Message)
+	}
+}
+
+func TestRenderNotification(t *testing.T) {
+	api, err := NewAPI(Config{
+		Templates: map[string]services.Notification{
+			"slack-template": {
+				Message: "{{.app.metadata.name}} is synced",
+				Slack: &services.SlackNotification{
+					Username: "{{.app.metadata.name}}-bot",
+					Blocks:   `[{"type": "section", "text": {"type": "mrkdwn", "text": "{{.app.metadata.name}}"}}]`,
+				},
+			},
+			"github-template": {
+				GitHub: &services.GitHubNotification{
+					Status: &services.GitHubStatus{
+						State:     "success",
+						Label:     "continuous-delivery/{{.app.metadata.name}}",
+						TargetURL: "https://argocd.example.com/applications/{{.app.metadata.name}}",
+					},
+				},
+			},
+		},
+	}, func(obj map[string]interface{}, _ services.Destination) map[string]interface{} {
+		return map[string]interface{}{"app": obj}
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	obj := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "guestbook"},
+		"spec":     map[string]interface{}{"source": map[string]interface{}{"repoURL": "https://github.com/argoproj/argocd-example-apps.git"}},
+		"status":   map[string]interface{}{"operationState": map[string]interface{}{"syncResult": map[string]interface{}{"revision": "abc123"}}},
+	}
+
+	notification, err := api.RenderNotification("slack-template", obj)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "guestbook is synced", notification.Message)
+		assert.Equal(t, "guestbook-bot", notification.Slack.Username)
+		assert.Equal(t, `[{"type": "section", "text": {"type": "mrkdwn", "text": "guestbook"}}]`, notification.Slack.Blocks)
+	}
+
+	notification, err = api.RenderNotification("github-template", obj)
+	if assert.NoError(t, err) {
+		assert.Equal(t, &services.GitHubStatus{
+			State:     "success",
+			Label:     "continuous-delivery/guestbook",
+			TargetURL: "https://argocd.example.com/applications/guestbook",
+		}, notification.GitHub.Status)
+	}
+
+	_, err = api.RenderNotification("unknown", obj)
+	assert.EqualError(t, err, "template 'unknown' is not supported")
+}
+
+func TestFormatDigest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := getConfig(ctrl)
+	cfg.Templates["my-email"] = services.Notification{
+		Email: &services.EmailNotification{Subject: "digest", Body: "first"},
+	}
+	cfg.Templates["other-template"] = services.Notification{
+		Message: "bye {{ .foo }}",
+		Email:   &services.EmailNotification{Subject: "ignored", Body: "second"},
+	}
+	api, err := NewAPI(cfg, getVars)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	notification, err := api.FormatDigest(
+		map[string]interface{}{"foo": "world"},
+		[][]string{{"my-template", "my-email"}, {"other-template"}},
+		services.Destination{Service: "slack", Recipient: "my-channel"},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, &services.Notification{
+		Message: "hello world slack:my-channel\n\nbye world",
+		Email:   &services.EmailNotification{Subject: "digest", Body: "first\n\nsecond"},
+	}, notification)
+}
+
 func TestAddService(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -71,3 +286,62 @@ func TestAddService(t *testing.T) {
 	assert.NotNil(t, servicesMap["slack"])
 	assert.NotNil(t, servicesMap["hello"])
 }
+
+type validatableService struct {
+	services.NotificationService
+	err error
+}
+
+func (s validatableService) Validate(_ context.Context) error {
+	return s.err
+}
+
+func TestValidateServices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	api, err := NewAPI(Config{Services: map[string]ServiceFactory{
+		"valid": func() (services.NotificationService, error) {
+			return validatableService{}, nil
+		},
+		"invalid": func() (services.NotificationService, error) {
+			return validatableService{err: errors.New("invalid token")}, nil
+		},
+		"other": func() (services.NotificationService, error) {
+			return mocks.NewMockNotificationService(ctrl), nil
+		},
+	}}, getVars)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, map[string]error{
+		"valid":   nil,
+		"invalid": errors.New("invalid token"),
+	}, ValidateServices(context.Background(), api))
+}
+
+func TestGetConfiguredDestinations(t *testing.T) {
+	api, err := NewAPI(Config{}, getVars)
+	if !assert.NoError(t, err) {
+		return
+	}
+	api.AddNotificationService("slack", services.NewSlackService(services.SlackOptions{
+		Channels:        []string{"deployments", "alerts"},
+		RecipientTokens: map[string]string{"other-workspace": "token", "alerts": "token"},
+	}))
+	api.AddNotificationService("teams", services.NewTeamsService(services.TeamsOptions{
+		RecipientUrls: map[string]string{"ops": "https://example.com/ops", "dev": "https://example.com/dev"},
+	}))
+	api.AddNotificationService("opsgenie", services.NewOpsgenieService(services.OpsgenieOptions{
+		ApiKeys: map[string]string{"platform-team": "key"},
+	}))
+	api.AddNotificationService("webhook", services.NewWebhookService(services.WebhookOptions{URL: "https://example.com"}))
+
+	assert.Equal(t, map[string][]string{
+		"slack":    {"alerts", "deployments", "other-workspace"},
+		"teams":    {"dev", "ops"},
+		"opsgenie": {"platform-team"},
+		"webhook":  {},
+	}, api.GetConfiguredDestinations())
+}
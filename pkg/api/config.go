@@ -3,7 +3,10 @@ package api
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	texttemplate "text/template"
+	"time"
 
 	"github.com/argoproj/notifications-engine/pkg/services"
 	"github.com/argoproj/notifications-engine/pkg/subscriptions"
@@ -12,6 +15,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	yaml3 "gopkg.in/yaml.v3"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
 	"sigs.k8s.io/yaml"
 )
@@ -31,18 +35,79 @@ type Config struct {
 	ServiceDefaultTriggers map[string][]string
 	Namespace              string
 	IsSelfServiceConfig    bool
+	// ServiceDefaultTemplates holds the template per service which is used if none of the templates of a trigger
+	// configures the service
+	ServiceDefaultTemplates map[string]string
+	// SendTimeout bounds the duration of a single notification delivery; overrides the controller default if set
+	SendTimeout time.Duration
+	// ServiceMaxConcurrent limits the number of concurrent deliveries per service; overrides the controller default of the service if set
+	ServiceMaxConcurrent map[string]int
+	// DeduplicationWindow allows sending the same notification again once the window has passed; zero means notify once
+	DeduplicationWindow time.Duration
+	// MaxStateEntries caps the number of tracked deliveries in the notified state annotation
+	MaxStateEntries int
+	// MaxStateSize caps the size of the notified state annotation in bytes
+	MaxStateSize int
+	// TemplateFuncs holds additional functions available to all templates; overrides built-in functions with the same name
+	TemplateFuncs texttemplate.FuncMap
+	// SkipEmptyMessages skips the delivery of notifications which render neither a message nor service specific content
+	SkipEmptyMessages bool
+	// IncludeFailureCount counts the consecutive evaluations in which a trigger condition was triggered and makes the
+	// count available to the templates as the failureCount template variable
+	IncludeFailureCount bool
+	// IncludePreviousMessage keeps the message last delivered about a trigger condition to a destination in the notified
+	// state and makes it available to the templates as the previousMessage template variable
+	IncludePreviousMessage bool
+	// TemplateVars holds static variables available to all templates under TemplateVarsKey, e.g. the cluster name.
+	// Variables of the object with the same name take precedence.
+	TemplateVars map[string]interface{}
+	// TemplateVarsKey is the template variable which holds TemplateVars, defaults to "context"
+	TemplateVarsKey string
+	// TriggerPriority orders the deliveries of the triggers of a resource, triggers with a higher priority are
+	// delivered first. Triggers without a priority have priority 0.
+	TriggerPriority map[string]int
 }
 
-// Returns list of destinations for the specified trigger
+// GetServiceTemplates returns the templates used to notify the service. If none of the given templates configures the
+// service, the default template of the service is used in addition to them. The default template is rendered first,
+// so the given templates take precedence over it.
+func (cfg Config) GetServiceTemplates(service string, templates []string) []string {
+	defaultTemplate := cfg.ServiceDefaultTemplates[service]
+	if defaultTemplate == "" {
+		return templates
+	}
+	for _, name := range templates {
+		if template, ok := cfg.Templates[name]; ok && template.HasServiceFields(service) {
+			return templates
+		}
+	}
+	return append([]string{defaultTemplate}, templates...)
+}
+
+// Returns list of destinations for the specified trigger. Subscriptions with an expression are skipped since
+// the expression requires the resource, use GetResourceGlobalDestinations instead.
 func (cfg Config) GetGlobalDestinations(labels map[string]string) services.Destinations {
+	return cfg.getGlobalDestinations(labels, nil)
+}
+
+// GetResourceGlobalDestinations returns list of destinations of the subscriptions which selector and expression
+// match the given resource
+func (cfg Config) GetResourceGlobalDestinations(obj map[string]interface{}) services.Destinations {
+	return cfg.getGlobalDestinations((&unstructured.Unstructured{Object: obj}).GetLabels(), obj)
+}
+
+func (cfg Config) getGlobalDestinations(labels map[string]string, obj map[string]interface{}) services.Destinations {
 	dests := services.Destinations{}
 	for _, s := range cfg.Subscriptions {
 		triggers := s.Triggers
 		if len(triggers) == 0 {
 			triggers = cfg.DefaultTriggers
 		}
+		if !s.Selector.Matches(fields.Set(labels)) || !s.MatchesExpr(obj) {
+			continue
+		}
 		for _, trigger := range triggers {
-			if s.MatchesTrigger(trigger) && s.Selector.Matches(fields.Set(labels)) {
+			if s.MatchesTrigger(trigger) {
 				for _, recipient := range s.Recipients {
 					parts := strings.Split(recipient, ":")
 					dest := services.Destination{Service: parts[0]}
@@ -73,6 +138,13 @@ func replaceStringSecret(val string, secretValues map[string][]byte) string {
 
 // ParseConfig retrieves Config from given ConfigMap and Secret
 func ParseConfig(configMap *v1.ConfigMap, secret *v1.Secret) (*Config, error) {
+	return ParseConfigWithSecretRefs(configMap, secret, nil)
+}
+
+// ParseConfigWithSecretRefs retrieves Config from given ConfigMap and Secret like ParseConfig and resolves the service
+// credentials which reference an environment variable or a file of the controller, see services.SecretRefs. It must
+// only be used for configurations managed by the operator of the controller.
+func ParseConfigWithSecretRefs(configMap *v1.ConfigMap, secret *v1.Secret, refs *services.SecretRefs) (*Config, error) {
 	cfg := Config{
 		Services:               map[string]ServiceFactory{},
 		Triggers:               map[string][]triggers.Condition{},
@@ -92,6 +164,87 @@ func ParseConfig(configMap *v1.ConfigMap, secret *v1.Secret) (*Config, error) {
 		}
 	}
 
+	if sendTimeout, ok := configMap.Data["sendTimeout"]; ok {
+		timeout, err := time.ParseDuration(sendTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse sendTimeout: %v", err)
+		}
+		cfg.SendTimeout = timeout
+	}
+
+	if serviceDefaultTemplatesYaml, ok := configMap.Data["serviceDefaultTemplates"]; ok {
+		if err := yaml.Unmarshal([]byte(serviceDefaultTemplatesYaml), &cfg.ServiceDefaultTemplates); err != nil {
+			return nil, fmt.Errorf("failed to parse serviceDefaultTemplates: %v", err)
+		}
+	}
+
+	if serviceMaxConcurrentYaml, ok := configMap.Data["serviceMaxConcurrent"]; ok {
+		if err := yaml.Unmarshal([]byte(serviceMaxConcurrentYaml), &cfg.ServiceMaxConcurrent); err != nil {
+			return nil, fmt.Errorf("failed to parse serviceMaxConcurrent: %v", err)
+		}
+	}
+
+	if deduplicationWindow, ok := configMap.Data["deduplicationWindow"]; ok {
+		window, err := time.ParseDuration(deduplicationWindow)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse deduplicationWindow: %v", err)
+		}
+		cfg.DeduplicationWindow = window
+	}
+
+	if maxStateEntries, ok := configMap.Data["maxStateEntries"]; ok {
+		entries, err := strconv.Atoi(maxStateEntries)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse maxStateEntries: %v", err)
+		}
+		cfg.MaxStateEntries = entries
+	}
+
+	if maxStateSize, ok := configMap.Data["maxStateSize"]; ok {
+		size, err := strconv.Atoi(maxStateSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse maxStateSize: %v", err)
+		}
+		cfg.MaxStateSize = size
+	}
+
+	if skipEmptyMessages, ok := configMap.Data["skipEmptyMessages"]; ok {
+		skip, err := strconv.ParseBool(skipEmptyMessages)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse skipEmptyMessages: %v", err)
+		}
+		cfg.SkipEmptyMessages = skip
+	}
+
+	if includeFailureCount, ok := configMap.Data["includeFailureCount"]; ok {
+		include, err := strconv.ParseBool(includeFailureCount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse includeFailureCount: %v", err)
+		}
+		cfg.IncludeFailureCount = include
+	}
+
+	if includePreviousMessage, ok := configMap.Data["includePreviousMessage"]; ok {
+		include, err := strconv.ParseBool(includePreviousMessage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse includePreviousMessage: %v", err)
+		}
+		cfg.IncludePreviousMessage = include
+	}
+
+	if templateVarsYaml, ok := configMap.Data["templateVars"]; ok {
+		if err := yaml.Unmarshal([]byte(templateVarsYaml), &cfg.TemplateVars); err != nil {
+			return nil, fmt.Errorf("failed to parse templateVars: %v", err)
+		}
+	}
+	cfg.TemplateVarsKey = configMap.Data["templateVarsKey"]
+
+	if triggerPriorityYaml, ok := configMap.Data["triggerPriority"]; ok {
+		if err := yaml.Unmarshal([]byte(triggerPriorityYaml), &cfg.TriggerPriority); err != nil {
+			return nil, fmt.Errorf("failed to parse triggerPriority: %v", err)
+		}
+	}
+
 	for k, v := range configMap.Data {
 		parts := strings.Split(k, ".")
 		switch {
@@ -120,7 +273,7 @@ func ParseConfig(configMap *v1.ConfigMap, secret *v1.Secret) (*Config, error) {
 			}
 
 			cfg.Services[name] = func() (services.NotificationService, error) {
-				return services.NewService(serviceType, optsData)
+				return services.NewServiceWithSecretRefs(serviceType, optsData, refs)
 			}
 		case strings.HasPrefix(k, "trigger."):
 			name := strings.Join(parts[1:], ".")
@@ -141,6 +294,63 @@ func ParseConfig(configMap *v1.ConfigMap, secret *v1.Secret) (*Config, error) {
 	return &cfg, nil
 }
 
+// MergeConfig returns the configuration of a namespace merged with the default configuration. Templates, triggers,
+// services and service default triggers of the namespace take precedence over the default ones with the same name,
+// the default ones are inherited otherwise. Subscriptions of both configurations apply. Default triggers and delivery
+// settings of the namespace replace the default ones if set.
+func MergeConfig(defaultCfg Config, namespaceCfg Config) Config {
+	res := namespaceCfg
+	res.Services = mergeMaps(defaultCfg.Services, namespaceCfg.Services)
+	res.Triggers = mergeMaps(defaultCfg.Triggers, namespaceCfg.Triggers)
+	res.Templates = mergeMaps(defaultCfg.Templates, namespaceCfg.Templates)
+	res.ServiceDefaultTriggers = mergeMaps(defaultCfg.ServiceDefaultTriggers, namespaceCfg.ServiceDefaultTriggers)
+	res.ServiceDefaultTemplates = mergeMaps(defaultCfg.ServiceDefaultTemplates, namespaceCfg.ServiceDefaultTemplates)
+	res.ServiceMaxConcurrent = mergeMaps(defaultCfg.ServiceMaxConcurrent, namespaceCfg.ServiceMaxConcurrent)
+	res.TemplateVars = mergeMaps(defaultCfg.TemplateVars, namespaceCfg.TemplateVars)
+	res.TriggerPriority = mergeMaps(defaultCfg.TriggerPriority, namespaceCfg.TriggerPriority)
+	res.Subscriptions = append(append(subscriptions.DefaultSubscriptions{}, namespaceCfg.Subscriptions...), defaultCfg.Subscriptions...)
+	if len(res.DefaultTriggers) == 0 {
+		res.DefaultTriggers = defaultCfg.DefaultTriggers
+	}
+	if res.SendTimeout == 0 {
+		res.SendTimeout = defaultCfg.SendTimeout
+	}
+	if res.DeduplicationWindow == 0 {
+		res.DeduplicationWindow = defaultCfg.DeduplicationWindow
+	}
+	if res.MaxStateEntries == 0 {
+		res.MaxStateEntries = defaultCfg.MaxStateEntries
+	}
+	if res.MaxStateSize == 0 {
+		res.MaxStateSize = defaultCfg.MaxStateSize
+	}
+	if res.TemplateVarsKey == "" {
+		res.TemplateVarsKey = defaultCfg.TemplateVarsKey
+	}
+	if !res.SkipEmptyMessages {
+		res.SkipEmptyMessages = defaultCfg.SkipEmptyMessages
+	}
+	if !res.IncludeFailureCount {
+		res.IncludeFailureCount = defaultCfg.IncludeFailureCount
+	}
+	if !res.IncludePreviousMessage {
+		res.IncludePreviousMessage = defaultCfg.IncludePreviousMessage
+	}
+	return res
+}
+
+// mergeMaps returns a new map with the entries of both maps, the entries of the second map take precedence
+func mergeMaps[V any](first map[string]V, second map[string]V) map[string]V {
+	res := make(map[string]V, len(first)+len(second))
+	for k, v := range first {
+		res[k] = v
+	}
+	for k, v := range second {
+		res[k] = v
+	}
+	return res
+}
+
 func replaceServiceConfigSecrets(inputYaml string, secret *v1.Secret) ([]byte, error) {
 	var node yaml3.Node
 	err := yaml3.Unmarshal([]byte(inputYaml), &node)
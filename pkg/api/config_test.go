@@ -2,6 +2,7 @@ package api
 
 import (
 	"testing"
+	"time"
 
 	"github.com/argoproj/notifications-engine/pkg/services"
 	"github.com/argoproj/notifications-engine/pkg/subscriptions"
@@ -193,3 +194,312 @@ func TestParseConfig_Subscriptions(t *testing.T) {
 		{Triggers: []string{"my-trigger2"}, Selector: label},
 	}), cfg.Subscriptions)
 }
+
+func TestGetResourceGlobalDestinations_Expr(t *testing.T) {
+	cfg, err := ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"subscriptions": `
+- recipients: [slack:platform]
+  expr: obj.spec.project == 'platform'
+- recipients: [slack:labeled]
+  selector: test=true
+- recipients: [slack:labeled-platform]
+  selector: test=true
+  expr: obj.spec.project == 'platform'`,
+			"defaultTriggers": `[on-sync-succeeded]`,
+		},
+	}, emptySecret)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	newObj := func(project string, labels map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "guestbook", "labels": labels},
+			"spec":     map[string]interface{}{"project": project},
+		}
+	}
+
+	assert.Equal(t, services.Destinations{"on-sync-succeeded": {
+		{Service: "slack", Recipient: "platform"},
+	}}, cfg.GetResourceGlobalDestinations(newObj("platform", nil)))
+
+	assert.Equal(t, services.Destinations{"on-sync-succeeded": {
+		{Service: "slack", Recipient: "platform"},
+		{Service: "slack", Recipient: "labeled"},
+		{Service: "slack", Recipient: "labeled-platform"},
+	}}, cfg.GetResourceGlobalDestinations(newObj("platform", map[string]interface{}{"test": "true"})))
+
+	assert.Equal(t, services.Destinations{"on-sync-succeeded": {
+		{Service: "slack", Recipient: "labeled"},
+	}}, cfg.GetResourceGlobalDestinations(newObj("default", map[string]interface{}{"test": "true"})))
+
+	// expressions cannot be evaluated without the resource
+	assert.Equal(t, services.Destinations{"on-sync-succeeded": {
+		{Service: "slack", Recipient: "labeled"},
+	}}, cfg.GetGlobalDestinations(map[string]string{"test": "true"}))
+}
+
+func TestParseConfig_SubscriptionsInvalidExpr(t *testing.T) {
+	_, err := ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"subscriptions": `
+- recipients: [slack:platform]
+  expr: obj.spec.project ==`,
+		},
+	}, emptySecret)
+	assert.Error(t, err)
+}
+
+func TestParseConfig_SendTimeout(t *testing.T) {
+	cfg, err := ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"sendTimeout": "30s",
+		},
+	}, emptySecret)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 30*time.Second, cfg.SendTimeout)
+
+	_, err = ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"sendTimeout": "soon",
+		},
+	}, emptySecret)
+	assert.Error(t, err)
+}
+
+func TestParseConfig_ServiceMaxConcurrent(t *testing.T) {
+	cfg, err := ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"serviceMaxConcurrent": "github: 1\nslack: 10",
+		},
+	}, emptySecret)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"github": 1, "slack": 10}, cfg.ServiceMaxConcurrent)
+
+	_, err = ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"serviceMaxConcurrent": "github: one",
+		},
+	}, emptySecret)
+	assert.ErrorContains(t, err, "failed to parse serviceMaxConcurrent")
+}
+
+func TestParseConfig_ServiceDefaultTemplates(t *testing.T) {
+	cfg, err := ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"serviceDefaultTemplates": "slack: slack-layout\nteams: teams-layout",
+		},
+	}, emptySecret)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"slack": "slack-layout", "teams": "teams-layout"}, cfg.ServiceDefaultTemplates)
+
+	_, err = ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"serviceDefaultTemplates": "[slack-layout]",
+		},
+	}, emptySecret)
+	assert.ErrorContains(t, err, "failed to parse serviceDefaultTemplates")
+}
+
+func TestGetServiceTemplates(t *testing.T) {
+	cfg, err := ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"template.app-synced":     `message: synced`,
+			"template.app-degraded":   "message: degraded\nslack:\n  attachments: '[]'",
+			"template.slack-layout":   "slack:\n  blocks: '[]'",
+			"serviceDefaultTemplates": "slack: slack-layout",
+		},
+	}, emptySecret)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []string{"slack-layout", "app-synced"}, cfg.GetServiceTemplates("slack", []string{"app-synced"}))
+	// templates which configure the service take precedence
+	assert.Equal(t, []string{"app-synced", "app-degraded"}, cfg.GetServiceTemplates("slack", []string{"app-synced", "app-degraded"}))
+	// services without default template are not affected
+	assert.Equal(t, []string{"app-synced"}, cfg.GetServiceTemplates("email", []string{"app-synced"}))
+}
+
+func TestParseConfig_DeduplicationWindow(t *testing.T) {
+	cfg, err := ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"deduplicationWindow": "1h",
+		},
+	}, emptySecret)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, time.Hour, cfg.DeduplicationWindow)
+}
+
+func TestParseConfig_StateLimits(t *testing.T) {
+	cfg, err := ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"maxStateEntries": "20",
+			"maxStateSize":    "4096",
+		},
+	}, emptySecret)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 20, cfg.MaxStateEntries)
+	assert.Equal(t, 4096, cfg.MaxStateSize)
+}
+
+func TestParseConfig_SkipEmptyMessages(t *testing.T) {
+	cfg, err := ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"skipEmptyMessages": "true",
+		},
+	}, emptySecret)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, cfg.SkipEmptyMessages)
+
+	_, err = ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"skipEmptyMessages": "sometimes",
+		},
+	}, emptySecret)
+	assert.ErrorContains(t, err, "failed to parse skipEmptyMessages")
+}
+
+func TestParseConfig_IncludeFailureCount(t *testing.T) {
+	cfg, err := ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"includeFailureCount": "true",
+		},
+	}, emptySecret)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, cfg.IncludeFailureCount)
+
+	_, err = ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"includeFailureCount": "maybe",
+		},
+	}, emptySecret)
+	assert.ErrorContains(t, err, "failed to parse includeFailureCount")
+}
+
+func TestParseConfig_IncludePreviousMessage(t *testing.T) {
+	cfg, err := ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"includePreviousMessage": "true",
+		},
+	}, emptySecret)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, cfg.IncludePreviousMessage)
+
+	_, err = ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"includePreviousMessage": "maybe",
+		},
+	}, emptySecret)
+	assert.ErrorContains(t, err, "failed to parse includePreviousMessage")
+}
+
+func TestParseConfig_TemplateVars(t *testing.T) {
+	cfg, err := ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"templateVars": `
+cluster: prod-eu
+labels:
+  team: platform`,
+			"templateVarsKey": "static",
+		},
+	}, emptySecret)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, map[string]interface{}{"cluster": "prod-eu", "labels": map[string]interface{}{"team": "platform"}}, cfg.TemplateVars)
+	assert.Equal(t, "static", cfg.TemplateVarsKey)
+}
+
+func TestParseConfig_TriggerPriority(t *testing.T) {
+	cfg, err := ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"triggerPriority": "on-health-degraded: 10\non-deployed: -1",
+		},
+	}, emptySecret)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, map[string]int{"on-health-degraded": 10, "on-deployed": -1}, cfg.TriggerPriority)
+
+	_, err = ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"triggerPriority": "on-health-degraded: high",
+		},
+	}, emptySecret)
+	assert.ErrorContains(t, err, "failed to parse triggerPriority")
+}
+
+func TestMergeConfig(t *testing.T) {
+	defaultCfg, err := ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"service.slack":           `{"token": "abc"}`,
+			"template.my-template":    `message: default`,
+			"template.other-template": `message: other`,
+			"trigger.my-trigger":      `[{when: "true", send: [my-template]}]`,
+			"defaultTriggers":         `[my-trigger]`,
+			"defaultTriggers.slack":   `[my-trigger]`,
+			"subscriptions":           `[{recipients: ["slack:default"]}]`,
+			"sendTimeout":             "10s",
+			"deduplicationWindow":     "1h",
+			"serviceMaxConcurrent":    "github: 1\nslack: 10",
+			"serviceDefaultTemplates": "slack: my-template",
+		},
+	}, emptySecret)
+	if !assert.NoError(t, err) {
+		return
+	}
+	namespaceCfg, err := ParseConfig(&v1.ConfigMap{
+		Data: map[string]string{
+			"service.email":        `{"username": "test"}`,
+			"template.my-template": `message: namespace`,
+			"trigger.my-trigger":   `[{when: "false", send: [my-template]}]`,
+			"subscriptions":        `[{recipients: ["email:namespace"]}]`,
+			"sendTimeout":          "5s",
+			"serviceMaxConcurrent": "slack: 5",
+		},
+	}, emptySecret)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	cfg := MergeConfig(*defaultCfg, *namespaceCfg)
+
+	assert.Len(t, cfg.Services, 2)
+	assert.Contains(t, cfg.Services, "slack")
+	assert.Contains(t, cfg.Services, "email")
+	assert.Equal(t, "namespace", cfg.Templates["my-template"].Message)
+	assert.Equal(t, "other", cfg.Templates["other-template"].Message)
+	assert.Equal(t, "false", cfg.Triggers["my-trigger"][0].When)
+	assert.Equal(t, []string{"my-trigger"}, cfg.DefaultTriggers)
+	assert.Equal(t, map[string][]string{"slack": {"my-trigger"}}, cfg.ServiceDefaultTriggers)
+	assert.Len(t, cfg.Subscriptions, 2)
+	assert.Equal(t, 5*time.Second, cfg.SendTimeout)
+	assert.Equal(t, time.Hour, cfg.DeduplicationWindow)
+	assert.Equal(t, map[string]int{"github": 1, "slack": 5}, cfg.ServiceMaxConcurrent)
+	assert.Equal(t, map[string]string{"slack": "my-template"}, cfg.ServiceDefaultTemplates)
+	// the merged configurations are not modified
+	assert.Equal(t, "default", defaultCfg.Templates["my-template"].Message)
+	assert.Len(t, namespaceCfg.Services, 1)
+}
@@ -1,12 +1,14 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	texttemplate "text/template"
 
 	log "github.com/sirupsen/logrus"
 
-	"k8s.io/utils/strings/slices"
+	"github.com/argoproj/notifications-engine/pkg/services"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -27,6 +29,12 @@ type Settings struct {
 	// For self-service notification, we get notification configurations from rollout resource namespace
 	// and also the default namespace
 	DefaultNamespace string
+	// TemplateFuncs holds additional functions available to all notification templates. The functions take
+	// precedence over built-in functions with the same name.
+	TemplateFuncs texttemplate.FuncMap
+	// SecretRefs allows the services configured in the default namespace to reference environment variables and files
+	// of the controller, see services.SecretRefs. References are never resolved in the configuration of other namespaces.
+	SecretRefs *services.SecretRefs
 }
 
 // Factory creates an API instance
@@ -87,9 +95,37 @@ func (f *apiFactory) invalidateIfHasName(name string, obj interface{}) {
 	}
 	if metaObj.GetName() == name {
 		f.lock.Lock()
-		defer f.lock.Unlock()
-		f.apiMap[metaObj.GetNamespace()] = nil
+		var invalidated []API
+		if metaObj.GetNamespace() == f.Settings.DefaultNamespace {
+			// the APIs of all namespaces inherit the default configuration
+			for _, api := range f.apiMap {
+				invalidated = append(invalidated, api)
+			}
+			f.apiMap = make(map[string]API)
+		} else {
+			invalidated = append(invalidated, f.apiMap[metaObj.GetNamespace()])
+			f.apiMap[metaObj.GetNamespace()] = nil
+		}
+		f.lock.Unlock()
 		log.Info("invalidated cache for resource in namespace: ", metaObj.GetNamespace(), " with the name: ", metaObj.GetName())
+		for _, api := range invalidated {
+			closeServices(api)
+		}
+	}
+}
+
+// closeServices closes the notification services of an API which is no longer used, so that the services release
+// their connections. Notifications which are still being sent using the API fail and are retried with the new API.
+func closeServices(api API) {
+	if api == nil {
+		return
+	}
+	for name, service := range api.GetNotificationServices() {
+		if closable, ok := service.(services.Closable); ok {
+			if err := closable.Close(); err != nil {
+				log.Warnf("Failed to close notification service %s: %v", name, err)
+			}
+		}
 	}
 }
 
@@ -133,61 +169,107 @@ func (f *apiFactory) GetAPI() (API, error) {
 	return apis[f.Settings.DefaultNamespace], nil
 }
 
-// GetAPIsFromNamespace returns a map of API instances for a given namespace, if there is an error in populating the API for a namespace, it will be skipped
-// and the error will be logged and returned. The caller is responsible for handling the error. The API map will also be returned with any successfully constructed
-// API instances.
+// GetAPIsFromNamespace returns the API for resources of the given namespace keyed by the namespace of its configuration.
+// If the namespace has its own configuration, the API uses the namespace configuration merged with the default one,
+// see MergeConfig. Otherwise, or if the API of the namespace cannot be created, the API of the default namespace is
+// returned. Errors are logged and returned along with the successfully constructed API.
 func (f *apiFactory) GetAPIsFromNamespace(namespace string) (map[string]API, error) {
 	f.lock.Lock()
 	defer f.lock.Unlock()
 
 	apis := make(map[string]API)
 
-	// namespaces to look for notification configurations
-	namespaces := []string{namespace}
-	if !slices.Contains(namespaces, f.Settings.DefaultNamespace) {
-		namespaces = append(namespaces, f.Settings.DefaultNamespace)
-	}
-
 	errors := []error{}
-	for _, namespace := range namespaces {
-		if f.apiMap[namespace] == nil {
-			api, err := f.getApiFromNamespace(namespace)
-			if err != nil {
-				log.Error("error getting api from namespace: ", namespace, " error: ", err)
-				errors = append(errors, err)
-				continue
-			}
-			f.apiMap[namespace] = api
-			apis[namespace] = f.apiMap[namespace]
-		} else {
-			apis[namespace] = f.apiMap[namespace]
+	if namespace != f.Settings.DefaultNamespace {
+		api, err := f.getCachedApi(namespace)
+		if err != nil {
+			log.Error("error getting api from namespace: ", namespace, " error: ", err)
+			errors = append(errors, err)
+		} else if api != nil {
+			apis[namespace] = api
+			return apis, nil
 		}
 	}
 
+	api, err := f.getCachedApi(f.Settings.DefaultNamespace)
+	if err != nil {
+		log.Error("error getting api from namespace: ", f.Settings.DefaultNamespace, " error: ", err)
+		errors = append(errors, err)
+	} else {
+		apis[f.Settings.DefaultNamespace] = api
+	}
+
 	if len(errors) > 0 {
 		return apis, fmt.Errorf("errors getting apis: %s", errors)
 	}
 	return apis, nil
 }
 
+// getCachedApi returns the API of the namespace, which is nil if the namespace has no configuration of its own
+func (f *apiFactory) getCachedApi(namespace string) (API, error) {
+	if api := f.apiMap[namespace]; api != nil {
+		return api, nil
+	}
+	api, err := f.getApiFromNamespace(namespace)
+	if err != nil || api == nil {
+		return nil, err
+	}
+	f.apiMap[namespace] = api
+	return api, nil
+}
+
+// ValidateServices validates the notification services configured in the default namespace, see ValidateServices
+func (f *apiFactory) ValidateServices(ctx context.Context) (map[string]error, error) {
+	api, err := f.GetAPI()
+	if err != nil {
+		return nil, err
+	}
+	return ValidateServices(ctx, api), nil
+}
+
 func (f *apiFactory) getApiFromNamespace(namespace string) (API, error) {
 	cm, secret, err := f.getConfigMapAndSecret(namespace)
 	if err != nil {
 		return nil, err
 	}
-	return f.getApiFromConfigmapAndSecret(cm, secret)
+	if namespace == f.Settings.DefaultNamespace {
+		return f.getApiFromConfigmapAndSecret(cm, secret, nil)
+	}
+	if cm.Name == "" {
+		return nil, nil
+	}
 
+	defaultCm, defaultSecret, err := f.getConfigMapAndSecret(f.Settings.DefaultNamespace)
+	if err != nil {
+		return nil, err
+	}
+	defaultCfg, err := ParseConfigWithSecretRefs(defaultCm, defaultSecret, f.SecretRefs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the configuration of the default namespace: %w", err)
+	}
+	return f.getApiFromConfigmapAndSecret(cm, secret, defaultCfg)
 }
 
-func (f *apiFactory) getApiFromConfigmapAndSecret(cm *v1.ConfigMap, secret *v1.Secret) (API, error) {
-	cfg, err := ParseConfig(cm, secret)
+// getApiFromConfigmapAndSecret creates the API from the configuration in the config map and secret. The configuration is
+// merged with the default configuration if given.
+func (f *apiFactory) getApiFromConfigmapAndSecret(cm *v1.ConfigMap, secret *v1.Secret, defaultCfg *Config) (API, error) {
+	var refs *services.SecretRefs
+	if cm.Namespace == f.Settings.DefaultNamespace {
+		refs = f.SecretRefs
+	}
+	cfg, err := ParseConfigWithSecretRefs(cm, secret, refs)
 	if err != nil {
 		return nil, err
 	}
+	if defaultCfg != nil {
+		merged := MergeConfig(*defaultCfg, *cfg)
+		cfg = &merged
+	}
 
 	if cm.Namespace != f.Settings.DefaultNamespace {
 		cfg.IsSelfServiceConfig = true
 	}
+	cfg.TemplateFuncs = f.TemplateFuncs
 	getVars, err := f.InitGetVars(cfg, cm, secret)
 	if err != nil {
 		return nil, err
@@ -71,3 +71,124 @@ func TestGetAPI(t *testing.T) {
 	assert.Len(t, svcs, 1)
 	assert.NotNil(t, svcs["email"])
 }
+
+func TestGetAPIsFromNamespace_MergesDefaultConfig(t *testing.T) {
+	defaultCm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-config-map", Namespace: "default"},
+		Data: map[string]string{
+			"service.slack":         `{"token": "abc"}`,
+			"template.app-synced":   `message: default {{.obj.name}} synced`,
+			"template.app-degraded": `message: default {{.obj.name}} degraded`,
+		},
+	}
+	tenantCm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-config-map", Namespace: "tenant"},
+		Data: map[string]string{
+			"service.email":       `{"username": "test"}`,
+			"template.app-synced": `message: tenant {{.obj.name}} synced`,
+		},
+	}
+
+	clientset := fake.NewSimpleClientset(defaultCm, tenantCm)
+	informerFactory := informers.NewSharedInformerFactory(clientset, time.Minute)
+
+	secrets := informerFactory.Core().V1().Secrets().Informer()
+	configMaps := informerFactory.Core().V1().ConfigMaps().Informer()
+	factory := NewFactory(settings, "default", secrets, configMaps)
+
+	go informerFactory.Start(context.Background().Done())
+	if !cache.WaitForCacheSync(context.Background().Done(), configMaps.HasSynced, secrets.HasSynced) {
+		assert.Fail(t, "failed to sync informers")
+	}
+
+	apis, err := factory.GetAPIsFromNamespace("tenant")
+	require.NoError(t, err)
+	require.Len(t, apis, 1)
+	api := apis["tenant"]
+	require.NotNil(t, api)
+
+	assert.True(t, api.GetConfig().IsSelfServiceConfig)
+	svcs := api.GetNotificationServices()
+	assert.Len(t, svcs, 2)
+	assert.NotNil(t, svcs["slack"])
+	assert.NotNil(t, svcs["email"])
+
+	obj := map[string]interface{}{"name": "guestbook"}
+	notification, err := api.RenderNotification("app-synced", obj)
+	require.NoError(t, err)
+	assert.Equal(t, "tenant guestbook synced", notification.Message)
+	notification, err = api.RenderNotification("app-degraded", obj)
+	require.NoError(t, err)
+	assert.Equal(t, "default guestbook degraded", notification.Message)
+
+	// namespaces without configuration use the default API
+	apis, err = factory.GetAPIsFromNamespace("other")
+	require.NoError(t, err)
+	require.Len(t, apis, 1)
+	notification, err = apis["default"].RenderNotification("app-synced", obj)
+	require.NoError(t, err)
+	assert.Equal(t, "default guestbook synced", notification.Message)
+}
+
+func TestGetAPIsFromNamespace_SecretRefsOnlyInDefaultNamespace(t *testing.T) {
+	newFactory := func(defaultData map[string]string, tenantData map[string]string) *apiFactory {
+		clientset := fake.NewSimpleClientset(
+			&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "my-config-map", Namespace: "default"}, Data: defaultData},
+			&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "my-config-map", Namespace: "tenant"}, Data: tenantData},
+		)
+		informerFactory := informers.NewSharedInformerFactory(clientset, time.Minute)
+		secrets := informerFactory.Core().V1().Secrets().Informer()
+		configMaps := informerFactory.Core().V1().ConfigMaps().Informer()
+		refsSettings := settings
+		refsSettings.SecretRefs = &services.SecretRefs{}
+		factory := NewFactory(refsSettings, "default", secrets, configMaps)
+		go informerFactory.Start(context.Background().Done())
+		if !cache.WaitForCacheSync(context.Background().Done(), configMaps.HasSynced, secrets.HasSynced) {
+			assert.Fail(t, "failed to sync informers")
+		}
+		return factory
+	}
+	missingRef := `{"token": "env://NOTIFICATIONS_TEST_MISSING"}`
+
+	// the reference of the default namespace is resolved, so the missing variable fails the service creation
+	factory := newFactory(map[string]string{"service.slack": missingRef}, nil)
+	_, err := factory.GetAPI()
+	assert.ErrorContains(t, err, "failed to resolve slack token: environment variable 'NOTIFICATIONS_TEST_MISSING' is not set")
+
+	// the reference of the tenant namespace is used as a literal value
+	factory = newFactory(nil, map[string]string{"service.slack.tenant": missingRef})
+	apis, err := factory.GetAPIsFromNamespace("tenant")
+	require.NoError(t, err)
+	assert.NotNil(t, apis["tenant"].GetNotificationServices()["tenant"])
+}
+
+type closableService struct {
+	services.NotificationService
+	closed bool
+}
+
+func (s *closableService) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestInvalidateClosesServices(t *testing.T) {
+	defaultService, tenantService := &closableService{}, &closableService{}
+	factory := &apiFactory{
+		Settings: Settings{ConfigMapName: "my-config-map", DefaultNamespace: "default"},
+		apiMap: map[string]API{
+			"default": &api{notificationServices: map[string]services.NotificationService{"kafka": defaultService}},
+			"tenant":  &api{notificationServices: map[string]services.NotificationService{"kafka": tenantService}},
+			"other":   nil,
+		},
+	}
+
+	factory.invalidateIfHasName("my-config-map", &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "my-config-map", Namespace: "tenant"}})
+	assert.True(t, tenantService.closed)
+	assert.False(t, defaultService.closed)
+
+	// the APIs of all namespaces are dropped once the default configuration changes
+	factory.invalidateIfHasName("my-config-map", &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "my-config-map", Namespace: "default"}})
+	assert.True(t, defaultService.closed)
+	assert.Empty(t, factory.apiMap)
+}
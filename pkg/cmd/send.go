@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/argoproj/notifications-engine/pkg/services"
+)
+
+func newSendCommand(cmdContext *commandContext) *cobra.Command {
+	var (
+		serviceName string
+		recipient   string
+	)
+	var command = cobra.Command{
+		Use:   "send TEMPLATE RESOURCE_NAME",
+		Short: "Sends the notification generated using the specified template to a configured notification service",
+		Example: fmt.Sprintf(`
+# Send app-sync-succeeded notification of guestbook application to my-channel using the slack service
+%s send app-sync-succeeded guestbook --service slack --recipient my-channel
+
+# Send the notification of the resource in guestbook.yaml using the services configured in my-config-map.yaml
+%s send app-sync-succeeded ./guestbook.yaml --service slack --recipient my-channel --config-map ./my-config-map.yaml`,
+			cmdContext.cliName, cmdContext.cliName),
+		RunE: func(c *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return fmt.Errorf("expected two arguments, got %d", len(args))
+			}
+			if serviceName == "" {
+				return fmt.Errorf("--service is required")
+			}
+			templateName := args[0]
+			resourceName := args[1]
+			notificationsAPI, err := cmdContext.getAPI()
+			if err != nil {
+				_, _ = fmt.Fprintf(cmdContext.stderr, "failed to get api: %v\n", err)
+				return nil
+			}
+			service, ok := notificationsAPI.GetNotificationServices()[serviceName]
+			if !ok {
+				_, _ = fmt.Fprintf(cmdContext.stderr, "notification service '%s' is not configured\n", serviceName)
+				return nil
+			}
+			res, err := cmdContext.loadResource(resourceName)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmdContext.stderr, "failed to load resource: %v\n", err)
+				return nil
+			}
+
+			dest := services.Destination{Service: serviceName, Recipient: recipient}
+			notification, err := notificationsAPI.FormatNotification(res.Object, []string{templateName}, dest)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmdContext.stderr, "failed to render template '%s': %v\n", templateName, err)
+				return nil
+			}
+			if err := service.SendContext(context.Background(), *notification, dest); err != nil {
+				_, _ = fmt.Fprintf(cmdContext.stderr, "failed to send notification to '%s:%s': %v\n", serviceName, recipient, err)
+				return nil
+			}
+			_, _ = fmt.Fprintf(cmdContext.stdout, "notification sent to '%s:%s'\n", serviceName, recipient)
+			return nil
+		},
+	}
+	command.Flags().StringVar(&serviceName, "service", "", "Name of the notification service, e.g. slack")
+	command.Flags().StringVar(&recipient, "recipient", "", "Recipient of the notification, e.g. slack channel")
+	return &command
+}
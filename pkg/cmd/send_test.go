@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSend(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		received = append(received, r.Method+" "+r.URL.Path+" "+string(data))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cmData := map[string]string{
+		"service.webhook.test": "url: " + server.URL,
+		"template.my-template": `
+webhook:
+  test:
+    method: POST
+    body: hello {{.app.metadata.name}}`,
+	}
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	ctx, closer, err := newTestContext(&stdout, &stderr, cmData, newTestResource("guestbook"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer closer()
+
+	command := newSendCommand(ctx)
+	assert.NoError(t, command.Flags().Set("service", "test"))
+	assert.NoError(t, command.Flags().Set("recipient", "my-recipient"))
+	err = command.RunE(command, []string{"my-template", "guestbook"})
+	assert.NoError(t, err)
+	assert.Empty(t, stderr.String())
+	assert.Equal(t, []string{"POST / hello guestbook"}, received)
+	assert.Equal(t, "notification sent to 'test:my-recipient'\n", stdout.String())
+}
+
+func TestSend_Failed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	cmData := map[string]string{
+		"service.webhook.test": "url: " + server.URL,
+		"template.my-template": `message: hello`,
+	}
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	ctx, closer, err := newTestContext(&stdout, &stderr, cmData, newTestResource("guestbook"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer closer()
+
+	command := newSendCommand(ctx)
+	assert.NoError(t, command.Flags().Set("service", "test"))
+	err = command.RunE(command, []string{"my-template", "guestbook"})
+	assert.NoError(t, err)
+	assert.Empty(t, stdout.String())
+	assert.Contains(t, stderr.String(), "failed to send notification to 'test:'")
+}
+
+func TestSend_ServiceNotConfigured(t *testing.T) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	ctx, closer, err := newTestContext(&stdout, &stderr, map[string]string{"template.my-template": `message: hello`}, newTestResource("guestbook"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer closer()
+
+	command := newSendCommand(ctx)
+	assert.NoError(t, command.Flags().Set("service", "slack"))
+	err = command.RunE(command, []string{"my-template", "guestbook"})
+	assert.NoError(t, err)
+	assert.Empty(t, stdout.String())
+	assert.Equal(t, "notification service 'slack' is not configured\n", stderr.String())
+}
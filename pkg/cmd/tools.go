@@ -47,6 +47,8 @@ func NewToolsCommand(name string, cliName string, resource schema.GroupVersionRe
 
 	command.AddCommand(newTriggerCommand(&cmdContext))
 	command.AddCommand(newTemplateCommand(&cmdContext))
+	command.AddCommand(newValidateCommand(&cmdContext))
+	command.AddCommand(newSendCommand(&cmdContext))
 
 	command.PersistentFlags().StringVar(&cmdContext.configMapPath,
 		"config-map", "", fmt.Sprintf("%s.yaml file path", settings.ConfigMapName))
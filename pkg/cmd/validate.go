@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/argoproj/notifications-engine/pkg/api"
+	"github.com/argoproj/notifications-engine/pkg/util/misc"
+
+	"github.com/spf13/cobra"
+)
+
+func newValidateCommand(cmdContext *commandContext) *cobra.Command {
+	var command = cobra.Command{
+		Use:   "validate",
+		Short: "Validates the configuration and credentials of the notification services",
+		Example: fmt.Sprintf(`
+# Validate the services configured in '%s' ConfigMap
+%s validate
+
+# Validate the services using my-config-map.yaml instead of '%s' ConfigMap
+%s validate --config-map ./my-config-map.yaml`, cmdContext.ConfigMapName, cmdContext.cliName, cmdContext.ConfigMapName, cmdContext.cliName),
+		RunE: func(c *cobra.Command, args []string) error {
+			notificationsAPI, err := cmdContext.getAPI()
+			if err != nil {
+				_, _ = fmt.Fprintf(cmdContext.stderr, "failed to get api: %v\n", err)
+				return nil
+			}
+			results := api.ValidateServices(context.Background(), notificationsAPI)
+
+			failed := 0
+			w := tabwriter.NewWriter(cmdContext.stdout, 5, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintf(w, "SERVICE\tRESULT\n")
+			misc.IterateStringKeyMap(results, func(name string) {
+				result := "valid"
+				if err := results[name]; err != nil {
+					result = err.Error()
+					failed++
+				}
+				_, _ = fmt.Fprintf(w, "%s\t%s\n", name, result)
+			})
+			_ = w.Flush()
+			if failed > 0 {
+				return errors.New("some services are not valid")
+			}
+			return nil
+		},
+	}
+	return &command
+}
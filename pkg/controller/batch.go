@@ -0,0 +1,269 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+// patchFieldManager is the field manager of the annotations applied using server-side apply
+const patchFieldManager = "notifications-engine"
+
+// WithBatchedPatches configures the controller to buffer the annotation patches of resources and to send them every
+// interval, or as soon as the patches of maxBatch resources are buffered. Patches of the same resource are coalesced,
+// so processing a resource repeatedly within the interval results in a single API call. Until its patch is sent, the
+// resource is processed with the buffered annotations. Patches which are still buffered when the process exits are
+// lost, in which case the notifications might be sent again.
+func WithBatchedPatches(interval time.Duration, maxBatch int) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.patches = &patchBatcher{
+			interval: interval,
+			maxBatch: maxBatch,
+			full:     make(chan struct{}, 1),
+			pending:  map[string]*pendingPatch{},
+		}
+	}
+}
+
+// pendingPatch holds the annotation changes of a resource. Nil values remove the annotation.
+type pendingPatch struct {
+	apiVersion  string
+	kind        string
+	namespace   string
+	name        string
+	annotations map[string]*string
+	// version is incremented whenever the patch is coalesced with a newer one
+	version int
+}
+
+type patchBatcher struct {
+	interval time.Duration
+	maxBatch int
+	// full is signalled once maxBatch resources have buffered patches
+	full chan struct{}
+
+	lock    sync.Mutex
+	pending map[string]*pendingPatch
+	// flushLock prevents concurrent flushes from sending the same patch
+	flushLock sync.Mutex
+}
+
+// add buffers the annotation changes of the resource, overriding the buffered changes of the same annotations
+func (b *patchBatcher) add(key string, resource v1.Object, annotations map[string]*string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	p, ok := b.pending[key]
+	if !ok {
+		p = &pendingPatch{namespace: resource.GetNamespace(), name: resource.GetName(), annotations: map[string]*string{}}
+		if obj, ok := resource.(runtime.Object); ok {
+			p.apiVersion, p.kind = obj.GetObjectKind().GroupVersionKind().ToAPIVersionAndKind()
+		}
+		b.pending[key] = p
+	}
+	for k, v := range annotations {
+		p.annotations[k] = v
+	}
+	p.version++
+	if b.maxBatch > 0 && len(b.pending) >= b.maxBatch {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// overlay returns a copy of the resource with the buffered annotation changes applied, or the resource itself if
+// none are buffered
+func (b *patchBatcher) overlay(resource v1.Object) v1.Object {
+	if b == nil {
+		return resource
+	}
+	key, err := cache.MetaNamespaceKeyFunc(resource)
+	if err != nil {
+		return resource
+	}
+	obj, ok := resource.(runtime.Object)
+	if !ok {
+		return resource
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	p, ok := b.pending[key]
+	if !ok {
+		return resource
+	}
+	res := obj.DeepCopyObject().(v1.Object)
+	annotations := res.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	for k, v := range p.annotations {
+		if v == nil {
+			delete(annotations, k)
+		} else {
+			annotations[k] = *v
+		}
+	}
+	res.SetAnnotations(annotations)
+	return res
+}
+
+// take returns copies of the buffered patches
+func (b *patchBatcher) take() map[string]pendingPatch {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	res := make(map[string]pendingPatch, len(b.pending))
+	for key, p := range b.pending {
+		patch := *p
+		patch.annotations = make(map[string]*string, len(p.annotations))
+		for k, v := range p.annotations {
+			patch.annotations[k] = v
+		}
+		res[key] = patch
+	}
+	return res
+}
+
+// done drops the buffered patch of the resource unless it was coalesced with a newer one since it was taken
+func (b *patchBatcher) done(key string, version int) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if p, ok := b.pending[key]; ok && p.version == version {
+		delete(b.pending, key)
+	}
+}
+
+// batchPatch buffers the changes between the annotations of the resource and the given annotations
+func (c *notificationController) batchPatch(resource v1.Object, annotations map[string]string, logEntry *log.Entry) {
+	key, err := cache.MetaNamespaceKeyFunc(resource)
+	if err != nil {
+		logEntry.Errorf("Failed to get resource key: %v", err)
+		return
+	}
+	current := resource.GetAnnotations()
+	changes := map[string]*string{}
+	for k, v := range annotations {
+		if old, ok := current[k]; !ok || old != v {
+			v := v
+			changes[k] = &v
+		}
+	}
+	for k := range current {
+		if _, ok := annotations[k]; !ok {
+			changes[k] = nil
+		}
+	}
+	// the annotations of the notified state are applied together, since server-side apply removes the annotations
+	// which the controller applied before but omits
+	if len(changes) > 0 {
+		for _, k := range c.stateAnnotationKeys() {
+			if v, ok := annotations[k]; ok && changes[k] == nil {
+				v := v
+				changes[k] = &v
+			}
+		}
+	}
+	c.patches.add(key, resource, changes)
+}
+
+// runPatchBatcher sends the buffered patches every interval, or once the batch is full, until the stop channel is
+// closed. The remaining patches are sent before it returns.
+func (c *notificationController) runPatchBatcher(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(c.patches.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			c.flushPatches()
+			return
+		case <-ticker.C:
+			c.flushPatches()
+		case <-c.patches.full:
+			c.flushPatches()
+		}
+	}
+}
+
+// flushPatches sends the buffered patches. Patches which fail are sent again with the next flush.
+func (c *notificationController) flushPatches() {
+	c.patches.flushLock.Lock()
+	defer c.patches.flushLock.Unlock()
+	for key, p := range c.patches.take() {
+		logEntry := c.logger.WithField("resource", key)
+		resource, err := c.sendPatch(p)
+		if apierrors.IsNotFound(err) {
+			logEntry.Debugf("Skipped patch of deleted resource: %v", err)
+			c.patches.done(key, p.version)
+			continue
+		}
+		if err != nil {
+			logEntry.Errorf("Failed to patch resource: %v", err)
+			continue
+		}
+		// the informer is updated before the patch is dropped, so the resource is never processed without it
+		if err := c.informer.GetStore().Update(resource); err != nil {
+			logEntry.Warnf("Failed to store update resource in informer: %v", err)
+		}
+		c.patches.done(key, p.version)
+	}
+}
+
+// stateAnnotationKeys returns the keys of the annotations which hold the notified state
+func (c *notificationController) stateAnnotationKeys() []string {
+	return []string{c.subscriptionOpts.NotifiedAnnotationKey(), c.subscriptionOpts.NotifiedDataAnnotationKey()}
+}
+
+// isStateApply returns true if the changes set the notified state annotations only
+func (c *notificationController) isStateApply(annotations map[string]*string) bool {
+	if len(annotations) == 0 {
+		return false
+	}
+	for k, v := range annotations {
+		if v == nil || !slices.Contains(c.stateAnnotationKeys(), k) {
+			return false
+		}
+	}
+	return true
+}
+
+// sendPatch sends the notified state annotations using server-side apply, so that the controller manages only these
+// annotations. Other changes, e.g. removed annotations, are sent as a merge patch.
+func (c *notificationController) sendPatch(p pendingPatch) (*unstructured.Unstructured, error) {
+	client := c.client.Namespace(p.namespace)
+	if p.kind != "" && c.isStateApply(p.annotations) {
+		metadata := map[string]interface{}{"name": p.name, "annotations": p.annotations}
+		if p.namespace != "" {
+			metadata["namespace"] = p.namespace
+		}
+		data, err := json.Marshal(map[string]interface{}{
+			"apiVersion": p.apiVersion,
+			"kind":       p.kind,
+			"metadata":   metadata,
+		})
+		if err != nil {
+			return nil, err
+		}
+		force := true
+		res, err := client.Patch(context.Background(), p.name, types.ApplyPatchType, data, v1.PatchOptions{FieldManager: patchFieldManager, Force: &force})
+		if !apierrors.IsUnsupportedMediaType(err) {
+			return res, err
+		}
+	}
+	data, err := json.Marshal(map[string]map[string]interface{}{
+		"metadata": {"annotations": p.annotations},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return client.Patch(context.Background(), p.name, types.MergePatchType, data, v1.PatchOptions{})
+}
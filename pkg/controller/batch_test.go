@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubetesting "k8s.io/client-go/testing"
+
+	notificationApi "github.com/argoproj/notifications-engine/pkg/api"
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/argoproj/notifications-engine/pkg/subscriptions"
+	"github.com/argoproj/notifications-engine/pkg/triggers"
+)
+
+func TestWithBatchedPatches(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	app := newResource("test", withAnnotations(map[string]string{
+		subscriptions.SubscribeAnnotationKey("my-trigger", "mock"): "recipient",
+	}))
+
+	var lock sync.Mutex
+	var patches []kubetesting.PatchAction
+	client := newFakeClient(app)
+	client.PrependReactor("patch", "*", func(action kubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		patch := action.(kubetesting.PatchAction)
+		lock.Lock()
+		patches = append(patches, patch)
+		lock.Unlock()
+		if patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		// the fake client does not support server-side apply
+		res := app.DeepCopy()
+		var applied unstructured.Unstructured
+		if err := json.Unmarshal(patch.GetPatch(), &applied.Object); err != nil {
+			return true, nil, err
+		}
+		annotations := res.GetAnnotations()
+		for k, v := range applied.GetAnnotations() {
+			annotations[k] = v
+		}
+		res.SetAnnotations(annotations)
+		return true, res, nil
+	})
+	ctrl, api, err := newController(t, ctx, client, WithBatchedPatches(time.Hour, 100))
+	assert.NoError(t, err)
+
+	const processings = 5
+	var conditions int
+	api.EXPECT().GetConfig().Return(notificationApi.Config{}).AnyTimes()
+	api.EXPECT().RunTrigger("my-trigger", gomock.Any()).DoAndReturn(func(trigger string, vars map[string]interface{}) ([]triggers.ConditionResult, error) {
+		// the last processing triggers the same condition again, which is already notified
+		if conditions < processings-1 {
+			conditions++
+		}
+		return []triggers.ConditionResult{{Triggered: true, Templates: []string{"test"}, Key: fmt.Sprintf("[0].%d", conditions)}}, nil
+	}).Times(processings)
+	api.EXPECT().SendContext(gomock.Any(), gomock.Any(), []string{"test"}, services.Destination{Service: "mock", Recipient: "recipient"}).Return(nil).Times(processings - 1)
+
+	for i := 0; i < processings; i++ {
+		// the informer holds the resource without the buffered annotations
+		eventSequence := NotificationEventSequence{}
+		ctrl.processResource(api, app, logEntry, &eventSequence)
+		assert.Empty(t, eventSequence.Errors)
+		assert.Empty(t, eventSequence.Warnings)
+	}
+	assert.Empty(t, patches)
+
+	ctrl.flushPatches()
+
+	if assert.Len(t, patches, 1) {
+		assert.Equal(t, types.ApplyPatchType, patches[0].GetPatchType())
+		var applied unstructured.Unstructured
+		assert.NoError(t, json.Unmarshal(patches[0].GetPatch(), &applied.Object))
+		assert.Len(t, NewState(applied.GetAnnotations()[notifiedAnnotationKey]), processings-1)
+	}
+	assert.Empty(t, ctrl.patches.pending)
+
+	obj, exists, err := ctrl.informer.GetStore().Get(app)
+	if assert.NoError(t, err) && assert.True(t, exists) {
+		assert.Len(t, NewState(obj.(*unstructured.Unstructured).GetAnnotations()[notifiedAnnotationKey]), processings-1)
+	}
+}
+
+func TestWithBatchedPatches_RemovedAnnotations(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	state := NotificationsState{}
+	_ = state.SetAlreadyNotified(false, "", "my-trigger", triggers.ConditionResult{}, services.Destination{Service: "mock", Recipient: "recipient"}, true)
+	app := newResource("test", withAnnotations(map[string]string{
+		subscriptions.SubscribeAnnotationKey("my-trigger", "mock"): "recipient",
+		notifiedAnnotationKey: mustToJson(state),
+	}))
+
+	patchCh := make(chan kubetesting.PatchAction, 1)
+	client := newFakeClient(app)
+	client.PrependReactor("patch", "*", func(action kubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		patchCh <- action.(kubetesting.PatchAction)
+		return true, app, nil
+	})
+	ctrl, api, err := newController(t, ctx, client, WithBatchedPatches(time.Hour, 1))
+	assert.NoError(t, err)
+	api.EXPECT().GetConfig().Return(notificationApi.Config{}).AnyTimes()
+	api.EXPECT().RunTrigger("my-trigger", gomock.Any()).Return([]triggers.ConditionResult{{Triggered: false}}, nil)
+
+	go ctrl.Run(1, ctx.Done())
+
+	// the batch of a single resource is full, so the patch is sent right away
+	select {
+	case <-time.After(time.Second * 5):
+		t.Error("application was not patched")
+	case patch := <-patchCh:
+		assert.Equal(t, types.MergePatchType, patch.GetPatchType())
+		assert.JSONEq(t, fmt.Sprintf(`{"metadata": {"annotations": {%q: null}}}`, notifiedAnnotationKey), string(patch.GetPatch()))
+	}
+}
+
+func TestWithBatchedPatches_AppliesStateAnnotationsTogether(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	app := newResource("test", withAnnotations(map[string]string{
+		notifiedAnnotationKey:     `{"my-trigger::mock:recipient":1}`,
+		notifiedDataAnnotationKey: `{"failures:1:my-trigger:":1}`,
+	}))
+	ctrl, _, err := newController(t, ctx, newFakeClient(app), WithBatchedPatches(time.Hour, 100))
+	assert.NoError(t, err)
+
+	ctrl.batchPatch(app, map[string]string{
+		notifiedAnnotationKey:     `{"my-trigger::mock:recipient":2}`,
+		notifiedDataAnnotationKey: `{"failures:1:my-trigger:":1}`,
+	}, logEntry)
+
+	// the unchanged notified data is applied as well, so that server-side apply does not remove it
+	patch := ctrl.patches.take()["default/test"]
+	assert.True(t, ctrl.isStateApply(patch.annotations))
+	if assert.Len(t, patch.annotations, 2) {
+		assert.Equal(t, `{"failures:1:my-trigger:":1}`, *patch.annotations[notifiedDataAnnotationKey])
+	}
+}
@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"github.com/argoproj/notifications-engine/pkg/services"
+)
+
+const (
+	circuitClosed   = "closed"
+	circuitOpen     = "open"
+	circuitHalfOpen = "half-open"
+)
+
+// circuitBreaker stops deliveries to a destination after a number of consecutive failures. Once the cooldown
+// has passed, a single probe delivery is allowed which closes the circuit if it succeeds or opens it again otherwise.
+type circuitBreaker struct {
+	failures      int
+	cooldown      time.Duration
+	onStateChange func(dest services.Destination, state string)
+
+	lock     sync.Mutex
+	circuits map[services.Destination]*circuit
+}
+
+type circuit struct {
+	state               string
+	consecutiveFailures int
+	openedAt            time.Time
+}
+
+func newCircuitBreaker(failures int, cooldown time.Duration, onStateChange func(dest services.Destination, state string)) *circuitBreaker {
+	return &circuitBreaker{
+		failures:      failures,
+		cooldown:      cooldown,
+		onStateChange: onStateChange,
+		circuits:      map[services.Destination]*circuit{},
+	}
+}
+
+func (b *circuitBreaker) getCircuit(dest services.Destination) *circuit {
+	c, ok := b.circuits[dest]
+	if !ok {
+		c = &circuit{state: circuitClosed}
+		b.circuits[dest] = c
+	}
+	return c
+}
+
+func (b *circuitBreaker) setState(dest services.Destination, c *circuit, state string) {
+	c.state = state
+	if b.onStateChange != nil {
+		b.onStateChange(dest, state)
+	}
+}
+
+// allow returns true if a delivery to the destination may be attempted. It returns the time the circuit
+// allows the next probe otherwise.
+func (b *circuitBreaker) allow(dest services.Destination, now time.Time) (bool, time.Time) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	c := b.getCircuit(dest)
+	switch c.state {
+	case circuitOpen:
+		if probeAt := c.openedAt.Add(b.cooldown); now.Before(probeAt) {
+			return false, probeAt
+		}
+		b.setState(dest, c, circuitHalfOpen)
+		return true, time.Time{}
+	case circuitHalfOpen:
+		// the probe is still in flight
+		return false, c.openedAt.Add(b.cooldown)
+	}
+	return true, time.Time{}
+}
+
+// record updates the circuit of the destination with the result of a delivery
+func (b *circuitBreaker) record(dest services.Destination, succeeded bool, now time.Time) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	c := b.getCircuit(dest)
+	if succeeded {
+		c.consecutiveFailures = 0
+		if c.state != circuitClosed {
+			b.setState(dest, c, circuitClosed)
+		}
+		return
+	}
+	c.consecutiveFailures++
+	if c.state == circuitHalfOpen || c.consecutiveFailures >= b.failures {
+		c.openedAt = now
+		if c.state != circuitOpen {
+			b.setState(dest, c, circuitOpen)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/argoproj/notifications-engine/pkg/services"
+)
+
+func TestCircuitBreaker_States(t *testing.T) {
+	var states []string
+	breaker := newCircuitBreaker(2, time.Minute, func(_ services.Destination, state string) {
+		states = append(states, state)
+	})
+	dest := services.Destination{Service: "slack", Recipient: "channel"}
+	other := services.Destination{Service: "slack", Recipient: "other-channel"}
+	now := time.Date(2024, 1, 2, 2, 30, 0, 0, time.UTC)
+
+	breaker.record(dest, false, now)
+	allowed, _ := breaker.allow(dest, now)
+	assert.True(t, allowed)
+
+	breaker.record(dest, false, now)
+	allowed, probeAt := breaker.allow(dest, now)
+	assert.False(t, allowed)
+	assert.Equal(t, now.Add(time.Minute), probeAt)
+
+	// circuits are kept per recipient
+	allowed, _ = breaker.allow(other, now)
+	assert.True(t, allowed)
+
+	// only a single probe is allowed
+	now = now.Add(time.Minute)
+	allowed, _ = breaker.allow(dest, now)
+	assert.True(t, allowed)
+	allowed, _ = breaker.allow(dest, now)
+	assert.False(t, allowed)
+
+	// a failed probe opens the circuit again
+	breaker.record(dest, false, now)
+	allowed, _ = breaker.allow(dest, now)
+	assert.False(t, allowed)
+
+	now = now.Add(time.Minute)
+	allowed, _ = breaker.allow(dest, now)
+	assert.True(t, allowed)
+	breaker.record(dest, true, now)
+	allowed, _ = breaker.allow(dest, now)
+	assert.True(t, allowed)
+
+	assert.Equal(t, []string{circuitOpen, circuitHalfOpen, circuitOpen, circuitHalfOpen, circuitClosed}, states)
+}
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// serviceSemaphores limits the number of concurrent deliveries per notification service. The zero value is ready
+// to use.
+type serviceSemaphores struct {
+	lock       sync.Mutex
+	semaphores map[string]chan struct{}
+}
+
+// acquire blocks until fewer than limit deliveries to the service are in flight or the context is done. The returned
+// function must be called once the delivery completes.
+func (s *serviceSemaphores) acquire(ctx context.Context, service string, limit int) (func(), error) {
+	s.lock.Lock()
+	if s.semaphores == nil {
+		s.semaphores = map[string]chan struct{}{}
+	}
+	semaphore, ok := s.semaphores[service]
+	// the limit of the service might change once the configuration is reloaded
+	if !ok || cap(semaphore) != limit {
+		semaphore = make(chan struct{}, limit)
+		s.semaphores[service] = semaphore
+	}
+	s.lock.Unlock()
+
+	select {
+	case semaphore <- struct{}{}:
+		return func() { <-semaphore }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// limitConcurrency returns a send function which waits until fewer than limit deliveries to the service are in
+// flight. Non-positive limits don't restrict the concurrency.
+func (c *notificationController) limitConcurrency(send func(ctx context.Context) error, service string, limit int) func(ctx context.Context) error {
+	if limit <= 0 {
+		return send
+	}
+	return func(ctx context.Context) error {
+		release, err := c.semaphores.acquire(ctx, service, limit)
+		if err != nil {
+			return fmt.Errorf("concurrency limit wait aborted: %w", err)
+		}
+		defer release()
+		return send(ctx)
+	}
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	notificationApi "github.com/argoproj/notifications-engine/pkg/api"
+	"github.com/argoproj/notifications-engine/pkg/services"
+)
+
+func TestServiceMaxConcurrent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, _, err := newController(t, ctx, newFakeClient(), WithServiceMaxConcurrent(map[string]int{"github": 1}))
+	assert.NoError(t, err)
+
+	inFlight := map[string]*int32{"github": new(int32), "slack": new(int32)}
+	maxInFlight := map[string]*int32{"github": new(int32), "slack": new(int32)}
+	// deliveries to slack block until both of them are in flight, so they complete only if they run in parallel
+	slackStarted := sync.WaitGroup{}
+	slackStarted.Add(2)
+	send := func(service string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			current := atomic.AddInt32(inFlight[service], 1)
+			defer atomic.AddInt32(inFlight[service], -1)
+			for {
+				highest := atomic.LoadInt32(maxInFlight[service])
+				if current <= highest || atomic.CompareAndSwapInt32(maxInFlight[service], highest, current) {
+					break
+				}
+			}
+			if service == "slack" {
+				slackStarted.Done()
+				slackStarted.Wait()
+			} else {
+				time.Sleep(20 * time.Millisecond)
+			}
+			return nil
+		}
+	}
+
+	cfg := notificationApi.Config{}
+	var wg sync.WaitGroup
+	for _, service := range []string{"github", "github", "github", "slack", "slack"} {
+		wg.Add(1)
+		go func(service string) {
+			defer wg.Done()
+			limited := ctrl.limitConcurrency(send(service), service, ctrl.getServiceMaxConcurrent(cfg, service))
+			assert.NoError(t, ctrl.sendWithTimeout(context.Background(), limited, services.Destination{Service: service}, time.Second))
+		}(service)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(maxInFlight["github"]))
+	assert.Equal(t, int32(2), atomic.LoadInt32(maxInFlight["slack"]))
+}
+
+func TestServiceMaxConcurrent_ConfigOverridesOpts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, _, err := newController(t, ctx, newFakeClient(), WithServiceMaxConcurrent(map[string]int{"github": 1, "slack": 2}))
+	assert.NoError(t, err)
+
+	cfg := notificationApi.Config{ServiceMaxConcurrent: map[string]int{"github": 3}}
+	assert.Equal(t, 3, ctrl.getServiceMaxConcurrent(cfg, "github"))
+	assert.Equal(t, 2, ctrl.getServiceMaxConcurrent(cfg, "slack"))
+	assert.Equal(t, 0, ctrl.getServiceMaxConcurrent(cfg, "email"))
+}
+
+func TestServiceMaxConcurrent_WaitAborted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, _, err := newController(t, ctx, newFakeClient())
+	assert.NoError(t, err)
+
+	release, err := ctrl.semaphores.acquire(ctx, "github", 1)
+	assert.NoError(t, err)
+	defer release()
+
+	limited := ctrl.limitConcurrency(func(ctx context.Context) error {
+		t.Error("notification must not be sent while the limit is reached")
+		return nil
+	}, "github", 1)
+	err = ctrl.sendWithTimeout(context.Background(), limited, services.Destination{Service: "github"}, 10*time.Millisecond)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
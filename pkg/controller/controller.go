@@ -3,15 +3,22 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand"
 	"reflect"
 	"runtime/debug"
+	"sort"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"golang.org/x/time/rate"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
 	runtimeutil "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic"
@@ -21,6 +28,8 @@ import (
 	"github.com/argoproj/notifications-engine/pkg/api"
 	"github.com/argoproj/notifications-engine/pkg/services"
 	"github.com/argoproj/notifications-engine/pkg/subscriptions"
+	"github.com/argoproj/notifications-engine/pkg/triggers"
+	"github.com/argoproj/notifications-engine/pkg/util/redact"
 )
 
 // NotificationDelivery represents a notification that was delivered
@@ -31,6 +40,21 @@ type NotificationDelivery struct {
 	Destination services.Destination
 	// AlreadyNotified indicates that this notification was already delivered in a previous iteration
 	AlreadyNotified bool
+	// DryRun indicates that the notification was not sent because the controller runs in dry-run mode
+	DryRun bool
+	// Suppressed indicates that the notification was not sent because it falls in a suppression window.
+	// It is delivered once the window ends.
+	Suppressed bool
+	// Cancelled indicates that the notification was not sent because a previous delivery of the trigger failed
+	// and the controller fails fast. It is delivered once the resource is processed again.
+	Cancelled bool
+	// Error is the error which occurred while delivering the notification, if any
+	Error error
+	// CorrelationID identifies the delivery in the controller logs
+	CorrelationID string
+	// Empty indicates that the notification was not sent because it rendered neither a message nor service
+	// specific content and the configuration skips empty messages
+	Empty bool
 }
 
 // NotificationEventSequence represents a sequence of events that occurred while
@@ -46,6 +70,8 @@ type NotificationEventSequence struct {
 	Errors []error
 	// Warnings is a list of warnings that occurred during the processing iteration
 	Warnings []error
+	// SkipReason is the reason the processing of the resource was skipped, if it was
+	SkipReason string
 }
 
 func (s *NotificationEventSequence) addDelivered(event NotificationDelivery) {
@@ -62,6 +88,9 @@ func (s *NotificationEventSequence) addWarning(warn error) {
 
 type NotificationController interface {
 	Run(threadiness int, stopCh <-chan struct{})
+	// SendToDestinations sends the notification rendered from the templates to the destinations without evaluating
+	// triggers or updating the notified state of the resource
+	SendToDestinations(obj map[string]interface{}, templates []string, dests services.Destinations) []NotificationDelivery
 }
 
 type Opts func(ctrl *notificationController)
@@ -84,12 +113,158 @@ func WithAlterDestinations(f func(obj v1.Object, destinations services.Destinati
 	}
 }
 
+// WithDynamicRecipients resolves recipients of the service from the resource, e.g. a channel stored in an annotation.
+// The expression is evaluated with the resource available as obj and must return a recipient or a list of
+// recipients. The recipients are added to every trigger the resource is subscribed to using the service, an empty
+// result adds nothing. Invalid expressions are ignored.
+func WithDynamicRecipients(service string, expr string) Opts {
+	return func(ctrl *notificationController) {
+		recipients, err := newDynamicRecipients(service, expr)
+		if err != nil {
+			log.Errorf("Ignoring dynamic recipients of service '%s': %v", service, err)
+			return
+		}
+		ctrl.dynamicRecipients = append(ctrl.dynamicRecipients, recipients)
+	}
+}
+
 func WithSkipProcessing(f func(obj v1.Object) (bool, string)) Opts {
 	return func(ctrl *notificationController) {
 		ctrl.skipProcessing = f
 	}
 }
 
+// WithAnnotationPrefix configures the prefix of the subscription and notified state annotations of the
+// controller, so that several controllers in a process can use different prefixes.
+func WithAnnotationPrefix(prefix string) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.subscriptionOpts = subscriptions.Options{AnnotationPrefix: prefix}
+	}
+}
+
+// WithRetryPolicy configures the controller to retry failed deliveries up to maxRetries times
+// using exponential backoff with jitter, starting with baseDelay.
+func WithRetryPolicy(maxRetries int, baseDelay time.Duration) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.maxRetries = maxRetries
+		ctrl.retryBaseDelay = baseDelay
+	}
+}
+
+// WithSendTimeout bounds the time a single delivery attempt may take. A timeout configured
+// in the notifications config takes precedence over this value.
+func WithSendTimeout(timeout time.Duration) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.sendTimeout = timeout
+	}
+}
+
+// WithProcessTimeout bounds the time spent processing a single resource. Once the timeout expires, in-flight deliveries
+// are cancelled, the remaining notifications of the resource are not attempted and the resource is requeued using the
+// rate limiter of the work queue. Zero, the default, means no timeout.
+func WithProcessTimeout(timeout time.Duration) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.processTimeout = timeout
+	}
+}
+
+// WithServiceMaxConcurrent limits the number of deliveries to a notification service which are in flight at the same
+// time across all workers, e.g. to serialize deliveries to a service that doesn't tolerate concurrent requests.
+// A limit configured in the notifications config takes precedence over this value.
+func WithServiceMaxConcurrent(limits map[string]int) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.maxConcurrent = limits
+	}
+}
+
+// WithServiceRateLimits limits the rate of deliveries per notification service. Deliveries exceeding
+// the limit wait until they are allowed instead of failing.
+func WithServiceRateLimits(limits map[string]rate.Limit) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.rateLimiters = map[string]*rate.Limiter{}
+		for service, limit := range limits {
+			ctrl.rateLimiters[service] = rate.NewLimiter(limit, 1)
+		}
+	}
+}
+
+// WithCircuitBreaker stops deliveries to a destination after the given number of consecutive failures. Once the
+// cooldown has passed, a single delivery is attempted which resumes deliveries if it succeeds.
+func WithCircuitBreaker(failures int, cooldown time.Duration) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.circuitBreaker = newCircuitBreaker(failures, cooldown, func(dest services.Destination, state string) {
+			ctrl.metricsRegistry.IncCircuitBreakerTransitionsCounter(dest.Service, state)
+		})
+	}
+}
+
+// WithDryRun configures the controller to record the notifications it would deliver without sending them
+// and without persisting the notified state.
+func WithDryRun(dryRun bool) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.dryRun = dryRun
+	}
+}
+
+// WithSuppressionWindows suppresses notifications while the current time falls in one of the given windows.
+// Suppressed notifications are not marked as notified, so they are delivered once the window ends.
+// Windows with an invalid schedule or duration are ignored.
+func WithSuppressionWindows(windows []SuppressionWindow) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.suppressionWindows = nil
+		for _, window := range windows {
+			w, err := newSuppressionWindow(window)
+			if err != nil {
+				log.Errorf("Ignoring invalid suppression window: %v", err)
+				continue
+			}
+			ctrl.suppressionWindows = append(ctrl.suppressionWindows, w)
+		}
+	}
+}
+
+// WithFailFast configures the controller to stop delivering the notifications of a trigger once one of its deliveries
+// failed. The remaining notifications are recorded as cancelled and are not marked as notified, so they are delivered
+// once the resource is processed again. By default, every notification is attempted.
+func WithFailFast(failFast bool) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.failFast = failFast
+	}
+}
+
+// WithBeforeSend registers a hook which is invoked with the formatted notification right before it is sent to the
+// destination. The hook may modify the notification, which is scoped to the single delivery. Returning an error
+// aborts the delivery to that destination. The correlation ID of the delivery is passed to the hook, e.g. to be
+// added to the outgoing request.
+func WithBeforeSend(f func(n *services.Notification, dest services.Destination, correlationID string) error) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.beforeSend = f
+	}
+}
+
+// WithDeliverySink registers a sink that is notified about the outcome of every delivery as it happens.
+func WithDeliverySink(sink DeliverySink) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.deliverySink = sink
+	}
+}
+
+// WithLogger configures the logger of the controller, e.g. to log in JSON format or to install a redaction hook
+// which masks credentials, see redact.NewHook. The standard logger is used by default.
+func WithLogger(logger *log.Logger) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.logger = logger
+	}
+}
+
+// WithTracerProvider enables tracing of the notification flow. The controller starts a span for every processed
+// resource and a child span for every delivery to a destination.
+func WithTracerProvider(provider trace.TracerProvider) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.tracer = provider.Tracer(tracerName)
+	}
+}
+
 // WithEventCallback registers a callback to invoke when an object has been
 // processed for notifications.
 func WithEventCallback(f func(eventSequence NotificationEventSequence)) Opts {
@@ -104,30 +279,16 @@ func NewController(
 	apiFactory api.Factory,
 	opts ...Opts,
 ) *notificationController {
-	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
-	informer.AddEventHandler(
-		cache.ResourceEventHandlerFuncs{
-			AddFunc: func(obj interface{}) {
-				key, err := cache.MetaNamespaceKeyFunc(obj)
-				if err == nil {
-					queue.Add(key)
-				}
-			},
-			UpdateFunc: func(old, new interface{}) {
-				key, err := cache.MetaNamespaceKeyFunc(new)
-				if err == nil {
-					queue.Add(key)
-				}
-			},
-		},
-	)
-
 	ctrl := &notificationController{
 		client:          client,
 		informer:        informer,
-		queue:           queue,
 		metricsRegistry: NewMetricsRegistry(""),
 		apiFactory:      apiFactory,
+		rateLimiter:     workqueue.DefaultControllerRateLimiter(),
+		ctx:             context.Background(),
+		logger:          log.StandardLogger(),
+		tracer:          noop.NewTracerProvider().Tracer(tracerName),
+		now:             time.Now,
 		toUnstructured: func(obj v1.Object) (*unstructured.Unstructured, error) {
 			res, ok := obj.(*unstructured.Unstructured)
 			if !ok {
@@ -139,6 +300,42 @@ func NewController(
 	for i := range opts {
 		opts[i](ctrl)
 	}
+
+	queue := newInstrumentedQueue(workqueue.NewNamedRateLimitingQueue(ctrl.rateLimiter, ctrl.queueName), ctrl.queueName, ctrl.metricsRegistry)
+	ctrl.queue = queue
+	informer.AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				key, err := cache.MetaNamespaceKeyFunc(obj)
+				if err == nil {
+					queue.Add(key)
+				}
+			},
+			UpdateFunc: func(old, new interface{}) {
+				key, err := cache.MetaNamespaceKeyFunc(new)
+				if err == nil {
+					queue.Add(key)
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				if ctrl.stateless != nil {
+					ctrl.stateless.forget(obj)
+				}
+				if ctrl.skipUnchanged != nil {
+					if key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+						ctrl.skipUnchanged.forget(key)
+					}
+				}
+				if ctrl.versions != nil {
+					if key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+						if err := ctrl.versions.Delete(key); err != nil {
+							ctrl.logger.WithField("resource", key).Warnf("Failed to delete processed resource version: %v", err)
+						}
+					}
+				}
+			},
+		},
+	)
 	return ctrl
 }
 
@@ -155,31 +352,66 @@ func NewControllerWithNamespaceSupport(
 }
 
 type notificationController struct {
-	client            dynamic.NamespaceableResourceInterface
-	informer          cache.SharedIndexInformer
-	queue             workqueue.RateLimitingInterface
-	apiFactory        api.Factory
-	metricsRegistry   *MetricsRegistry
-	skipProcessing    func(obj v1.Object) (bool, string)
-	alterDestinations func(obj v1.Object, destinations services.Destinations, cfg api.Config) services.Destinations
-	toUnstructured    func(obj v1.Object) (*unstructured.Unstructured, error)
-	eventCallback     func(eventSequence NotificationEventSequence)
-	namespaceSupport  bool
+	client             dynamic.NamespaceableResourceInterface
+	informer           cache.SharedIndexInformer
+	queue              workqueue.RateLimitingInterface
+	rateLimiter        workqueue.RateLimiter
+	queueName          string
+	apiFactory         api.Factory
+	metricsRegistry    *MetricsRegistry
+	skipProcessing     func(obj v1.Object) (bool, string)
+	alterDestinations  func(obj v1.Object, destinations services.Destinations, cfg api.Config) services.Destinations
+	dynamicRecipients  []*dynamicRecipients
+	toUnstructured     func(obj v1.Object) (*unstructured.Unstructured, error)
+	eventCallback      func(eventSequence NotificationEventSequence)
+	namespaceSupport   bool
+	subscriptionOpts   subscriptions.Options
+	maxRetries         int
+	retryBaseDelay     time.Duration
+	sendTimeout        time.Duration
+	rateLimiters       map[string]*rate.Limiter
+	maxConcurrent      map[string]int
+	semaphores         serviceSemaphores
+	circuitBreaker     *circuitBreaker
+	dryRun             bool
+	failFast           bool
+	deliverySink       DeliverySink
+	beforeSend         func(n *services.Notification, dest services.Destination, correlationID string) error
+	suppressionWindows []*suppressionWindow
+	digest             *digester
+	skipUnchanged      *unchangedFilter
+	stateless          *memoryState
+	now                func() time.Time
+	ctx                context.Context
+	tracer             trace.Tracer
+	logger             *log.Logger
+	statusWriter       StatusWriter
+	processTimeout     time.Duration
+	patches            *patchBatcher
+	versions           ResourceVersionStore
+	deadLetter         func(DeadLetter)
 }
 
 func (c *notificationController) Run(threadiness int, stopCh <-chan struct{}) {
 	defer runtimeutil.HandleCrash()
 	defer c.queue.ShutDown()
 
-	log.Warn("Controller is running.")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.ctx = ctx
+
+	c.logger.Warn("Controller is running.")
 	for i := 0; i < threadiness; i++ {
 		go wait.Until(func() {
 			for c.processQueueItem() {
 			}
 		}, time.Second, stopCh)
 	}
+	if c.patches != nil {
+		go c.runPatchBatcher(stopCh)
+	}
 	<-stopCh
-	log.Warn("Controller has stopped.")
+	c.logger.Warn("Controller has stopped.")
 }
 
 // check if an api is a self-service API
@@ -189,72 +421,518 @@ func (c *notificationController) isSelfServiceConfigureApi(api api.API) bool {
 
 func (c *notificationController) processResourceWithAPI(api api.API, resource v1.Object, logEntry *log.Entry, eventSequence *NotificationEventSequence) (map[string]string, error) {
 	apiNamespace := api.GetConfig().Namespace
-	notificationsState := NewStateFromRes(resource)
+	var notificationsState NotificationsState
+	if c.stateless != nil {
+		notificationsState = c.stateless.load(resource)
+	} else {
+		notificationsState = newStateFromRes(resource, c.subscriptionOpts)
+	}
 
-	destinations := c.getDestinations(resource, api.GetConfig())
+	cfg := api.GetConfig()
+	destinations := c.getDestinations(resource, cfg)
 	if len(destinations) == 0 {
 		return resource.GetAnnotations(), nil
 	}
+	withoutUnknownTemplates(destinations, cfg, logEntry, eventSequence)
 
 	un, err := c.toUnstructured(resource)
 	if err != nil {
 		return nil, err
 	}
 
-	for trigger, destinations := range destinations {
+	ctx := c.contextOf(logEntry)
+	for _, trigger := range sortedTriggers(destinations, cfg.TriggerPriority) {
+		if ctx.Err() != nil {
+			// the remaining notifications are attempted once the resource is processed again
+			break
+		}
+		destinations := sortedDestinations(destinations[trigger])
+		evaluationStartedAt := time.Now()
 		res, err := api.RunTrigger(trigger, un.Object)
+		c.metricsRegistry.ObserveTriggerEvaluationDuration(trigger, time.Since(evaluationStartedAt))
 		if err != nil {
 			logEntry.Errorf("Failed to execute condition of trigger %s: %v using the configuration in namespace %s", trigger, err, apiNamespace)
 			eventSequence.addWarning(fmt.Errorf("failed to execute condition of trigger %s: %v using the configuration in namespace %s", trigger, err, apiNamespace))
 		}
 		logEntry.Infof("Trigger %s result: %v", trigger, res)
 
+		triggerFailed := false
 		for _, cr := range res {
 			c.metricsRegistry.IncTriggerEvaluationsCounter(trigger, cr.Triggered)
 
 			if !cr.Triggered {
 				for _, to := range destinations {
-					notificationsState.SetAlreadyNotified(c.isSelfServiceConfigureApi(api), apiNamespace, trigger, cr, to, false)
+					if recovered := notificationsState.SetAlreadyNotified(c.isSelfServiceConfigureApi(api), apiNamespace, trigger, cr, to, false); recovered && len(cr.RecoverTemplates) > 0 {
+						c.sendRecoveryNotification(api, un, apiNamespace, cfg, trigger, cr, to, notificationsState, logEntry, eventSequence)
+					}
+				}
+				if cfg.IncludeFailureCount {
+					notificationsState.setFailureCount(failureCountKey(c.isSelfServiceConfigureApi(api), apiNamespace, trigger, cr), 0)
 				}
 				continue
 			}
 
+			// the count includes the current evaluation and is recorded once a notification about it is delivered
+			failureCount := 0
+			if cfg.IncludeFailureCount {
+				failureCount = notificationsState.failureCount(failureCountKey(c.isSelfServiceConfigureApi(api), apiNamespace, trigger, cr)) + 1
+			}
 			for _, to := range destinations {
-				if changed := notificationsState.SetAlreadyNotified(c.isSelfServiceConfigureApi(api), apiNamespace, trigger, cr, to, true); !changed {
+				if ctx.Err() != nil {
+					break
+				}
+				if changed := notificationsState.SetAlreadyNotifiedWithWindow(c.isSelfServiceConfigureApi(api), apiNamespace, trigger, cr, to, true, cfg.DeduplicationWindow); !changed {
 					logEntry.Infof("Notification about condition '%s.%s' already sent to '%v' using the configuration in namespace %s", trigger, cr.Key, to, apiNamespace)
 					eventSequence.addDelivered(NotificationDelivery{
 						Trigger:         trigger,
 						Destination:     to,
 						AlreadyNotified: true,
 					})
-				} else {
-					logEntry.Infof("Sending notification about condition '%s.%s' to '%v' using the configuration in namespace %s", trigger, cr.Key, to, apiNamespace)
-					if err := api.Send(un.Object, cr.Templates, to); err != nil {
-						logEntry.Errorf("Failed to notify recipient %s defined in resource %s/%s: %v using the configuration in namespace %s",
-							to, resource.GetNamespace(), resource.GetName(), err, apiNamespace)
-						notificationsState.SetAlreadyNotified(c.isSelfServiceConfigureApi(api), apiNamespace, trigger, cr, to, false)
-						c.metricsRegistry.IncDeliveriesCounter(trigger, to.Service, false)
-						eventSequence.addError(fmt.Errorf("failed to deliver notification %s to %s: %v using the configuration in namespace %s", trigger, to, err, apiNamespace))
-					} else {
-						logEntry.Debugf("Notification %s was sent using the configuration in namespace %s", to.Recipient, apiNamespace)
-						c.metricsRegistry.IncDeliveriesCounter(trigger, to.Service, true)
-						eventSequence.addDelivered(NotificationDelivery{
-							Trigger:         trigger,
-							Destination:     to,
-							AlreadyNotified: false,
-						})
+					if c.deliverySink != nil {
+						c.deliverySink.OnSkipped(DeliveryEvent{Resource: resource, Trigger: trigger, Destination: to, Templates: destinationTemplates(cr, to), StartedAt: time.Now()}, skipReasonAlreadyNotified)
+					}
+				} else if c.isSuppressed(trigger) {
+					logEntry.Infof("Notification about condition '%s.%s' to '%v' is suppressed by a suppression window using the configuration in namespace %s", trigger, cr.Key, to, apiNamespace)
+					notificationsState.unmarkNotified(c.isSelfServiceConfigureApi(api), apiNamespace, trigger, cr, to)
+					eventSequence.addDelivered(NotificationDelivery{
+						Trigger:     trigger,
+						Destination: to,
+						Suppressed:  true,
+					})
+					if c.deliverySink != nil {
+						c.deliverySink.OnSkipped(DeliveryEvent{Resource: resource, Trigger: trigger, Destination: to, Templates: destinationTemplates(cr, to), StartedAt: c.now()}, skipReasonSuppressed)
 					}
+				} else if c.failFast && triggerFailed {
+					logEntry.Infof("Notification about condition '%s.%s' to '%v' is cancelled since a previous delivery of the trigger failed using the configuration in namespace %s", trigger, cr.Key, to, apiNamespace)
+					notificationsState.unmarkNotified(c.isSelfServiceConfigureApi(api), apiNamespace, trigger, cr, to)
+					eventSequence.addDelivered(NotificationDelivery{
+						Trigger:     trigger,
+						Destination: to,
+						Cancelled:   true,
+					})
+					if c.deliverySink != nil {
+						c.deliverySink.OnSkipped(DeliveryEvent{Resource: resource, Trigger: trigger, Destination: to, Templates: destinationTemplates(cr, to), StartedAt: c.now()}, skipReasonCancelled)
+					}
+				} else if c.digest.appliesTo(to) && !c.dryRun {
+					// the condition is marked as notified once the digest is delivered
+					notificationsState.unmarkNotified(c.isSelfServiceConfigureApi(api), apiNamespace, trigger, cr, to)
+					c.collectDigest(un, apiNamespace, trigger, cr, to, logEntry)
+				} else if delivery := c.sendSingleNotification(api, un, apiNamespace, cfg, trigger, cr, failureCount, to, notificationsState, logEntry, eventSequence); delivery.Error != nil {
+					triggerFailed = true
 				}
 			}
 		}
 	}
 
-	return notificationsState.Persist(resource)
+	c.sendDueDigests(api, un, apiNamespace, notificationsState, logEntry, eventSequence)
+
+	if c.dryRun {
+		return resource.GetAnnotations(), nil
+	}
+	if c.stateless != nil {
+		c.stateless.store(resource, notificationsState)
+		return resource.GetAnnotations(), nil
+	}
+	return notificationsState.persist(resource, c.subscriptionOpts, cfg.MaxStateEntries, cfg.MaxStateSize)
+}
+
+// sendRecoveryNotification sends the recovery templates of a condition which is no longer triggered. The caller
+// ensures that a notification about the condition was delivered to the destination. If the recovery can't be sent
+// now, the destination is marked as notified again so that the recovery is attempted once more.
+func (c *notificationController) sendRecoveryNotification(api api.API, un *unstructured.Unstructured, apiNamespace string, cfg api.Config, trigger string, cr triggers.ConditionResult, to services.Destination, notificationsState NotificationsState, logEntry *log.Entry, eventSequence *NotificationEventSequence) {
+	if c.isSuppressed(trigger) {
+		logEntry.Infof("Recovery notification about condition '%s.%s' to '%v' is suppressed by a suppression window using the configuration in namespace %s", trigger, cr.Key, to, apiNamespace)
+		notificationsState.SetAlreadyNotified(c.isSelfServiceConfigureApi(api), apiNamespace, trigger, cr, to, true)
+		eventSequence.addDelivered(NotificationDelivery{Trigger: trigger, Destination: to, Suppressed: true})
+		return
+	}
+	recovery := cr
+	recovery.Templates = cr.RecoverTemplates
+	failureCount := notificationsState.failureCount(failureCountKey(c.isSelfServiceConfigureApi(api), apiNamespace, trigger, cr))
+	if delivery := c.sendSingleNotification(api, un, apiNamespace, cfg, trigger, recovery, failureCount, to, notificationsState, logEntry, eventSequence); delivery.Error != nil {
+		notificationsState.SetAlreadyNotified(c.isSelfServiceConfigureApi(api), apiNamespace, trigger, cr, to, true)
+	}
+}
+
+// sortedTriggers returns the triggers of the destinations by descending priority and then in ascending order so
+// that notifications are always processed in the same order
+func sortedTriggers(destinations services.Destinations, priority map[string]int) []string {
+	res := make([]string, 0, len(destinations))
+	for trigger := range destinations {
+		res = append(res, trigger)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if priority[res[i]] != priority[res[j]] {
+			return priority[res[i]] > priority[res[j]]
+		}
+		return res[i] < res[j]
+	})
+	return res
+}
+
+// withoutUnknownTemplates drops the template overrides of the destinations which name templates that are not
+// configured, so that the templates of the trigger are used instead
+func withoutUnknownTemplates(destinations services.Destinations, cfg api.Config, logEntry *log.Entry, eventSequence *NotificationEventSequence) {
+	for trigger, dests := range destinations {
+		for i, to := range dests {
+			if _, ok := cfg.Templates[to.Template]; to.Template == "" || ok {
+				continue
+			}
+			logEntry.Warnf("Template '%s' of recipient '%v' of trigger %s is not configured, using the templates of the trigger", to.Template, to, trigger)
+			eventSequence.addWarning(fmt.Errorf("template '%s' of recipient '%v' of trigger %s is not configured using the configuration in namespace %s", to.Template, to, trigger, cfg.Namespace))
+			dests[i].Template = ""
+		}
+	}
+}
+
+// destinationTemplates returns the templates of the condition, or the template of the destination if the subscription
+// overrides them
+func destinationTemplates(cr triggers.ConditionResult, to services.Destination) []string {
+	if to.Template != "" {
+		return []string{to.Template}
+	}
+	return cr.Templates
+}
+
+// sortedDestinations returns a copy of the destinations ordered by service and then by recipient
+func sortedDestinations(destinations []services.Destination) []services.Destination {
+	res := make([]services.Destination, len(destinations))
+	copy(res, destinations)
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Service != res[j].Service {
+			return res[i].Service < res[j].Service
+		}
+		return res[i].Recipient < res[j].Recipient
+	})
+	return res
+}
+
+// isSuppressed returns true if the current time falls in a suppression window that applies to the trigger
+func (c *notificationController) isSuppressed(trigger string) bool {
+	now := c.now()
+	for _, w := range c.suppressionWindows {
+		if w.appliesTo(trigger) && w.isActive(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// getSendTimeout returns the delivery timeout configured in the notifications config, falling back to the controller default
+func (c *notificationController) getSendTimeout(cfg api.Config) time.Duration {
+	if cfg.SendTimeout > 0 {
+		return cfg.SendTimeout
+	}
+	return c.sendTimeout
+}
+
+// getServiceMaxConcurrent returns the maximum number of concurrent deliveries to the service configured in the
+// notifications config, falling back to the controller default. Zero means unlimited.
+func (c *notificationController) getServiceMaxConcurrent(cfg api.Config, service string) int {
+	if limit, ok := cfg.ServiceMaxConcurrent[service]; ok {
+		return limit
+	}
+	return c.maxConcurrent[service]
+}
+
+// sendSingleNotification sends the notification to the destination and returns the delivery. Failed deliveries are
+// reported as errors of the event sequence and carry the error. The failure count is passed to the templates and
+// recorded once the notification is delivered.
+func (c *notificationController) sendSingleNotification(api api.API, un *unstructured.Unstructured, apiNamespace string, cfg api.Config, trigger string, cr triggers.ConditionResult, failureCount int, to services.Destination, notificationsState NotificationsState, logEntry *log.Entry, eventSequence *NotificationEventSequence) NotificationDelivery {
+	correlationID := utilrand.String(8)
+	// recipients and errors of some services carry credentials, e.g. tokens in webhook URLs, which must not be logged
+	redactedTo := services.Destination{Service: to.Service, Recipient: redact.String(to.Recipient)}
+	logEntry = logEntry.WithFields(log.Fields{
+		"trigger":       trigger,
+		"service":       to.Service,
+		"recipient":     redactedTo.Recipient,
+		"correlationID": correlationID,
+	})
+	delivery := NotificationDelivery{Trigger: trigger, Destination: to, CorrelationID: correlationID}
+	span := c.startDeliverySpan(logEntry, trigger, to)
+	defer func() {
+		endDeliverySpan(span, delivery)
+	}()
+	templates := cfg.GetServiceTemplates(to.Service, destinationTemplates(cr, to))
+	event := DeliveryEvent{Resource: un, Trigger: trigger, Destination: to, Templates: templates, StartedAt: time.Now(), CorrelationID: correlationID}
+	if c.dryRun {
+		logEntry.Infof("Dry run: skipped sending notification about condition '%s.%s' to '%v' using the configuration in namespace %s", trigger, cr.Key, redactedTo, apiNamespace)
+		c.metricsRegistry.IncDryRunDeliveriesCounter(trigger, to.Service)
+		delivery.DryRun = true
+		eventSequence.addDelivered(delivery)
+		if c.deliverySink != nil {
+			c.deliverySink.OnSkipped(event, skipReasonDryRun)
+		}
+		return delivery
+	}
+
+	logEntry.Infof("Sending notification about condition '%s.%s' to '%v' using the configuration in namespace %s", trigger, cr.Key, redactedTo, apiNamespace)
+	obj := withConditionKey(un.Object, cr.Key, services.DeliveryIdempotencyKey(trigger, cr.Key, to, un.GetResourceVersion()))
+	var failureKey string
+	if cfg.IncludeFailureCount && cr.Key != "" {
+		failureKey = failureCountKey(c.isSelfServiceConfigureApi(api), apiNamespace, trigger, cr)
+		obj = withFailureCount(obj, failureCount)
+	}
+	var messageKey string
+	if cfg.IncludePreviousMessage {
+		messageKey = previousMessageKey(c.isSelfServiceConfigureApi(api), apiNamespace, trigger, cr, to)
+		obj = withPreviousMessage(obj, notificationsState.previousMessage(messageKey))
+	}
+	notification, send, err := c.prepareSend(api, obj, templates, to, correlationID, cfg)
+	if errors.Is(err, errEmptyNotification) {
+		logEntry.Infof("Skipped empty notification about condition '%s.%s' to '%v' using the configuration in namespace %s", trigger, cr.Key, redactedTo, apiNamespace)
+		delivery.Empty = true
+		eventSequence.addDelivered(delivery)
+		if c.deliverySink != nil {
+			c.deliverySink.OnSkipped(event, skipReasonEmpty)
+		}
+		return delivery
+	}
+	if err == nil {
+		serviceState := notificationsState.serviceState()
+		send = withServiceState(send, serviceState)
+		send = withSpan(send, span)
+		send = c.limitConcurrency(send, to.Service, c.getServiceMaxConcurrent(cfg, to.Service))
+		err = c.sendWithCircuitBreaker(send, trigger, to, c.getSendTimeout(cfg), logEntry)
+		notificationsState.setServiceState(serviceState)
+	}
+	event.Duration = time.Since(event.StartedAt)
+	c.metricsRegistry.ObserveDeliveryDuration(trigger, to.Service, event.Duration)
+	if err != nil {
+		logEntry.Errorf("Failed to notify recipient %s defined in resource %s/%s: %s using the configuration in namespace %s",
+			redactedTo, un.GetNamespace(), un.GetName(), redact.String(err.Error()), apiNamespace)
+		notificationsState.SetAlreadyNotified(c.isSelfServiceConfigureApi(api), apiNamespace, trigger, cr, to, false)
+		c.metricsRegistry.IncDeliveriesCounter(trigger, to.Service, false)
+		if errors.Is(err, context.DeadlineExceeded) {
+			eventSequence.addError(fmt.Errorf("timed out delivering notification %s to %s: %w using the configuration in namespace %s", trigger, to, err, apiNamespace))
+		} else {
+			eventSequence.addError(fmt.Errorf("failed to deliver notification %s to %s: %v using the configuration in namespace %s", trigger, to, err, apiNamespace))
+		}
+		if c.deliverySink != nil {
+			c.deliverySink.OnError(event, err)
+		}
+		if c.deadLetter != nil && c.contextOf(logEntry).Err() == nil {
+			key, _ := cache.MetaNamespaceKeyFunc(un)
+			c.deadLetter(DeadLetter{Key: key, Trigger: trigger, Destination: to, Notification: notification, Error: err})
+		}
+		delivery.Error = err
+	} else {
+		logEntry.Debugf("Notification %s was sent using the configuration in namespace %s", redactedTo.Recipient, apiNamespace)
+		if cfg.IncludePreviousMessage && notification != nil {
+			notificationsState.setPreviousMessage(messageKey, notification.Message)
+		}
+		// the count is recorded by the first delivery of the evaluation, so that every destination sees the same count
+		if failureKey != "" && failureCount > notificationsState.failureCount(failureKey) {
+			notificationsState.setFailureCount(failureKey, failureCount)
+		}
+		c.metricsRegistry.IncDeliveriesCounter(trigger, to.Service, true)
+		eventSequence.addDelivered(delivery)
+		if c.deliverySink != nil {
+			c.deliverySink.OnDelivered(event)
+		}
+	}
+	return delivery
+}
+
+func (c *notificationController) SendToDestinations(obj map[string]interface{}, templates []string, dests services.Destinations) []NotificationDelivery {
+	var deliveries []NotificationDelivery
+	triggerNames := make([]string, 0, len(dests))
+	for trigger := range dests {
+		triggerNames = append(triggerNames, trigger)
+	}
+	sort.Strings(triggerNames)
+
+	api, err := c.apiFactory.GetAPI()
+	if err != nil {
+		for _, trigger := range triggerNames {
+			for _, to := range dests[trigger] {
+				deliveries = append(deliveries, NotificationDelivery{Trigger: trigger, Destination: to, Error: fmt.Errorf("failed to get api: %v", err)})
+			}
+		}
+		return deliveries
+	}
+
+	cfg := api.GetConfig()
+	un := &unstructured.Unstructured{Object: obj}
+	logEntry := c.logger.WithField("resource", fmt.Sprintf("%s/%s", un.GetNamespace(), un.GetName()))
+	// the notified state is not persisted since no trigger was evaluated
+	notificationsState := NotificationsState{}
+	for _, trigger := range triggerNames {
+		for _, to := range dests[trigger] {
+			var eventSequence NotificationEventSequence
+			deliveries = append(deliveries, c.sendSingleNotification(api, un, cfg.Namespace, cfg, trigger, triggers.ConditionResult{Templates: templates}, 0, to, notificationsState, logEntry, &eventSequence))
+		}
+	}
+	return deliveries
+}
+
+// withConditionKey returns a copy of the object which carries the key of the triggered condition to the templates and
+// the idempotency key of the delivery to the notification services
+func withConditionKey(obj map[string]interface{}, key string, idempotencyKey string) map[string]interface{} {
+	if key == "" {
+		return obj
+	}
+	res := make(map[string]interface{}, len(obj)+2)
+	for k, v := range obj {
+		res[k] = v
+	}
+	res[api.ConditionKeyField] = key
+	res[api.IdempotencyKeyField] = idempotencyKey
+	return res
+}
+
+// withFailureCount returns a copy of the object which carries the failure count of the condition to the templates
+func withFailureCount(obj map[string]interface{}, failureCount int) map[string]interface{} {
+	res := make(map[string]interface{}, len(obj)+1)
+	for k, v := range obj {
+		res[k] = v
+	}
+	res[api.FailureCountField] = failureCount
+	return res
+}
+
+// withPreviousMessage returns a copy of the object which carries the message last delivered about the condition to the
+// templates
+func withPreviousMessage(obj map[string]interface{}, message string) map[string]interface{} {
+	res := make(map[string]interface{}, len(obj)+1)
+	for k, v := range obj {
+		res[k] = v
+	}
+	res[api.PreviousMessageField] = message
+	return res
+}
+
+// errEmptyNotification is returned by prepareSend if empty notifications are skipped and the notification is empty
+var errEmptyNotification = errors.New("notification is empty")
+
+// prepareSend returns the function which sends the notification. If a before send hook or a dead letter callback is
+// configured, empty notifications are skipped or the previous message is kept, the notification is formatted once and
+// returned as well. The hook may modify it or abort the delivery by returning an error.
+func (c *notificationController) prepareSend(api api.API, obj map[string]interface{}, templates []string, to services.Destination, correlationID string, cfg api.Config) (*services.Notification, func(ctx context.Context) error, error) {
+	skipEmpty := cfg.SkipEmptyMessages
+	if c.beforeSend == nil && c.deadLetter == nil && !skipEmpty && !cfg.IncludePreviousMessage {
+		return nil, func(ctx context.Context) error {
+			return api.SendContext(ctx, obj, templates, to)
+		}, nil
+	}
+	// every call returns a new notification, so the hook does not race with other deliveries
+	notification, err := api.FormatNotification(obj, templates, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	if skipEmpty && notification.IsEmpty() {
+		return nil, nil, errEmptyNotification
+	}
+	if c.beforeSend != nil {
+		if err := c.beforeSend(notification, to, correlationID); err != nil {
+			return nil, nil, fmt.Errorf("before send hook failed: %w", err)
+		}
+	}
+	return notification, func(ctx context.Context) error {
+		return api.SendNotificationContext(ctx, *notification, to)
+	}, nil
+}
+
+// sendWithCircuitBreaker fails fast without sending the notification while the circuit of the destination is open
+func (c *notificationController) sendWithCircuitBreaker(send func(ctx context.Context) error, trigger string, to services.Destination, timeout time.Duration, logEntry *log.Entry) error {
+	if c.circuitBreaker == nil {
+		return c.sendWithRetry(send, trigger, to, timeout, logEntry)
+	}
+	if ok, probeAt := c.circuitBreaker.allow(to, c.now()); !ok {
+		return fmt.Errorf("circuit open for %s, next attempt after %s", to, probeAt.Format(time.RFC3339))
+	}
+	err := c.sendWithRetry(send, trigger, to, timeout, logEntry)
+	c.circuitBreaker.record(to, err == nil, c.now())
+	return err
+}
+
+// sendWithRetry sends the notification and, if a retry policy is configured, retries failed
+// attempts using exponential backoff with jitter. Retries are aborted once the controller is stopped or the
+// processing of the resource timed out.
+func (c *notificationController) sendWithRetry(send func(ctx context.Context) error, trigger string, to services.Destination, timeout time.Duration, logEntry *log.Entry) error {
+	ctx := c.contextOf(logEntry)
+	err := c.sendWithTimeout(ctx, send, to, timeout)
+	for attempt := 0; err != nil && attempt < c.maxRetries; attempt++ {
+		delay := retryDelay(c.retryBaseDelay, attempt)
+		logEntry.Warnf("Failed to notify recipient %s: %s, retrying in %s (attempt %d/%d)", redact.String(fmt.Sprint(to)), redact.String(err.Error()), delay, attempt+1, c.maxRetries)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("retry aborted: %w (last error: %v)", ctx.Err(), err)
+		case <-time.After(delay):
+		}
+		c.metricsRegistry.IncDeliveryRetriesCounter(trigger, to.Service)
+		err = c.sendWithTimeout(ctx, send, to, timeout)
+	}
+	return err
+}
+
+// sendWithTimeout sends the notification and gives up waiting for it once the timeout expires, so that
+// a slow destination does not hold the worker. The context passed to the service is cancelled at the same time,
+// which aborts the in-flight request. A zero timeout waits for the delivery to complete.
+func (c *notificationController) sendWithTimeout(parent context.Context, send func(ctx context.Context) error, to services.Destination, timeout time.Duration) error {
+	if err := c.waitForRateLimit(parent, to.Service); err != nil {
+		return err
+	}
+	if timeout <= 0 {
+		return send(parent)
+	}
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	res := make(chan error, 1)
+	go func() {
+		res <- send(ctx)
+	}()
+	select {
+	case err := <-res:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("delivery did not complete within %s: %w", timeout, ctx.Err())
+	}
+}
+
+// waitForRateLimit blocks until the rate limit of the given service allows another delivery
+func (c *notificationController) waitForRateLimit(ctx context.Context, service string) error {
+	limiter, ok := c.rateLimiters[service]
+	if !ok || limiter.Allow() {
+		return nil
+	}
+	c.metricsRegistry.IncRateLimitedCounter(service)
+	if err := limiter.Wait(ctx); err != nil {
+		return fmt.Errorf("rate limit wait aborted: %w", err)
+	}
+	return nil
+}
+
+// retryDelay returns the exponential backoff delay for the given attempt with up to 50% of random jitter added
+func retryDelay(baseDelay time.Duration, attempt int) time.Duration {
+	delay := baseDelay << attempt
+	if delay <= 0 {
+		return baseDelay
+	}
+	if jitter := int64(delay / 2); jitter > 0 {
+		delay += time.Duration(rand.Int63n(jitter))
+	}
+	return delay
 }
 
 func (c *notificationController) getDestinations(resource v1.Object, cfg api.Config) services.Destinations {
-	res := cfg.GetGlobalDestinations(resource.GetLabels())
-	res.Merge(subscriptions.NewAnnotations(resource.GetAnnotations()).GetDestinations(cfg.DefaultTriggers, cfg.ServiceDefaultTriggers))
+	var res services.Destinations
+	un, err := c.toUnstructured(resource)
+	if err == nil {
+		res = cfg.GetResourceGlobalDestinations(un.Object)
+	} else {
+		c.logger.Errorf("Failed to convert resource to unstructured, expression based subscriptions are ignored: %v", err)
+		res = cfg.GetGlobalDestinations(resource.GetLabels())
+	}
+	res.Merge(c.subscriptionOpts.NewAnnotations(resource.GetAnnotations()).GetDestinations(cfg.DefaultTriggers, cfg.ServiceDefaultTriggers))
+	if un != nil {
+		for _, recipients := range c.dynamicRecipients {
+			if err := recipients.apply(un.Object, res); err != nil {
+				c.logger.Errorf("Failed to resolve dynamic recipients of service '%s': %v", recipients.service, err)
+			}
+		}
+	}
 	if c.alterDestinations != nil {
 		res = c.alterDestinations(resource, res, cfg)
 	}
@@ -270,7 +948,7 @@ func (c *notificationController) processQueueItem() (processNext bool) {
 	processNext = true
 	defer func() {
 		if r := recover(); r != nil {
-			log.Errorf("Recovered from panic: %+v\n%s", r, debug.Stack())
+			c.logger.Errorf("Recovered from panic: %+v\n%s", r, debug.Stack())
 		}
 		c.queue.Done(key)
 	}()
@@ -282,9 +960,31 @@ func (c *notificationController) processQueueItem() (processNext bool) {
 		}
 	}()
 
+	ctx, span := c.startResourceSpan(key.(string))
+	defer func() {
+		endSpan(span, eventSequence.Errors...)
+	}()
+	if c.processTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.processTimeout)
+		defer cancel()
+	}
+	defer func() {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && c.ctx.Err() == nil {
+			c.logger.WithField("resource", key).Warnf("Processing did not complete within %s, requeueing", c.processTimeout)
+			eventSequence.addError(fmt.Errorf("processing of resource %s did not complete within %s: %w", key, c.processTimeout, ctx.Err()))
+			if c.skipUnchanged != nil {
+				c.skipUnchanged.forget(key.(string))
+			}
+			c.queue.AddRateLimited(key)
+		} else {
+			c.queue.Forget(key)
+		}
+	}()
+
 	obj, exists, err := c.informer.GetIndexer().GetByKey(key.(string))
 	if err != nil {
-		log.Errorf("Failed to get resource '%s' from informer index: %+v", key, err)
+		c.logger.Errorf("Failed to get resource '%s' from informer index: %+v", key, err)
 		eventSequence.addError(err)
 		return
 	}
@@ -294,19 +994,37 @@ func (c *notificationController) processQueueItem() (processNext bool) {
 	}
 	resource, ok := obj.(v1.Object)
 	if !ok {
-		log.Errorf("Failed to get resource '%s' from informer index: %+v", key, err)
+		c.logger.Errorf("Failed to get resource '%s' from informer index: %+v", key, err)
 		eventSequence.addError(err)
 		return
 	}
 	eventSequence.Resource = resource
 
-	logEntry := log.WithField("resource", key)
+	logEntry := c.logger.WithField("resource", key).WithContext(ctx)
 	logEntry.Info("Start processing")
 	if c.skipProcessing != nil {
 		if skipProcessing, reason := c.skipProcessing(resource); skipProcessing {
 			logEntry.Infof("Processing skipped: %s", reason)
+			eventSequence.SkipReason = reason
+			return
+		}
+	}
+	if c.skipUnchanged != nil {
+		if unchanged, reason := c.isUnchanged(resource); unchanged {
+			logEntry.Infof("Processing skipped: %s", reason)
+			eventSequence.SkipReason = reason
+			return
+		}
+		defer c.forgetFailed(key.(string), &eventSequence)
+	}
+	if c.versions != nil {
+		if c.isVersionProcessed(key.(string), resource, logEntry) {
+			logEntry.Infof("Processing skipped: %s", skipReasonVersionProcessed)
+			eventSequence.SkipReason = skipReasonVersionProcessed
 			return
 		}
+		version := resource.GetResourceVersion()
+		defer c.recordVersion(ctx, key.(string), version, &eventSequence, logEntry)
 	}
 
 	if !c.namespaceSupport {
@@ -329,7 +1047,7 @@ func (c *notificationController) processQueueItem() (processNext bool) {
 			//refresh
 			obj, exists, err := c.informer.GetIndexer().GetByKey(key.(string))
 			if err != nil {
-				log.Errorf("Failed to get resource '%s' from informer index: %+v", key, err)
+				c.logger.Errorf("Failed to get resource '%s' from informer index: %+v", key, err)
 				eventSequence.addError(err)
 				return
 			}
@@ -339,18 +1057,20 @@ func (c *notificationController) processQueueItem() (processNext bool) {
 			}
 			resource, ok = obj.(v1.Object)
 			if !ok {
-				log.Errorf("Failed to get resource '%s' from informer index: %+v", key, err)
+				c.logger.Errorf("Failed to get resource '%s' from informer index: %+v", key, err)
 				eventSequence.addError(err)
 				return
 			}
 		}
 	}
+	c.writeStatus(resource, logEntry, &eventSequence)
 	logEntry.Info("Processing completed")
 
 	return
 }
 
 func (c *notificationController) processResource(api api.API, resource v1.Object, logEntry *log.Entry, eventSequence *NotificationEventSequence) {
+	resource = c.patches.overlay(resource)
 	annotations, err := c.processResourceWithAPI(api, resource, logEntry, eventSequence)
 	if err != nil {
 		logEntry.Errorf("Failed to process: %v", err)
@@ -359,6 +1079,10 @@ func (c *notificationController) processResource(api api.API, resource v1.Object
 	}
 
 	if !mapsEqual(resource.GetAnnotations(), annotations) {
+		if c.patches != nil {
+			c.batchPatch(resource, annotations, logEntry)
+			return
+		}
 		annotationsPatch := make(map[string]interface{})
 		for k, v := range annotations {
 			annotationsPatch[k] = v
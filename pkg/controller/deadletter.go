@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"github.com/argoproj/notifications-engine/pkg/services"
+)
+
+// DeadLetter describes a notification which could not be delivered, so that it can be stored and replayed later
+type DeadLetter struct {
+	// Key is the namespace/name key of the resource the notification is about
+	Key string
+	// Trigger is the trigger of the notification, or "digest" if the notification is a digest
+	Trigger string
+	// Destination is the destination of the notification
+	Destination services.Destination
+	// Notification is the rendered notification, or nil if the notification could not be rendered
+	Notification *services.Notification
+	// Error is the error of the last delivery attempt
+	Error error
+}
+
+// WithDeadLetter registers a callback which receives the notifications whose delivery has failed, after the retries
+// configured by WithRetryPolicy are exhausted. Deliveries aborted because the controller is stopped or the processing
+// of the resource timed out are not passed to the callback, since they are attempted again. The callback is invoked
+// synchronously by the controller workers, so it must be safe for concurrent use and should not block.
+func WithDeadLetter(f func(DeadLetter)) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.deadLetter = f
+	}
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	notificationApi "github.com/argoproj/notifications-engine/pkg/api"
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/argoproj/notifications-engine/pkg/subscriptions"
+	"github.com/argoproj/notifications-engine/pkg/triggers"
+)
+
+func TestDeadLetter(t *testing.T) {
+	destination := services.Destination{Service: "mock", Recipient: "recipient"}
+	subscribed := withAnnotations(map[string]string{
+		subscriptions.SubscribeAnnotationKey("my-trigger", "mock"): "recipient",
+	})
+
+	t.Run("InvokedWhenRetriesExhausted", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+		app := newResource("test", subscribed)
+
+		var deadLetters []DeadLetter
+		ctrl, api, err := newController(t, ctx, newFakeClient(app),
+			WithRetryPolicy(2, time.Millisecond),
+			WithDeadLetter(func(deadLetter DeadLetter) {
+				deadLetters = append(deadLetters, deadLetter)
+			}))
+		assert.NoError(t, err)
+
+		notification := services.Notification{Message: "test is degraded"}
+		api.EXPECT().GetConfig().Return(notificationApi.Config{}).AnyTimes()
+		api.EXPECT().RunTrigger("my-trigger", gomock.Any()).Return([]triggers.ConditionResult{{Triggered: true, Templates: []string{"test"}}}, nil)
+		api.EXPECT().FormatNotification(gomock.Any(), []string{"test"}, destination).Return(&notification, nil)
+		api.EXPECT().SendNotificationContext(gomock.Any(), notification, destination).Return(errors.New("service unavailable")).Times(3)
+
+		eventSequence := NotificationEventSequence{}
+		annotations, err := ctrl.processResourceWithAPI(api, app, logEntry, &eventSequence)
+		assert.NoError(t, err)
+
+		assert.Len(t, eventSequence.Errors, 1)
+		assert.Empty(t, NewState(annotations[notifiedAnnotationKey]))
+		assert.Equal(t, []DeadLetter{{
+			Key:          "default/test",
+			Trigger:      "my-trigger",
+			Destination:  destination,
+			Notification: &notification,
+			Error:        errors.New("service unavailable"),
+		}}, deadLetters)
+	})
+
+	t.Run("NotInvokedWhenRetrySucceeds", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+		app := newResource("test", subscribed)
+
+		var deadLetters []DeadLetter
+		ctrl, api, err := newController(t, ctx, newFakeClient(app),
+			WithRetryPolicy(2, time.Millisecond),
+			WithDeadLetter(func(deadLetter DeadLetter) {
+				deadLetters = append(deadLetters, deadLetter)
+			}))
+		assert.NoError(t, err)
+
+		api.EXPECT().GetConfig().Return(notificationApi.Config{}).AnyTimes()
+		api.EXPECT().RunTrigger("my-trigger", gomock.Any()).Return([]triggers.ConditionResult{{Triggered: true, Templates: []string{"test"}}}, nil)
+		api.EXPECT().FormatNotification(gomock.Any(), []string{"test"}, destination).Return(&services.Notification{Message: "test is degraded"}, nil)
+		gomock.InOrder(
+			api.EXPECT().SendNotificationContext(gomock.Any(), gomock.Any(), destination).Return(errors.New("service unavailable")),
+			api.EXPECT().SendNotificationContext(gomock.Any(), gomock.Any(), destination).Return(nil),
+		)
+
+		annotations, err := ctrl.processResourceWithAPI(api, app, logEntry, &NotificationEventSequence{})
+		assert.NoError(t, err)
+
+		assert.NotEmpty(t, NewState(annotations[notifiedAnnotationKey]))
+		assert.Empty(t, deadLetters)
+	})
+
+	t.Run("NotInvokedOnShutdown", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+		app := newResource("test", subscribed)
+
+		var deadLetters []DeadLetter
+		ctrl, api, err := newController(t, ctx, newFakeClient(app),
+			WithRetryPolicy(5, time.Hour),
+			WithDeadLetter(func(deadLetter DeadLetter) {
+				deadLetters = append(deadLetters, deadLetter)
+			}))
+		assert.NoError(t, err)
+
+		// the controller is stopped while the first attempt is in flight
+		stoppedCtx, stop := context.WithCancel(context.TODO())
+		defer stop()
+		ctrl.ctx = stoppedCtx
+
+		api.EXPECT().GetConfig().Return(notificationApi.Config{}).AnyTimes()
+		api.EXPECT().RunTrigger("my-trigger", gomock.Any()).Return([]triggers.ConditionResult{{Triggered: true, Templates: []string{"test"}}}, nil)
+		api.EXPECT().FormatNotification(gomock.Any(), []string{"test"}, destination).Return(&services.Notification{Message: "test is degraded"}, nil)
+		api.EXPECT().SendNotificationContext(gomock.Any(), gomock.Any(), destination).DoAndReturn(func(context.Context, services.Notification, services.Destination) error {
+			stop()
+			return errors.New("service unavailable")
+		})
+
+		eventSequence := NotificationEventSequence{}
+		_, err = ctrl.processResourceWithAPI(api, app, logEntry, &eventSequence)
+		assert.NoError(t, err)
+
+		assert.Len(t, eventSequence.Errors, 1)
+		assert.Empty(t, deadLetters)
+	})
+}
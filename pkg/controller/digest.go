@@ -0,0 +1,236 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/argoproj/notifications-engine/pkg/api"
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/argoproj/notifications-engine/pkg/triggers"
+	"github.com/argoproj/notifications-engine/pkg/util/redact"
+)
+
+// digestTrigger is the trigger reported for the delivery of a digest, e.g. in traces and dead letters, since a digest
+// covers the conditions of several triggers
+const digestTrigger = "digest"
+
+// DigestConfig configures the aggregation of notifications into digests
+type DigestConfig struct {
+	// Services is the list of services which receive digests. Defaults to email
+	Services []string
+	// DigestInterval is how long notifications about a resource are collected before the digest is sent.
+	// If zero, the notifications produced by a single processing iteration are sent as one digest.
+	DigestInterval time.Duration
+}
+
+// WithDigest configures the controller to aggregate the notifications about a resource sent to the same
+// destination of the given services into a single digest. Collected notifications are kept in memory until the digest
+// is sent and the conditions covered by a digest are marked as notified once it is delivered, so the conditions are
+// collected again if the digest fails or the controller restarts before it is sent. Digests are delivered like single
+// notifications, subject to the before send hook, rate limits, the circuit breaker, retries and send timeouts, and
+// failed digests are passed to the dead letter callback.
+func WithDigest(cfg DigestConfig) Opts {
+	return func(ctrl *notificationController) {
+		if len(cfg.Services) == 0 {
+			cfg.Services = []string{"email"}
+		}
+		ctrl.digest = &digester{
+			services: map[string]bool{},
+			interval: cfg.DigestInterval,
+			pending:  map[digestKey]*pendingDigest{},
+		}
+		for _, service := range cfg.Services {
+			ctrl.digest.services[service] = true
+		}
+	}
+}
+
+type digestKey struct {
+	resource     string
+	apiNamespace string
+	destination  services.Destination
+}
+
+type digestEntry struct {
+	trigger string
+	cr      triggers.ConditionResult
+}
+
+type pendingDigest struct {
+	startedAt time.Time
+	entries   []digestEntry
+}
+
+type digester struct {
+	services map[string]bool
+	interval time.Duration
+
+	lock    sync.Mutex
+	pending map[digestKey]*pendingDigest
+}
+
+func (d *digester) appliesTo(dest services.Destination) bool {
+	return d != nil && d.services[dest.Service]
+}
+
+// add collects the condition unless the digest covers it already and returns true if it started a new digest
+func (d *digester) add(key digestKey, trigger string, cr triggers.ConditionResult, now time.Time) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	digest, ok := d.pending[key]
+	if !ok {
+		digest = &pendingDigest{startedAt: now}
+		d.pending[key] = digest
+	}
+	for _, entry := range digest.entries {
+		// the conditions are not marked as notified until the digest is sent, so they are collected on every processing
+		if entry.trigger == trigger && entry.cr.Key == cr.Key && entry.cr.OncePer == cr.OncePer {
+			return false
+		}
+	}
+	digest.entries = append(digest.entries, digestEntry{trigger: trigger, cr: cr})
+	return !ok
+}
+
+// hasPending returns true if notifications about the resource are collected in a digest which is not sent yet
+func (d *digester) hasPending(resource string) bool {
+	if d == nil {
+		return false
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for key := range d.pending {
+		if key.resource == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// takeDue removes and returns the digests of the resource which have been collected for at least the digest interval
+func (d *digester) takeDue(resource string, apiNamespace string, now time.Time) map[digestKey]*pendingDigest {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	due := map[digestKey]*pendingDigest{}
+	for key, digest := range d.pending {
+		if key.resource == resource && key.apiNamespace == apiNamespace && now.Sub(digest.startedAt) >= d.interval {
+			due[key] = digest
+			delete(d.pending, key)
+		}
+	}
+	return due
+}
+
+// collectDigest adds the triggered condition to the digest of the destination instead of sending it right away
+func (c *notificationController) collectDigest(un *unstructured.Unstructured, apiNamespace string, trigger string, cr triggers.ConditionResult, to services.Destination, logEntry *log.Entry) {
+	key, err := cache.MetaNamespaceKeyFunc(un)
+	if err != nil {
+		logEntry.Errorf("Failed to get resource key: %v", err)
+		return
+	}
+	logEntry.Infof("Adding notification about condition '%s.%s' to the digest for '%v' using the configuration in namespace %s", trigger, cr.Key, to, apiNamespace)
+	if c.digest.add(digestKey{resource: key, apiNamespace: apiNamespace, destination: to}, trigger, cr, c.now()) && c.digest.interval > 0 {
+		// make sure the resource is processed again once the digest is due
+		c.queue.AddAfter(key, c.digest.interval)
+	}
+}
+
+// sendDueDigests sends the digests of the resource which are due and marks the conditions covered by the delivered
+// digests as notified. The conditions of digests which could not be sent are collected again.
+func (c *notificationController) sendDueDigests(api api.API, un *unstructured.Unstructured, apiNamespace string, notificationsState NotificationsState, logEntry *log.Entry, eventSequence *NotificationEventSequence) {
+	if c.digest == nil {
+		return
+	}
+	key, err := cache.MetaNamespaceKeyFunc(un)
+	if err != nil {
+		logEntry.Errorf("Failed to get resource key: %v", err)
+		return
+	}
+
+	due := c.digest.takeDue(key, apiNamespace, c.now())
+	keys := make([]digestKey, 0, len(due))
+	for k := range due {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].destination.Service+keys[i].destination.Recipient < keys[j].destination.Service+keys[j].destination.Recipient
+	})
+
+	cfg := api.GetConfig()
+	for _, k := range keys {
+		digest := due[k]
+		err := c.sendDigest(api, un, apiNamespace, cfg, k.destination, digest.entries, notificationsState, logEntry)
+		if err != nil {
+			eventSequence.addError(fmt.Errorf("failed to deliver digest to %s: %v using the configuration in namespace %s", k.destination, err, apiNamespace))
+		}
+		for _, entry := range digest.entries {
+			c.metricsRegistry.IncDeliveriesCounter(entry.trigger, k.destination.Service, err == nil)
+			if err != nil {
+				continue
+			}
+			notificationsState.SetAlreadyNotified(c.isSelfServiceConfigureApi(api), apiNamespace, entry.trigger, entry.cr, k.destination, true)
+			eventSequence.addDelivered(NotificationDelivery{
+				Trigger:     entry.trigger,
+				Destination: k.destination,
+			})
+		}
+	}
+}
+
+// sendDigest formats the digest and sends it using the same pipeline as single notifications
+func (c *notificationController) sendDigest(api api.API, un *unstructured.Unstructured, apiNamespace string, cfg api.Config, to services.Destination, entries []digestEntry, notificationsState NotificationsState, logEntry *log.Entry) error {
+	correlationID := utilrand.String(8)
+	redactedTo := services.Destination{Service: to.Service, Recipient: redact.String(to.Recipient)}
+	logEntry = logEntry.WithFields(log.Fields{
+		"trigger":       digestTrigger,
+		"service":       to.Service,
+		"recipient":     redactedTo.Recipient,
+		"correlationID": correlationID,
+	})
+	delivery := NotificationDelivery{Trigger: digestTrigger, Destination: to, CorrelationID: correlationID}
+	span := c.startDeliverySpan(logEntry, digestTrigger, to)
+	defer func() {
+		endDeliverySpan(span, delivery)
+	}()
+
+	templates := make([][]string, len(entries))
+	for i, entry := range entries {
+		templates[i] = cfg.GetServiceTemplates(to.Service, destinationTemplates(entry.cr, to))
+	}
+	logEntry.Infof("Sending digest of %d notifications to '%v' using the configuration in namespace %s", len(entries), redactedTo, apiNamespace)
+	notification, err := api.FormatDigest(un.Object, templates, to)
+	if err == nil && c.beforeSend != nil {
+		if err = c.beforeSend(notification, to, correlationID); err != nil {
+			err = fmt.Errorf("before send hook failed: %w", err)
+		}
+	}
+	if err == nil {
+		send := func(ctx context.Context) error {
+			return api.SendNotificationContext(ctx, *notification, to)
+		}
+		serviceState := notificationsState.serviceState()
+		send = withServiceState(send, serviceState)
+		send = withSpan(send, span)
+		send = c.limitConcurrency(send, to.Service, c.getServiceMaxConcurrent(cfg, to.Service))
+		err = c.sendWithCircuitBreaker(send, digestTrigger, to, c.getSendTimeout(cfg), logEntry)
+		notificationsState.setServiceState(serviceState)
+	}
+	if err != nil {
+		logEntry.Errorf("Failed to send digest to recipient %s defined in resource %s/%s: %s using the configuration in namespace %s",
+			redactedTo, un.GetNamespace(), un.GetName(), redact.String(err.Error()), apiNamespace)
+		if c.deadLetter != nil && c.contextOf(logEntry).Err() == nil {
+			key, _ := cache.MetaNamespaceKeyFunc(un)
+			c.deadLetter(DeadLetter{Key: key, Trigger: digestTrigger, Destination: to, Notification: notification, Error: err})
+		}
+	}
+	delivery.Error = err
+	return err
+}
@@ -3,6 +3,7 @@ package controller
 import (
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -24,20 +25,111 @@ func NewMetricsRegistry(prefix string) *MetricsRegistry {
 		[]string{"name", "triggered"},
 	)
 
+	deliveryRetriesCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: fmt.Sprintf("%s_notifications_delivery_retries_total", prefix),
+			Help: "Number of notification delivery retry attempts.",
+		},
+		[]string{"trigger", "service"},
+	)
+
+	rateLimitedCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: fmt.Sprintf("%s_notifications_rate_limited_total", prefix),
+			Help: "Number of notification deliveries delayed by the service rate limit.",
+		},
+		[]string{"service"},
+	)
+
+	dryRunDeliveriesCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: fmt.Sprintf("%s_notifications_dry_run_deliveries_total", prefix),
+			Help: "Number of notifications that would have been delivered in dry-run mode.",
+		},
+		[]string{"trigger", "service"},
+	)
+
+	circuitBreakerTransitionsCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: fmt.Sprintf("%s_notifications_circuit_breaker_transitions_total", prefix),
+			Help: "Number of circuit breaker state transitions.",
+		},
+		[]string{"service", "state"},
+	)
+
+	triggerEvaluationDuration := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    fmt.Sprintf("%s_notifications_trigger_eval_duration_seconds", prefix),
+			Help:    "Duration of trigger evaluations.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 12),
+		},
+		[]string{"name"},
+	)
+
+	deliveryDuration := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    fmt.Sprintf("%s_notifications_delivery_duration_seconds", prefix),
+			Help:    "Duration of notification deliveries, including retries.",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"trigger", "service"},
+	)
+
+	queueDepth := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_notifications_queue_depth", prefix),
+			Help: "Number of resources waiting in the work queue.",
+		},
+		[]string{"name"},
+	)
+
+	queueLatency := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    fmt.Sprintf("%s_notifications_queue_latency_seconds", prefix),
+			Help:    "How long resources stay in the work queue before being processed.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+		},
+		[]string{"name"},
+	)
+
 	registry := &MetricsRegistry{
-		Registry:                  prometheus.NewRegistry(),
-		deliveriesCounter:         deliveriesCounter,
-		triggerEvaluationsCounter: triggerEvaluationsCounter,
+		Registry:                         prometheus.NewRegistry(),
+		deliveriesCounter:                deliveriesCounter,
+		triggerEvaluationsCounter:        triggerEvaluationsCounter,
+		deliveryRetriesCounter:           deliveryRetriesCounter,
+		rateLimitedCounter:               rateLimitedCounter,
+		dryRunDeliveriesCounter:          dryRunDeliveriesCounter,
+		triggerEvaluationDuration:        triggerEvaluationDuration,
+		deliveryDuration:                 deliveryDuration,
+		circuitBreakerTransitionsCounter: circuitBreakerTransitionsCounter,
+		queueDepth:                       queueDepth,
+		queueLatency:                     queueLatency,
 	}
 	registry.MustRegister(deliveriesCounter)
 	registry.MustRegister(triggerEvaluationsCounter)
+	registry.MustRegister(deliveryRetriesCounter)
+	registry.MustRegister(rateLimitedCounter)
+	registry.MustRegister(dryRunDeliveriesCounter)
+	registry.MustRegister(circuitBreakerTransitionsCounter)
+	registry.MustRegister(triggerEvaluationDuration)
+	registry.MustRegister(deliveryDuration)
+	registry.MustRegister(queueDepth)
+	registry.MustRegister(queueLatency)
 	return registry
 }
 
 type MetricsRegistry struct {
 	*prometheus.Registry
-	deliveriesCounter         *prometheus.CounterVec
-	triggerEvaluationsCounter *prometheus.CounterVec
+	deliveriesCounter                *prometheus.CounterVec
+	triggerEvaluationsCounter        *prometheus.CounterVec
+	deliveryRetriesCounter           *prometheus.CounterVec
+	rateLimitedCounter               *prometheus.CounterVec
+	dryRunDeliveriesCounter          *prometheus.CounterVec
+	triggerEvaluationDuration        *prometheus.HistogramVec
+	deliveryDuration                 *prometheus.HistogramVec
+	circuitBreakerTransitionsCounter *prometheus.CounterVec
+	queueDepth                       *prometheus.GaugeVec
+	queueLatency                     *prometheus.HistogramVec
 }
 
 func (r *MetricsRegistry) IncDeliveriesCounter(trigger string, service string, succeeded bool) {
@@ -47,3 +139,35 @@ func (r *MetricsRegistry) IncDeliveriesCounter(trigger string, service string, s
 func (r *MetricsRegistry) IncTriggerEvaluationsCounter(name string, triggered bool) {
 	r.triggerEvaluationsCounter.WithLabelValues(name, strconv.FormatBool(triggered)).Inc()
 }
+
+func (r *MetricsRegistry) IncDeliveryRetriesCounter(trigger string, service string) {
+	r.deliveryRetriesCounter.WithLabelValues(trigger, service).Inc()
+}
+
+func (r *MetricsRegistry) IncRateLimitedCounter(service string) {
+	r.rateLimitedCounter.WithLabelValues(service).Inc()
+}
+
+func (r *MetricsRegistry) IncDryRunDeliveriesCounter(trigger string, service string) {
+	r.dryRunDeliveriesCounter.WithLabelValues(trigger, service).Inc()
+}
+
+func (r *MetricsRegistry) IncCircuitBreakerTransitionsCounter(service string, state string) {
+	r.circuitBreakerTransitionsCounter.WithLabelValues(service, state).Inc()
+}
+
+func (r *MetricsRegistry) ObserveTriggerEvaluationDuration(trigger string, d time.Duration) {
+	r.triggerEvaluationDuration.WithLabelValues(trigger).Observe(d.Seconds())
+}
+
+func (r *MetricsRegistry) ObserveDeliveryDuration(trigger string, service string, d time.Duration) {
+	r.deliveryDuration.WithLabelValues(trigger, service).Observe(d.Seconds())
+}
+
+func (r *MetricsRegistry) SetQueueDepth(name string, depth int) {
+	r.queueDepth.WithLabelValues(name).Set(float64(depth))
+}
+
+func (r *MetricsRegistry) ObserveQueueLatency(name string, d time.Duration) {
+	r.queueLatency.WithLabelValues(name).Observe(d.Seconds())
+}
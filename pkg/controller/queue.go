@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+)
+
+// WithRateLimiter configures the rate limiter of the controller work queue.
+// Defaults to workqueue.DefaultControllerRateLimiter()
+func WithRateLimiter(rateLimiter workqueue.RateLimiter) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.rateLimiter = rateLimiter
+	}
+}
+
+// WithQueueName configures the name of the controller work queue, which is used to label the queue metrics
+func WithQueueName(name string) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.queueName = name
+	}
+}
+
+// instrumentedQueue reports the depth of the queue and how long items wait before being processed.
+// The latency is only measured for items added without delay.
+type instrumentedQueue struct {
+	workqueue.RateLimitingInterface
+	name            string
+	metricsRegistry *MetricsRegistry
+
+	lock     sync.Mutex
+	addTimes map[interface{}]time.Time
+}
+
+func newInstrumentedQueue(queue workqueue.RateLimitingInterface, name string, metricsRegistry *MetricsRegistry) *instrumentedQueue {
+	return &instrumentedQueue{
+		RateLimitingInterface: queue,
+		name:                  name,
+		metricsRegistry:       metricsRegistry,
+		addTimes:              map[interface{}]time.Time{},
+	}
+}
+
+func (q *instrumentedQueue) Add(item interface{}) {
+	q.lock.Lock()
+	if _, ok := q.addTimes[item]; !ok {
+		q.addTimes[item] = time.Now()
+	}
+	q.lock.Unlock()
+	q.RateLimitingInterface.Add(item)
+	q.metricsRegistry.SetQueueDepth(q.name, q.Len())
+}
+
+func (q *instrumentedQueue) Get() (interface{}, bool) {
+	item, shutdown := q.RateLimitingInterface.Get()
+	if !shutdown {
+		q.lock.Lock()
+		if addedAt, ok := q.addTimes[item]; ok {
+			q.metricsRegistry.ObserveQueueLatency(q.name, time.Since(addedAt))
+			delete(q.addTimes, item)
+		}
+		q.lock.Unlock()
+	}
+	q.metricsRegistry.SetQueueDepth(q.name, q.Len())
+	return item, shutdown
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/util/workqueue"
+)
+
+type recordingRateLimiter struct {
+	whenCalls []interface{}
+}
+
+func (r *recordingRateLimiter) When(item interface{}) time.Duration {
+	r.whenCalls = append(r.whenCalls, item)
+	return 0
+}
+
+func (r *recordingRateLimiter) Forget(interface{}) {}
+
+func (r *recordingRateLimiter) NumRequeues(interface{}) int {
+	return 42
+}
+
+func TestWithRateLimiter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	rateLimiter := &recordingRateLimiter{}
+	ctrl, _, err := newController(t, ctx, newFakeClient(), WithRateLimiter(rateLimiter))
+	assert.NoError(t, err)
+
+	ctrl.queue.AddRateLimited("default/test")
+
+	assert.Equal(t, []interface{}{"default/test"}, rateLimiter.whenCalls)
+	assert.Equal(t, 42, ctrl.queue.NumRequeues("default/test"))
+}
+
+func TestDefaultRateLimiter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	ctrl, _, err := newController(t, ctx, newFakeClient())
+	assert.NoError(t, err)
+
+	assert.IsType(t, &workqueue.MaxOfRateLimiter{}, ctrl.rateLimiter)
+}
+
+func TestQueueMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := newResource("test")
+
+	ctrl, _, err := newController(t, ctx, newFakeClient(app), WithQueueName("applications"))
+	assert.NoError(t, err)
+
+	// blocks until the informer has added the resource
+	key, _ := ctrl.queue.Get()
+	ctrl.queue.Done(key)
+	assert.Equal(t, "default/test", key)
+
+	families, err := ctrl.metricsRegistry.Gather()
+	assert.NoError(t, err)
+	depthReported := false
+	for _, family := range families {
+		if family.GetName() == "_notifications_queue_depth" {
+			for _, metric := range family.GetMetric() {
+				assert.Equal(t, "applications", metric.GetLabel()[0].GetValue())
+				assert.Equal(t, float64(0), metric.GetGauge().GetValue())
+				depthReported = true
+			}
+		}
+	}
+	assert.True(t, depthReported)
+	assert.Equal(t, uint64(1), histogramSampleCount(t, ctrl.metricsRegistry, "_notifications_queue_latency_seconds"))
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
+
+	"github.com/argoproj/notifications-engine/pkg/services"
+)
+
+// dynamicRecipients resolves recipients of a service from the resource using an expression
+type dynamicRecipients struct {
+	service string
+	expr    string
+	program *vm.Program
+}
+
+func newDynamicRecipients(service string, expression string) (*dynamicRecipients, error) {
+	program, err := expr.Compile(expression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile recipients expression '%s': %w", expression, err)
+	}
+	return &dynamicRecipients{service: service, expr: expression, program: program}, nil
+}
+
+// resolve evaluates the expression against the resource. The expression must return either a single recipient or a
+// list of recipients, empty recipients are ignored.
+func (r *dynamicRecipients) resolve(obj map[string]interface{}) ([]string, error) {
+	val, err := expr.Run(r.program, map[string]interface{}{"obj": obj})
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate recipients expression '%s': %w", r.expr, err)
+	}
+	var values []interface{}
+	switch v := val.(type) {
+	case nil:
+	case string:
+		values = []interface{}{v}
+	case []string:
+		for _, s := range v {
+			values = append(values, s)
+		}
+	case []interface{}:
+		values = v
+	default:
+		return nil, fmt.Errorf("recipients expression '%s' must return a string or a list of strings, got %T", r.expr, val)
+	}
+	var recipients []string
+	for _, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("recipients expression '%s' must return a string or a list of strings, got %T in the list", r.expr, value)
+		}
+		if s = strings.TrimSpace(s); s != "" {
+			recipients = append(recipients, s)
+		}
+	}
+	return recipients, nil
+}
+
+// apply appends the resolved recipients to every trigger which has a destination of the service
+func (r *dynamicRecipients) apply(obj map[string]interface{}, destinations services.Destinations) error {
+	var triggers []string
+	for trigger, dests := range destinations {
+		for _, dest := range dests {
+			if dest.Service == r.service {
+				triggers = append(triggers, trigger)
+				break
+			}
+		}
+	}
+	if len(triggers) == 0 {
+		return nil
+	}
+	recipients, err := r.resolve(obj)
+	if err != nil {
+		return err
+	}
+	for _, trigger := range triggers {
+		for _, recipient := range recipients {
+			destinations[trigger] = append(destinations[trigger], services.Destination{Service: r.service, Recipient: recipient})
+		}
+	}
+	return nil
+}
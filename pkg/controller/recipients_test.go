@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	notificationApi "github.com/argoproj/notifications-engine/pkg/api"
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/argoproj/notifications-engine/pkg/subscriptions"
+)
+
+func TestWithDynamicRecipients(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	for _, tc := range []struct {
+		description string
+		expr        string
+		annotations map[string]string
+		spec        map[string]interface{}
+		expected    services.Destinations
+	}{{
+		description: "SingleRecipient",
+		expr:        `obj.metadata.annotations["team-channel"]`,
+		annotations: map[string]string{"team-channel": "team-a"},
+		expected: services.Destinations{
+			"my-trigger": {{Service: "slack", Recipient: "default"}, {Service: "slack", Recipient: "team-a"}},
+		},
+	}, {
+		description: "MultipleRecipients",
+		expr:        `obj.spec.channels`,
+		spec:        map[string]interface{}{"channels": []interface{}{"team-a", "", "team-b"}},
+		expected: services.Destinations{
+			"my-trigger": {{Service: "slack", Recipient: "default"}, {Service: "slack", Recipient: "team-a"}, {Service: "slack", Recipient: "team-b"}},
+		},
+	}, {
+		description: "EmptyResult",
+		expr:        `obj.metadata.annotations["team-channel"]`,
+		annotations: map[string]string{"team-channel": ""},
+		expected: services.Destinations{
+			"my-trigger": {{Service: "slack", Recipient: "default"}},
+		},
+	}} {
+		t.Run(tc.description, func(t *testing.T) {
+			annotations := map[string]string{subscriptions.SubscribeAnnotationKey("my-trigger", "slack"): "default"}
+			for k, v := range tc.annotations {
+				annotations[k] = v
+			}
+			app := newResource("test", withAnnotations(annotations), func(app *unstructured.Unstructured) {
+				if tc.spec != nil {
+					app.Object["spec"] = tc.spec
+				}
+			})
+			ctrl, _, err := newController(t, ctx, newFakeClient(app), WithDynamicRecipients("slack", tc.expr))
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, ctrl.getDestinations(app, notificationApi.Config{}))
+		})
+	}
+}
+
+func TestWithDynamicRecipients_OtherServiceNotAffected(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := newResource("test", withAnnotations(map[string]string{
+		subscriptions.SubscribeAnnotationKey("my-trigger", "email"): "team@example.com",
+		"team-channel": "team-a",
+	}))
+	ctrl, _, err := newController(t, ctx, newFakeClient(app), WithDynamicRecipients("slack", `obj.metadata.annotations["team-channel"]`))
+	require.NoError(t, err)
+
+	assert.Equal(t, services.Destinations{
+		"my-trigger": {{Service: "email", Recipient: "team@example.com"}},
+	}, ctrl.getDestinations(app, notificationApi.Config{}))
+}
+
+func TestDynamicRecipients_InvalidResult(t *testing.T) {
+	recipients, err := newDynamicRecipients("slack", `42`)
+	require.NoError(t, err)
+
+	_, err = recipients.resolve(map[string]interface{}{})
+	assert.EqualError(t, err, "recipients expression '42' must return a string or a list of strings, got int")
+}
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/argoproj/notifications-engine/pkg/services"
+)
+
+const (
+	skipReasonAlreadyNotified = "already notified"
+	skipReasonDryRun          = "dry run"
+	skipReasonSuppressed      = "suppressed"
+	skipReasonCancelled       = "cancelled"
+	skipReasonEmpty           = "empty"
+)
+
+// DeliveryEvent describes the outcome of delivering a notification to a single destination
+type DeliveryEvent struct {
+	// Resource is the resource the notification is about
+	Resource v1.Object
+	// Trigger is the trigger of the notification
+	Trigger string
+	// Destination is the destination of the notification
+	Destination services.Destination
+	// Templates is the list of templates used to render the notification
+	Templates []string
+	// StartedAt is the time the delivery started
+	StartedAt time.Time
+	// Duration is the time it took to deliver the notification, including retries
+	Duration time.Duration
+	// CorrelationID identifies the delivery in the controller logs
+	CorrelationID string
+}
+
+// DeliverySink receives delivery outcomes as soon as each destination is resolved. Methods are invoked
+// synchronously by the controller workers, so implementations must be safe for concurrent use and should not block.
+type DeliverySink interface {
+	// OnDelivered is invoked when the notification was sent
+	OnDelivered(event DeliveryEvent)
+	// OnError is invoked when the notification could not be sent
+	OnError(event DeliveryEvent, err error)
+	// OnSkipped is invoked when the notification was not sent, e.g. because it was already sent before
+	OnSkipped(event DeliveryEvent, reason string)
+}
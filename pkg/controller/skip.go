@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+// WithSkipUnchangedSince configures the controller to skip processing a resource if the RFC3339 timestamp stored
+// in the given field (e.g. "status.operationState.finishedAt") is older than d and none of its subscription
+// annotations changed since it was last processed. Resources without a valid timestamp and resources whose last
+// processing failed are always processed.
+func WithSkipUnchangedSince(field string, d time.Duration) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.skipUnchanged = &unchangedFilter{
+			fields:        strings.Split(field, "."),
+			since:         d,
+			subscriptions: map[string]string{},
+		}
+	}
+}
+
+type unchangedFilter struct {
+	fields []string
+	since  time.Duration
+
+	lock sync.Mutex
+	// subscriptions holds the subscription annotations of the resources at the time they were last processed
+	subscriptions map[string]string
+}
+
+// subscriptionsOf returns a stable representation of the subscription annotations of the resource
+func (c *notificationController) subscriptionsOf(resource v1.Object) string {
+	var res []string
+	for k, v := range resource.GetAnnotations() {
+		if c.subscriptionOpts.IsSubscriptionAnnotation(k) {
+			res = append(res, k+"="+v)
+		}
+	}
+	sort.Strings(res)
+	return strings.Join(res, "\n")
+}
+
+// isUnchanged returns true and the skip reason if the resource last transitioned too long ago and its subscriptions
+// did not change since it was last processed. Otherwise, the subscriptions of the resource are recorded as processed.
+func (c *notificationController) isUnchanged(resource v1.Object) (bool, string) {
+	key, err := cache.MetaNamespaceKeyFunc(resource)
+	if err != nil {
+		return false, ""
+	}
+	subscriptions := c.subscriptionsOf(resource)
+
+	f := c.skipUnchanged
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	processed, seen := f.subscriptions[key]
+	f.subscriptions[key] = subscriptions
+	if !seen || processed != subscriptions {
+		return false, ""
+	}
+
+	un, err := c.toUnstructured(resource)
+	if err != nil {
+		return false, ""
+	}
+	field := strings.Join(f.fields, ".")
+	value, found, err := unstructured.NestedString(un.Object, f.fields...)
+	if err != nil || !found {
+		return false, ""
+	}
+	transitionedAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		c.logger.WithField("resource", key).Warnf("Failed to parse field '%s' value '%s': %v", field, value, err)
+		return false, ""
+	}
+	if c.now().Sub(transitionedAt) > f.since {
+		return true, fmt.Sprintf("%s is older than %s and subscriptions are unchanged", field, f.since)
+	}
+	return false, ""
+}
+
+// forgetFailed drops the recorded subscriptions of the resource if its processing failed, so that the failed
+// notifications are attempted again once the resource is processed, e.g. on resync, even if it is unchanged
+func (c *notificationController) forgetFailed(key string, eventSequence *NotificationEventSequence) {
+	if len(eventSequence.Errors) > 0 || len(eventSequence.Warnings) > 0 {
+		c.skipUnchanged.forget(key)
+	}
+}
+
+// forget drops the recorded subscriptions of the resource, so that it is processed again even if it is unchanged
+func (f *unchangedFilter) forget(key string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	delete(f.subscriptions, key)
+}
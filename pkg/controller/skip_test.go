@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	notificationApi "github.com/argoproj/notifications-engine/pkg/api"
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/argoproj/notifications-engine/pkg/subscriptions"
+	"github.com/argoproj/notifications-engine/pkg/triggers"
+)
+
+const finishedAtField = "status.operationState.finishedAt"
+
+func withFinishedAt(finishedAt time.Time) func(obj *unstructured.Unstructured) {
+	return func(app *unstructured.Unstructured) {
+		_ = unstructured.SetNestedField(app.Object, finishedAt.Format(time.RFC3339), "status", "operationState", "finishedAt")
+	}
+}
+
+func TestSkipUnchangedSince(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	subscribed := withAnnotations(map[string]string{
+		subscriptions.SubscribeAnnotationKey("my-trigger", "mock"): "recipient",
+	})
+
+	t.Run("OldResourceSkipped", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+		app := newResource("test", subscribed, withFinishedAt(now.Add(-2*time.Hour)))
+
+		var actualSequence *NotificationEventSequence
+		ctrl, _, err := newController(t, ctx, newFakeClient(app),
+			WithSkipUnchangedSince(finishedAtField, time.Hour),
+			WithEventCallback(func(eventSequence NotificationEventSequence) {
+				actualSequence = &eventSequence
+			}))
+		assert.NoError(t, err)
+		ctrl.now = func() time.Time { return now }
+
+		// the resource has not been processed yet
+		unchanged, _ := ctrl.isUnchanged(app)
+		assert.False(t, unchanged)
+
+		// the mock api does not expect any call, so the resource must not be processed
+		ctrl.processQueueItem()
+
+		assert.Equal(t, "status.operationState.finishedAt is older than 1h0m0s and subscriptions are unchanged", actualSequence.SkipReason)
+		assert.Empty(t, actualSequence.Delivered)
+	})
+
+	t.Run("RecentlyChangedResourceProcessed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+		app := newResource("test", subscribed, withFinishedAt(now.Add(-5*time.Minute)))
+
+		ctrl, _, err := newController(t, ctx, newFakeClient(app), WithSkipUnchangedSince(finishedAtField, time.Hour))
+		assert.NoError(t, err)
+		ctrl.now = func() time.Time { return now }
+
+		for i := 0; i < 2; i++ {
+			unchanged, reason := ctrl.isUnchanged(app)
+			assert.False(t, unchanged)
+			assert.Empty(t, reason)
+		}
+	})
+
+	t.Run("SubscriptionChangedResourceProcessed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+		app := newResource("test", subscribed, withFinishedAt(now.Add(-2*time.Hour)))
+
+		ctrl, _, err := newController(t, ctx, newFakeClient(app), WithSkipUnchangedSince(finishedAtField, time.Hour))
+		assert.NoError(t, err)
+		ctrl.now = func() time.Time { return now }
+
+		unchanged, _ := ctrl.isUnchanged(app)
+		assert.False(t, unchanged)
+
+		app.SetAnnotations(map[string]string{
+			subscriptions.SubscribeAnnotationKey("my-trigger", "mock"): "recipient;another-recipient",
+		})
+		unchanged, _ = ctrl.isUnchanged(app)
+		assert.False(t, unchanged)
+
+		unchanged, _ = ctrl.isUnchanged(app)
+		assert.True(t, unchanged)
+	})
+
+	t.Run("FailedResourceProcessedAgain", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+		app := newResource("test", subscribed, withFinishedAt(now.Add(-2*time.Hour)))
+
+		var actualSequence *NotificationEventSequence
+		ctrl, api, err := newController(t, ctx, newFakeClient(app),
+			WithSkipUnchangedSince(finishedAtField, time.Hour),
+			WithEventCallback(func(eventSequence NotificationEventSequence) {
+				actualSequence = &eventSequence
+			}))
+		assert.NoError(t, err)
+		ctrl.now = func() time.Time { return now }
+		dest := services.Destination{Service: "mock", Recipient: "recipient"}
+		api.EXPECT().GetConfig().Return(notificationApi.Config{}).AnyTimes()
+		api.EXPECT().RunTrigger("my-trigger", gomock.Any()).Return([]triggers.ConditionResult{{Triggered: true, Templates: []string{"test"}}}, nil).Times(2)
+		gomock.InOrder(
+			api.EXPECT().SendContext(gomock.Any(), gomock.Any(), []string{"test"}, dest).Return(assert.AnError),
+			api.EXPECT().SendContext(gomock.Any(), gomock.Any(), []string{"test"}, dest).Return(nil),
+		)
+
+		// the failed delivery is attempted again although the resource is unchanged
+		ctrl.processQueueItem()
+		assert.Len(t, actualSequence.Errors, 1)
+		ctrl.queue.Add("default/test")
+		ctrl.processQueueItem()
+		assert.Empty(t, actualSequence.SkipReason)
+		assert.Empty(t, actualSequence.Errors)
+
+		// the resource is skipped once it is processed successfully
+		ctrl.queue.Add("default/test")
+		ctrl.processQueueItem()
+		assert.NotEmpty(t, actualSequence.SkipReason)
+	})
+
+	t.Run("DeletedResourceForgotten", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+		app := newResource("test", subscribed, withFinishedAt(now.Add(-2*time.Hour)))
+		client := newFakeClient(app)
+
+		ctrl, _, err := newController(t, ctx, client, WithSkipUnchangedSince(finishedAtField, time.Hour))
+		assert.NoError(t, err)
+		ctrl.now = func() time.Time { return now }
+
+		unchanged, _ := ctrl.isUnchanged(app)
+		assert.False(t, unchanged)
+
+		assert.NoError(t, client.Resource(testGVR).Namespace(testNamespace).Delete(ctx, "test", v1.DeleteOptions{}))
+		assert.Eventually(t, func() bool {
+			ctrl.skipUnchanged.lock.Lock()
+			defer ctrl.skipUnchanged.lock.Unlock()
+			_, seen := ctrl.skipUnchanged.subscriptions["default/test"]
+			return !seen
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("MissingFieldProcessed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+		app := newResource("test", subscribed)
+
+		ctrl, _, err := newController(t, ctx, newFakeClient(app), WithSkipUnchangedSince(finishedAtField, time.Hour))
+		assert.NoError(t, err)
+		ctrl.now = func() time.Time { return now }
+
+		for i := 0; i < 2; i++ {
+			unchanged, _ := ctrl.isUnchanged(app)
+			assert.False(t, unchanged)
+		}
+	})
+}
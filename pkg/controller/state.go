@@ -1,11 +1,17 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
+	log "github.com/sirupsen/logrus"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/argoproj/notifications-engine/pkg/services"
@@ -15,8 +21,48 @@ import (
 
 const (
 	notifiedHistoryMaxSize = 100
+	// notifiedStateMaxSize keeps the notified state annotation well below the 256KB limit of all annotations
+	notifiedStateMaxSize = 64 * 1024
+	// notifiedDataMaxEntries and notifiedDataMaxSize bound the notified data annotation independently of the limits
+	// of the notified state configured by the notifications config
+	notifiedDataMaxEntries = 100
+	notifiedDataMaxSize    = 64 * 1024
+	// previousMessageMaxSize bounds each kept message, and previousMessagesMaxEntries and previousMessagesMaxSize bound
+	// all kept messages, so that they leave most of the notified data annotation to the values of notification
+	// services and failure counts
+	previousMessageMaxSize     = 1024
+	previousMessagesMaxEntries = 20
+	previousMessagesMaxSize    = 16 * 1024
 )
 
+// serviceStatePrefix marks the entries of the notified state which hold the values of notification services. The key
+// and the value are encoded in the key of the entry, so that the format of the state does not change, and the entry
+// holds the time the value was set, so that the values which are no longer set are evicted first.
+const serviceStatePrefix = "service:"
+
+// failureCountPrefix marks the entries of the notified state which count the consecutive evaluations in which a trigger
+// condition was triggered and a notification about it was delivered. Like the values of notification services, the
+// count is encoded in the key of the entry.
+const failureCountPrefix = "failures:"
+
+// notifiedDataPrefixes are the prefixes of the entries of the notified state which do not record a delivery. They are
+// persisted in the notified data annotation with limits of their own, so that they neither evict the records of
+// deliveries, which would send the notifications again, nor are evicted by them.
+var notifiedDataPrefixes = []string{serviceStatePrefix, failureCountPrefix, previousMessagePrefix}
+
+func isNotifiedDataEntry(entry string) bool {
+	for _, prefix := range notifiedDataPrefixes {
+		if strings.HasPrefix(entry, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// previousMessagePrefix marks the entries of the notified state which hold the message last delivered about a trigger
+// condition to a destination, encoded in the key of the entry like the values of notification services
+const previousMessagePrefix = "message:"
+
 func StateItemKey(isSelfConfig bool, apiNamespace, trigger string, conditionResult triggers.ConditionResult, dest services.Destination) string {
 	var key string
 	if isSelfConfig {
@@ -53,8 +99,21 @@ func (s NotificationsState) truncate(maxSize int) {
 
 // SetAlreadyNotified set the state of given trigger/destination and return if state has been changed
 func (s NotificationsState) SetAlreadyNotified(isSelfConfig bool, apiNamespace, trigger string, result triggers.ConditionResult, dest services.Destination, isNotified bool) bool {
+	return s.SetAlreadyNotifiedWithWindow(isSelfConfig, apiNamespace, trigger, result, dest, isNotified, 0)
+}
+
+// SetAlreadyNotifiedWithWindow is the same as SetAlreadyNotified but treats notifications sent longer than
+// the deduplication window ago as not sent, so the notification is delivered again. Zero window means notify once.
+func (s NotificationsState) SetAlreadyNotifiedWithWindow(isSelfConfig bool, apiNamespace, trigger string, result triggers.ConditionResult, dest services.Destination, isNotified bool, window time.Duration) bool {
 	key := StateItemKey(isSelfConfig, apiNamespace, trigger, result, dest)
-	if _, alreadyNotified := s[key]; alreadyNotified == isNotified {
+	if isSelfConfig {
+		s.migrateLegacyKey(key, StateItemKey(false, apiNamespace, trigger, result, dest))
+	}
+	notifiedAt, alreadyNotified := s[key]
+	if isNotified && alreadyNotified && window > 0 && time.Since(time.Unix(notifiedAt, 0)) >= window {
+		alreadyNotified = false
+	}
+	if alreadyNotified == isNotified {
 		return false
 	}
 	if isNotified {
@@ -68,10 +127,46 @@ func (s NotificationsState) SetAlreadyNotified(isSelfConfig bool, apiNamespace,
 	return true
 }
 
+// unmarkNotified removes the mark of a notification which was marked as notified but then not delivered, e.g. because
+// it was suppressed. Unlike SetAlreadyNotified, the mark is removed for conditions with oncePer as well, since the
+// notification was never sent for the current oncePer value.
+func (s NotificationsState) unmarkNotified(isSelfConfig bool, apiNamespace, trigger string, result triggers.ConditionResult, dest services.Destination) {
+	delete(s, StateItemKey(isSelfConfig, apiNamespace, trigger, result, dest))
+}
+
+// migrateLegacyKey moves the entry of the legacy key to the key unless the key has an entry already. Resources of
+// namespaces with their own configuration used to be processed with the default configuration as well, which recorded
+// its deliveries without the namespace, so these deliveries are not sent again by the merged configuration.
+func (s NotificationsState) migrateLegacyKey(key string, legacyKey string) {
+	if _, ok := s[key]; ok {
+		return
+	}
+	if notifiedAt, ok := s[legacyKey]; ok {
+		s[key] = notifiedAt
+		delete(s, legacyKey)
+	}
+}
+
 func (s NotificationsState) Persist(res metav1.Object) (map[string]string, error) {
-	s.truncate(notifiedHistoryMaxSize)
+	return s.PersistWithLimits(res, notifiedHistoryMaxSize, notifiedStateMaxSize)
+}
+
+// PersistWithLimits is the same as Persist but keeps at most maxEntries items and evicts the oldest items
+// until the serialized state fits into maxSize bytes. Zero limits fall back to the defaults.
+func (s NotificationsState) PersistWithLimits(res metav1.Object, maxEntries int, maxSize int) (map[string]string, error) {
+	return s.persist(res, subscriptions.Options{}, maxEntries, maxSize)
+}
+
+// persist returns the annotations of the resource with the notified state. The entries which do not record a delivery
+// are persisted in the notified data annotation.
+func (s NotificationsState) persist(res metav1.Object, opts subscriptions.Options, maxEntries int, maxSize int) (map[string]string, error) {
+	if maxEntries <= 0 {
+		maxEntries = notifiedHistoryMaxSize
+	}
+	if maxSize <= 0 {
+		maxSize = notifiedStateMaxSize
+	}
 
-	notifiedAnnotationKey := subscriptions.NotifiedAnnotationKey()
 	annotations := map[string]string{}
 
 	if res.GetAnnotations() != nil {
@@ -80,19 +175,66 @@ func (s NotificationsState) Persist(res metav1.Object) (map[string]string, error
 		}
 	}
 
-	if len(s) == 0 {
-		delete(annotations, notifiedAnnotationKey)
-	} else {
-		stateJson, err := json.Marshal(s)
-		if err != nil {
-			return nil, err
+	notified, data := s.split()
+	if err := data.truncatePreviousMessages(); err != nil {
+		return nil, err
+	}
+	if err := notified.persistAnnotation(res, annotations, opts.NotifiedAnnotationKey(), maxEntries, maxSize); err != nil {
+		return nil, err
+	}
+	if err := data.persistAnnotation(res, annotations, opts.NotifiedDataAnnotationKey(), notifiedDataMaxEntries, notifiedDataMaxSize); err != nil {
+		return nil, err
+	}
+	// the evicted entries are dropped from the state as well
+	for k := range s {
+		_, isNotified := notified[k]
+		_, isData := data[k]
+		if !isNotified && !isData {
+			delete(s, k)
 		}
-		annotations[notifiedAnnotationKey] = string(stateJson)
 	}
-
 	return annotations, nil
 }
 
+// split returns the entries which record deliveries and the entries which are persisted in the notified data annotation
+func (s NotificationsState) split() (NotificationsState, NotificationsState) {
+	notified, data := NotificationsState{}, NotificationsState{}
+	for k, v := range s {
+		if isNotifiedDataEntry(k) {
+			data[k] = v
+		} else {
+			notified[k] = v
+		}
+	}
+	return notified, data
+}
+
+// persistAnnotation sets the annotation to the state, or removes it if the state is empty, after evicting the oldest
+// entries until the state fits into the limits
+func (s NotificationsState) persistAnnotation(res metav1.Object, annotations map[string]string, annotationKey string, maxEntries int, maxSize int) error {
+	s.truncate(maxEntries)
+	if len(s) == 0 {
+		delete(annotations, annotationKey)
+		return nil
+	}
+	stateJson, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if len(stateJson) > maxSize {
+		entries := len(s)
+		for len(stateJson) > maxSize && len(s) > 1 {
+			s.truncate(len(s) - 1)
+			if stateJson, err = json.Marshal(s); err != nil {
+				return err
+			}
+		}
+		log.Warnf("Annotation %s of %s/%s exceeds %d bytes, evicted %d oldest entries", annotationKey, res.GetNamespace(), res.GetName(), maxSize, entries-len(s))
+	}
+	annotations[annotationKey] = string(stateJson)
+	return nil
+}
+
 func NewState(val string) NotificationsState {
 	if val == "" {
 		return NotificationsState{}
@@ -105,9 +247,211 @@ func NewState(val string) NotificationsState {
 }
 
 func NewStateFromRes(res metav1.Object) NotificationsState {
-	notifiedAnnotationKey := subscriptions.NotifiedAnnotationKey()
-	if annotations := res.GetAnnotations(); annotations != nil {
-		return NewState(annotations[notifiedAnnotationKey])
+	return newStateFromRes(res, subscriptions.Options{})
+}
+
+// newStateFromRes returns the notified state of the resource, including the entries of the notified data annotation
+func newStateFromRes(res metav1.Object, opts subscriptions.Options) NotificationsState {
+	annotations := res.GetAnnotations()
+	if annotations == nil {
+		return NotificationsState{}
+	}
+	state := NewState(annotations[opts.NotifiedAnnotationKey()])
+	for k, v := range NewState(annotations[opts.NotifiedDataAnnotationKey()]) {
+		state[k] = v
+	}
+	return state
+}
+
+// failureCountKey returns the key of the condition whose consecutive triggered and delivered evaluations are counted. Unlike
+// StateItemKey, the key includes neither the destination nor the oncePer value, so that the evaluations of the same
+// condition, e.g. for every failed revision, are counted together.
+func failureCountKey(isSelfConfig bool, apiNamespace, trigger string, conditionResult triggers.ConditionResult) string {
+	if isSelfConfig {
+		return fmt.Sprintf("%s:%s:%s", apiNamespace, trigger, conditionResult.Key)
+	}
+	return fmt.Sprintf("%s:%s", trigger, conditionResult.Key)
+}
+
+func parseFailureCountEntryKey(entry string) (string, int, bool) {
+	if !strings.HasPrefix(entry, failureCountPrefix) {
+		return "", 0, false
+	}
+	count, key, ok := strings.Cut(entry[len(failureCountPrefix):], ":")
+	if !ok {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return "", 0, false
+	}
+	return key, n, true
+}
+
+// failureCount returns the number of consecutive evaluations in which the condition was triggered and delivered
+func (s NotificationsState) failureCount(key string) int {
+	for entry := range s {
+		if entryKey, count, ok := parseFailureCountEntryKey(entry); ok && entryKey == key {
+			return count
+		}
+	}
+	return 0
+}
+
+// setFailureCount sets the number of consecutive evaluations in which the condition was triggered and delivered. Zero
+// count removes the entry.
+func (s NotificationsState) setFailureCount(key string, count int) {
+	for entry := range s {
+		if entryKey, _, ok := parseFailureCountEntryKey(entry); ok && entryKey == key {
+			delete(s, entry)
+		}
+	}
+	if count > 0 {
+		s[fmt.Sprintf("%s%d:%s", failureCountPrefix, count, key)] = time.Now().Unix()
+	}
+}
+
+// stateEntryKey returns the key of the entry which holds the key and the value, e.g. the value of a notification service
+func stateEntryKey(prefix string, key string, value string) string {
+	data, _ := json.Marshal([]string{key, value})
+	return prefix + string(data)
+}
+
+func parseStateEntryKey(prefix string, entry string) (string, string, bool) {
+	if !strings.HasPrefix(entry, prefix) {
+		return "", "", false
+	}
+	var keyValue []string
+	if err := json.Unmarshal([]byte(entry[len(prefix):]), &keyValue); err != nil || len(keyValue) != 2 {
+		return "", "", false
+	}
+	return keyValue[0], keyValue[1], true
+}
+
+// previousMessageKey returns the key of the condition and destination whose last delivered message is kept. The key
+// does not include the oncePer value, so that the message delivered e.g. for the previous revision is available.
+func previousMessageKey(isSelfConfig bool, apiNamespace, trigger string, conditionResult triggers.ConditionResult, dest services.Destination) string {
+	return StateItemKey(isSelfConfig, apiNamespace, trigger, triggers.ConditionResult{Key: conditionResult.Key}, dest)
+}
+
+// previousMessage returns the message last delivered about the condition to the destination, or an empty string
+func (s NotificationsState) previousMessage(key string) string {
+	for entry := range s {
+		if entryKey, message, ok := parseStateEntryKey(previousMessagePrefix, entry); ok && entryKey == key {
+			return message
+		}
+	}
+	return ""
+}
+
+// setPreviousMessage replaces the message last delivered about the condition to the destination. Messages longer than
+// previousMessageMaxSize bytes are truncated.
+func (s NotificationsState) setPreviousMessage(key string, message string) {
+	for entry := range s {
+		if entryKey, _, ok := parseStateEntryKey(previousMessagePrefix, entry); ok && entryKey == key {
+			delete(s, entry)
+		}
+	}
+	s[stateEntryKey(previousMessagePrefix, key, truncateMessage(message, previousMessageMaxSize))] = time.Now().Unix()
+}
+
+// truncatePreviousMessages evicts the oldest kept messages until the messages fit into their own limits
+func (s NotificationsState) truncatePreviousMessages() error {
+	messages := NotificationsState{}
+	for k, v := range s {
+		if strings.HasPrefix(k, previousMessagePrefix) {
+			messages[k] = v
+		}
+	}
+	messages.truncate(previousMessagesMaxEntries)
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return err
+	}
+	for len(data) > previousMessagesMaxSize && len(messages) > 0 {
+		messages.truncate(len(messages) - 1)
+		if data, err = json.Marshal(messages); err != nil {
+			return err
+		}
+	}
+	for k := range s {
+		if _, ok := messages[k]; strings.HasPrefix(k, previousMessagePrefix) && !ok {
+			delete(s, k)
+		}
+	}
+	return nil
+}
+
+// truncateMessage shortens the message to at most n bytes including the appended ellipsis without splitting a
+// character
+func truncateMessage(message string, n int) string {
+	const ellipsis = "..."
+	if len(message) <= n {
+		return message
+	}
+	end := n - len(ellipsis)
+	for end > 0 && !utf8.RuneStart(message[end]) {
+		end--
+	}
+	return message[:end] + ellipsis
+}
+
+// deliveryServiceState is the state of the notification services during a delivery. The values are set on a copy,
+// which is merged into the notified state once the delivery completes, so that a service which outlives the timeout of
+// the delivery does not modify the notified state while it is persisted.
+type deliveryServiceState struct {
+	lock    sync.Mutex
+	values  map[string]string
+	changed map[string]string
+}
+
+func (s *deliveryServiceState) Get(key string) (string, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	value, ok := s.values[key]
+	return value, ok
+}
+
+func (s *deliveryServiceState) Set(key string, value string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.values[key] = value
+	s.changed[key] = value
+}
+
+// serviceState returns a copy of the values of notification services held by the state
+func (s NotificationsState) serviceState() *deliveryServiceState {
+	state := &deliveryServiceState{values: map[string]string{}, changed: map[string]string{}}
+	for entry := range s {
+		if key, value, ok := parseStateEntryKey(serviceStatePrefix, entry); ok {
+			state.values[key] = value
+		}
+	}
+	return state
+}
+
+// setServiceState merges the values set during the delivery into the state
+func (s NotificationsState) setServiceState(state *deliveryServiceState) {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	if len(state.changed) == 0 {
+		return
+	}
+	for entry := range s {
+		if key, _, ok := parseStateEntryKey(serviceStatePrefix, entry); ok {
+			if _, changed := state.changed[key]; changed {
+				delete(s, entry)
+			}
+		}
+	}
+	for key, value := range state.changed {
+		s[stateEntryKey(serviceStatePrefix, key, value)] = time.Now().Unix()
+	}
+}
+
+// withServiceState passes the state to the notification service, so that it can persist values with the notified state
+func withServiceState(send func(ctx context.Context) error, state services.ServiceState) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return send(services.WithServiceState(ctx, state))
 	}
-	return NotificationsState{}
 }
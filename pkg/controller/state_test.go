@@ -2,13 +2,18 @@ package controller
 
 import (
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/argoproj/notifications-engine/pkg/triggers"
 
 	"github.com/argoproj/notifications-engine/pkg/services"
 
 	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/argoproj/notifications-engine/pkg/subscriptions"
 )
 
 func TestNotificationState_Truncate(t *testing.T) {
@@ -56,3 +61,160 @@ func TestSetAlreadyNotified_OncePerItem(t *testing.T) {
 	_, ok = state["abc:app-synced:0:slack:my-channel"]
 	assert.True(t, ok)
 }
+
+func TestSetAlreadyNotifiedWithWindow(t *testing.T) {
+	dest := services.Destination{Service: "slack", Recipient: "my-channel"}
+	key := "app-synced:0:slack:my-channel"
+
+	t.Run("ZeroWindowNotifiesOnce", func(t *testing.T) {
+		state := NotificationsState{key: time.Now().Add(-24 * time.Hour).Unix()}
+		changed := state.SetAlreadyNotifiedWithWindow(false, "", "app-synced", triggers.ConditionResult{Key: "0"}, dest, true, 0)
+		assert.False(t, changed)
+	})
+
+	t.Run("WithinWindow", func(t *testing.T) {
+		state := NotificationsState{key: time.Now().Add(-time.Minute).Unix()}
+		changed := state.SetAlreadyNotifiedWithWindow(false, "", "app-synced", triggers.ConditionResult{Key: "0"}, dest, true, time.Hour)
+		assert.False(t, changed)
+	})
+
+	t.Run("WindowElapsed", func(t *testing.T) {
+		notifiedAt := time.Now().Add(-2 * time.Hour).Unix()
+		state := NotificationsState{key: notifiedAt}
+		changed := state.SetAlreadyNotifiedWithWindow(false, "", "app-synced", triggers.ConditionResult{Key: "0"}, dest, true, time.Hour)
+		assert.True(t, changed)
+		assert.Greater(t, state[key], notifiedAt)
+	})
+}
+
+func TestPersistWithLimits(t *testing.T) {
+	res := &unstructured.Unstructured{}
+	res.SetAnnotations(map[string]string{"foo": "bar"})
+
+	t.Run("MaxEntries", func(t *testing.T) {
+		state := NotificationsState{}
+		for i := 0; i < 5; i++ {
+			state[strconv.Itoa(i)] = int64(i)
+		}
+
+		annotations, err := state.PersistWithLimits(res, 2, 0)
+		assert.NoError(t, err)
+
+		assert.Equal(t, "bar", annotations["foo"])
+		assert.Equal(t, NotificationsState{"3": 3, "4": 4}, NewState(annotations[subscriptions.NotifiedAnnotationKey()]))
+	})
+
+	t.Run("MaxSize", func(t *testing.T) {
+		state := NotificationsState{}
+		for i := 0; i < 5; i++ {
+			state[strconv.Itoa(i)] = int64(i)
+		}
+
+		annotations, err := state.PersistWithLimits(res, 0, len(`{"3":3,"4":4}`))
+		assert.NoError(t, err)
+
+		assert.Equal(t, NotificationsState{"3": 3, "4": 4}, NewState(annotations[subscriptions.NotifiedAnnotationKey()]))
+	})
+
+	t.Run("WithinLimits", func(t *testing.T) {
+		state := NotificationsState{"0": 0, "1": 1}
+
+		annotations, err := state.PersistWithLimits(res, 10, 1024)
+		assert.NoError(t, err)
+
+		assert.Equal(t, NotificationsState{"0": 0, "1": 1}, NewState(annotations[subscriptions.NotifiedAnnotationKey()]))
+	})
+
+	t.Run("NotifiedData", func(t *testing.T) {
+		state := NotificationsState{"0": 0, "1": 1, "failures:1:my-trigger:[0].degraded": 2}
+
+		annotations, err := state.PersistWithLimits(res, 2, 0)
+		assert.NoError(t, err)
+
+		// the notified data neither counts toward the limits of the notified state nor evicts its entries
+		assert.Equal(t, NotificationsState{"0": 0, "1": 1}, NewState(annotations[subscriptions.NotifiedAnnotationKey()]))
+		assert.Equal(t, NotificationsState{"failures:1:my-trigger:[0].degraded": 2}, NewState(annotations[subscriptions.Options{}.NotifiedDataAnnotationKey()]))
+
+		res := &unstructured.Unstructured{}
+		res.SetAnnotations(annotations)
+		assert.Equal(t, state, NewStateFromRes(res))
+	})
+}
+
+func TestServiceState(t *testing.T) {
+	state := NotificationsState{"my-trigger::mock:recipient": 1}
+
+	serviceState := state.serviceState()
+	_, ok := serviceState.Get("slack/status")
+	assert.False(t, ok)
+	serviceState.Set("slack/status", "C123/1")
+	state.setServiceState(serviceState)
+	assert.Len(t, state, 2)
+
+	serviceState = state.serviceState()
+	value, ok := serviceState.Get("slack/status")
+	assert.True(t, ok)
+	assert.Equal(t, "C123/1", value)
+	serviceState.Set("slack/status", "C123/2")
+	state.setServiceState(serviceState)
+
+	// the previous value is replaced and the notified state is kept
+	assert.Len(t, state, 2)
+	assert.Contains(t, state, "my-trigger::mock:recipient")
+	value, _ = state.serviceState().Get("slack/status")
+	assert.Equal(t, "C123/2", value)
+
+	// the values are persisted apart from the records of the deliveries
+	annotations, err := state.PersistWithLimits(&unstructured.Unstructured{}, 1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, NotificationsState{"my-trigger::mock:recipient": 1}, NewState(annotations[subscriptions.NotifiedAnnotationKey()]))
+	value, _ = NewState(annotations[subscriptions.Options{}.NotifiedDataAnnotationKey()]).serviceState().Get("slack/status")
+	assert.Equal(t, "C123/2", value)
+}
+
+func TestFailureCount(t *testing.T) {
+	state := NotificationsState{"my-trigger:[0].degraded:mock:recipient": 1}
+	key := failureCountKey(false, "", "my-trigger", triggers.ConditionResult{Key: "[0].degraded", OncePer: "abc"})
+	assert.Equal(t, "my-trigger:[0].degraded", key)
+	assert.Equal(t, 0, state.failureCount(key))
+
+	state.setFailureCount(key, 1)
+	state.setFailureCount(key, 2)
+	assert.Equal(t, 2, state.failureCount(key))
+	assert.Len(t, state, 2)
+	assert.Contains(t, state, "failures:2:my-trigger:[0].degraded")
+
+	state.setFailureCount(key, 0)
+	assert.Equal(t, 0, state.failureCount(key))
+	assert.Equal(t, NotificationsState{"my-trigger:[0].degraded:mock:recipient": 1}, state)
+}
+
+func TestPreviousMessage(t *testing.T) {
+	state := NotificationsState{}
+	serviceState := state.serviceState()
+	serviceState.Set("slack/status", "C123/1")
+	state.setServiceState(serviceState)
+
+	key := previousMessageKey(false, "", "my-trigger", triggers.ConditionResult{Key: "[0].degraded", OncePer: "abc"}, services.Destination{Service: "mock", Recipient: "recipient"})
+	assert.Equal(t, "my-trigger:[0].degraded:mock:recipient", key)
+	assert.Empty(t, state.previousMessage(key))
+
+	// large messages are truncated
+	state.setPreviousMessage(key, strings.Repeat("a", 100*1024))
+	assert.Equal(t, strings.Repeat("a", previousMessageMaxSize-3)+"...", state.previousMessage(key))
+	state.setPreviousMessage(key, "sync failed")
+	assert.Equal(t, "sync failed", state.previousMessage(key))
+	assert.Len(t, state, 2)
+
+	// the messages to many destinations evict each other instead of the values of notification services
+	for i := 0; i < notifiedDataMaxEntries; i++ {
+		state.setPreviousMessage(key+strconv.Itoa(i), strings.Repeat("a", 100*1024))
+	}
+	annotations, err := state.PersistWithLimits(&unstructured.Unstructured{}, 0, 0)
+	assert.NoError(t, err)
+	data := NewState(annotations[subscriptions.Options{}.NotifiedDataAnnotationKey()])
+	value, ok := data.serviceState().Get("slack/status")
+	assert.True(t, ok)
+	assert.Equal(t, "C123/1", value)
+	assert.Less(t, len(data), previousMessagesMaxEntries+2)
+}
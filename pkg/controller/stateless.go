@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"sync"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// WithStatelessMode configures the controller to neither read nor write the notified state annotation of resources,
+// e.g. for short-lived resources which are deleted right after they are processed. The resources are never patched.
+// Instead, the notified state is kept in memory until the resource is deleted, so notifications are not sent again
+// during the lifetime of the process.
+func WithStatelessMode(enabled bool) Opts {
+	return func(ctrl *notificationController) {
+		if enabled {
+			ctrl.stateless = &memoryState{states: map[string]NotificationsState{}}
+		} else {
+			ctrl.stateless = nil
+		}
+	}
+}
+
+// memoryState holds the notified state of resources in memory
+type memoryState struct {
+	lock   sync.Mutex
+	states map[string]NotificationsState
+}
+
+// load returns a copy of the notified state of the resource
+func (m *memoryState) load(resource v1.Object) NotificationsState {
+	res := NotificationsState{}
+	key, err := cache.MetaNamespaceKeyFunc(resource)
+	if err != nil {
+		return res
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for k, v := range m.states[key] {
+		res[k] = v
+	}
+	return res
+}
+
+// store replaces the notified state of the resource
+func (m *memoryState) store(resource v1.Object, state NotificationsState) {
+	key, err := cache.MetaNamespaceKeyFunc(resource)
+	if err != nil {
+		return
+	}
+	notified, data := state.split()
+	notified.truncate(notifiedHistoryMaxSize)
+	data.truncate(notifiedDataMaxEntries)
+	for k, v := range data {
+		notified[k] = v
+	}
+	state = notified
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if len(state) == 0 {
+		delete(m.states, key)
+	} else {
+		m.states[key] = state
+	}
+}
+
+// forget drops the notified state of a deleted resource
+func (m *memoryState) forget(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.states, key)
+}
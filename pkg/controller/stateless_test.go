@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubetesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+
+	notificationApi "github.com/argoproj/notifications-engine/pkg/api"
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/argoproj/notifications-engine/pkg/subscriptions"
+	"github.com/argoproj/notifications-engine/pkg/triggers"
+)
+
+func TestStatelessMode(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	// the notified state annotation of the resource is ignored
+	state := NotificationsState{}
+	_ = state.SetAlreadyNotified(false, "", "my-trigger", triggers.ConditionResult{}, services.Destination{Service: "mock", Recipient: "recipient"}, true)
+	app := newResource("test", withAnnotations(map[string]string{
+		subscriptions.SubscribeAnnotationKey("my-trigger", "mock"): "recipient",
+		notifiedAnnotationKey: mustToJson(state),
+	}))
+
+	var patches int32
+	client := newFakeClient(app)
+	client.PrependReactor("patch", "*", func(action kubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		atomic.AddInt32(&patches, 1)
+		return true, nil, nil
+	})
+	ctrl, api, err := newController(t, ctx, client, WithStatelessMode(true))
+	assert.NoError(t, err)
+
+	api.EXPECT().GetConfig().Return(notificationApi.Config{}).AnyTimes()
+	api.EXPECT().RunTrigger("my-trigger", gomock.Any()).Return([]triggers.ConditionResult{{Triggered: true, Templates: []string{"test"}}}, nil).Times(2)
+	// the in-memory state prevents sending the notification again
+	api.EXPECT().SendContext(gomock.Any(), gomock.Any(), []string{"test"}, services.Destination{Service: "mock", Recipient: "recipient"}).Return(nil).Times(1)
+
+	for i := 0; i < 2; i++ {
+		eventSequence := NotificationEventSequence{}
+		ctrl.processResource(api, app, logEntry, &eventSequence)
+		assert.Empty(t, eventSequence.Errors)
+		assert.Empty(t, eventSequence.Warnings)
+	}
+	assert.Equal(t, int32(0), atomic.LoadInt32(&patches))
+}
+
+func TestStatelessMode_FailedDeliveryIsRetried(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := newResource("test", withAnnotations(map[string]string{
+		subscriptions.SubscribeAnnotationKey("my-trigger", "mock"): "recipient",
+	}))
+	ctrl, api, err := newController(t, ctx, newFakeClient(app), WithStatelessMode(true))
+	assert.NoError(t, err)
+
+	api.EXPECT().GetConfig().Return(notificationApi.Config{}).AnyTimes()
+	api.EXPECT().RunTrigger("my-trigger", gomock.Any()).Return([]triggers.ConditionResult{{Triggered: true, Templates: []string{"test"}}}, nil).Times(3)
+	gomock.InOrder(
+		api.EXPECT().SendContext(gomock.Any(), gomock.Any(), []string{"test"}, gomock.Any()).Return(assert.AnError),
+		api.EXPECT().SendContext(gomock.Any(), gomock.Any(), []string{"test"}, gomock.Any()).Return(nil),
+	)
+
+	for i := 0; i < 3; i++ {
+		annotations, err := ctrl.processResourceWithAPI(api, app, logEntry, &NotificationEventSequence{})
+		assert.NoError(t, err)
+		assert.Equal(t, app.GetAnnotations(), annotations)
+	}
+}
+
+func TestStatelessMode_ForgetsDeletedResources(t *testing.T) {
+	state := &memoryState{states: map[string]NotificationsState{}}
+	app := newResource("test")
+	state.store(app, NotificationsState{"my-trigger::mock:recipient": 1})
+	assert.Equal(t, NotificationsState{"my-trigger::mock:recipient": 1}, state.load(app))
+
+	state.forget(cache.DeletedFinalStateUnknown{Key: "default/test", Obj: app})
+	assert.Empty(t, state.load(app))
+	assert.Empty(t, state.states)
+}
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// StatusWriter returns the status fields which record the deliveries of a processing iteration of the resource, e.g.
+// the last delivery time per trigger. The fields are merged into the status of the resource. Returning nil skips the
+// update.
+type StatusWriter func(obj v1.Object, deliveries []NotificationDelivery) (map[string]interface{}, error)
+
+// WithStatusWriter configures the controller to record the deliveries in the status subresource of the resource,
+// separately from the notified state annotation. The writer is invoked once the resource is processed, if
+// notifications were sent or failed. Resources without a status subresource are left unchanged.
+func WithStatusWriter(writer StatusWriter) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.statusWriter = writer
+	}
+}
+
+// writeStatus patches the status subresource of the resource with the fields returned by the status writer
+func (c *notificationController) writeStatus(resource v1.Object, logEntry *log.Entry, eventSequence *NotificationEventSequence) {
+	if c.statusWriter == nil || c.dryRun {
+		return
+	}
+	var deliveries []NotificationDelivery
+	for _, delivery := range eventSequence.Delivered {
+		// deliveries which did not reach the service leave the status unchanged, so that writing the status
+		// does not trigger another update once the resource is processed again
+		if delivery.AlreadyNotified || delivery.DryRun || delivery.Suppressed || delivery.Cancelled || delivery.Empty {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if len(deliveries) == 0 {
+		return
+	}
+
+	status, err := c.statusWriter(resource, deliveries)
+	if err != nil {
+		logEntry.Errorf("Failed to write status: %v", err)
+		eventSequence.addWarning(fmt.Errorf("failed to write status %v", err))
+		return
+	}
+	if status == nil {
+		return
+	}
+	patchData, err := json.Marshal(map[string]interface{}{"status": status})
+	if err != nil {
+		logEntry.Errorf("Failed to marshal status patch: %v", err)
+		eventSequence.addWarning(fmt.Errorf("failed to marshal status patch %v", err))
+		return
+	}
+	_, err = c.client.Namespace(resource.GetNamespace()).Patch(context.Background(), resource.GetName(), types.MergePatchType, patchData, v1.PatchOptions{}, "status")
+	if apierrors.IsNotFound(err) {
+		// the resource has no status subresource or was deleted in the meantime
+		logEntry.Debugf("Skipped status update: %v", err)
+		return
+	}
+	if err != nil {
+		logEntry.Errorf("Failed to patch resource status: %v", err)
+		eventSequence.addWarning(fmt.Errorf("failed to patch resource status %v", err))
+	}
+}
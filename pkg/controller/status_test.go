@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubetesting "k8s.io/client-go/testing"
+
+	notificationApi "github.com/argoproj/notifications-engine/pkg/api"
+	"github.com/argoproj/notifications-engine/pkg/mocks"
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/argoproj/notifications-engine/pkg/subscriptions"
+	"github.com/argoproj/notifications-engine/pkg/triggers"
+)
+
+func lastDeliveredStatusWriter(now time.Time) StatusWriter {
+	return func(obj v1.Object, deliveries []NotificationDelivery) (map[string]interface{}, error) {
+		lastDelivered := map[string]interface{}{}
+		for _, delivery := range deliveries {
+			if delivery.Error == nil {
+				lastDelivered[delivery.Trigger] = now.Format(time.RFC3339)
+			}
+		}
+		if len(lastDelivered) == 0 {
+			return nil, nil
+		}
+		return map[string]interface{}{"notifications": map[string]interface{}{"lastDelivered": lastDelivered}}, nil
+	}
+}
+
+func TestWithStatusWriter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := newResource("test", withAnnotations(map[string]string{
+		subscriptions.SubscribeAnnotationKey("my-trigger", "mock"): "recipient",
+	}))
+
+	client := newFakeClient(app)
+	var statusPatches []string
+	client.PrependReactor("patch", "*", func(action kubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		if action.GetSubresource() == "status" {
+			statusPatches = append(statusPatches, string(action.(kubetesting.PatchAction).GetPatch()))
+		}
+		return false, nil, nil
+	})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctrl, api, err := newController(t, ctx, client, WithStatusWriter(lastDeliveredStatusWriter(now)))
+	assert.NoError(t, err)
+	ctrl.namespaceSupport = false
+	ctrl.apiFactory = &mocks.FakeFactory{Api: api}
+
+	api.EXPECT().GetConfig().Return(notificationApi.Config{}).AnyTimes()
+	api.EXPECT().RunTrigger("my-trigger", gomock.Any()).Return([]triggers.ConditionResult{{Triggered: true, Templates: []string{"test"}}}, nil).AnyTimes()
+	api.EXPECT().SendContext(gomock.Any(), gomock.Any(), []string{"test"}, services.Destination{Service: "mock", Recipient: "recipient"}).Return(nil)
+
+	ctrl.processQueueItem()
+
+	assert.Equal(t, []string{`{"status":{"notifications":{"lastDelivered":{"my-trigger":"2024-01-01T00:00:00Z"}}}}`}, statusPatches)
+
+	// the notification was already delivered, so the status is not written again
+	ctrl.queue.Add("default/test")
+	ctrl.processQueueItem()
+	assert.Len(t, statusPatches, 1)
+}
+
+func TestWithStatusWriter_NoStatusSubresource(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := newResource("test")
+
+	client := newFakeClient(app)
+	client.PrependReactor("patch", "*", func(action kubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, nil, apierrors.NewNotFound(schema.GroupResource{Group: testGVR.Group, Resource: testGVR.Resource}, "test")
+	})
+	ctrl, _, err := newController(t, ctx, client, WithStatusWriter(lastDeliveredStatusWriter(time.Now())))
+	assert.NoError(t, err)
+
+	eventSequence := NotificationEventSequence{Delivered: []NotificationDelivery{{Trigger: "my-trigger"}}}
+	ctrl.writeStatus(app, logEntry, &eventSequence)
+	assert.Empty(t, eventSequence.Warnings)
+}
@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SuppressionWindow defines a recurring period of time during which notifications are not sent
+type SuppressionWindow struct {
+	// Schedule is a cron expression (minute hour day-of-month month day-of-week) evaluated in UTC that defines when the window starts
+	Schedule string
+	// Duration is how long the window lasts after each start
+	Duration time.Duration
+	// Triggers is the list of triggers the window applies to. The window applies to all triggers if the list is empty
+	Triggers []string
+}
+
+type suppressionWindow struct {
+	SuppressionWindow
+	schedule *cronSchedule
+}
+
+func newSuppressionWindow(window SuppressionWindow) (*suppressionWindow, error) {
+	if window.Duration <= 0 {
+		return nil, fmt.Errorf("suppression window duration must be positive, got %s", window.Duration)
+	}
+	schedule, err := parseCronSchedule(window.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	return &suppressionWindow{SuppressionWindow: window, schedule: schedule}, nil
+}
+
+func (w *suppressionWindow) appliesTo(trigger string) bool {
+	if len(w.Triggers) == 0 {
+		return true
+	}
+	for _, t := range w.Triggers {
+		if t == trigger {
+			return true
+		}
+	}
+	return false
+}
+
+// isActive returns true if the window started at a scheduled minute within the last Duration before now
+func (w *suppressionWindow) isActive(now time.Time) bool {
+	now = now.UTC()
+	for start := now.Truncate(time.Minute); now.Sub(start) < w.Duration; start = start.Add(-time.Minute) {
+		if w.schedule.matches(start) {
+			return true
+		}
+	}
+	return false
+}
+
+type cronSchedule struct {
+	minutes     map[int]bool
+	hours       map[int]bool
+	daysOfMonth map[int]bool
+	months      map[int]bool
+	daysOfWeek  map[int]bool
+	// domRestricted and dowRestricted are false if the corresponding field is '*'
+	domRestricted bool
+	dowRestricted bool
+}
+
+func parseCronSchedule(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule '%s' must have 5 fields: minute hour day-of-month month day-of-week", expr)
+	}
+	var err error
+	s := &cronSchedule{
+		domRestricted: fields[2] != "*",
+		dowRestricted: fields[4] != "*",
+	}
+	if s.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("schedule '%s' has invalid minute: %v", expr, err)
+	}
+	if s.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("schedule '%s' has invalid hour: %v", expr, err)
+	}
+	if s.daysOfMonth, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("schedule '%s' has invalid day of month: %v", expr, err)
+	}
+	if s.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("schedule '%s' has invalid month: %v", expr, err)
+	}
+	if s.daysOfWeek, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("schedule '%s' has invalid day of week: %v", expr, err)
+	}
+	// both 0 and 7 stand for Sunday
+	if s.daysOfWeek[7] {
+		s.daysOfWeek[0] = true
+	}
+	return s, nil
+}
+
+// parseCronField parses a comma separated list of values, ranges (a-b) and steps (*/n, a-b/n)
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	res := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		rangeExpr, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rangeExpr = part[:i]
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in '%s'", part)
+			}
+		}
+
+		from, to := min, max
+		if rangeExpr != "*" {
+			bounds := strings.SplitN(rangeExpr, "-", 2)
+			var err error
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value '%s'", part)
+			}
+			to = from
+			if len(bounds) == 1 && step > 1 {
+				to = max
+			}
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value '%s'", part)
+				}
+			}
+		}
+		if from < min || to > max || from > to {
+			return nil, fmt.Errorf("value '%s' is out of range %d-%d", part, min, max)
+		}
+		for v := from; v <= to; v += step {
+			res[v] = true
+		}
+	}
+	return res, nil
+}
+
+func (s *cronSchedule) matches(t time.Time) bool {
+	if !s.minutes[t.Minute()] || !s.hours[t.Hour()] || !s.months[int(t.Month())] {
+		return false
+	}
+	domMatch, dowMatch := s.daysOfMonth[t.Day()], s.daysOfWeek[int(t.Weekday())]
+	// same as cron: if both day fields are restricted, either of them has to match
+	if s.domRestricted && s.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
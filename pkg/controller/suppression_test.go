@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCronSchedule_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := parseCronSchedule(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestSuppressionWindow_IsActive(t *testing.T) {
+	// 2024-01-02 is a Tuesday
+	at := func(value string) time.Time {
+		res, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			panic(err)
+		}
+		return res
+	}
+
+	testCases := map[string]struct {
+		schedule string
+		duration time.Duration
+		now      time.Time
+		active   bool
+	}{
+		"AtStart":              {"0 2 * * *", time.Hour, at("2024-01-02T02:00:00Z"), true},
+		"InsideWindow":         {"0 2 * * *", time.Hour, at("2024-01-02T02:59:59Z"), true},
+		"AfterWindow":          {"0 2 * * *", time.Hour, at("2024-01-02T03:00:00Z"), false},
+		"BeforeWindow":         {"0 2 * * *", time.Hour, at("2024-01-02T01:59:00Z"), false},
+		"SpansMidnight":        {"0 22 * * *", 4 * time.Hour, at("2024-01-03T01:30:00Z"), true},
+		"EvaluatedInUTC":       {"0 2 * * *", time.Hour, at("2024-01-02T04:30:00+02:00"), true},
+		"MatchingWeekday":      {"0 0 * * 1-5", 24 * time.Hour, at("2024-01-02T12:00:00Z"), true},
+		"NotMatchingWeekday":   {"0 0 * * 0,6", 24 * time.Hour, at("2024-01-02T12:00:00Z"), false},
+		"DayOfMonthOrWeekday":  {"0 0 15 * 2", 24 * time.Hour, at("2024-01-02T12:00:00Z"), true},
+		"StepWithinHour":       {"*/15 * * * *", 5 * time.Minute, at("2024-01-02T12:33:00Z"), true},
+		"StepOutsideOfWindow":  {"*/15 * * * *", 5 * time.Minute, at("2024-01-02T12:38:00Z"), false},
+		"NotMatchingMonth":     {"0 0 * 2-12 *", 24 * time.Hour, at("2024-01-02T12:00:00Z"), false},
+		"SundayAsSevenMatches": {"0 0 * * 7", 24 * time.Hour, at("2024-01-07T12:00:00Z"), true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			w, err := newSuppressionWindow(SuppressionWindow{Schedule: tc.schedule, Duration: tc.duration})
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tc.active, w.isActive(tc.now))
+		})
+	}
+}
+
+func TestSuppressionWindow_AppliesTo(t *testing.T) {
+	w := suppressionWindow{SuppressionWindow: SuppressionWindow{Triggers: []string{"on-sync-running"}}}
+	assert.True(t, w.appliesTo("on-sync-running"))
+	assert.False(t, w.appliesTo("on-health-degraded"))
+
+	w = suppressionWindow{}
+	assert.True(t, w.appliesTo("on-health-degraded"))
+}
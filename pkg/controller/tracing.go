@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"context"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/argoproj/notifications-engine/pkg/util/redact"
+)
+
+const tracerName = "github.com/argoproj/notifications-engine/pkg/controller"
+
+const (
+	resourceSpanName = "notifications.process"
+	deliverySpanName = "notifications.deliver"
+
+	deliveryOutcomeDelivered = "delivered"
+	deliveryOutcomeFailed    = "failed"
+	deliveryOutcomeDryRun    = "dry_run"
+)
+
+// startResourceSpan starts the root span of processing the resource. The returned context carries the span to the
+// deliveries through the log entry of the resource.
+func (c *notificationController) startResourceSpan(key string) (context.Context, trace.Span) {
+	return c.tracer.Start(c.ctx, resourceSpanName, trace.WithAttributes(attribute.String("notification.resource", key)))
+}
+
+// startDeliverySpan starts the span of a delivery as a child of the span carried by the context of the log entry
+func (c *notificationController) startDeliverySpan(logEntry *log.Entry, trigger string, to services.Destination) trace.Span {
+	_, span := c.tracer.Start(c.contextOf(logEntry), deliverySpanName, trace.WithAttributes(
+		attribute.String("notification.trigger", trigger),
+		attribute.String("notification.service", to.Service),
+		attribute.String("notification.recipient", redact.String(to.Recipient)),
+	))
+	return span
+}
+
+// contextOf returns the context carried by the log entry of the resource, which is cancelled once the processing of the
+// resource times out, or the context of the controller if the entry carries none
+func (c *notificationController) contextOf(logEntry *log.Entry) context.Context {
+	if logEntry.Context != nil {
+		return logEntry.Context
+	}
+	return c.ctx
+}
+
+// endDeliverySpan records the outcome of the delivery and ends its span
+func endDeliverySpan(span trace.Span, delivery NotificationDelivery) {
+	outcome := deliveryOutcomeDelivered
+	if delivery.DryRun {
+		outcome = deliveryOutcomeDryRun
+	} else if delivery.Error != nil {
+		outcome = deliveryOutcomeFailed
+	}
+	span.SetAttributes(attribute.String("notification.outcome", outcome))
+	endSpan(span, delivery.Error)
+}
+
+// endSpan records the errors on the span and ends it. The status of the span is set to error if any error occurred.
+func endSpan(span trace.Span, errs ...error) {
+	var messages []string
+	for _, err := range errs {
+		if err != nil {
+			span.RecordError(err)
+			messages = append(messages, err.Error())
+		}
+	}
+	if len(messages) > 0 {
+		span.SetStatus(codes.Error, strings.Join(messages, "; "))
+	}
+	span.End()
+}
+
+// withSpan passes the span of the delivery to the notification service, so that instrumented clients can attach
+// their own spans to it
+func withSpan(send func(ctx context.Context) error, span trace.Span) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return send(trace.ContextWithSpan(ctx, span))
+	}
+}
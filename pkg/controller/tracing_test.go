@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	notificationApi "github.com/argoproj/notifications-engine/pkg/api"
+	"github.com/argoproj/notifications-engine/pkg/mocks"
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/argoproj/notifications-engine/pkg/subscriptions"
+	"github.com/argoproj/notifications-engine/pkg/triggers"
+)
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]string {
+	res := map[attribute.Key]string{}
+	for _, attr := range span.Attributes() {
+		res[attr.Key] = attr.Value.Emit()
+	}
+	return res
+}
+
+func TestWithTracerProvider(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := newResource("test", withAnnotations(map[string]string{
+		subscriptions.SubscribeAnnotationKey("my-trigger", "mock"): "recipient1,recipient2",
+	}))
+	recorder := tracetest.NewSpanRecorder()
+	ctrl, api, err := newController(t, ctx, newFakeClient(app), WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))))
+	assert.NoError(t, err)
+	ctrl.namespaceSupport = false
+	ctrl.apiFactory = &mocks.FakeFactory{Api: api}
+
+	api.EXPECT().GetConfig().Return(notificationApi.Config{}).AnyTimes()
+	api.EXPECT().RunTrigger("my-trigger", gomock.Any()).Return([]triggers.ConditionResult{{Triggered: true, Templates: []string{"test"}}}, nil)
+	var serviceSpan trace.SpanContext
+	api.EXPECT().SendContext(gomock.Any(), gomock.Any(), []string{"test"}, services.Destination{Service: "mock", Recipient: "recipient1"}).
+		DoAndReturn(func(ctx context.Context, _ map[string]interface{}, _ []string, _ services.Destination) error {
+			serviceSpan = trace.SpanContextFromContext(ctx)
+			return nil
+		})
+	api.EXPECT().SendContext(gomock.Any(), gomock.Any(), []string{"test"}, services.Destination{Service: "mock", Recipient: "recipient2"}).
+		Return(errors.New("fail"))
+
+	ctrl.processQueueItem()
+
+	spans := recorder.Ended()
+	if !assert.Len(t, spans, 3) {
+		return
+	}
+	delivered, failed, root := spans[0], spans[1], spans[2]
+
+	assert.Equal(t, resourceSpanName, root.Name())
+	assert.False(t, root.Parent().IsValid())
+	assert.Equal(t, map[attribute.Key]string{"notification.resource": "default/test"}, spanAttributes(root))
+	assert.Equal(t, codes.Error, root.Status().Code)
+
+	assert.Equal(t, deliverySpanName, delivered.Name())
+	assert.Equal(t, root.SpanContext().SpanID(), delivered.Parent().SpanID())
+	assert.Equal(t, map[attribute.Key]string{
+		"notification.trigger":   "my-trigger",
+		"notification.service":   "mock",
+		"notification.recipient": "recipient1",
+		"notification.outcome":   deliveryOutcomeDelivered,
+	}, spanAttributes(delivered))
+	assert.Equal(t, codes.Unset, delivered.Status().Code)
+	assert.Empty(t, delivered.Events())
+	assert.Equal(t, delivered.SpanContext().SpanID(), serviceSpan.SpanID())
+
+	assert.Equal(t, deliverySpanName, failed.Name())
+	assert.Equal(t, root.SpanContext().SpanID(), failed.Parent().SpanID())
+	assert.Equal(t, deliveryOutcomeFailed, spanAttributes(failed)["notification.outcome"])
+	assert.Equal(t, codes.Error, failed.Status().Code)
+	assert.Equal(t, "fail", failed.Status().Description)
+	if assert.Len(t, failed.Events(), 1) {
+		assert.Equal(t, "exception", failed.Events()[0].Name)
+	}
+}
+
+func TestWithoutTracerProvider(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := newResource("test", withAnnotations(map[string]string{
+		subscriptions.SubscribeAnnotationKey("my-trigger", "mock"): "recipient",
+	}))
+	ctrl, api, err := newController(t, ctx, newFakeClient(app))
+	assert.NoError(t, err)
+	ctrl.namespaceSupport = false
+	ctrl.apiFactory = &mocks.FakeFactory{Api: api}
+
+	api.EXPECT().GetConfig().Return(notificationApi.Config{}).AnyTimes()
+	api.EXPECT().RunTrigger("my-trigger", gomock.Any()).Return([]triggers.ConditionResult{{Triggered: true, Templates: []string{"test"}}}, nil)
+	api.EXPECT().SendContext(gomock.Any(), gomock.Any(), []string{"test"}, services.Destination{Service: "mock", Recipient: "recipient"}).
+		DoAndReturn(func(ctx context.Context, _ map[string]interface{}, _ []string, _ services.Destination) error {
+			assert.False(t, trace.SpanContextFromContext(ctx).IsValid())
+			return nil
+		})
+
+	ctrl.processQueueItem()
+}
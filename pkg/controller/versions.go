@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const skipReasonVersionProcessed = "resource version already processed"
+
+// ResourceVersionStore persists the resource versions the controller last processed, e.g. in a ConfigMap or a
+// database, so that they survive controller restarts
+type ResourceVersionStore interface {
+	// Get returns the last processed version of the resource and true, or false if the resource was not processed yet
+	Get(key string) (string, bool, error)
+	// Set records the version of the resource as processed
+	Set(key string, version string) error
+	// Delete forgets the resource once it is deleted
+	Delete(key string) error
+}
+
+// WithResourceVersionTracking configures the controller to record the resource version of every resource it
+// processed in the given store and to skip resources whose version did not change since then, e.g. the resources
+// replayed by the informer after a restart. The resource version is used rather than the generation, because the
+// generation does not change when only the status of a resource changes.
+// Note that periodic resyncs do not change the resource version either, so triggers which depend on time only are
+// not re-evaluated until the resource changes.
+func WithResourceVersionTracking(store ResourceVersionStore) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.versions = store
+	}
+}
+
+// isVersionProcessed returns true if the current version of the resource was already processed. Resources are
+// processed if the store fails, since skipping them might lose notifications.
+func (c *notificationController) isVersionProcessed(key string, resource v1.Object, logEntry *log.Entry) bool {
+	version, ok, err := c.versions.Get(key)
+	if err != nil {
+		logEntry.Warnf("Failed to get processed resource version: %v", err)
+		return false
+	}
+	return ok && version != "" && version == resource.GetResourceVersion()
+}
+
+// recordVersion records the version of the resource as processed, unless the processing has failed, e.g. a trigger
+// condition could not be evaluated, or did not complete in time, so that the resource is processed again after a restart.
+// The version is not recorded either while a digest of the resource is pending, so that the resource is processed once
+// the digest is due and the digest, which is kept in memory, is collected again after a restart.
+func (c *notificationController) recordVersion(ctx context.Context, key string, version string, eventSequence *NotificationEventSequence, logEntry *log.Entry) {
+	if version == "" || len(eventSequence.Errors) > 0 || len(eventSequence.Warnings) > 0 || ctx.Err() != nil || c.digest.hasPending(key) {
+		return
+	}
+	if err := c.versions.Set(key, version); err != nil {
+		logEntry.Warnf("Failed to record processed resource version: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	notificationApi "github.com/argoproj/notifications-engine/pkg/api"
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/argoproj/notifications-engine/pkg/subscriptions"
+	"github.com/argoproj/notifications-engine/pkg/triggers"
+)
+
+type fakeVersionStore map[string]string
+
+func (s fakeVersionStore) Get(key string) (string, bool, error) {
+	version, ok := s[key]
+	return version, ok, nil
+}
+
+func (s fakeVersionStore) Set(key string, version string) error {
+	s[key] = version
+	return nil
+}
+
+func (s fakeVersionStore) Delete(key string) error {
+	delete(s, key)
+	return nil
+}
+
+func withResourceVersion(version string) func(obj *unstructured.Unstructured) {
+	return func(app *unstructured.Unstructured) {
+		app.SetResourceVersion(version)
+	}
+}
+
+func TestResourceVersionTracking(t *testing.T) {
+	subscribed := withAnnotations(map[string]string{
+		subscriptions.SubscribeAnnotationKey("my-trigger", "mock"): "recipient",
+	})
+
+	t.Run("ReplayedUnchangedResourceSkipped", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+		app := newResource("test", subscribed, withResourceVersion("1"))
+		store := fakeVersionStore{"default/test": "1"}
+
+		var actualSequence *NotificationEventSequence
+		ctrl, _, err := newController(t, ctx, newFakeClient(app),
+			WithResourceVersionTracking(store),
+			WithEventCallback(func(eventSequence NotificationEventSequence) {
+				actualSequence = &eventSequence
+			}))
+		assert.NoError(t, err)
+
+		// the mock api does not expect any call, so the resource must not be processed
+		ctrl.processQueueItem()
+
+		assert.Equal(t, skipReasonVersionProcessed, actualSequence.SkipReason)
+		assert.Equal(t, fakeVersionStore{"default/test": "1"}, store)
+	})
+
+	t.Run("ChangedResourceProcessed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+		app := newResource("test", subscribed, withResourceVersion("2"))
+		store := fakeVersionStore{"default/test": "1"}
+
+		var actualSequence *NotificationEventSequence
+		ctrl, api, err := newController(t, ctx, newFakeClient(app),
+			WithResourceVersionTracking(store),
+			WithEventCallback(func(eventSequence NotificationEventSequence) {
+				actualSequence = &eventSequence
+			}))
+		assert.NoError(t, err)
+		api.EXPECT().GetConfig().Return(notificationApi.Config{}).AnyTimes()
+		api.EXPECT().RunTrigger("my-trigger", gomock.Any()).Return([]triggers.ConditionResult{{Triggered: false}}, nil)
+
+		ctrl.processQueueItem()
+
+		assert.Empty(t, actualSequence.SkipReason)
+		assert.Empty(t, actualSequence.Errors)
+		assert.Equal(t, fakeVersionStore{"default/test": "2"}, store)
+	})
+
+	t.Run("FailedResourceNotRecorded", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+		app := newResource("test", subscribed, withResourceVersion("2"))
+		store := fakeVersionStore{}
+
+		ctrl, api, err := newController(t, ctx, newFakeClient(app), WithResourceVersionTracking(store))
+		assert.NoError(t, err)
+		api.EXPECT().GetConfig().Return(notificationApi.Config{}).AnyTimes()
+		api.EXPECT().RunTrigger("my-trigger", gomock.Any()).Return(nil, assert.AnError)
+
+		ctrl.processQueueItem()
+
+		assert.Empty(t, store)
+	})
+
+	t.Run("PendingDigestNotRecorded", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+		app := newResource("test", withAnnotations(map[string]string{
+			subscriptions.SubscribeAnnotationKey("my-trigger", "email"): "user@example.com",
+		}), withResourceVersion("1"))
+		store := fakeVersionStore{}
+
+		ctrl, api, err := newController(t, ctx, newFakeClient(app), WithResourceVersionTracking(store), WithDigest(DigestConfig{DigestInterval: time.Hour}))
+		assert.NoError(t, err)
+		now := time.Date(2024, 1, 2, 2, 30, 0, 0, time.UTC)
+		ctrl.now = func() time.Time {
+			return now
+		}
+		email := services.Destination{Service: "email", Recipient: "user@example.com"}
+		api.EXPECT().GetConfig().Return(notificationApi.Config{}).AnyTimes()
+		api.EXPECT().RunTrigger("my-trigger", gomock.Any()).Return([]triggers.ConditionResult{{Triggered: true, Templates: []string{"test"}}}, nil).Times(2)
+
+		ctrl.processQueueItem()
+		assert.Empty(t, store)
+
+		// the resource is processed once the digest is due although its version did not change
+		now = now.Add(time.Hour)
+		api.EXPECT().FormatDigest(gomock.Any(), [][]string{{"test"}}, email).Return(&services.Notification{Message: "test"}, nil)
+		api.EXPECT().SendNotificationContext(gomock.Any(), gomock.Any(), email).Return(nil)
+		ctrl.queue.Add("default/test")
+		ctrl.processQueueItem()
+
+		assert.Equal(t, fakeVersionStore{"default/test": "1"}, store)
+	})
+}
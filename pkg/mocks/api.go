@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	api "github.com/argoproj/notifications-engine/pkg/api"
@@ -48,6 +49,36 @@ func (mr *MockAPIMockRecorder) AddNotificationService(arg0, arg1 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNotificationService", reflect.TypeOf((*MockAPI)(nil).AddNotificationService), arg0, arg1)
 }
 
+// FormatDigest mocks base method.
+func (m *MockAPI) FormatDigest(arg0 map[string]interface{}, arg1 [][]string, arg2 services.Destination) (*services.Notification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FormatDigest", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*services.Notification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FormatDigest indicates an expected call of FormatDigest.
+func (mr *MockAPIMockRecorder) FormatDigest(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FormatDigest", reflect.TypeOf((*MockAPI)(nil).FormatDigest), arg0, arg1, arg2)
+}
+
+// FormatNotification mocks base method.
+func (m *MockAPI) FormatNotification(arg0 map[string]interface{}, arg1 []string, arg2 services.Destination) (*services.Notification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FormatNotification", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*services.Notification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FormatNotification indicates an expected call of FormatNotification.
+func (mr *MockAPIMockRecorder) FormatNotification(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FormatNotification", reflect.TypeOf((*MockAPI)(nil).FormatNotification), arg0, arg1, arg2)
+}
+
 // GetConfig mocks base method.
 func (m *MockAPI) GetConfig() api.Config {
 	m.ctrl.T.Helper()
@@ -62,6 +93,20 @@ func (mr *MockAPIMockRecorder) GetConfig() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConfig", reflect.TypeOf((*MockAPI)(nil).GetConfig))
 }
 
+// GetConfiguredDestinations mocks base method.
+func (m *MockAPI) GetConfiguredDestinations() map[string][]string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConfiguredDestinations")
+	ret0, _ := ret[0].(map[string][]string)
+	return ret0
+}
+
+// GetConfiguredDestinations indicates an expected call of GetConfiguredDestinations.
+func (mr *MockAPIMockRecorder) GetConfiguredDestinations() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConfiguredDestinations", reflect.TypeOf((*MockAPI)(nil).GetConfiguredDestinations))
+}
+
 // GetNotificationServices mocks base method.
 func (m *MockAPI) GetNotificationServices() map[string]services.NotificationService {
 	m.ctrl.T.Helper()
@@ -76,6 +121,21 @@ func (mr *MockAPIMockRecorder) GetNotificationServices() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNotificationServices", reflect.TypeOf((*MockAPI)(nil).GetNotificationServices))
 }
 
+// RenderNotification mocks base method.
+func (m *MockAPI) RenderNotification(arg0 string, arg1 map[string]interface{}) (services.Notification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenderNotification", arg0, arg1)
+	ret0, _ := ret[0].(services.Notification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RenderNotification indicates an expected call of RenderNotification.
+func (mr *MockAPIMockRecorder) RenderNotification(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenderNotification", reflect.TypeOf((*MockAPI)(nil).RenderNotification), arg0, arg1)
+}
+
 // RunTrigger mocks base method.
 func (m *MockAPI) RunTrigger(arg0 string, arg1 map[string]interface{}) ([]triggers.ConditionResult, error) {
 	m.ctrl.T.Helper()
@@ -104,3 +164,45 @@ func (mr *MockAPIMockRecorder) Send(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockAPI)(nil).Send), arg0, arg1, arg2)
 }
+
+// SendContext mocks base method.
+func (m *MockAPI) SendContext(arg0 context.Context, arg1 map[string]interface{}, arg2 []string, arg3 services.Destination) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendContext", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendContext indicates an expected call of SendContext.
+func (mr *MockAPIMockRecorder) SendContext(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendContext", reflect.TypeOf((*MockAPI)(nil).SendContext), arg0, arg1, arg2, arg3)
+}
+
+// SendNotification mocks base method.
+func (m *MockAPI) SendNotification(arg0 services.Notification, arg1 services.Destination) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendNotification", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendNotification indicates an expected call of SendNotification.
+func (mr *MockAPIMockRecorder) SendNotification(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendNotification", reflect.TypeOf((*MockAPI)(nil).SendNotification), arg0, arg1)
+}
+
+// SendNotificationContext mocks base method.
+func (m *MockAPI) SendNotificationContext(arg0 context.Context, arg1 services.Notification, arg2 services.Destination) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendNotificationContext", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendNotificationContext indicates an expected call of SendNotificationContext.
+func (mr *MockAPIMockRecorder) SendNotificationContext(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendNotificationContext", reflect.TypeOf((*MockAPI)(nil).SendNotificationContext), arg0, arg1, arg2)
+}
@@ -100,12 +100,13 @@ func (n AlertmanagerNotification) GetTemplater(name string, f texttemplate.FuncM
 			return fmt.Errorf("at least one label pair required")
 		}
 
-		notification.Alertmanager.Labels = n.Labels
+		// copy labels and annotations so that rendering does not overwrite the template
+		notification.Alertmanager.Labels = copyStringMap(n.Labels)
 		if err := notification.Alertmanager.parseLabels(name, f, vars); err != nil {
 			return err
 		}
 		if len(n.Annotations) > 0 {
-			notification.Alertmanager.Annotations = n.Annotations
+			notification.Alertmanager.Annotations = copyStringMap(n.Annotations)
 			if err := notification.Alertmanager.parseAnnotations(name, f, vars); err != nil {
 				return err
 			}
@@ -163,12 +164,19 @@ func (n *AlertmanagerNotification) parseLabels(name string, f texttemplate.FuncM
 
 // Send using create alertmanager events
 func (s alertmanagerService) Send(notification Notification, dest Destination) error {
+	return s.SendContext(context.Background(), notification, dest)
+}
+
+func (s alertmanagerService) SendContext(ctx context.Context, notification Notification, dest Destination) error {
 	if notification.Alertmanager == nil {
 		return fmt.Errorf("notification alertmanager no config")
 	}
 	if len(notification.Alertmanager.Labels) == 0 {
 		return fmt.Errorf("alertmanager at least one label pair required")
 	}
+	if notification.Alertmanager.Labels[alertNameLabel] == "" {
+		return fmt.Errorf("alertmanager label '%s' is required", alertNameLabel)
+	}
 
 	rawBody, err := json.Marshal([]*AlertmanagerNotification{notification.Alertmanager})
 	if err != nil {
@@ -181,7 +189,7 @@ func (s alertmanagerService) Send(notification Notification, dest Destination) e
 	for _, target := range s.opts.Targets {
 		wg.Add(1)
 
-		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.opts.Timeout)*time.Second)
+		ctx, cancel := context.WithTimeout(ctx, time.Duration(s.opts.Timeout)*time.Second)
 		defer cancel()
 
 		go func(target string) {
@@ -244,6 +252,14 @@ func (s alertmanagerService) sendOneTarget(ctx context.Context, target string, r
 	return nil
 }
 
+func copyStringMap(in map[string]string) map[string]string {
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
+
 func convertGitURLtoHTTP(url string) string {
 	if !strings.HasPrefix(url, "git@") {
 		return url
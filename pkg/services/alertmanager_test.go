@@ -1,6 +1,10 @@
 package services
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"text/template"
 
@@ -159,3 +163,71 @@ func Test_AlertManagerNoLabels(t *testing.T) {
 	err := svc.Send(n, Destination{})
 	assert.EqualError(t, err, "alertmanager at least one label pair required")
 }
+
+func Test_AlertManagerNoAlertname(t *testing.T) {
+	n := Notification{
+		Alertmanager: &AlertmanagerNotification{
+			Labels: map[string]string{"severity": "warning"},
+		},
+	}
+	svc := NewAlertmanagerService(AlertmanagerOptions{})
+	err := svc.Send(n, Destination{})
+	assert.EqualError(t, err, "alertmanager label 'alertname' is required")
+}
+
+func TestSend_AlertmanagerUnreachableTarget(t *testing.T) {
+	var received []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2/alerts", r.URL.Path)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer server.Close()
+
+	// reserve a port and close the listener so that connections to it are refused
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	s := NewAlertmanagerService(AlertmanagerOptions{
+		Targets: []string{
+			strings.TrimPrefix(unreachable.URL, "http://"),
+			strings.TrimPrefix(server.URL, "http://"),
+		},
+	})
+	err := s.Send(Notification{
+		Alertmanager: &AlertmanagerNotification{
+			Labels: map[string]string{"alertname": "TestSend"},
+		},
+	}, Destination{})
+	assert.NoError(t, err)
+
+	if assert.Len(t, received, 1) {
+		assert.Equal(t, map[string]interface{}{"alertname": "TestSend"}, received[0]["labels"])
+	}
+}
+
+func TestGetTemplater_AlertmanagerDoesNotModifyTemplate(t *testing.T) {
+	labels := map[string]string{"alertname": "App_Deployed", "app": "{{.app.metadata.name}}"}
+	n := Notification{
+		Alertmanager: &AlertmanagerNotification{Labels: labels},
+	}
+
+	templater, err := n.GetTemplater("", template.FuncMap{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for _, name := range []string{"guestbook", "helm-guestbook"} {
+		var notification Notification
+		err = templater(&notification, map[string]interface{}{
+			"app": map[string]interface{}{
+				"metadata": map[string]interface{}{"name": name},
+				"spec":     map[string]interface{}{"source": map[string]interface{}{"repoURL": "https://github.com/argoproj/argocd-example-apps.git"}},
+			},
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, name, notification.Alertmanager.Labels["app"])
+	}
+	assert.Equal(t, "{{.app.metadata.name}}", labels["app"])
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	texttemplate "text/template"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+)
+
+type AwsSnsNotification struct {
+	Subject           string            `json:"subject,omitempty"`
+	MessageAttributes map[string]string `json:"messageAttributes,omitempty"`
+	// MessageStructure set to "json" allows sending a different message for each protocol, in which case the
+	// message must be a JSON object with a "default" key and optional per protocol keys
+	MessageStructure string `json:"messageStructure,omitempty"`
+}
+
+type AwsSnsOptions struct {
+	// TopicArn is the ARN of the topic used when the recipient is empty
+	TopicArn    string `json:"topicArn,omitempty"`
+	Account     string `json:"account"`
+	Region      string `json:"region"`
+	EndpointUrl string `json:"endpointUrl,omitempty"`
+	AwsAccess
+}
+
+func NewAwsSnsService(opts AwsSnsOptions) NotificationService {
+	return &awsSnsService{opts: opts}
+}
+
+type awsSnsService struct {
+	opts AwsSnsOptions
+}
+
+func (s awsSnsService) Send(notification Notification, dest Destination) error {
+	return s.SendContext(context.Background(), notification, dest)
+}
+
+func (s awsSnsService) SendContext(ctx context.Context, notif Notification, dest Destination) error {
+	topicArn, err := s.getTopicArn(dest)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, s.setOptions()...)
+	if err != nil {
+		return fmt.Errorf("failed to load aws configuration: %w", err)
+	}
+
+	client := sns.NewFromConfig(cfg)
+
+	output, err := PublishMsg(ctx, client, s.publishInput(topicArn, notif))
+	if err != nil {
+		log.Error("Got an error publishing the message: ", err)
+		return err
+	}
+	log.Debug("Message published with Id: ", aws.ToString(output.MessageId))
+
+	return nil
+}
+
+// getTopicArn returns the ARN of the topic the notification is published to. The recipient takes precedence over
+// the configured topic and might be either a topic ARN or a topic name in the configured account and region.
+func (s awsSnsService) getTopicArn(dest Destination) (string, error) {
+	topic := dest.Recipient
+	if topic == "" {
+		topic = s.opts.TopicArn
+	}
+	if topic == "" {
+		return "", fmt.Errorf("aws sns topic is not specified")
+	}
+	if strings.HasPrefix(topic, "arn:") {
+		return topic, nil
+	}
+	region := s.opts.Region
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if s.opts.Account == "" || region == "" {
+		return "", fmt.Errorf("account and region are required to resolve the arn of aws sns topic '%s'", topic)
+	}
+	return fmt.Sprintf("arn:aws:sns:%s:%s:%s", region, s.opts.Account, topic), nil
+}
+
+func (s awsSnsService) publishInput(topicArn string, notif Notification) *sns.PublishInput {
+	input := &sns.PublishInput{
+		TopicArn: aws.String(topicArn),
+		Message:  aws.String(notif.Message),
+	}
+
+	if notif.AwsSns != nil {
+		if notif.AwsSns.Subject != "" {
+			input.Subject = aws.String(notif.AwsSns.Subject)
+		}
+		if notif.AwsSns.MessageStructure != "" {
+			input.MessageStructure = aws.String(notif.AwsSns.MessageStructure)
+		}
+		if len(notif.AwsSns.MessageAttributes) > 0 {
+			input.MessageAttributes = map[string]types.MessageAttributeValue{}
+			for k, v := range notif.AwsSns.MessageAttributes {
+				input.MessageAttributes[k] = types.MessageAttributeValue{
+					DataType:    aws.String("String"),
+					StringValue: aws.String(v),
+				}
+			}
+		}
+	}
+	return input
+}
+
+func (s awsSnsService) setOptions() []func(*config.LoadOptions) error {
+	var options []func(*config.LoadOptions) error
+
+	// When Credentials Are provided in service configuration - use them.
+	if s.opts.AwsAccess.Key != "" && s.opts.AwsAccess.Secret != "" {
+		options = append(options, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(s.opts.AwsAccess.Key, s.opts.AwsAccess.Secret, "default")))
+	}
+
+	if s.opts.Region != "" {
+		options = append(options, config.WithRegion(s.opts.Region))
+	}
+
+	// Useful for testing with localstack
+	if s.opts.EndpointUrl != "" {
+		endpointRegion := os.Getenv("AWS_DEFAULT_REGION")
+		if s.opts.Region != "" {
+			endpointRegion = s.opts.Region
+		}
+
+		customResolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+			if service == sns.ServiceID {
+				return aws.Endpoint{
+					PartitionID:   "aws",
+					URL:           s.opts.EndpointUrl,
+					SigningRegion: endpointRegion,
+				}, nil
+			}
+			// returning EndpointNotFoundError will allow the service to fallback to it's default resolution
+			return aws.Endpoint{}, &aws.EndpointNotFoundError{}
+		})
+		options = append(options, config.WithEndpointResolverWithOptions(customResolver))
+	}
+	return options
+}
+
+func (n *AwsSnsNotification) GetTemplater(name string, f texttemplate.FuncMap) (Templater, error) {
+	subject, err := texttemplate.New(name).Funcs(f).Parse(n.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	messageStructure, err := texttemplate.New(name).Funcs(f).Parse(n.MessageStructure)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := make(map[string]*texttemplate.Template, len(n.MessageAttributes))
+	for k, v := range n.MessageAttributes {
+		attributes[k], err = texttemplate.New(name + k).Funcs(f).Parse(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return func(notification *Notification, vars map[string]interface{}) error {
+		if notification.AwsSns == nil {
+			notification.AwsSns = &AwsSnsNotification{}
+		}
+
+		var subjectData bytes.Buffer
+		if err := subject.Execute(&subjectData, vars); err != nil {
+			return err
+		}
+		notification.AwsSns.Subject = subjectData.String()
+
+		var messageStructureData bytes.Buffer
+		if err := messageStructure.Execute(&messageStructureData, vars); err != nil {
+			return err
+		}
+		notification.AwsSns.MessageStructure = messageStructureData.String()
+
+		if len(attributes) > 0 {
+			notification.AwsSns.MessageAttributes = make(map[string]string, len(attributes))
+			for k, tmpl := range attributes {
+				var attributeData bytes.Buffer
+				if err := tmpl.Execute(&attributeData, vars); err != nil {
+					return err
+				}
+				notification.AwsSns.MessageAttributes[k] = attributeData.String()
+			}
+		}
+
+		return nil
+	}, nil
+}
+
+type SNSPublishAPI interface {
+	Publish(ctx context.Context,
+		params *sns.PublishInput,
+		optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+var PublishMsg = func(c context.Context, api SNSPublishAPI, input *sns.PublishInput) (*sns.PublishOutput, error) {
+	return api.Publish(c, input)
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTemplater_AwsSns(t *testing.T) {
+	attributes := map[string]string{"app": "{{.app}}"}
+	n := Notification{
+		Message: "{{.message}}",
+		AwsSns: &AwsSnsNotification{
+			Subject:           "{{.app}} is synced",
+			MessageAttributes: attributes,
+			MessageStructure:  "json",
+		},
+	}
+
+	templater, err := n.GetTemplater("", template.FuncMap{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var notification Notification
+	err = templater(&notification, map[string]interface{}{
+		"message": "abcdef",
+		"app":     "guestbook",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "abcdef", notification.Message)
+	assert.Equal(t, "guestbook is synced", notification.AwsSns.Subject)
+	assert.Equal(t, "json", notification.AwsSns.MessageStructure)
+	assert.Equal(t, map[string]string{"app": "guestbook"}, notification.AwsSns.MessageAttributes)
+	assert.Equal(t, map[string]string{"app": "{{.app}}"}, attributes)
+}
+
+func TestPublishInput_AwsSns(t *testing.T) {
+	topicArn := "arn:aws:sns:us-east-1:123:test"
+	s := awsSnsService{}
+
+	t.Run("subject and attributes", func(t *testing.T) {
+		input := s.publishInput(topicArn, Notification{
+			Message: `{"default": "Hello"}`,
+			AwsSns: &AwsSnsNotification{
+				Subject:           "Synced",
+				MessageAttributes: map[string]string{"app": "guestbook"},
+				MessageStructure:  "json",
+			},
+		})
+
+		assert.Equal(t, topicArn, *input.TopicArn)
+		assert.Equal(t, `{"default": "Hello"}`, *input.Message)
+		assert.Equal(t, "Synced", *input.Subject)
+		assert.Equal(t, "json", *input.MessageStructure)
+		assert.Equal(t, map[string]types.MessageAttributeValue{
+			"app": {DataType: aws.String("String"), StringValue: aws.String("guestbook")},
+		}, input.MessageAttributes)
+	})
+
+	t.Run("message only", func(t *testing.T) {
+		input := s.publishInput(topicArn, Notification{Message: "Hello"})
+
+		assert.Equal(t, topicArn, *input.TopicArn)
+		assert.Equal(t, "Hello", *input.Message)
+		assert.Nil(t, input.Subject)
+		assert.Nil(t, input.MessageStructure)
+		assert.Nil(t, input.MessageAttributes)
+	})
+}
+
+func TestGetTopicArn_AwsSns(t *testing.T) {
+	s := awsSnsService{opts: AwsSnsOptions{
+		TopicArn: "arn:aws:sns:us-east-1:123:default",
+		Account:  "123",
+		Region:   "us-east-2",
+	}}
+
+	topicArn, err := s.getTopicArn(Destination{})
+	assert.NoError(t, err)
+	assert.Equal(t, "arn:aws:sns:us-east-1:123:default", topicArn)
+
+	topicArn, err = s.getTopicArn(Destination{Recipient: "arn:aws:sns:us-west-1:456:other"})
+	assert.NoError(t, err)
+	assert.Equal(t, "arn:aws:sns:us-west-1:456:other", topicArn)
+
+	topicArn, err = s.getTopicArn(Destination{Recipient: "other"})
+	assert.NoError(t, err)
+	assert.Equal(t, "arn:aws:sns:us-east-2:123:other", topicArn)
+
+	_, err = awsSnsService{}.getTopicArn(Destination{})
+	assert.EqualError(t, err, "aws sns topic is not specified")
+}
+
+func TestSend_AwsSns(t *testing.T) {
+	savePublishMsg := PublishMsg
+	defer func() { PublishMsg = savePublishMsg }()
+
+	var published *sns.PublishInput
+	PublishMsg = func(c context.Context, api SNSPublishAPI, input *sns.PublishInput) (*sns.PublishOutput, error) {
+		published = input
+		return &sns.PublishOutput{MessageId: aws.String("1")}, nil
+	}
+
+	s := NewAwsSnsService(AwsSnsOptions{Region: "us-east-1"})
+	err := s.Send(Notification{
+		Message: "Hello",
+		AwsSns:  &AwsSnsNotification{Subject: "Synced"},
+	}, Destination{Recipient: "arn:aws:sns:us-east-1:123:test"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "arn:aws:sns:us-east-1:123:test", *published.TopicArn)
+	assert.Equal(t, "Synced", *published.Subject)
+}
@@ -12,18 +12,25 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 )
 
 type AwsSqsNotification struct {
-	MessageAttributes map[string]string `json:"messageAttributes"`
-	MessageGroupId    string            `json:"messageGroupId,omitempty"`
+	MessageAttributes      map[string]string `json:"messageAttributes"`
+	MessageGroupId         string            `json:"messageGroupId,omitempty"`
+	MessageDeduplicationId string            `json:"messageDeduplicationId,omitempty"`
 }
 
+// defaultSqsDelaySeconds is the delay of sent messages unless configured otherwise. Messages with a message group id
+// are sent to FIFO queues, which don't support the delay of single messages, and are never delayed.
+const defaultSqsDelaySeconds int32 = 10
+
 type AwsSqsOptions struct {
-	Queue       string `json:"queue"`
-	Account     string `json:"account"`
-	Region      string `json:"region"`
-	EndpointUrl string `json:"endpointUrl,omitempty"`
+	Queue        string `json:"queue"`
+	Account      string `json:"account"`
+	Region       string `json:"region"`
+	EndpointUrl  string `json:"endpointUrl,omitempty"`
+	DelaySeconds *int32 `json:"delaySeconds,omitempty"`
 	AwsAccess
 }
 
@@ -40,22 +47,26 @@ type awsSqsService struct {
 	opts AwsSqsOptions
 }
 
-func (s awsSqsService) Send(notif Notification, dest Destination) error {
+func (s awsSqsService) Send(notification Notification, dest Destination) error {
+	return s.SendContext(context.Background(), notification, dest)
+}
+
+func (s awsSqsService) SendContext(ctx context.Context, notif Notification, dest Destination) error {
 	options := s.setOptions()
-	cfg, err := config.LoadDefaultConfig(context.TODO(), options...)
+	cfg, err := config.LoadDefaultConfig(ctx, options...)
 	if err != nil {
 		log.Fatalf("failed to load configuration, %v", err)
 	}
 
 	client := sqs.NewFromConfig(cfg)
 
-	queueUrl, err := GetQueueURL(context.TODO(), client, s.getQueueInput(dest))
+	queueUrl, err := GetQueueURL(ctx, client, s.getQueueInput(dest))
 	if err != nil {
 		log.Error("Got an error getting the queue URL: ", err)
 		return err
 	}
 
-	sendMessage, err := SendMsg(context.TODO(), client, s.sendMessageInput(queueUrl.QueueUrl, notif))
+	sendMessage, err := SendMsg(ctx, client, s.sendMessageInput(queueUrl.QueueUrl, notif))
 	if err != nil {
 		log.Error("Got an error sending the message: ", err)
 		return err
@@ -66,12 +77,42 @@ func (s awsSqsService) Send(notif Notification, dest Destination) error {
 }
 
 func (s awsSqsService) sendMessageInput(queueUrl *string, notif Notification) *sqs.SendMessageInput {
-	return &sqs.SendMessageInput{
-		QueueUrl:     queueUrl,
-		MessageBody:  aws.String(notif.Message),
-		DelaySeconds: 10,
+	input := &sqs.SendMessageInput{
+		QueueUrl:    queueUrl,
+		MessageBody: aws.String(notif.Message),
+	}
+
+	// FIFO queues, which require a message group id, reject the delay of single messages
+	if notif.AwsSqs == nil || notif.AwsSqs.MessageGroupId == "" {
+		input.DelaySeconds = defaultSqsDelaySeconds
+		if s.opts.DelaySeconds != nil {
+			input.DelaySeconds = *s.opts.DelaySeconds
+		}
+	} else if s.opts.DelaySeconds != nil && *s.opts.DelaySeconds != 0 {
+		log.Warnf("Ignoring the delay of %d seconds since the message is sent with a message group id", *s.opts.DelaySeconds)
 	}
 
+	if notif.AwsSqs != nil {
+		if len(notif.AwsSqs.MessageAttributes) > 0 {
+			input.MessageAttributes = map[string]types.MessageAttributeValue{}
+			for k, v := range notif.AwsSqs.MessageAttributes {
+				input.MessageAttributes[k] = types.MessageAttributeValue{
+					DataType:    aws.String("String"),
+					StringValue: aws.String(v),
+				}
+			}
+		}
+		if notif.AwsSqs.MessageGroupId != "" {
+			input.MessageGroupId = aws.String(notif.AwsSqs.MessageGroupId)
+		}
+		if notif.AwsSqs.MessageDeduplicationId != "" {
+			input.MessageDeduplicationId = aws.String(notif.AwsSqs.MessageDeduplicationId)
+		} else if notif.AwsSqs.MessageGroupId != "" && notif.IdempotencyKey != "" {
+			// only FIFO queues, which require a message group id, accept deduplication ids
+			input.MessageDeduplicationId = aws.String(notif.IdempotencyKey)
+		}
+	}
+	return input
 }
 func (s awsSqsService) getQueueInput(dest Destination) *sqs.GetQueueUrlInput {
 	result := &sqs.GetQueueUrlInput{}
@@ -136,13 +177,22 @@ func (n *AwsSqsNotification) GetTemplater(name string, f texttemplate.FuncMap) (
 		return nil, err
 	}
 
+	deduplicationId, err := texttemplate.New(name).Funcs(f).Parse(n.MessageDeduplicationId)
+	if err != nil {
+		return nil, err
+	}
+
 	return func(notification *Notification, vars map[string]interface{}) error {
 		if notification.AwsSqs == nil {
 			notification.AwsSqs = &AwsSqsNotification{}
 		}
 
 		if len(n.MessageAttributes) > 0 {
-			notification.AwsSqs.MessageAttributes = n.MessageAttributes
+			// copy the attributes so that rendering does not overwrite the template
+			notification.AwsSqs.MessageAttributes = make(map[string]string, len(n.MessageAttributes))
+			for k, v := range n.MessageAttributes {
+				notification.AwsSqs.MessageAttributes[k] = v
+			}
 			if err := notification.AwsSqs.parseMessageAttributes(name, f, vars); err != nil {
 				return err
 			}
@@ -156,6 +206,14 @@ func (n *AwsSqsNotification) GetTemplater(name string, f texttemplate.FuncMap) (
 			notification.AwsSqs.MessageGroupId = val
 		}
 
+		var deduplicationIdBuff bytes.Buffer
+		if err := deduplicationId.Execute(&deduplicationIdBuff, vars); err != nil {
+			return err
+		}
+		if val := deduplicationIdBuff.String(); val != "" {
+			notification.AwsSqs.MessageDeduplicationId = val
+		}
+
 		return nil
 	}, nil
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -47,6 +48,99 @@ func TestGetTemplater_AwsSqs(t *testing.T) {
 	assert.Equal(t, "a1b2c3", notification.AwsSqs.MessageGroupId)
 }
 
+func TestGetTemplater_AwsSqs_DeduplicationId(t *testing.T) {
+	attributes := map[string]string{
+		"attributeKey": "{{.messageAttributeValue}}",
+	}
+	n := Notification{
+		AwsSqs: &AwsSqsNotification{
+			MessageAttributes:      attributes,
+			MessageDeduplicationId: "{{.revision}}",
+		},
+	}
+
+	templater, err := n.GetTemplater("", template.FuncMap{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var notification Notification
+	err = templater(&notification, map[string]interface{}{
+		"messageAttributeValue": "123456",
+		"revision":              "abc123",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "abc123", notification.AwsSqs.MessageDeduplicationId)
+	assert.Equal(t, map[string]string{"attributeKey": "123456"}, notification.AwsSqs.MessageAttributes)
+	assert.Equal(t, map[string]string{"attributeKey": "{{.messageAttributeValue}}"}, attributes)
+}
+
+func TestSendMessageInput_AwsSqs(t *testing.T) {
+	queueUrl := aws.String("https://sqs.us-east-1.amazonaws.com/123/test.fifo")
+	notification := Notification{
+		Message: "Hello",
+		AwsSqs: &AwsSqsNotification{
+			MessageAttributes:      map[string]string{"app": "guestbook"},
+			MessageGroupId:         "guestbook-deployment",
+			MessageDeduplicationId: "abc123",
+		},
+	}
+
+	t.Run("attributes, group and deduplication id", func(t *testing.T) {
+		delaySeconds := int32(0)
+		input := SendMessageInput(NewTypedAwsSqsService(AwsSqsOptions{DelaySeconds: &delaySeconds}), queueUrl, notification)
+
+		assert.Equal(t, queueUrl, input.QueueUrl)
+		assert.Equal(t, "Hello", *input.MessageBody)
+		assert.Equal(t, int32(0), input.DelaySeconds)
+		assert.Equal(t, "guestbook-deployment", *input.MessageGroupId)
+		assert.Equal(t, "abc123", *input.MessageDeduplicationId)
+		assert.Equal(t, map[string]types.MessageAttributeValue{
+			"app": {DataType: aws.String("String"), StringValue: aws.String("guestbook")},
+		}, input.MessageAttributes)
+	})
+
+	t.Run("no delay with message group id", func(t *testing.T) {
+		input := SendMessageInput(NewTypedAwsSqsService(AwsSqsOptions{}), queueUrl, notification)
+		assert.Equal(t, int32(0), input.DelaySeconds)
+
+		delaySeconds := int32(5)
+		input = SendMessageInput(NewTypedAwsSqsService(AwsSqsOptions{DelaySeconds: &delaySeconds}), queueUrl, notification)
+		assert.Equal(t, int32(0), input.DelaySeconds)
+
+		input = SendMessageInput(NewTypedAwsSqsService(AwsSqsOptions{DelaySeconds: &delaySeconds}), queueUrl, Notification{Message: "Hello"})
+		assert.Equal(t, int32(5), input.DelaySeconds)
+	})
+
+	t.Run("idempotency key as deduplication id of fifo queue", func(t *testing.T) {
+		input := SendMessageInput(NewTypedAwsSqsService(AwsSqsOptions{}), queueUrl, Notification{
+			Message:        "Hello",
+			AwsSqs:         &AwsSqsNotification{MessageGroupId: "guestbook-deployment"},
+			IdempotencyKey: "def456",
+		})
+		assert.Equal(t, "def456", *input.MessageDeduplicationId)
+
+		input = SendMessageInput(NewTypedAwsSqsService(AwsSqsOptions{}), queueUrl, Notification{
+			Message:        "Hello",
+			AwsSqs:         &AwsSqsNotification{},
+			IdempotencyKey: "def456",
+		})
+		assert.Nil(t, input.MessageDeduplicationId)
+	})
+
+	t.Run("default delay", func(t *testing.T) {
+		input := SendMessageInput(NewTypedAwsSqsService(AwsSqsOptions{}), queueUrl, Notification{Message: "Hello"})
+
+		assert.Equal(t, int32(10), input.DelaySeconds)
+		assert.Nil(t, input.MessageGroupId)
+		assert.Nil(t, input.MessageDeduplicationId)
+		assert.Nil(t, input.MessageAttributes)
+	})
+}
+
 func TestSend_AwsSqs(t *testing.T) {
 
 	// Overriding methods inside, so service.Send could be called.
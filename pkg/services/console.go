@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"io"
 
 	"github.com/argoproj/notifications-engine/pkg/util/misc"
@@ -10,7 +11,11 @@ type consoleService struct {
 	stdout io.Writer
 }
 
-func (c *consoleService) Send(notification Notification, _ Destination) error {
+func (c *consoleService) Send(notification Notification, dest Destination) error {
+	return c.SendContext(context.Background(), notification, dest)
+}
+
+func (c *consoleService) SendContext(_ context.Context, notification Notification, _ Destination) error {
 	return misc.PrintFormatted(notification, "yaml", c.stdout)
 }
 
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	texttemplate "text/template"
+
+	log "github.com/sirupsen/logrus"
+
+	httputil "github.com/argoproj/notifications-engine/pkg/util/http"
+)
+
+// discordMaxContentLength is the maximum number of characters Discord accepts in the message content
+const discordMaxContentLength = 2000
+
+type DiscordNotification struct {
+	Username  string `json:"username,omitempty"`
+	AvatarURL string `json:"avatarURL,omitempty"`
+	Embeds    string `json:"embeds,omitempty"`
+	TTS       bool   `json:"tts,omitempty"`
+}
+
+func (n *DiscordNotification) GetTemplater(name string, f texttemplate.FuncMap) (Templater, error) {
+	username, err := texttemplate.New(name).Funcs(f).Parse(n.Username)
+	if err != nil {
+		return nil, fmt.Errorf("error in '%s' discord.username : %w", name, err)
+	}
+
+	avatarURL, err := texttemplate.New(name).Funcs(f).Parse(n.AvatarURL)
+	if err != nil {
+		return nil, fmt.Errorf("error in '%s' discord.avatarURL : %w", name, err)
+	}
+
+	embeds, err := texttemplate.New(name).Funcs(f).Parse(n.Embeds)
+	if err != nil {
+		return nil, fmt.Errorf("error in '%s' discord.embeds : %w", name, err)
+	}
+
+	return func(notification *Notification, vars map[string]interface{}) error {
+		if notification.Discord == nil {
+			notification.Discord = &DiscordNotification{}
+		}
+
+		var usernameBuff bytes.Buffer
+		if err := username.Execute(&usernameBuff, vars); err != nil {
+			return err
+		}
+		notification.Discord.Username = usernameBuff.String()
+
+		var avatarURLBuff bytes.Buffer
+		if err := avatarURL.Execute(&avatarURLBuff, vars); err != nil {
+			return err
+		}
+		notification.Discord.AvatarURL = avatarURLBuff.String()
+
+		var embedsBuff bytes.Buffer
+		if err := embeds.Execute(&embedsBuff, vars); err != nil {
+			return err
+		}
+		notification.Discord.Embeds = embedsBuff.String()
+
+		notification.Discord.TTS = n.TTS
+		return nil
+	}, nil
+}
+
+type DiscordOptions struct {
+	RecipientUrls map[string]string `json:"recipientUrls"`
+}
+
+type discordService struct {
+	opts DiscordOptions
+}
+
+func NewDiscordService(opts DiscordOptions) NotificationService {
+	return &discordService{opts: opts}
+}
+
+type discordMessage struct {
+	Content   string                   `json:"content,omitempty"`
+	Username  string                   `json:"username,omitempty"`
+	AvatarURL string                   `json:"avatar_url,omitempty"`
+	TTS       bool                     `json:"tts,omitempty"`
+	Embeds    []map[string]interface{} `json:"embeds,omitempty"`
+}
+
+func (s discordService) Send(notification Notification, dest Destination) error {
+	return s.SendContext(context.Background(), notification, dest)
+}
+
+func (s discordService) SendContext(ctx context.Context, notification Notification, dest Destination) error {
+	webhookUrl, ok := s.opts.RecipientUrls[dest.Recipient]
+	if !ok {
+		return fmt.Errorf("no discord webhook configured for recipient %s", dest.Recipient)
+	}
+
+	messages, err := discordNotificationToMessages(notification)
+	if err != nil {
+		return err
+	}
+
+	transport := httputil.NewTransport(webhookUrl, false)
+	client := &http.Client{
+		Transport: httputil.NewLoggingRoundTripper(transport, log.WithField("service", "discord")),
+	}
+
+	for _, message := range messages {
+		body, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+		if err := postDiscordMessage(ctx, client, webhookUrl, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func postDiscordMessage(ctx context.Context, client *http.Client, webhookUrl string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	response, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		data, err := io.ReadAll(response.Body)
+		if err != nil {
+			data = []byte(fmt.Sprintf("unable to read response data: %v", err))
+		}
+		return fmt.Errorf("discord webhook post error, status code %d: %s", response.StatusCode, data)
+	}
+	return nil
+}
+
+// discordNotificationToMessages converts the notification into webhook messages. Content longer than
+// Discord's limit is split into several messages; embeds are attached to the last one.
+func discordNotificationToMessages(n Notification) ([]discordMessage, error) {
+	template := discordMessage{}
+	var embeds []map[string]interface{}
+	if n.Discord != nil {
+		template.Username = n.Discord.Username
+		template.AvatarURL = n.Discord.AvatarURL
+		template.TTS = n.Discord.TTS
+		if n.Discord.Embeds != "" {
+			if err := json.Unmarshal([]byte(n.Discord.Embeds), &embeds); err != nil {
+				return nil, fmt.Errorf("discord embeds unmarshalling error %w", err)
+			}
+		}
+	}
+
+	chunks := splitDiscordContent(n.Message, discordMaxContentLength)
+	if len(chunks) == 0 {
+		chunks = []string{""}
+	}
+
+	var messages []discordMessage
+	for _, chunk := range chunks {
+		message := template
+		message.Content = chunk
+		messages = append(messages, message)
+	}
+	messages[len(messages)-1].Embeds = embeds
+	return messages, nil
+}
+
+// splitDiscordContent splits content into chunks of at most maxLength characters, preferring to split on line breaks
+func splitDiscordContent(content string, maxLength int) []string {
+	var chunks []string
+	runes := []rune(content)
+	for len(runes) > maxLength {
+		end := maxLength
+		for i := maxLength - 1; i > 0; i-- {
+			if runes[i] == '\n' {
+				end = i + 1
+				break
+			}
+		}
+		chunks = append(chunks, string(runes[:end]))
+		runes = runes[end:]
+	}
+	if len(runes) > 0 {
+		chunks = append(chunks, string(runes))
+	}
+	return chunks
+}
+
+// Recipients returns the recipients which have a webhook configured
+func (s discordService) Recipients() []string {
+	return sortedRecipients(s.opts.RecipientUrls)
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTemplater_Discord(t *testing.T) {
+	notificationTemplate := Notification{
+		Discord: &DiscordNotification{
+			Username:  "username {{.value}}",
+			AvatarURL: "https://example.com/{{.value}}.png",
+			Embeds:    `[{"title": "{{.value}}"}]`,
+			TTS:       true,
+		},
+	}
+
+	templater, err := notificationTemplate.GetTemplater("test", template.FuncMap{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	notification := Notification{}
+	err = templater(&notification, map[string]interface{}{
+		"value": "value",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "username value", notification.Discord.Username)
+	assert.Equal(t, "https://example.com/value.png", notification.Discord.AvatarURL)
+	assert.Equal(t, `[{"title": "value"}]`, notification.Discord.Embeds)
+	assert.True(t, notification.Discord.TTS)
+}
+
+func TestDiscord_Send(t *testing.T) {
+	var received []discordMessage
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		data, err := io.ReadAll(request.Body)
+		assert.NoError(t, err)
+
+		var message discordMessage
+		assert.NoError(t, json.Unmarshal(data, &message))
+		received = append(received, message)
+		writer.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	service := NewDiscordService(DiscordOptions{
+		RecipientUrls: map[string]string{
+			"test": server.URL,
+		},
+	})
+
+	err := service.Send(Notification{
+		Message: "hello",
+		Discord: &DiscordNotification{
+			Username: "argocd",
+			Embeds:   `[{"title": "Application synced"}]`,
+		},
+	}, Destination{Service: "discord", Recipient: "test"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if assert.Len(t, received, 1) {
+		assert.Equal(t, "hello", received[0].Content)
+		assert.Equal(t, "argocd", received[0].Username)
+		assert.Equal(t, []map[string]interface{}{{"title": "Application synced"}}, received[0].Embeds)
+	}
+}
+
+func TestDiscord_SendError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusBadRequest)
+		_, _ = writer.Write([]byte(`{"message": "Cannot send an empty message", "code": 50006}`))
+	}))
+	defer server.Close()
+
+	service := NewDiscordService(DiscordOptions{
+		RecipientUrls: map[string]string{
+			"test": server.URL,
+		},
+	})
+
+	err := service.Send(Notification{}, Destination{Service: "discord", Recipient: "test"})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Cannot send an empty message")
+	}
+}
+
+func TestDiscord_UnknownRecipient(t *testing.T) {
+	service := NewDiscordService(DiscordOptions{})
+	err := service.Send(Notification{Message: "hello"}, Destination{Service: "discord", Recipient: "test"})
+	assert.EqualError(t, err, "no discord webhook configured for recipient test")
+}
+
+func TestDiscordNotificationToMessages_InvalidEmbeds(t *testing.T) {
+	_, err := discordNotificationToMessages(Notification{
+		Message: "hello",
+		Discord: &DiscordNotification{Embeds: `{"title": "not a list"`},
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "discord embeds unmarshalling error")
+	}
+}
+
+func TestDiscordNotificationToMessages_SplitsLongContent(t *testing.T) {
+	firstLine := strings.Repeat("a", 1500) + "\n"
+	secondLine := strings.Repeat("b", 1500)
+
+	messages, err := discordNotificationToMessages(Notification{
+		Message: firstLine + secondLine,
+		Discord: &DiscordNotification{Username: "argocd", Embeds: `[{"title": "Application synced"}]`},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if assert.Len(t, messages, 2) {
+		assert.Equal(t, firstLine, messages[0].Content)
+		assert.Equal(t, secondLine, messages[1].Content)
+		assert.Equal(t, "argocd", messages[0].Username)
+		assert.Equal(t, "argocd", messages[1].Username)
+		assert.Nil(t, messages[0].Embeds)
+		assert.Len(t, messages[1].Embeds, 1)
+	}
+}
+
+func TestSplitDiscordContent(t *testing.T) {
+	assert.Nil(t, splitDiscordContent("", 10))
+	assert.Equal(t, []string{"short"}, splitDiscordContent("short", 10))
+	assert.Equal(t, []string{"aaaaaaaaaa", "aaaaa"}, splitDiscordContent(strings.Repeat("a", 15), 10))
+	assert.Equal(t, []string{"ééééé", "ééé"}, splitDiscordContent(strings.Repeat("é", 8), 5))
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"strings"
 	texttemplate "text/template"
 
@@ -81,6 +82,10 @@ func NewEmailService(opts EmailOptions) *emailService {
 }
 
 func (s *emailService) Send(notification Notification, dest Destination) error {
+	return s.SendContext(context.Background(), notification, dest)
+}
+
+func (s *emailService) SendContext(_ context.Context, notification Notification, dest Destination) error {
 	subject := ""
 	body := notification.Message
 	to := s.parseTo(dest.Recipient)
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	texttemplate "text/template"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+)
+
+type EventBridgeNotification struct {
+	DetailType string `json:"detailType,omitempty"`
+	Source     string `json:"source,omitempty"`
+	// Detail is the JSON object sent as the detail of the event. The message is sent as the "message" field of the
+	// detail if it is empty.
+	Detail    string   `json:"detail,omitempty"`
+	Resources []string `json:"resources,omitempty"`
+}
+
+// defaultEventBusName is the event bus the events are put to unless the recipient or the configuration specifies one
+const defaultEventBusName = "default"
+
+type EventBridgeOptions struct {
+	// EventBusName is the name or ARN of the event bus used when the recipient is empty
+	EventBusName string `json:"eventBusName,omitempty"`
+	// Source and DetailType are used when the template does not specify them
+	Source      string `json:"source,omitempty"`
+	DetailType  string `json:"detailType,omitempty"`
+	Region      string `json:"region"`
+	EndpointUrl string `json:"endpointUrl,omitempty"`
+	AwsAccess
+}
+
+func NewEventBridgeService(opts EventBridgeOptions) NotificationService {
+	return &eventBridgeService{opts: opts}
+}
+
+type eventBridgeService struct {
+	opts EventBridgeOptions
+}
+
+func (s eventBridgeService) Send(notification Notification, dest Destination) error {
+	return s.SendContext(context.Background(), notification, dest)
+}
+
+func (s eventBridgeService) SendContext(ctx context.Context, notif Notification, dest Destination) error {
+	input, err := s.putEventsInput(notif, dest)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, s.setOptions()...)
+	if err != nil {
+		return fmt.Errorf("failed to load aws configuration: %w", err)
+	}
+
+	client := eventbridge.NewFromConfig(cfg)
+
+	output, err := PutEventsFn(ctx, client, input)
+	if err != nil {
+		log.Error("Got an error putting the event: ", err)
+		return err
+	}
+	if output.FailedEntryCount > 0 && len(output.Entries) > 0 {
+		entry := output.Entries[0]
+		return fmt.Errorf("failed to put event to aws eventbridge: %s: %s", aws.ToString(entry.ErrorCode), aws.ToString(entry.ErrorMessage))
+	}
+	if len(output.Entries) > 0 {
+		log.Debug("Event put with Id: ", aws.ToString(output.Entries[0].EventId))
+	}
+
+	return nil
+}
+
+// putEventsInput builds the request putting the notification as a single event to the event bus of the recipient,
+// or the configured event bus if the recipient is empty
+func (s eventBridgeService) putEventsInput(notif Notification, dest Destination) (*eventbridge.PutEventsInput, error) {
+	eventBus := dest.Recipient
+	if eventBus == "" {
+		eventBus = s.opts.EventBusName
+	}
+	if eventBus == "" {
+		eventBus = defaultEventBusName
+	}
+
+	n := EventBridgeNotification{}
+	if notif.EventBridge != nil {
+		n = *notif.EventBridge
+	}
+	if n.Source == "" {
+		n.Source = s.opts.Source
+	}
+	if n.DetailType == "" {
+		n.DetailType = s.opts.DetailType
+	}
+	if n.Source == "" || n.DetailType == "" {
+		return nil, fmt.Errorf("aws eventbridge source and detail type are required")
+	}
+
+	detail := n.Detail
+	if detail == "" {
+		data, err := json.Marshal(map[string]string{"message": notif.Message})
+		if err != nil {
+			return nil, err
+		}
+		detail = string(data)
+	} else if !json.Valid([]byte(detail)) {
+		return nil, fmt.Errorf("aws eventbridge detail '%s' is not a valid JSON", detail)
+	}
+
+	return &eventbridge.PutEventsInput{
+		Entries: []types.PutEventsRequestEntry{{
+			EventBusName: aws.String(eventBus),
+			Source:       aws.String(n.Source),
+			DetailType:   aws.String(n.DetailType),
+			Detail:       aws.String(detail),
+			Resources:    n.Resources,
+		}},
+	}, nil
+}
+
+func (s eventBridgeService) setOptions() []func(*config.LoadOptions) error {
+	var options []func(*config.LoadOptions) error
+
+	// When Credentials Are provided in service configuration - use them.
+	if s.opts.AwsAccess.Key != "" && s.opts.AwsAccess.Secret != "" {
+		options = append(options, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(s.opts.AwsAccess.Key, s.opts.AwsAccess.Secret, "default")))
+	}
+
+	if s.opts.Region != "" {
+		options = append(options, config.WithRegion(s.opts.Region))
+	}
+
+	// Useful for testing with localstack
+	if s.opts.EndpointUrl != "" {
+		endpointRegion := os.Getenv("AWS_DEFAULT_REGION")
+		if s.opts.Region != "" {
+			endpointRegion = s.opts.Region
+		}
+
+		customResolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+			if service == eventbridge.ServiceID {
+				return aws.Endpoint{
+					PartitionID:   "aws",
+					URL:           s.opts.EndpointUrl,
+					SigningRegion: endpointRegion,
+				}, nil
+			}
+			// returning EndpointNotFoundError will allow the service to fallback to it's default resolution
+			return aws.Endpoint{}, &aws.EndpointNotFoundError{}
+		})
+		options = append(options, config.WithEndpointResolverWithOptions(customResolver))
+	}
+	return options
+}
+
+func (n *EventBridgeNotification) GetTemplater(name string, f texttemplate.FuncMap) (Templater, error) {
+	detailType, err := texttemplate.New(name).Funcs(f).Parse(n.DetailType)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := texttemplate.New(name).Funcs(f).Parse(n.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	detail, err := texttemplate.New(name).Funcs(f).Parse(n.Detail)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]*texttemplate.Template, len(n.Resources))
+	for i, v := range n.Resources {
+		resources[i], err = texttemplate.New(fmt.Sprintf("%s%d", name, i)).Funcs(f).Parse(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return func(notification *Notification, vars map[string]interface{}) error {
+		if notification.EventBridge == nil {
+			notification.EventBridge = &EventBridgeNotification{}
+		}
+
+		var detailTypeData bytes.Buffer
+		if err := detailType.Execute(&detailTypeData, vars); err != nil {
+			return err
+		}
+		notification.EventBridge.DetailType = detailTypeData.String()
+
+		var sourceData bytes.Buffer
+		if err := source.Execute(&sourceData, vars); err != nil {
+			return err
+		}
+		notification.EventBridge.Source = sourceData.String()
+
+		var detailData bytes.Buffer
+		if err := detail.Execute(&detailData, vars); err != nil {
+			return err
+		}
+		notification.EventBridge.Detail = detailData.String()
+
+		if len(resources) > 0 {
+			notification.EventBridge.Resources = nil
+			for _, tmpl := range resources {
+				var resourceData bytes.Buffer
+				if err := tmpl.Execute(&resourceData, vars); err != nil {
+					return err
+				}
+				// resources rendered empty, e.g. by a condition, are omitted
+				if val := resourceData.String(); val != "" {
+					notification.EventBridge.Resources = append(notification.EventBridge.Resources, val)
+				}
+			}
+		}
+
+		return nil
+	}, nil
+}
+
+type EventBridgePutEventsAPI interface {
+	PutEvents(ctx context.Context,
+		params *eventbridge.PutEventsInput,
+		optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error)
+}
+
+var PutEventsFn = func(c context.Context, api EventBridgePutEventsAPI, input *eventbridge.PutEventsInput) (*eventbridge.PutEventsOutput, error) {
+	return api.PutEvents(c, input)
+}
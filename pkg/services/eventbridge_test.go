@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTemplater_EventBridge(t *testing.T) {
+	n := Notification{
+		EventBridge: &EventBridgeNotification{
+			DetailType: "{{.reason}}",
+			Source:     "argocd.{{.kind}}",
+			Detail:     `{"app": "{{.app}}"}`,
+			Resources:  []string{"{{.arn}}", "{{if false}}omitted{{end}}"},
+		},
+	}
+
+	templater, err := n.GetTemplater("", template.FuncMap{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var notification Notification
+	err = templater(&notification, map[string]interface{}{
+		"reason": "SyncSucceeded",
+		"kind":   "application",
+		"app":    "guestbook",
+		"arn":    "arn:aws:eks:us-east-1:123:cluster/prod",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, &EventBridgeNotification{
+		DetailType: "SyncSucceeded",
+		Source:     "argocd.application",
+		Detail:     `{"app": "guestbook"}`,
+		Resources:  []string{"arn:aws:eks:us-east-1:123:cluster/prod"},
+	}, notification.EventBridge)
+}
+
+func TestPutEventsInput_EventBridge(t *testing.T) {
+	t.Run("templated entry", func(t *testing.T) {
+		s := eventBridgeService{opts: EventBridgeOptions{EventBusName: "configured"}}
+		input, err := s.putEventsInput(Notification{
+			Message: "Hello",
+			EventBridge: &EventBridgeNotification{
+				DetailType: "SyncSucceeded",
+				Source:     "argocd",
+				Detail:     `{"app": "guestbook"}`,
+				Resources:  []string{"arn:aws:eks:us-east-1:123:cluster/prod"},
+			},
+		}, Destination{Recipient: "my-bus"})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, []types.PutEventsRequestEntry{{
+			EventBusName: aws.String("my-bus"),
+			Source:       aws.String("argocd"),
+			DetailType:   aws.String("SyncSucceeded"),
+			Detail:       aws.String(`{"app": "guestbook"}`),
+			Resources:    []string{"arn:aws:eks:us-east-1:123:cluster/prod"},
+		}}, input.Entries)
+	})
+
+	t.Run("configured defaults", func(t *testing.T) {
+		s := eventBridgeService{opts: EventBridgeOptions{EventBusName: "configured", Source: "argocd", DetailType: "Notification"}}
+		input, err := s.putEventsInput(Notification{Message: "Hello"}, Destination{})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, []types.PutEventsRequestEntry{{
+			EventBusName: aws.String("configured"),
+			Source:       aws.String("argocd"),
+			DetailType:   aws.String("Notification"),
+			Detail:       aws.String(`{"message":"Hello"}`),
+		}}, input.Entries)
+
+		input, err = eventBridgeService{opts: EventBridgeOptions{Source: "argocd", DetailType: "Notification"}}.putEventsInput(Notification{}, Destination{})
+		if assert.NoError(t, err) {
+			assert.Equal(t, "default", *input.Entries[0].EventBusName)
+		}
+	})
+
+	t.Run("missing source", func(t *testing.T) {
+		_, err := eventBridgeService{}.putEventsInput(Notification{EventBridge: &EventBridgeNotification{DetailType: "Notification"}}, Destination{})
+		assert.EqualError(t, err, "aws eventbridge source and detail type are required")
+	})
+
+	t.Run("invalid detail", func(t *testing.T) {
+		_, err := eventBridgeService{}.putEventsInput(Notification{EventBridge: &EventBridgeNotification{
+			Source: "argocd", DetailType: "Notification", Detail: "not json",
+		}}, Destination{})
+		assert.EqualError(t, err, "aws eventbridge detail 'not json' is not a valid JSON")
+	})
+}
+
+func TestSend_EventBridge(t *testing.T) {
+	savePutEvents := PutEventsFn
+	defer func() { PutEventsFn = savePutEvents }()
+
+	var input *eventbridge.PutEventsInput
+	PutEventsFn = func(c context.Context, api EventBridgePutEventsAPI, in *eventbridge.PutEventsInput) (*eventbridge.PutEventsOutput, error) {
+		input = in
+		return &eventbridge.PutEventsOutput{Entries: []types.PutEventsResultEntry{{EventId: aws.String("1")}}}, nil
+	}
+
+	s := NewEventBridgeService(EventBridgeOptions{Region: "us-east-1", Source: "argocd", DetailType: "Notification"})
+	err := s.Send(Notification{Message: "Hello"}, Destination{Recipient: "my-bus"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "my-bus", *input.Entries[0].EventBusName)
+
+	PutEventsFn = func(c context.Context, api EventBridgePutEventsAPI, in *eventbridge.PutEventsInput) (*eventbridge.PutEventsOutput, error) {
+		return &eventbridge.PutEventsOutput{
+			FailedEntryCount: 1,
+			Entries:          []types.PutEventsResultEntry{{ErrorCode: aws.String("InternalFailure"), ErrorMessage: aws.String("try again")}},
+		}, nil
+	}
+	err = s.Send(Notification{Message: "Hello"}, Destination{Recipient: "my-bus"})
+	assert.EqualError(t, err, "failed to put event to aws eventbridge: InternalFailure: try again")
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	texttemplate "text/template"
 	"time"
+	"unicode"
 	"unicode/utf8"
 
 	"github.com/bradleyfalzon/ghinstallation/v2"
@@ -30,23 +31,43 @@ type GitHubOptions struct {
 	InstallationID    interface{} `json:"installationID"`
 	PrivateKey        string      `json:"privateKey"`
 	EnterpriseBaseURL string      `json:"enterpriseBaseURL"`
+	// Installations lists owner specific installations of the app. Repositories of other owners use InstallationID.
+	Installations []GitHubInstallation `json:"installations,omitempty"`
+	// MessageSizeLimit applies to pull request comments
+	MessageSizeLimit
+}
+
+// gitHubMaxCommentSize is the maximum number of characters of pull request comments accepted by GitHub
+const gitHubMaxCommentSize = 65536
+
+type GitHubInstallation struct {
+	Owner          string      `json:"owner"`
+	InstallationID interface{} `json:"installationID"`
 }
 
 type GitHubNotification struct {
 	repoURL            string
 	revision           string
+	repoURLs           []string
+	revisions          []string
 	Status             *GitHubStatus             `json:"status,omitempty"`
 	Deployment         *GitHubDeployment         `json:"deployment,omitempty"`
 	PullRequestComment *GitHubPullRequestComment `json:"pullRequestComment,omitempty"`
 	RepoURLPath        string                    `json:"repoURLPath,omitempty"`
 	RevisionPath       string                    `json:"revisionPath,omitempty"`
 	CheckRun           *GitHubCheckRun           `json:"checkRun,omitempty"`
+	// RevisionsPath renders the comma or whitespace separated revisions of multi-source applications. The commit
+	// status is posted to each revision in the repository rendered at the same position by RepoURLsPath.
+	RevisionsPath string `json:"revisionsPath,omitempty"`
+	// RepoURLsPath renders the repositories of the revisions, defaults to the repositories of the application sources
+	RepoURLsPath string `json:"repoURLsPath,omitempty"`
 }
 
 type GitHubStatus struct {
 	State     string `json:"state,omitempty"`
 	Label     string `json:"label,omitempty"`
 	TargetURL string `json:"targetURL,omitempty"`
+	Ref       string `json:"ref,omitempty"`
 }
 
 type GitHubCheckRun struct {
@@ -61,9 +82,24 @@ type GitHubCheckRun struct {
 	Output      *GitHubCheckRunOutput `json:"output,omitempty"`
 }
 type GitHubCheckRunOutput struct {
-	Title   string `json:"title,omitempty"`
-	Summary string `json:"summary,omitempty"`
-	Text    string `json:"text,omitempty"`
+	Title       string                     `json:"title,omitempty"`
+	Summary     string                     `json:"summary,omitempty"`
+	Text        string                     `json:"text,omitempty"`
+	Annotations []GitHubCheckRunAnnotation `json:"annotations,omitempty"`
+}
+
+// GitHubCheckRunAnnotation is a finding about lines of a file which is shown in the pull request diff
+type GitHubCheckRunAnnotation struct {
+	Path            string `json:"path,omitempty"`
+	StartLine       int    `json:"start_line,omitempty"`
+	EndLine         int    `json:"end_line,omitempty"`
+	AnnotationLevel string `json:"annotation_level,omitempty"`
+	Message         string `json:"message,omitempty"`
+	Title           string `json:"title,omitempty"`
+}
+
+type gitHubCheckRunAnnotationTemplates struct {
+	path, annotationLevel, message, title *texttemplate.Template
 }
 
 type GitHubDeployment struct {
@@ -75,15 +111,41 @@ type GitHubDeployment struct {
 	AutoMerge            *bool    `json:"autoMerge,omitempty"`
 	TransientEnvironment *bool    `json:"transientEnvironment,omitempty"`
 	Reference            string   `json:"reference,omitempty"`
+	// AutoInactive marks previous deployments of the environment inactive once a status is successful. Defaults to true
+	AutoInactive *bool `json:"autoInactive,omitempty"`
+	// ProductionEnvironment specifies whether the environment is used by end-users. Defaults to GitHub's choice based on the environment name
+	ProductionEnvironment *bool `json:"productionEnvironment,omitempty"`
 }
 
 type GitHubPullRequestComment struct {
 	Content string `json:"content,omitempty"`
+	// CommentTag identifies the comment created by the notification so that it is updated instead of adding a new one
+	CommentTag string `json:"commentTag,omitempty"`
+	// CommentTagStrategy controls how an existing comment is matched: "contains" (default) or "exact-line"
+	CommentTagStrategy string `json:"commentTagStrategy,omitempty"`
+	// State limits the pull requests which are commented to the ones in the given state: "open" (default),
+	// "closed" or "all"
+	State string `json:"state,omitempty"`
+	// UploadLargeContentAsGist uploads content exceeding the maximum comment size as a secret gist and comments a link
+	// to it instead of truncating the content
+	UploadLargeContentAsGist bool `json:"uploadLargeContentAsGist,omitempty"`
 }
 
+const (
+	commentTagStrategyContains  = "contains"
+	commentTagStrategyExactLine = "exact-line"
+)
+
+const (
+	pullRequestStateOpen   = "open"
+	pullRequestStateClosed = "closed"
+	pullRequestStateAll    = "all"
+)
+
 const (
 	repoURLtemplate  = "{{.app.spec.source.repoURL}}"
 	revisionTemplate = "{{.app.status.operationState.syncResult.revision}}"
+	repoURLsTemplate = "{{range .app.spec.sources}}{{.repoURL}},{{end}}"
 )
 
 func (g *GitHubNotification) GetTemplater(name string, f texttemplate.FuncMap) (Templater, error) {
@@ -104,7 +166,22 @@ func (g *GitHubNotification) GetTemplater(name string, f texttemplate.FuncMap) (
 		return nil, err
 	}
 
-	var statusState, label, targetURL *texttemplate.Template
+	var repoURLs, revisions *texttemplate.Template
+	if g.RevisionsPath != "" {
+		if g.RepoURLsPath == "" {
+			g.RepoURLsPath = repoURLsTemplate
+		}
+		repoURLs, err = texttemplate.New(name).Funcs(f).Parse(g.RepoURLsPath)
+		if err != nil {
+			return nil, err
+		}
+		revisions, err = texttemplate.New(name).Funcs(f).Parse(g.RevisionsPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var statusState, label, targetURL, statusRef *texttemplate.Template
 	if g.Status != nil {
 		statusState, err = texttemplate.New(name).Funcs(f).Parse(g.Status.State)
 		if err != nil {
@@ -120,6 +197,11 @@ func (g *GitHubNotification) GetTemplater(name string, f texttemplate.FuncMap) (
 		if err != nil {
 			return nil, err
 		}
+
+		statusRef, err = texttemplate.New(name).Funcs(f).Parse(g.Status.Ref)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	var deploymentState, environment, environmentURL, reference, logURL *texttemplate.Template
@@ -150,12 +232,17 @@ func (g *GitHubNotification) GetTemplater(name string, f texttemplate.FuncMap) (
 		}
 	}
 
-	var pullRequestCommentContent *texttemplate.Template
+	var pullRequestCommentContent, commentTag *texttemplate.Template
 	if g.PullRequestComment != nil {
 		pullRequestCommentContent, err = texttemplate.New(name).Funcs(f).Parse(g.PullRequestComment.Content)
 		if err != nil {
 			return nil, err
 		}
+
+		commentTag, err = texttemplate.New(name).Funcs(f).Parse(g.PullRequestComment.CommentTag)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	var checkRunName, detailsURL, status, conclusion, startedAt, completedAt *texttemplate.Template
@@ -200,12 +287,33 @@ func (g *GitHubNotification) GetTemplater(name string, f texttemplate.FuncMap) (
 			return nil, err
 		}
 	}
+	var annotations []gitHubCheckRunAnnotationTemplates
+	if g.CheckRun != nil && g.CheckRun.Output != nil {
+		for _, annotation := range g.CheckRun.Output.Annotations {
+			var templates gitHubCheckRunAnnotationTemplates
+			if templates.path, err = texttemplate.New(name).Funcs(f).Parse(annotation.Path); err != nil {
+				return nil, err
+			}
+			if templates.annotationLevel, err = texttemplate.New(name).Funcs(f).Parse(annotation.AnnotationLevel); err != nil {
+				return nil, err
+			}
+			if templates.message, err = texttemplate.New(name).Funcs(f).Parse(annotation.Message); err != nil {
+				return nil, err
+			}
+			if templates.title, err = texttemplate.New(name).Funcs(f).Parse(annotation.Title); err != nil {
+				return nil, err
+			}
+			annotations = append(annotations, templates)
+		}
+	}
 
 	return func(notification *Notification, vars map[string]interface{}) error {
 		if notification.GitHub == nil {
 			notification.GitHub = &GitHubNotification{
-				RepoURLPath:  g.RepoURLPath,
-				RevisionPath: g.RevisionPath,
+				RepoURLPath:   g.RepoURLPath,
+				RevisionPath:  g.RevisionPath,
+				RepoURLsPath:  g.RepoURLsPath,
+				RevisionsPath: g.RevisionsPath,
 			}
 		}
 
@@ -221,6 +329,22 @@ func (g *GitHubNotification) GetTemplater(name string, f texttemplate.FuncMap) (
 		}
 		notification.GitHub.revision = revisionData.String()
 
+		if g.RevisionsPath != "" {
+			var repoURLsData bytes.Buffer
+			if err := repoURLs.Execute(&repoURLsData, vars); err != nil {
+				return err
+			}
+			var revisionsData bytes.Buffer
+			if err := revisions.Execute(&revisionsData, vars); err != nil {
+				return err
+			}
+			notification.GitHub.repoURLs = splitList(repoURLsData.String())
+			notification.GitHub.revisions = splitList(revisionsData.String())
+			if len(notification.GitHub.repoURLs) != len(notification.GitHub.revisions) {
+				return fmt.Errorf("GitHub revisionsPath resolved to %d revisions, but repoURLsPath to %d repositories", len(notification.GitHub.revisions), len(notification.GitHub.repoURLs))
+			}
+		}
+
 		if g.Status != nil {
 			if notification.GitHub.Status == nil {
 				notification.GitHub.Status = &GitHubStatus{}
@@ -243,6 +367,12 @@ func (g *GitHubNotification) GetTemplater(name string, f texttemplate.FuncMap) (
 				return err
 			}
 			notification.GitHub.Status.TargetURL = targetData.String()
+
+			var refData bytes.Buffer
+			if err := statusRef.Execute(&refData, vars); err != nil {
+				return err
+			}
+			notification.GitHub.Status.Ref = refData.String()
 		}
 
 		if g.Deployment != nil {
@@ -288,6 +418,14 @@ func (g *GitHubNotification) GetTemplater(name string, f texttemplate.FuncMap) (
 				notification.GitHub.Deployment.TransientEnvironment = g.Deployment.TransientEnvironment
 			}
 
+			if g.Deployment.AutoInactive == nil {
+				deploymentAutoInactiveDefault := true
+				notification.GitHub.Deployment.AutoInactive = &deploymentAutoInactiveDefault
+			} else {
+				notification.GitHub.Deployment.AutoInactive = g.Deployment.AutoInactive
+			}
+			notification.GitHub.Deployment.ProductionEnvironment = g.Deployment.ProductionEnvironment
+
 			var referenceData bytes.Buffer
 			if err := reference.Execute(&referenceData, vars); err != nil {
 				return err
@@ -306,6 +444,15 @@ func (g *GitHubNotification) GetTemplater(name string, f texttemplate.FuncMap) (
 				return err
 			}
 			notification.GitHub.PullRequestComment.Content = contentData.String()
+
+			var commentTagData bytes.Buffer
+			if err := commentTag.Execute(&commentTagData, vars); err != nil {
+				return err
+			}
+			notification.GitHub.PullRequestComment.CommentTag = commentTagData.String()
+			notification.GitHub.PullRequestComment.CommentTagStrategy = g.PullRequestComment.CommentTagStrategy
+			notification.GitHub.PullRequestComment.State = g.PullRequestComment.State
+			notification.GitHub.PullRequestComment.UploadLargeContentAsGist = g.PullRequestComment.UploadLargeContentAsGist
 		}
 
 		if g.CheckRun != nil {
@@ -363,6 +510,29 @@ func (g *GitHubNotification) GetTemplater(name string, f texttemplate.FuncMap) (
 				return err
 			}
 			notification.GitHub.CheckRun.Output.Text = textData.String()
+			notification.GitHub.CheckRun.Output.Annotations = nil
+			for i, templates := range annotations {
+				annotation := GitHubCheckRunAnnotation{
+					StartLine: g.CheckRun.Output.Annotations[i].StartLine,
+					EndLine:   g.CheckRun.Output.Annotations[i].EndLine,
+				}
+				for _, field := range []struct {
+					tmpl *texttemplate.Template
+					dest *string
+				}{
+					{templates.path, &annotation.Path},
+					{templates.annotationLevel, &annotation.AnnotationLevel},
+					{templates.message, &annotation.Message},
+					{templates.title, &annotation.Title},
+				} {
+					var data bytes.Buffer
+					if err := field.tmpl.Execute(&data, vars); err != nil {
+						return err
+					}
+					*field.dest = data.String()
+				}
+				notification.GitHub.CheckRun.Output.Annotations = append(notification.GitHub.CheckRun.Output.Annotations, annotation)
+			}
 		}
 
 		return nil
@@ -370,11 +540,6 @@ func (g *GitHubNotification) GetTemplater(name string, f texttemplate.FuncMap) (
 }
 
 func NewGitHubService(opts GitHubOptions) (NotificationService, error) {
-	url := "https://api.github.com"
-	if opts.EnterpriseBaseURL != "" {
-		url = opts.EnterpriseBaseURL
-	}
-
 	appID, err := cast.ToInt64E(opts.AppID)
 	if err != nil {
 		return nil, err
@@ -385,34 +550,78 @@ func NewGitHubService(opts GitHubOptions) (NotificationService, error) {
 		return nil, err
 	}
 
-	tr := httputil.NewLoggingRoundTripper(
-		httputil.NewTransport(url, false), log.WithField("service", "github"))
-	itr, err := ghinstallation.New(tr, appID, installationID, []byte(opts.PrivateKey))
+	ownerInstallationIDs := map[string]int64{}
+	for _, installation := range opts.Installations {
+		if installation.Owner == "" {
+			return nil, fmt.Errorf("owner of GitHub installation %v is empty", installation.InstallationID)
+		}
+		ownerInstallationID, err := cast.ToInt64E(installation.InstallationID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid installationID of GitHub owner '%s': %v", installation.Owner, err)
+		}
+		ownerInstallationIDs[strings.ToLower(installation.Owner)] = ownerInstallationID
+	}
+
+	client, err := newGitHubInstallationClient(opts, appID, installationID)
 	if err != nil {
 		return nil, err
 	}
 
-	var client *github.Client
-	if opts.EnterpriseBaseURL == "" {
-		client = github.NewClient(&http.Client{Transport: itr})
-	} else {
-		itr.BaseURL = opts.EnterpriseBaseURL
-		client, err = github.NewEnterpriseClient(opts.EnterpriseBaseURL, "", &http.Client{Transport: itr})
-		if err != nil {
+	ownerClients := map[string]*github.Client{}
+	for owner, ownerInstallationID := range ownerInstallationIDs {
+		if ownerClients[owner], err = newGitHubInstallationClient(opts, appID, ownerInstallationID); err != nil {
 			return nil, err
 		}
 	}
 
 	return &gitHubService{
-		opts:   opts,
-		client: client,
+		opts:         opts,
+		client:       client,
+		ownerClients: ownerClients,
 	}, nil
 }
 
+func newGitHubInstallationClient(opts GitHubOptions, appID, installationID int64) (*github.Client, error) {
+	url := "https://api.github.com"
+	if opts.EnterpriseBaseURL != "" {
+		url = opts.EnterpriseBaseURL
+	}
+
+	tr := httputil.NewLoggingRoundTripper(
+		httputil.NewTransport(url, false), log.WithField("service", "github"))
+	itr, err := ghinstallation.New(tr, appID, installationID, []byte(opts.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.EnterpriseBaseURL == "" {
+		return github.NewClient(&http.Client{Transport: itr}), nil
+	}
+	itr.BaseURL = opts.EnterpriseBaseURL
+	return github.NewEnterpriseClient(opts.EnterpriseBaseURL, "", &http.Client{Transport: itr})
+}
+
 type gitHubService struct {
 	opts GitHubOptions
 
 	client *github.Client
+	// ownerClients holds the clients of owner specific installations keyed by lower-cased owner
+	ownerClients map[string]*github.Client
+}
+
+// clientFor returns the client of the installation configured for the owner, falling back to the default installation
+func (g gitHubService) clientFor(owner string) *github.Client {
+	if client, ok := g.ownerClients[strings.ToLower(owner)]; ok {
+		return client
+	}
+	return g.client
+}
+
+// splitList splits the rendered list of a template on commas and whitespace
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
 }
 
 func trunc(message string, n int) string {
@@ -436,31 +645,57 @@ func fullNameByRepoURL(rawURL string) string {
 	return path
 }
 
-func (g gitHubService) Send(notification Notification, _ Destination) error {
+// Validate checks that the app is able to access its installations
+func (g gitHubService) Validate(ctx context.Context) error {
+	// listing the repositories requires an installation access token, which is only issued to valid installations
+	if _, _, err := g.client.Apps.ListRepos(ctx, &github.ListOptions{PerPage: 1}); err != nil {
+		return fmt.Errorf("failed to access GitHub app installation %v: %w", g.opts.InstallationID, err)
+	}
+	for _, installation := range g.opts.Installations {
+		if _, _, err := g.clientFor(installation.Owner).Apps.ListRepos(ctx, &github.ListOptions{PerPage: 1}); err != nil {
+			return fmt.Errorf("failed to access GitHub app installation %v of owner '%s': %w", installation.InstallationID, installation.Owner, err)
+		}
+	}
+	return nil
+}
+
+func (g gitHubService) Send(notification Notification, dest Destination) error {
+	return g.SendContext(context.Background(), notification, dest)
+}
+
+func (g gitHubService) SendContext(ctx context.Context, notification Notification, _ Destination) error {
 	if notification.GitHub == nil {
 		return fmt.Errorf("config is empty")
 	}
 
+	multiSource := len(notification.GitHub.revisions) > 0
+	if multiSource {
+		// the commit status of multi-source applications is posted to the revision of each source, other
+		// notifications use the repository and revision of the single source
+		if notification.GitHub.Status != nil {
+			for i, revision := range notification.GitHub.revisions {
+				u := strings.Split(fullNameByRepoURL(notification.GitHub.repoURLs[i]), "/")
+				if len(u) < 2 {
+					// not a git repository, e.g. a Helm repository
+					continue
+				}
+				if err := g.createStatus(ctx, notification, u[0], u[1], revision); err != nil {
+					return err
+				}
+			}
+		}
+		if notification.GitHub.Deployment == nil && notification.GitHub.PullRequestComment == nil && notification.GitHub.CheckRun == nil {
+			return nil
+		}
+	}
+
 	u := strings.Split(fullNameByRepoURL(notification.GitHub.repoURL), "/")
 	if len(u) < 2 {
 		return fmt.Errorf("GitHub.repoURL (%s) does not have a `/`", notification.GitHub.repoURL)
 	}
-	if notification.GitHub.Status != nil {
-		// maximum is 140 characters
-		description := trunc(notification.Message, 140)
-		_, _, err := g.client.Repositories.CreateStatus(
-			context.Background(),
-			u[0],
-			u[1],
-			notification.GitHub.revision,
-			&github.RepoStatus{
-				State:       &notification.GitHub.Status.State,
-				Description: &description,
-				Context:     &notification.GitHub.Status.Label,
-				TargetURL:   &notification.GitHub.Status.TargetURL,
-			},
-		)
-		if err != nil {
+	client := g.clientFor(u[0])
+	if notification.GitHub.Status != nil && !multiSource {
+		if err := g.createStatus(ctx, notification, u[0], u[1], notification.GitHub.revision); err != nil {
 			return err
 		}
 	}
@@ -468,8 +703,8 @@ func (g gitHubService) Send(notification Notification, _ Destination) error {
 	if notification.GitHub.Deployment != nil {
 		// maximum is 140 characters
 		description := trunc(notification.Message, 140)
-		deployments, _, err := g.client.Repositories.ListDeployments(
-			context.Background(),
+		deployments, _, err := client.Repositories.ListDeployments(
+			ctx,
 			u[0],
 			u[1],
 			&github.DeploymentsListOptions{
@@ -491,24 +726,25 @@ func (g gitHubService) Send(notification Notification, _ Destination) error {
 		if len(deployments) != 0 {
 			deployment = deployments[0]
 		} else {
-			deployment, _, err = g.client.Repositories.CreateDeployment(
-				context.Background(),
+			deployment, _, err = client.Repositories.CreateDeployment(
+				ctx,
 				u[0],
 				u[1],
 				&github.DeploymentRequest{
-					Ref:                  &ref,
-					Environment:          &notification.GitHub.Deployment.Environment,
-					RequiredContexts:     &notification.GitHub.Deployment.RequiredContexts,
-					AutoMerge:            notification.GitHub.Deployment.AutoMerge,
-					TransientEnvironment: notification.GitHub.Deployment.TransientEnvironment,
+					Ref:                   &ref,
+					Environment:           &notification.GitHub.Deployment.Environment,
+					RequiredContexts:      &notification.GitHub.Deployment.RequiredContexts,
+					AutoMerge:             notification.GitHub.Deployment.AutoMerge,
+					TransientEnvironment:  notification.GitHub.Deployment.TransientEnvironment,
+					ProductionEnvironment: notification.GitHub.Deployment.ProductionEnvironment,
 				},
 			)
 			if err != nil {
 				return err
 			}
 		}
-		_, _, err = g.client.Repositories.CreateDeploymentStatus(
-			context.Background(),
+		_, _, err = client.Repositories.CreateDeploymentStatus(
+			ctx,
 			u[0],
 			u[1],
 			*deployment.ID,
@@ -518,6 +754,7 @@ func (g gitHubService) Send(notification Notification, _ Destination) error {
 				Description:    &description,
 				Environment:    &notification.GitHub.Deployment.Environment,
 				EnvironmentURL: &notification.GitHub.Deployment.EnvironmentURL,
+				AutoInactive:   notification.GitHub.Deployment.AutoInactive,
 			},
 		)
 		if err != nil {
@@ -526,14 +763,42 @@ func (g gitHubService) Send(notification Notification, _ Destination) error {
 	}
 
 	if notification.GitHub.PullRequestComment != nil {
-		// maximum is 65536 characters
-		body := trunc(notification.GitHub.PullRequestComment.Content, 65536)
+		prComment := notification.GitHub.PullRequestComment
+		strategy := text.Coalesce(prComment.CommentTagStrategy, commentTagStrategyContains)
+		if strategy != commentTagStrategyContains && strategy != commentTagStrategyExactLine {
+			return fmt.Errorf("commentTagStrategy '%s' is not valid, must be one of: %s, %s", strategy, commentTagStrategyContains, commentTagStrategyExactLine)
+		}
+		state := text.Coalesce(prComment.State, pullRequestStateOpen)
+		if state != pullRequestStateOpen && state != pullRequestStateClosed && state != pullRequestStateAll {
+			return fmt.Errorf("pull request state '%s' is not valid, must be one of: %s, %s, %s", state, pullRequestStateOpen, pullRequestStateClosed, pullRequestStateAll)
+		}
+
+		limit := g.opts.MessageSizeLimit
+		if limit.MaxMessageSize <= 0 {
+			limit.MaxMessageSize = gitHubMaxCommentSize
+		}
+		var marker string
+		if prComment.CommentTag != "" {
+			marker = commentTagMarker(prComment.CommentTag)
+			// the marker is appended on its own line and must be preserved
+			limit.MaxMessageSize -= utf8.RuneCountInString(marker) + 1
+		}
+		// the gist is created once a pull request is commented
+		uploadAsGist := prComment.UploadLargeContentAsGist && utf8.RuneCountInString(prComment.Content) > limit.MaxMessageSize
+		var body string
+		if !uploadAsGist {
+			content, err := limit.enforceRunes("github", prComment.Content, gitHubMaxCommentSize)
+			if err != nil {
+				return err
+			}
+			body = withCommentTagMarker(content, marker)
+		}
 		comment := &github.IssueComment{
 			Body: &body,
 		}
 
-		prs, _, err := g.client.PullRequests.ListPullRequestsWithCommit(
-			context.Background(),
+		prs, _, err := client.PullRequests.ListPullRequestsWithCommit(
+			ctx,
 			u[0],
 			u[1],
 			notification.GitHub.revision,
@@ -544,13 +809,41 @@ func (g gitHubService) Send(notification Notification, _ Destination) error {
 		}
 
 		for _, pr := range prs {
-			_, _, err = g.client.Issues.CreateComment(
-				context.Background(),
-				u[0],
-				u[1],
-				pr.GetNumber(),
-				comment,
-			)
+			if state != pullRequestStateAll && pr.GetState() != state {
+				continue
+			}
+			if uploadAsGist && body == "" {
+				gistURL, err := createCommentGist(ctx, client, prComment)
+				if err != nil {
+					return err
+				}
+				body = withCommentTagMarker(fmt.Sprintf("The content exceeds the maximum size of a comment and was uploaded to %s", gistURL), marker)
+			}
+			var existing *github.IssueComment
+			if marker != "" {
+				existing, err = findTaggedComment(ctx, client, u[0], u[1], pr.GetNumber(), marker, strategy)
+				if err != nil {
+					return err
+				}
+			}
+
+			if existing != nil {
+				_, _, err = client.Issues.EditComment(
+					ctx,
+					u[0],
+					u[1],
+					existing.GetID(),
+					comment,
+				)
+			} else {
+				_, _, err = client.Issues.CreateComment(
+					ctx,
+					u[0],
+					u[1],
+					pr.GetNumber(),
+					comment,
+				)
+			}
 			if err != nil {
 				return err
 			}
@@ -558,37 +851,16 @@ func (g gitHubService) Send(notification Notification, _ Destination) error {
 	}
 
 	if notification.GitHub.CheckRun != nil {
-		startedTime, err := time.Parse("YYYY-MM-DDTHH:MM:SSZ", notification.GitHub.CheckRun.StartedAt)
+		checkRunOptions, err := buildCheckRunOptions(notification.GitHub.revision, notification.GitHub.CheckRun, time.Now())
 		if err != nil {
 			return err
 		}
-		completedTime, err := time.Parse("YYYY-MM-DDTHH:MM:SSZ", notification.GitHub.CheckRun.CompletedAt)
-		if err != nil {
-			return err
-		}
-		externalID := "argocd-notifications"
-		checkRunOutput := &github.CheckRunOutput{}
-		if notification.GitHub.CheckRun.Output != nil {
-			checkRunOutput = &github.CheckRunOutput{
-				Title:   &notification.GitHub.CheckRun.Output.Title,
-				Text:    &notification.GitHub.CheckRun.Output.Text,
-				Summary: &notification.GitHub.CheckRun.Output.Summary,
-			}
-		}
 
-		_, _, err = g.client.Checks.CreateCheckRun(
-			context.Background(),
+		_, _, err = client.Checks.CreateCheckRun(
+			ctx,
 			u[0],
 			u[1],
-			github.CreateCheckRunOptions{
-				HeadSHA:     notification.GitHub.revision,
-				ExternalID:  &externalID,
-				Name:        notification.GitHub.CheckRun.Name,
-				DetailsURL:  &notification.GitHub.CheckRun.DetailsURL,
-				StartedAt:   &github.Timestamp{Time: startedTime},
-				CompletedAt: &github.Timestamp{Time: completedTime},
-				Output:      checkRunOutput,
-			},
+			*checkRunOptions,
 		)
 
 		if err != nil {
@@ -598,3 +870,199 @@ func (g gitHubService) Send(notification Notification, _ Destination) error {
 
 	return nil
 }
+
+// createStatus posts the commit status to the revision of the repository, unless the status sets another reference
+func (g gitHubService) createStatus(ctx context.Context, notification Notification, owner, repo, revision string) error {
+	// maximum is 140 characters
+	description := trunc(notification.Message, 140)
+	// if no reference is provided, use the revision
+	ref := notification.GitHub.Status.Ref
+	if ref == "" {
+		ref = revision
+	}
+	_, _, err := g.clientFor(owner).Repositories.CreateStatus(
+		ctx,
+		owner,
+		repo,
+		ref,
+		&github.RepoStatus{
+			State:       &notification.GitHub.Status.State,
+			Description: &description,
+			Context:     &notification.GitHub.Status.Label,
+			TargetURL:   &notification.GitHub.Status.TargetURL,
+		},
+	)
+	return err
+}
+
+// withCommentTagMarker appends the marker of the comment tag to the comment body on its own line
+func withCommentTagMarker(body, marker string) string {
+	if marker == "" {
+		return body
+	}
+	return body + "\n" + marker
+}
+
+// createCommentGist uploads the content of the pull request comment as a secret gist and returns its URL
+func createCommentGist(ctx context.Context, client *github.Client, prComment *GitHubPullRequestComment) (string, error) {
+	description := text.Coalesce(prComment.CommentTag, "argocd-notifications")
+	public := false
+	gist, _, err := client.Gists.Create(ctx, &github.Gist{
+		Description: &description,
+		Public:      &public,
+		Files: map[github.GistFilename]github.GistFile{
+			"comment.md": {Content: &prComment.Content},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload pull request comment as gist: %w", err)
+	}
+	return gist.GetHTMLURL(), nil
+}
+
+// commentTagMarker returns the hidden marker line which identifies comments created by the notification
+func commentTagMarker(tag string) string {
+	return fmt.Sprintf("<!-- argocd-notifications %s -->", tag)
+}
+
+func findTaggedComment(ctx context.Context, client *github.Client, owner, repo string, number int, marker, strategy string) (*github.IssueComment, error) {
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		comments, resp, err := client.Issues.ListComments(ctx, owner, repo, number, opts)
+		if err != nil {
+			return nil, err
+		}
+		if comment := matchTaggedComment(comments, marker, strategy); comment != nil {
+			return comment, nil
+		}
+		if resp == nil || resp.NextPage == 0 {
+			return nil, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// matchTaggedComment returns the first comment carrying the marker. The "exact-line" strategy only matches
+// comments which have the marker on a line of its own, so comments quoting the marker are ignored.
+func matchTaggedComment(comments []*github.IssueComment, marker, strategy string) *github.IssueComment {
+	for _, comment := range comments {
+		body := comment.GetBody()
+		if strategy == commentTagStrategyExactLine {
+			for _, line := range strings.Split(body, "\n") {
+				if strings.TrimSpace(line) == marker {
+					return comment
+				}
+			}
+		} else if strings.Contains(body, marker) {
+			return comment
+		}
+	}
+	return nil
+}
+
+var checkRunStatuses = []string{"queued", "in_progress", "completed"}
+
+var checkRunAnnotationLevels = []string{"notice", "warning", "failure"}
+
+// buildCheckRunOptions validates the check run status and conclusion and fills in the default start and completion times
+func buildCheckRunOptions(revision string, checkRun *GitHubCheckRun, now time.Time) (*github.CreateCheckRunOptions, error) {
+	status := text.Coalesce(checkRun.Status, "queued")
+	validStatus := false
+	for _, s := range checkRunStatuses {
+		if status == s {
+			validStatus = true
+			break
+		}
+	}
+	if !validStatus {
+		return nil, fmt.Errorf("check run status '%s' is not valid, must be one of: %s", status, strings.Join(checkRunStatuses, ", "))
+	}
+	if checkRun.Conclusion != "" && status != "completed" {
+		return nil, fmt.Errorf("check run conclusion '%s' can only be set when status is completed, got '%s'", checkRun.Conclusion, status)
+	}
+
+	startedTime := now
+	if checkRun.StartedAt != "" {
+		t, err := time.Parse(time.RFC3339, checkRun.StartedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse check run started_at '%s': %v", checkRun.StartedAt, err)
+		}
+		startedTime = t
+	}
+
+	externalID := "argocd-notifications"
+	opts := &github.CreateCheckRunOptions{
+		HeadSHA:    revision,
+		ExternalID: &externalID,
+		Name:       checkRun.Name,
+		DetailsURL: &checkRun.DetailsURL,
+		Status:     &status,
+		StartedAt:  &github.Timestamp{Time: startedTime},
+		Output:     &github.CheckRunOutput{},
+	}
+	if checkRun.Conclusion != "" {
+		opts.Conclusion = &checkRun.Conclusion
+	}
+	if status == "completed" {
+		completedTime := now
+		if checkRun.CompletedAt != "" {
+			t, err := time.Parse(time.RFC3339, checkRun.CompletedAt)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse check run completed_at '%s': %v", checkRun.CompletedAt, err)
+			}
+			completedTime = t
+		}
+		opts.CompletedAt = &github.Timestamp{Time: completedTime}
+	}
+	if checkRun.Output != nil {
+		opts.Output = &github.CheckRunOutput{
+			Title:   &checkRun.Output.Title,
+			Text:    &checkRun.Output.Text,
+			Summary: &checkRun.Output.Summary,
+		}
+		for i := range checkRun.Output.Annotations {
+			annotation, err := buildCheckRunAnnotation(checkRun.Output.Annotations[i])
+			if err != nil {
+				return nil, err
+			}
+			opts.Output.Annotations = append(opts.Output.Annotations, annotation)
+		}
+	}
+	return opts, nil
+}
+
+// buildCheckRunAnnotation validates the annotation level, which defaults to notice, and the lines of the annotation
+func buildCheckRunAnnotation(annotation GitHubCheckRunAnnotation) (*github.CheckRunAnnotation, error) {
+	level := text.Coalesce(annotation.AnnotationLevel, "notice")
+	validLevel := false
+	for _, l := range checkRunAnnotationLevels {
+		if level == l {
+			validLevel = true
+			break
+		}
+	}
+	if !validLevel {
+		return nil, fmt.Errorf("check run annotation level '%s' is not valid, must be one of: %s", level, strings.Join(checkRunAnnotationLevels, ", "))
+	}
+	if annotation.Path == "" {
+		return nil, fmt.Errorf("check run annotation path is required")
+	}
+	endLine := annotation.EndLine
+	if endLine == 0 {
+		endLine = annotation.StartLine
+	}
+	if annotation.StartLine < 1 || endLine < annotation.StartLine {
+		return nil, fmt.Errorf("check run annotation lines %d-%d of '%s' are not valid", annotation.StartLine, endLine, annotation.Path)
+	}
+	res := &github.CheckRunAnnotation{
+		Path:            github.String(annotation.Path),
+		StartLine:       github.Int(annotation.StartLine),
+		EndLine:         github.Int(endLine),
+		AnnotationLevel: github.String(level),
+		Message:         github.String(annotation.Message),
+	}
+	if annotation.Title != "" {
+		res.Title = github.String(annotation.Title)
+	}
+	return res, nil
+}
@@ -2,11 +2,14 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	texttemplate "text/template"
 
 	"github.com/google/uuid"
@@ -14,17 +17,28 @@ import (
 	log "github.com/sirupsen/logrus"
 	"sigs.k8s.io/yaml"
 
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
 	"google.golang.org/api/chat/v1"
 
 	httputil "github.com/argoproj/notifications-engine/pkg/util/http"
+	"github.com/argoproj/notifications-engine/pkg/util/text"
 )
 
 type GoogleChatNotification struct {
 	Cards     string `json:"cards"`
 	CardsV2   string `json:"cardsV2"`
 	ThreadKey string `json:"threadKey,omitempty"`
+	// DeliveryPolicy controls whether a new message is posted (Post, default) or the message previously
+	// posted to the thread is updated (Update)
+	DeliveryPolicy string `json:"deliveryPolicy,omitempty"`
 }
 
+const (
+	googleChatDeliveryPolicyPost   = "Post"
+	googleChatDeliveryPolicyUpdate = "Update"
+)
+
 type googleChatMessage struct {
 	Text    string            `json:"text"`
 	Cards   []chat.Card       `json:"cards,omitempty"`
@@ -75,23 +89,41 @@ func (n *GoogleChatNotification) GetTemplater(name string, f texttemplate.FuncMa
 			notification.GoogleChat.ThreadKey = val
 		}
 
+		if n.DeliveryPolicy != "" {
+			notification.GoogleChat.DeliveryPolicy = n.DeliveryPolicy
+		}
+
 		return nil
 	}, nil
 }
 
+const (
+	googleChatDefaultApiUrl = "https://chat.googleapis.com"
+	googleChatBotScope      = "https://www.googleapis.com/auth/chat.bot"
+)
+
 type GoogleChatOptions struct {
 	WebhookUrls map[string]string `json:"webhooks"`
+	// ServiceAccount is the JSON key of the service account used to post messages with the Chat API to the spaces
+	// the app was added to. The recipients which have no webhook configured are then the names of the spaces,
+	// e.g. spaces/AAAAxxxx
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+	// ApiUrl is the URL of the Chat API. Defaults to https://chat.googleapis.com
+	ApiUrl string `json:"apiUrl,omitempty"`
 }
 
 type googleChatService struct {
 	opts GoogleChatOptions
+	// messageNames caches the names of the messages posted to threads keyed by webhook URL and thread key
+	messageNames *sync.Map
 }
 
 func NewGoogleChatService(opts GoogleChatOptions) NotificationService {
-	return &googleChatService{opts: opts}
+	return &googleChatService{opts: opts, messageNames: &sync.Map{}}
 }
 
 type webhookReturn struct {
+	Name  string        `json:"name"`
 	Error *webhookError `json:"error"`
 }
 
@@ -104,6 +136,9 @@ type webhookError struct {
 func (s googleChatService) getClient(recipient string) (*googlechatClient, error) {
 	webhookUrl, ok := s.opts.WebhookUrls[recipient]
 	if !ok {
+		if s.opts.ServiceAccount != "" {
+			return s.getApiClient(recipient)
+		}
 		return nil, fmt.Errorf("no Google chat webhook configured for recipient %s", recipient)
 	}
 	transport := httputil.NewTransport(webhookUrl, false)
@@ -113,12 +148,33 @@ func (s googleChatService) getClient(recipient string) (*googlechatClient, error
 	return &googlechatClient{httpClient: client, url: webhookUrl}, nil
 }
 
+// getApiClient returns a client which posts messages to the space with the spaces.messages.create API,
+// authorized with an OAuth token of the service account
+func (s googleChatService) getApiClient(space string) (*googlechatClient, error) {
+	if !strings.HasPrefix(space, "spaces/") {
+		return nil, fmt.Errorf("recipient %s is neither a configured Google chat webhook nor a space name", space)
+	}
+	credentials, err := google.CredentialsFromJSON(context.Background(), []byte(s.opts.ServiceAccount), googleChatBotScope)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Google chat service account: %w", err)
+	}
+	apiUrl := strings.TrimRight(text.Coalesce(s.opts.ApiUrl, googleChatDefaultApiUrl), "/")
+	transport := httputil.NewTransport(apiUrl, false)
+	client := &http.Client{
+		Transport: &oauth2.Transport{
+			Source: credentials.TokenSource,
+			Base:   httputil.NewLoggingRoundTripper(transport, log.WithField("service", "googlechat")),
+		},
+	}
+	return &googlechatClient{httpClient: client, url: fmt.Sprintf("%s/v1/%s/messages", apiUrl, space)}, nil
+}
+
 type googlechatClient struct {
 	httpClient *http.Client
 	url        string
 }
 
-func (c *googlechatClient) sendMessage(message *googleChatMessage, threadKey string) (*webhookReturn, error) {
+func (c *googlechatClient) sendMessage(ctx context.Context, message *googleChatMessage, threadKey string) (*webhookReturn, error) {
 	jsonMessage, err := json.Marshal(message)
 	if err != nil {
 		return nil, err
@@ -133,11 +189,49 @@ func (c *googlechatClient) sendMessage(message *googleChatMessage, threadKey str
 		q.Add("threadKey", threadKey)
 		u.RawQuery = q.Encode()
 	}
-	response, err := c.httpClient.Post(u.String(), "application/json", bytes.NewReader(jsonMessage))
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(jsonMessage))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	return readWebhookReturn(response)
+}
+
+// updateMessage updates the message with the given name using the Google Chat messages.patch API.
+// The request is authorized with the key and token of the webhook URL or the token of the service account.
+func (c *googlechatClient) updateMessage(ctx context.Context, message *googleChatMessage, name string) (*webhookReturn, error) {
+	jsonMessage, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(c.url)
 	if err != nil {
 		return nil, err
 	}
+	u.Path = "/v1/" + name
+	q := u.Query()
+	q.Del("threadKey")
+	q.Set("updateMask", "text,cards,cardsV2")
+	u.RawQuery = q.Encode()
 
+	request, err := http.NewRequestWithContext(ctx, http.MethodPatch, u.String(), bytes.NewReader(jsonMessage))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	return readWebhookReturn(response)
+}
+
+func readWebhookReturn(response *http.Response) (*webhookReturn, error) {
 	defer func() {
 		_ = response.Body.Close()
 	}()
@@ -156,6 +250,10 @@ func (c *googlechatClient) sendMessage(message *googleChatMessage, threadKey str
 }
 
 func (s googleChatService) Send(notification Notification, dest Destination) error {
+	return s.SendContext(context.Background(), notification, dest)
+}
+
+func (s googleChatService) SendContext(ctx context.Context, notification Notification, dest Destination) error {
 	client, err := s.getClient(dest.Recipient)
 	if err != nil {
 		return fmt.Errorf("error creating client to webhook: %w", err)
@@ -165,18 +263,35 @@ func (s googleChatService) Send(notification Notification, dest Destination) err
 		return fmt.Errorf("cannot create message: %w", err)
 	}
 
-	var threadKey string
+	var threadKey, policy string
 	if notification.GoogleChat != nil {
 		threadKey = notification.GoogleChat.ThreadKey
+		policy = notification.GoogleChat.DeliveryPolicy
+	}
+	if policy != "" && policy != googleChatDeliveryPolicyPost && policy != googleChatDeliveryPolicyUpdate {
+		return fmt.Errorf("googlechat deliveryPolicy '%s' is not valid, must be one of: %s, %s", policy, googleChatDeliveryPolicyPost, googleChatDeliveryPolicyUpdate)
 	}
 
-	body, err := client.sendMessage(message, threadKey)
-	if err != nil {
-		return fmt.Errorf("cannot send message: %w", err)
+	cacheKey := client.url + "|" + threadKey
+	var body *webhookReturn
+	if name, ok := s.messageNames.Load(cacheKey); ok && threadKey != "" && policy == googleChatDeliveryPolicyUpdate {
+		body, err = client.updateMessage(ctx, message, name.(string))
+		if err != nil {
+			return fmt.Errorf("cannot update message: %w", err)
+		}
+	} else {
+		// messages are posted to the thread if there is no known message to update
+		body, err = client.sendMessage(ctx, message, threadKey)
+		if err != nil {
+			return fmt.Errorf("cannot send message: %w", err)
+		}
 	}
 	if body.Error != nil {
 		return fmt.Errorf("error with message: code=%d status=%s message=%s", body.Error.Code, body.Error.Status, body.Error.Message)
 	}
+	if threadKey != "" && body.Name != "" {
+		s.messageNames.Store(cacheKey, body.Name)
+	}
 	return nil
 }
 
@@ -214,3 +329,8 @@ func googleChatNotificationToMessage(n Notification) (*googleChatMessage, error)
 	}
 	return message, nil
 }
+
+// Recipients returns the recipients which have a webhook configured
+func (s googleChatService) Recipients() []string {
+	return sortedRecipients(s.opts.WebhookUrls)
+}
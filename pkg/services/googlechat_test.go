@@ -1,6 +1,12 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -287,6 +293,70 @@ func TestCreateClient_Error(t *testing.T) {
 	assert.Nil(t, client)
 }
 
+func newTestGoogleServiceAccount(t *testing.T, tokenUrl string) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "argocd@project.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		"token_uri":    tokenUrl,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCreateClient_ServiceAccount(t *testing.T) {
+	opts := GoogleChatOptions{
+		WebhookUrls:    map[string]string{"test": "testUrl"},
+		ServiceAccount: newTestGoogleServiceAccount(t, "https://oauth2.googleapis.com/token"),
+	}
+	service := NewGoogleChatService(opts).(*googleChatService)
+
+	client, err := service.getClient("test")
+	assert.NoError(t, err)
+	assert.Equal(t, "testUrl", client.url)
+
+	client, err = service.getClient("spaces/AAAA")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://chat.googleapis.com/v1/spaces/AAAA/messages", client.url)
+
+	_, err = service.getClient("another")
+	assert.EqualError(t, err, "recipient another is neither a configured Google chat webhook nor a space name")
+
+	service = NewGoogleChatService(GoogleChatOptions{ServiceAccount: "{"}).(*googleChatService)
+	_, err = service.getClient("spaces/AAAA")
+	assert.Error(t, err)
+}
+
+func TestSendMessage_ServiceAccount(t *testing.T) {
+	var receivedPath, receivedAuthorization string
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", "application/json")
+		if req.URL.Path == "/token" {
+			_, _ = res.Write([]byte(`{"access_token": "my-token", "token_type": "Bearer", "expires_in": 3600}`))
+			return
+		}
+		receivedPath = req.URL.Path
+		receivedAuthorization = req.Header.Get("Authorization")
+		_, _ = res.Write([]byte(`{"name": "spaces/AAAA/messages/1"}`))
+	}))
+	defer testServer.Close()
+
+	service := NewGoogleChatService(GoogleChatOptions{
+		ServiceAccount: newTestGoogleServiceAccount(t, testServer.URL+"/token"),
+		ApiUrl:         testServer.URL,
+	})
+	err := service.Send(Notification{Message: "hello"}, Destination{Service: "googlechat", Recipient: "spaces/AAAA"})
+	assert.NoError(t, err)
+	assert.Equal(t, "/v1/spaces/AAAA/messages", receivedPath)
+	assert.Equal(t, "Bearer my-token", receivedAuthorization)
+}
+
 func TestSendMessage_NoError(t *testing.T) {
 	called := false
 	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
@@ -340,3 +410,49 @@ func TestSendMessageWithThreadKey_NoError(t *testing.T) {
 	assert.Nil(t, err)
 	assert.True(t, called)
 }
+
+func TestSendMessage_DeliveryPolicy(t *testing.T) {
+	var requests []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		requests = append(requests, fmt.Sprintf("%s %s %s", req.Method, req.URL.Path, req.URL.Query().Get("updateMask")))
+		assert.Equal(t, "webhook-token", req.URL.Query().Get("token"))
+
+		res.WriteHeader(http.StatusOK)
+		_, err := res.Write([]byte(`{"name": "spaces/space/messages/message"}`))
+		if err != nil {
+			t.Fatal("error on write response body")
+		}
+	}))
+	defer func() { testServer.Close() }()
+
+	opts := GoogleChatOptions{WebhookUrls: map[string]string{"test": testServer.URL + "/v1/spaces/space/messages?token=webhook-token"}}
+	service := NewGoogleChatService(opts).(*googleChatService)
+	destination := Destination{Recipient: "test"}
+	send := func(threadKey string, policy string) {
+		err := service.Send(Notification{Message: "message", GoogleChat: &GoogleChatNotification{ThreadKey: threadKey, DeliveryPolicy: policy}}, destination)
+		assert.NoError(t, err)
+	}
+
+	// no message is known for the thread yet, so it is posted
+	send("thread", "Update")
+	// the message posted to the thread is updated
+	send("thread", "Update")
+	// a new message is posted regardless of the known message
+	send("thread", "Post")
+	// messages of other threads are not updated
+	send("other-thread", "Update")
+
+	assert.Equal(t, []string{
+		"POST /v1/spaces/space/messages ",
+		"PATCH /v1/spaces/space/messages/message text,cards,cardsV2",
+		"POST /v1/spaces/space/messages ",
+		"POST /v1/spaces/space/messages ",
+	}, requests)
+}
+
+func TestSendMessage_InvalidDeliveryPolicy(t *testing.T) {
+	opts := GoogleChatOptions{WebhookUrls: map[string]string{"test": "https://chat.googleapis.com/v1/spaces/space/messages"}}
+	service := NewGoogleChatService(opts).(*googleChatService)
+	err := service.Send(Notification{Message: "message", GoogleChat: &GoogleChatNotification{DeliveryPolicy: "PostAndUpdate"}}, Destination{Recipient: "test"})
+	assert.EqualError(t, err, "googlechat deliveryPolicy 'PostAndUpdate' is not valid, must be one of: Post, Update")
+}
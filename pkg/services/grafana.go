@@ -2,13 +2,16 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
 	"strings"
+	texttemplate "text/template"
 	"time"
 
 	httputil "github.com/argoproj/notifications-engine/pkg/util/http"
@@ -20,6 +23,77 @@ type GrafanaOptions struct {
 	ApiUrl             string `json:"apiUrl"`
 	ApiKey             string `json:"apiKey"`
 	InsecureSkipVerify bool   `json:"insecureSkipVerify"`
+	// Dashboards maps recipients to the UIDs of the dashboards they annotate. Other recipients are lists of tags
+	// separated with "|".
+	Dashboards map[string]string `json:"dashboards,omitempty"`
+}
+
+type GrafanaNotification struct {
+	// DashboardUID is the UID of the annotated dashboard, overrides the dashboard of the recipient
+	DashboardUID string `json:"dashboardUID,omitempty"`
+	// PanelID is the ID of the annotated panel of the dashboard
+	PanelID string   `json:"panelId,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	// Text is the text of the annotation. Defaults to the message.
+	Text string `json:"text,omitempty"`
+}
+
+func (n *GrafanaNotification) GetTemplater(name string, f texttemplate.FuncMap) (Templater, error) {
+	dashboardUID, err := texttemplate.New(name).Funcs(f).Parse(n.DashboardUID)
+	if err != nil {
+		return nil, err
+	}
+	panelID, err := texttemplate.New(name).Funcs(f).Parse(n.PanelID)
+	if err != nil {
+		return nil, err
+	}
+	var tags []*texttemplate.Template
+	for _, tag := range n.Tags {
+		tagTemplate, err := texttemplate.New(name).Funcs(f).Parse(tag)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tagTemplate)
+	}
+	text, err := texttemplate.New(name).Funcs(f).Parse(n.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(notification *Notification, vars map[string]interface{}) error {
+		if notification.Grafana == nil {
+			notification.Grafana = &GrafanaNotification{}
+		}
+
+		var dashboardUIDData bytes.Buffer
+		if err := dashboardUID.Execute(&dashboardUIDData, vars); err != nil {
+			return err
+		}
+		notification.Grafana.DashboardUID = dashboardUIDData.String()
+
+		var panelIDData bytes.Buffer
+		if err := panelID.Execute(&panelIDData, vars); err != nil {
+			return err
+		}
+		notification.Grafana.PanelID = panelIDData.String()
+
+		notification.Grafana.Tags = nil
+		for _, tag := range tags {
+			var tagData bytes.Buffer
+			if err := tag.Execute(&tagData, vars); err != nil {
+				return err
+			}
+			notification.Grafana.Tags = append(notification.Grafana.Tags, tagData.String())
+		}
+
+		var textData bytes.Buffer
+		if err := text.Execute(&textData, vars); err != nil {
+			return err
+		}
+		notification.Grafana.Text = textData.String()
+
+		return nil
+	}, nil
 }
 
 type grafanaService struct {
@@ -31,21 +105,25 @@ func NewGrafanaService(opts GrafanaOptions) NotificationService {
 }
 
 type GrafanaAnnotation struct {
-	Time     int64    `json:"time"` // unix ts in ms
-	IsRegion bool     `json:"isRegion"`
-	Tags     []string `json:"tags"`
-	Text     string   `json:"text"`
+	Time         int64    `json:"time"` // unix ts in ms
+	IsRegion     bool     `json:"isRegion"`
+	Tags         []string `json:"tags"`
+	Text         string   `json:"text"`
+	DashboardUID string   `json:"dashboardUID,omitempty"`
+	PanelID      int64    `json:"panelId,omitempty"`
 }
 
 func (s *grafanaService) Send(notification Notification, dest Destination) error {
-	ga := GrafanaAnnotation{
-		Time:     time.Now().Unix() * 1000, // unix ts in ms
-		IsRegion: false,
-		Tags:     strings.Split(dest.Recipient, "|"),
-		Text:     notification.Message,
+	return s.SendContext(context.Background(), notification, dest)
+}
+
+func (s *grafanaService) SendContext(ctx context.Context, notification Notification, dest Destination) error {
+	ga, err := buildGrafanaAnnotation(notification, dest, s.opts, time.Now())
+	if err != nil {
+		return err
 	}
 
-	if notification.Message == "" {
+	if ga.Text == "" {
 		log.Warnf("Message is an empty string or not provided in the notifications template")
 	}
 
@@ -62,7 +140,7 @@ func (s *grafanaService) Send(notification Notification, dest Destination) error
 	}
 	annotationApi := *apiUrl
 	annotationApi.Path = path.Join(apiUrl.Path, "annotations")
-	req, err := http.NewRequest("POST", annotationApi.String(), bytes.NewBuffer(jsonValue))
+	req, err := http.NewRequestWithContext(ctx, "POST", annotationApi.String(), bytes.NewBuffer(jsonValue))
 	if err != nil {
 		log.Errorf("Failed to create grafana annotation request: %s", err)
 		return err
@@ -90,3 +168,42 @@ func (s *grafanaService) Send(notification Notification, dest Destination) error
 
 	return err
 }
+
+// buildGrafanaAnnotation builds the time-point annotation. The recipient is the dashboard of the annotation if it is
+// mapped to one, or a list of tags separated with "|" otherwise.
+func buildGrafanaAnnotation(notification Notification, dest Destination, opts GrafanaOptions, now time.Time) (*GrafanaAnnotation, error) {
+	ga := &GrafanaAnnotation{
+		Time:     now.Unix() * 1000, // unix ts in ms
+		IsRegion: false,
+		Text:     notification.Message,
+	}
+	if dashboardUID, ok := opts.Dashboards[dest.Recipient]; ok {
+		ga.DashboardUID = dashboardUID
+		ga.Tags = []string{}
+	} else {
+		ga.Tags = strings.Split(dest.Recipient, "|")
+	}
+	if notification.Grafana == nil {
+		return ga, nil
+	}
+
+	if notification.Grafana.DashboardUID != "" {
+		ga.DashboardUID = notification.Grafana.DashboardUID
+	}
+	if notification.Grafana.PanelID != "" {
+		panelID, err := strconv.ParseInt(notification.Grafana.PanelID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("grafana panelId '%s' is not valid: %v", notification.Grafana.PanelID, err)
+		}
+		ga.PanelID = panelID
+	}
+	for _, tag := range notification.Grafana.Tags {
+		if tag != "" {
+			ga.Tags = append(ga.Tags, tag)
+		}
+	}
+	if notification.Grafana.Text != "" {
+		ga.Text = notification.Grafana.Text
+	}
+	return ga, nil
+}
@@ -1,10 +1,12 @@
 package services
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"text/template"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -54,3 +56,98 @@ func TestGrafana_UnSuccessfullySendsNotification(t *testing.T) {
 		Notification{}, Destination{Recipient: "tag1|tag2", Service: "test-service"})
 	assert.Error(t, err)
 }
+
+func TestGetTemplater_Grafana(t *testing.T) {
+	n := Notification{
+		Grafana: &GrafanaNotification{
+			DashboardUID: "{{.app.metadata.labels.dashboard}}",
+			PanelID:      "{{.app.metadata.labels.panel}}",
+			Tags:         []string{"argocd", "{{.app.metadata.name}}"},
+			Text:         "{{.app.metadata.name}} deployed",
+		},
+	}
+	templater, err := n.GetTemplater("", template.FuncMap{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var notification Notification
+	err = templater(&notification, map[string]interface{}{
+		"app": map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"name":   "guestbook",
+				"labels": map[string]interface{}{"dashboard": "abc123", "panel": "4"},
+			},
+		},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, &GrafanaNotification{
+		DashboardUID: "abc123",
+		PanelID:      "4",
+		Tags:         []string{"argocd", "guestbook"},
+		Text:         "guestbook deployed",
+	}, notification.Grafana)
+}
+
+func TestGrafana_SendsDashboardAnnotation(t *testing.T) {
+	var authorization string
+	var annotation map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.Equal(t, "/api/annotations", request.URL.Path)
+		authorization = request.Header.Get("Authorization")
+		annotation = nil
+		assert.NoError(t, json.NewDecoder(request.Body).Decode(&annotation))
+	}))
+	defer server.Close()
+
+	service := NewGrafanaService(GrafanaOptions{
+		ApiUrl:     server.URL + "/api",
+		ApiKey:     "api-key",
+		Dashboards: map[string]string{"production": "prod-dashboard"},
+	})
+
+	t.Run("Recipient", func(t *testing.T) {
+		err := service.Send(Notification{
+			Message: "message",
+			Grafana: &GrafanaNotification{PanelID: "2", Tags: []string{"guestbook"}, Text: "guestbook deployed"},
+		}, Destination{Recipient: "production", Service: "grafana"})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, "Bearer api-key", authorization)
+		assert.NotZero(t, annotation["time"])
+		delete(annotation, "time")
+		assert.Equal(t, map[string]interface{}{
+			"isRegion":     false,
+			"dashboardUID": "prod-dashboard",
+			"panelId":      float64(2),
+			"tags":         []interface{}{"guestbook"},
+			"text":         "guestbook deployed",
+		}, annotation)
+	})
+
+	t.Run("Template", func(t *testing.T) {
+		err := service.Send(Notification{
+			Message: "message",
+			Grafana: &GrafanaNotification{DashboardUID: "other-dashboard"},
+		}, Destination{Recipient: "tag1|tag2", Service: "grafana"})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, "other-dashboard", annotation["dashboardUID"])
+		assert.Equal(t, []interface{}{"tag1", "tag2"}, annotation["tags"])
+		assert.Equal(t, "message", annotation["text"])
+	})
+
+	t.Run("InvalidPanelID", func(t *testing.T) {
+		err := service.Send(Notification{
+			Grafana: &GrafanaNotification{PanelID: "first"},
+		}, Destination{Recipient: "production", Service: "grafana"})
+		assert.ErrorContains(t, err, "grafana panelId 'first' is not valid")
+	})
+}
@@ -0,0 +1,15 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// DeliveryIdempotencyKey returns a key which identifies the delivery of a notification about the trigger condition
+// to the destination for the given revision of the resource. The key is the same for identical inputs, so services
+// supporting deduplication can drop a notification sent twice, e.g. by two controller replicas.
+func DeliveryIdempotencyKey(trigger string, conditionKey string, dest Destination, revision string) string {
+	hash := sha256.Sum256([]byte(strings.Join([]string{trigger, conditionKey, dest.Service, dest.Recipient, revision}, "\x00")))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeliveryIdempotencyKey(t *testing.T) {
+	dest := Destination{Service: "slack", Recipient: "my-channel"}
+	key := DeliveryIdempotencyKey("on-sync-failed", "[0].abc", dest, "123")
+
+	assert.Len(t, key, 64)
+	assert.Equal(t, key, DeliveryIdempotencyKey("on-sync-failed", "[0].abc", dest, "123"))
+
+	for name, other := range map[string]string{
+		"trigger":        DeliveryIdempotencyKey("on-sync-succeeded", "[0].abc", dest, "123"),
+		"condition":      DeliveryIdempotencyKey("on-sync-failed", "[1].abc", dest, "123"),
+		"service":        DeliveryIdempotencyKey("on-sync-failed", "[0].abc", Destination{Service: "teams", Recipient: "my-channel"}, "123"),
+		"recipient":      DeliveryIdempotencyKey("on-sync-failed", "[0].abc", Destination{Service: "slack", Recipient: "other"}, "123"),
+		"revision":       DeliveryIdempotencyKey("on-sync-failed", "[0].abc", dest, "124"),
+		"field boundary": DeliveryIdempotencyKey("on-sync-failed[0]", ".abc", dest, "123"),
+	} {
+		assert.NotEqual(t, key, other, name)
+	}
+}
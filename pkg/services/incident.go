@@ -0,0 +1,197 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	texttemplate "text/template"
+
+	log "github.com/sirupsen/logrus"
+
+	httputil "github.com/argoproj/notifications-engine/pkg/util/http"
+)
+
+const (
+	incidentActionTrigger = "trigger"
+	incidentActionResolve = "resolve"
+)
+
+type IncidentNotification struct {
+	// Action is either trigger or resolve. Defaults to trigger
+	Action string `json:"action,omitempty"`
+	// IncidentKey identifies the incident, so that resolving it closes the incident opened with the same key
+	IncidentKey string `json:"incidentKey"`
+	// Payload is the JSON object sent as the payload of the incident. The message is sent as the "message" field of
+	// the payload if it is empty.
+	Payload string `json:"payload,omitempty"`
+}
+
+// IncidentIntegration holds the endpoints of an incident management integration
+type IncidentIntegration struct {
+	TriggerURL string   `json:"triggerURL"`
+	ResolveURL string   `json:"resolveURL,omitempty"`
+	Headers    []Header `json:"headers,omitempty"`
+}
+
+type IncidentOptions struct {
+	// Integrations holds the integrations keyed by recipient
+	Integrations       map[string]IncidentIntegration `json:"integrations"`
+	InsecureSkipVerify bool                           `json:"insecureSkipVerify"`
+	CABundle           string                         `json:"caBundle,omitempty"`
+	// Timeout bounds the requests to the integrations, e.g. "10s". Defaults to 30s
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// incidentEvent is the body of the requests to the integrations
+type incidentEvent struct {
+	Action      string          `json:"action"`
+	IncidentKey string          `json:"incidentKey"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+func (n *IncidentNotification) GetTemplater(name string, f texttemplate.FuncMap) (Templater, error) {
+	action, err := texttemplate.New(name).Funcs(f).Parse(n.Action)
+	if err != nil {
+		return nil, err
+	}
+	incidentKey, err := texttemplate.New(name).Funcs(f).Parse(n.IncidentKey)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := texttemplate.New(name).Funcs(f).Parse(n.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(notification *Notification, vars map[string]interface{}) error {
+		if notification.Incident == nil {
+			notification.Incident = &IncidentNotification{}
+		}
+		var actionData bytes.Buffer
+		if err := action.Execute(&actionData, vars); err != nil {
+			return err
+		}
+		notification.Incident.Action = actionData.String()
+
+		var incidentKeyData bytes.Buffer
+		if err := incidentKey.Execute(&incidentKeyData, vars); err != nil {
+			return err
+		}
+		notification.Incident.IncidentKey = incidentKeyData.String()
+
+		var payloadData bytes.Buffer
+		if err := payload.Execute(&payloadData, vars); err != nil {
+			return err
+		}
+		notification.Incident.Payload = payloadData.String()
+
+		return nil
+	}, nil
+}
+
+func NewIncidentService(opts IncidentOptions) NotificationService {
+	return &incidentService{opts: opts}
+}
+
+type incidentService struct {
+	opts IncidentOptions
+}
+
+func (s incidentService) Send(notification Notification, dest Destination) error {
+	return s.SendContext(context.Background(), notification, dest)
+}
+
+func (s incidentService) SendContext(ctx context.Context, notification Notification, dest Destination) error {
+	integration, ok := s.opts.Integrations[dest.Recipient]
+	if !ok {
+		return fmt.Errorf("no incident integration configured for recipient %s", dest.Recipient)
+	}
+	event, err := newIncidentEvent(notification)
+	if err != nil {
+		return err
+	}
+	url := integration.TriggerURL
+	if event.Action == incidentActionResolve {
+		url = integration.ResolveURL
+	}
+	if url == "" {
+		return fmt.Errorf("incident integration '%s' does not support the %s action", dest.Recipient, event.Action)
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for _, header := range integration.Headers {
+		req.Header.Set(header.Name, header.Value)
+	}
+
+	timeout, err := parseTimeout("incident", s.opts.Timeout)
+	if err != nil {
+		return err
+	}
+	client := httputil.NewServiceHTTPClient(url, httputil.TransportOptions{
+		InsecureSkipVerify: s.opts.InsecureSkipVerify,
+		CABundle:           []byte(s.opts.CABundle),
+		Timeout:            timeout,
+	}, log.WithField("service", dest.Service))
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			body = []byte(fmt.Sprintf("unable to read response data: %v", err))
+		}
+		return fmt.Errorf("request to incident integration '%s' has failed with error code %d : %s", dest.Recipient, resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// newIncidentEvent validates the incident of the notification and returns the event sent to the integration
+func newIncidentEvent(notification Notification) (*incidentEvent, error) {
+	n := IncidentNotification{}
+	if notification.Incident != nil {
+		n = *notification.Incident
+	}
+	event := &incidentEvent{Action: n.Action, IncidentKey: n.IncidentKey}
+	switch event.Action {
+	case "":
+		event.Action = incidentActionTrigger
+	case incidentActionTrigger, incidentActionResolve:
+	default:
+		return nil, fmt.Errorf("incident action '%s' is not valid, must be one of: %s, %s", n.Action, incidentActionTrigger, incidentActionResolve)
+	}
+	if event.IncidentKey == "" {
+		return nil, fmt.Errorf("incident key is required")
+	}
+
+	if n.Payload == "" {
+		data, err := json.Marshal(map[string]string{"message": notification.Message})
+		if err != nil {
+			return nil, err
+		}
+		event.Payload = data
+	} else if !json.Valid([]byte(n.Payload)) {
+		return nil, fmt.Errorf("incident payload '%s' is not a valid JSON", n.Payload)
+	} else {
+		event.Payload = json.RawMessage(n.Payload)
+	}
+	return event, nil
+}
+
+// Recipients returns the recipients which have an integration configured
+func (s incidentService) Recipients() []string {
+	return sortedRecipients(s.opts.Integrations)
+}
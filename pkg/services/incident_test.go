@@ -0,0 +1,121 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTemplater_Incident(t *testing.T) {
+	n := Notification{
+		Incident: &IncidentNotification{
+			Action:      "{{if .healthy}}resolve{{else}}trigger{{end}}",
+			IncidentKey: "{{.app}}-degraded",
+			Payload:     `{"app": "{{.app}}"}`,
+		},
+	}
+
+	templater, err := n.GetTemplater("", template.FuncMap{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var notification Notification
+	err = templater(&notification, map[string]interface{}{
+		"app":     "guestbook",
+		"healthy": true,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, &IncidentNotification{
+		Action:      "resolve",
+		IncidentKey: "guestbook-degraded",
+		Payload:     `{"app": "guestbook"}`,
+	}, notification.Incident)
+}
+
+func TestSend_Incident(t *testing.T) {
+	var received []string
+	var events []incidentEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		var event incidentEvent
+		assert.NoError(t, json.Unmarshal(data, &event))
+		received = append(received, r.URL.Path)
+		events = append(events, event)
+		assert.Equal(t, "my-token", r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	service := NewIncidentService(IncidentOptions{
+		Integrations: map[string]IncidentIntegration{
+			"ops": {
+				TriggerURL: server.URL + "/trigger",
+				ResolveURL: server.URL + "/resolve",
+				Headers:    []Header{{Name: "Authorization", Value: "my-token"}},
+			},
+		},
+	})
+
+	t.Run("trigger", func(t *testing.T) {
+		received, events = nil, nil
+		err := service.Send(Notification{
+			Message:  "guestbook is degraded",
+			Incident: &IncidentNotification{IncidentKey: "guestbook-degraded"},
+		}, Destination{Service: "incident", Recipient: "ops"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"/trigger"}, received)
+		if assert.Len(t, events, 1) {
+			assert.Equal(t, "trigger", events[0].Action)
+			assert.Equal(t, "guestbook-degraded", events[0].IncidentKey)
+			assert.JSONEq(t, `{"message": "guestbook is degraded"}`, string(events[0].Payload))
+		}
+	})
+
+	t.Run("resolve", func(t *testing.T) {
+		received, events = nil, nil
+		err := service.Send(Notification{
+			Incident: &IncidentNotification{Action: "resolve", IncidentKey: "guestbook-degraded", Payload: `{"app": "guestbook"}`},
+		}, Destination{Service: "incident", Recipient: "ops"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"/resolve"}, received)
+		if assert.Len(t, events, 1) {
+			assert.Equal(t, "resolve", events[0].Action)
+			assert.JSONEq(t, `{"app": "guestbook"}`, string(events[0].Payload))
+		}
+	})
+
+	t.Run("unknown recipient", func(t *testing.T) {
+		err := service.Send(Notification{Incident: &IncidentNotification{IncidentKey: "key"}}, Destination{Service: "incident", Recipient: "other"})
+		assert.EqualError(t, err, "no incident integration configured for recipient other")
+	})
+}
+
+func TestSend_IncidentResolveNotSupported(t *testing.T) {
+	service := NewIncidentService(IncidentOptions{
+		Integrations: map[string]IncidentIntegration{"ops": {TriggerURL: "http://localhost/trigger"}},
+	})
+	err := service.Send(Notification{
+		Incident: &IncidentNotification{Action: "resolve", IncidentKey: "key"},
+	}, Destination{Service: "incident", Recipient: "ops"})
+	assert.EqualError(t, err, "incident integration 'ops' does not support the resolve action")
+}
+
+func TestNewIncidentEvent_Invalid(t *testing.T) {
+	_, err := newIncidentEvent(Notification{Incident: &IncidentNotification{Action: "acknowledge", IncidentKey: "key"}})
+	assert.EqualError(t, err, "incident action 'acknowledge' is not valid, must be one of: trigger, resolve")
+
+	_, err = newIncidentEvent(Notification{Message: "hello"})
+	assert.EqualError(t, err, "incident key is required")
+
+	_, err = newIncidentEvent(Notification{Incident: &IncidentNotification{IncidentKey: "key", Payload: "{"}})
+	assert.EqualError(t, err, "incident payload '{' is not a valid JSON")
+}
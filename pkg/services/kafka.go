@@ -0,0 +1,231 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"sort"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+	log "github.com/sirupsen/logrus"
+)
+
+type KafkaNotification struct {
+	// Key is used to select the partition of the message
+	Key     string            `json:"key,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+type KafkaOptions struct {
+	Brokers []string `json:"brokers"`
+	// Topics maps recipients to topics. Recipients without a mapping are used as topic name
+	Topics map[string]string `json:"topics,omitempty"`
+	// Acks is the number of acknowledgements required from the brokers: "none", "one" or "all" (default)
+	Acks string `json:"acks,omitempty"`
+	// Timeout bounds the duration of publishing a message, e.g. "30s". Defaults to 10s
+	Timeout string     `json:"timeout,omitempty"`
+	SASL    *KafkaSASL `json:"sasl,omitempty"`
+	TLS     *KafkaTLS  `json:"tls,omitempty"`
+}
+
+type KafkaSASL struct {
+	// Mechanism is one of "plain" (default), "scram-sha-256" or "scram-sha-512"
+	Mechanism string `json:"mechanism,omitempty"`
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+}
+
+type KafkaTLS struct {
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+	// CABundle holds PEM encoded certificates of additional certificate authorities to trust
+	CABundle string `json:"caBundle,omitempty"`
+}
+
+const defaultKafkaTimeout = 10 * time.Second
+
+// kafkaProducer is the subset of kafka.Writer used by the service
+type kafkaProducer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+type kafkaService struct {
+	opts     KafkaOptions
+	timeout  time.Duration
+	producer kafkaProducer
+}
+
+func NewKafkaService(opts KafkaOptions) (NotificationService, error) {
+	if len(opts.Brokers) == 0 {
+		return nil, fmt.Errorf("kafka brokers are not specified")
+	}
+
+	acks, err := kafkaRequiredAcks(opts.Acks)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := defaultKafkaTimeout
+	if opts.Timeout != "" {
+		if timeout, err = time.ParseDuration(opts.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse kafka timeout: %v", err)
+		}
+	}
+
+	transport := &kafka.Transport{}
+	if opts.SASL != nil {
+		if transport.SASL, err = kafkaSASLMechanism(*opts.SASL); err != nil {
+			return nil, err
+		}
+	}
+	if opts.TLS != nil {
+		transport.TLS = &tls.Config{InsecureSkipVerify: opts.TLS.InsecureSkipVerify}
+		if opts.TLS.CABundle != "" {
+			certPool, err := x509.SystemCertPool()
+			if err != nil {
+				certPool = x509.NewCertPool()
+			}
+			certPool.AppendCertsFromPEM([]byte(opts.TLS.CABundle))
+			transport.TLS.RootCAs = certPool
+		}
+	}
+
+	return &kafkaService{
+		opts:    opts,
+		timeout: timeout,
+		producer: &kafka.Writer{
+			Addr:         kafka.TCP(opts.Brokers...),
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: acks,
+			Transport:    transport,
+		},
+	}, nil
+}
+
+func kafkaRequiredAcks(acks string) (kafka.RequiredAcks, error) {
+	switch acks {
+	case "", "all":
+		return kafka.RequireAll, nil
+	case "one":
+		return kafka.RequireOne, nil
+	case "none":
+		return kafka.RequireNone, nil
+	default:
+		return 0, fmt.Errorf("kafka acks '%s' is not valid, must be one of: none, one, all", acks)
+	}
+}
+
+func kafkaSASLMechanism(opts KafkaSASL) (sasl.Mechanism, error) {
+	switch opts.Mechanism {
+	case "", "plain":
+		return plain.Mechanism{Username: opts.Username, Password: opts.Password}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, opts.Username, opts.Password)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, opts.Username, opts.Password)
+	default:
+		return nil, fmt.Errorf("kafka sasl mechanism '%s' is not valid, must be one of: plain, scram-sha-256, scram-sha-512", opts.Mechanism)
+	}
+}
+
+func (s *kafkaService) Send(notification Notification, dest Destination) error {
+	return s.SendContext(context.Background(), notification, dest)
+}
+
+// Close flushes the pending messages and closes the connections to the brokers
+func (s *kafkaService) Close() error {
+	err := s.producer.Close()
+	// the writer closes the connections of its default transport only
+	if writer, ok := s.producer.(*kafka.Writer); ok {
+		if transport, ok := writer.Transport.(*kafka.Transport); ok {
+			transport.CloseIdleConnections()
+		}
+	}
+	return err
+}
+
+func (s *kafkaService) SendContext(ctx context.Context, notification Notification, dest Destination) error {
+	topic, ok := s.opts.Topics[dest.Recipient]
+	if !ok {
+		topic = dest.Recipient
+	}
+	if topic == "" {
+		return fmt.Errorf("kafka topic is not specified")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if err := s.producer.WriteMessages(ctx, kafkaMessage(topic, notification)); err != nil {
+		return fmt.Errorf("failed to publish kafka message to topic '%s': %w", topic, err)
+	}
+	log.Debugf("Kafka message published to topic '%s'", topic)
+	return nil
+}
+
+func kafkaMessage(topic string, notification Notification) kafka.Message {
+	message := kafka.Message{
+		Topic: topic,
+		Value: []byte(notification.Message),
+	}
+	if notification.Kafka == nil {
+		return message
+	}
+	if notification.Kafka.Key != "" {
+		message.Key = []byte(notification.Kafka.Key)
+	}
+	names := make([]string, 0, len(notification.Kafka.Headers))
+	for name := range notification.Kafka.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		message.Headers = append(message.Headers, kafka.Header{Key: name, Value: []byte(notification.Kafka.Headers[name])})
+	}
+	return message
+}
+
+func (n *KafkaNotification) GetTemplater(name string, f texttemplate.FuncMap) (Templater, error) {
+	key, err := texttemplate.New(name).Funcs(f).Parse(n.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(map[string]*texttemplate.Template, len(n.Headers))
+	for k, v := range n.Headers {
+		if headers[k], err = texttemplate.New(name + k).Funcs(f).Parse(v); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(notification *Notification, vars map[string]interface{}) error {
+		if notification.Kafka == nil {
+			notification.Kafka = &KafkaNotification{}
+		}
+
+		var keyData bytes.Buffer
+		if err := key.Execute(&keyData, vars); err != nil {
+			return err
+		}
+		notification.Kafka.Key = keyData.String()
+
+		if len(headers) > 0 {
+			notification.Kafka.Headers = make(map[string]string, len(headers))
+			for k, tmpl := range headers {
+				var headerData bytes.Buffer
+				if err := tmpl.Execute(&headerData, vars); err != nil {
+					return err
+				}
+				notification.Kafka.Headers[k] = headerData.String()
+			}
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeKafkaProducer struct {
+	messages []kafka.Message
+	deadline time.Time
+	err      error
+	closed   bool
+}
+
+func (p *fakeKafkaProducer) Close() error {
+	p.closed = true
+	return nil
+}
+
+func (p *fakeKafkaProducer) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	p.deadline, _ = ctx.Deadline()
+	p.messages = append(p.messages, msgs...)
+	return p.err
+}
+
+func TestGetTemplater_Kafka(t *testing.T) {
+	n := Notification{
+		Message: "{{.app.metadata.name}} is synced",
+		Kafka: &KafkaNotification{
+			Key:     "{{.app.metadata.name}}",
+			Headers: map[string]string{"revision": "{{.app.status.revision}}"},
+		},
+	}
+
+	templater, err := n.GetTemplater("", template.FuncMap{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var notification Notification
+	err = templater(&notification, map[string]interface{}{
+		"app": map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "guestbook"},
+			"status":   map[string]interface{}{"revision": "abc123"},
+		},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "guestbook is synced", notification.Message)
+	assert.Equal(t, "guestbook", notification.Kafka.Key)
+	assert.Equal(t, map[string]string{"revision": "abc123"}, notification.Kafka.Headers)
+}
+
+func TestSend_Kafka(t *testing.T) {
+	producer := &fakeKafkaProducer{}
+	service := &kafkaService{
+		opts:     KafkaOptions{Topics: map[string]string{"deployments": "argocd-deployments"}},
+		timeout:  time.Minute,
+		producer: producer,
+	}
+
+	before := time.Now()
+	err := service.Send(Notification{
+		Message: "guestbook is synced",
+		Kafka: &KafkaNotification{
+			Key:     "guestbook",
+			Headers: map[string]string{"revision": "abc123", "app": "guestbook"},
+		},
+	}, Destination{Service: "kafka", Recipient: "deployments"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, service.Send(Notification{Message: "hello"}, Destination{Service: "kafka", Recipient: "other-topic"}))
+
+	assert.Equal(t, []kafka.Message{{
+		Topic: "argocd-deployments",
+		Key:   []byte("guestbook"),
+		Value: []byte("guestbook is synced"),
+		Headers: []kafka.Header{
+			{Key: "app", Value: []byte("guestbook")},
+			{Key: "revision", Value: []byte("abc123")},
+		},
+	}, {
+		Topic: "other-topic",
+		Value: []byte("hello"),
+	}}, producer.messages)
+	assert.WithinDuration(t, before.Add(time.Minute), producer.deadline, 10*time.Second)
+}
+
+func TestSend_KafkaError(t *testing.T) {
+	service := &kafkaService{timeout: time.Minute, producer: &fakeKafkaProducer{err: errors.New("broker not available")}}
+
+	err := service.Send(Notification{Message: "hello"}, Destination{Service: "kafka", Recipient: "topic"})
+	assert.EqualError(t, err, "failed to publish kafka message to topic 'topic': broker not available")
+
+	err = service.Send(Notification{Message: "hello"}, Destination{Service: "kafka"})
+	assert.EqualError(t, err, "kafka topic is not specified")
+}
+
+func TestClose_Kafka(t *testing.T) {
+	producer := &fakeKafkaProducer{}
+	service := &kafkaService{timeout: time.Minute, producer: producer}
+
+	assert.Implements(t, (*Closable)(nil), service)
+	assert.NoError(t, service.Close())
+	assert.True(t, producer.closed)
+}
+
+func TestNewKafkaService(t *testing.T) {
+	service, err := NewKafkaService(KafkaOptions{
+		Brokers: []string{"localhost:9092"},
+		Acks:    "one",
+		Timeout: "30s",
+		SASL:    &KafkaSASL{Mechanism: "scram-sha-512", Username: "user", Password: "password"},
+		TLS:     &KafkaTLS{},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 30*time.Second, service.(*kafkaService).timeout)
+	writer := service.(*kafkaService).producer.(*kafka.Writer)
+	assert.Equal(t, kafka.RequireOne, writer.RequiredAcks)
+	assert.NotNil(t, writer.Transport.(*kafka.Transport).SASL)
+	assert.NotNil(t, writer.Transport.(*kafka.Transport).TLS)
+	assert.NoError(t, service.(Closable).Close())
+
+	_, err = NewKafkaService(KafkaOptions{})
+	assert.EqualError(t, err, "kafka brokers are not specified")
+
+	_, err = NewKafkaService(KafkaOptions{Brokers: []string{"localhost:9092"}, Acks: "some"})
+	assert.EqualError(t, err, "kafka acks 'some' is not valid, must be one of: none, one, all")
+
+	_, err = NewKafkaService(KafkaOptions{Brokers: []string{"localhost:9092"}, SASL: &KafkaSASL{Mechanism: "gssapi"}})
+	assert.EqualError(t, err, "kafka sasl mechanism 'gssapi' is not valid, must be one of: plain, scram-sha-256, scram-sha-512")
+}
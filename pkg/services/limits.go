@@ -0,0 +1,83 @@
+package services
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/argoproj/notifications-engine/pkg/util/text"
+)
+
+const (
+	// MessageSizePolicyTruncate truncates messages exceeding the maximum size and appends an ellipsis
+	MessageSizePolicyTruncate = "truncate"
+	// MessageSizePolicyReject fails the delivery of messages exceeding the maximum size
+	MessageSizePolicyReject = "reject"
+)
+
+const ellipsis = "..."
+
+// MessageSizeLimit configures how a service handles messages exceeding the maximum size it accepts
+type MessageSizeLimit struct {
+	// MaxMessageSize is the maximum size of the message in bytes, or in characters for services which limit the number
+	// of characters, e.g. GitHub. Defaults to the limit of the service
+	MaxMessageSize int `json:"maxMessageSize,omitempty"`
+	// MessageSizePolicy is either "truncate" (default) or "reject"
+	MessageSizePolicy string `json:"messageSizePolicy,omitempty"`
+}
+
+// enforce returns the message unchanged if it fits the maximum size. Otherwise, the message is either truncated
+// or rejected depending on the policy. defaultMaxSize is used unless the maximum size is configured.
+func (l MessageSizeLimit) enforce(service string, message string, defaultMaxSize int) (string, error) {
+	return l.enforceWith(service, message, defaultMaxSize, "bytes", func(message string) int { return len(message) }, truncateBytes)
+}
+
+// enforceRunes is the same as enforce but measures the size of the message in characters rather than bytes
+func (l MessageSizeLimit) enforceRunes(service string, message string, defaultMaxSize int) (string, error) {
+	return l.enforceWith(service, message, defaultMaxSize, "characters", utf8.RuneCountInString, truncateRunes)
+}
+
+func (l MessageSizeLimit) enforceWith(service string, message string, defaultMaxSize int, unit string, size func(string) int, truncate func(string, int) string) (string, error) {
+	maxSize := l.MaxMessageSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxSize
+	}
+	if size(message) <= maxSize {
+		return message, nil
+	}
+	switch policy := text.Coalesce(l.MessageSizePolicy, MessageSizePolicyTruncate); policy {
+	case MessageSizePolicyTruncate:
+		return truncate(message, maxSize), nil
+	case MessageSizePolicyReject:
+		return "", fmt.Errorf("%s message size of %d %s exceeds the maximum of %d %s", service, size(message), unit, maxSize, unit)
+	default:
+		return "", fmt.Errorf("messageSizePolicy '%s' is not valid, must be one of: %s, %s", policy, MessageSizePolicyTruncate, MessageSizePolicyReject)
+	}
+}
+
+// truncateBytes shortens the message to at most n bytes including the appended ellipsis without splitting
+// multibyte characters
+func truncateBytes(message string, n int) string {
+	if len(message) <= n {
+		return message
+	}
+	if n <= len(ellipsis) {
+		return ellipsis[:n]
+	}
+	end := n - len(ellipsis)
+	for end > 0 && !utf8.RuneStart(message[end]) {
+		end--
+	}
+	return message[:end] + ellipsis
+}
+
+// truncateRunes shortens the message to at most n characters including the appended ellipsis
+func truncateRunes(message string, n int) string {
+	runes := []rune(message)
+	if len(runes) <= n {
+		return message
+	}
+	if n <= len(ellipsis) {
+		return ellipsis[:n]
+	}
+	return string(runes[:n-len(ellipsis)]) + ellipsis
+}
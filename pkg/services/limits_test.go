@@ -0,0 +1,67 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageSizeLimit_Truncate(t *testing.T) {
+	limit := MessageSizeLimit{MaxMessageSize: 10}
+
+	message, err := limit.enforce("test", "short", 100)
+	assert.NoError(t, err)
+	assert.Equal(t, "short", message)
+
+	message, err = limit.enforce("test", strings.Repeat("a", 20), 100)
+	assert.NoError(t, err)
+	assert.Equal(t, "aaaaaaa...", message)
+
+	// the default maximum size is used unless configured
+	message, err = MessageSizeLimit{}.enforce("test", strings.Repeat("a", 20), 8)
+	assert.NoError(t, err)
+	assert.Equal(t, "aaaaa...", message)
+}
+
+func TestMessageSizeLimit_TruncateMultibyte(t *testing.T) {
+	// each character is 3 bytes long, so only 2 characters fit before the ellipsis
+	message, err := MessageSizeLimit{MaxMessageSize: 11}.enforce("test", strings.Repeat("世", 10), 100)
+	assert.NoError(t, err)
+	assert.Equal(t, "世世...", message)
+	assert.True(t, utf8.ValidString(message))
+	assert.LessOrEqual(t, len(message), 11)
+}
+
+func TestMessageSizeLimit_Runes(t *testing.T) {
+	// the size is measured in characters, so multibyte characters count once
+	limit := MessageSizeLimit{MaxMessageSize: 5}
+	message, err := limit.enforceRunes("test", strings.Repeat("世", 5), 100)
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Repeat("世", 5), message)
+
+	message, err = limit.enforceRunes("test", strings.Repeat("世", 10), 100)
+	assert.NoError(t, err)
+	assert.Equal(t, "世世...", message)
+
+	limit.MessageSizePolicy = MessageSizePolicyReject
+	_, err = limit.enforceRunes("test", strings.Repeat("世", 10), 100)
+	assert.EqualError(t, err, "test message size of 10 characters exceeds the maximum of 5 characters")
+}
+
+func TestMessageSizeLimit_Reject(t *testing.T) {
+	limit := MessageSizeLimit{MaxMessageSize: 10, MessageSizePolicy: MessageSizePolicyReject}
+
+	message, err := limit.enforce("test", "short", 100)
+	assert.NoError(t, err)
+	assert.Equal(t, "short", message)
+
+	_, err = limit.enforce("test", strings.Repeat("a", 20), 100)
+	assert.EqualError(t, err, "test message size of 20 bytes exceeds the maximum of 10 bytes")
+}
+
+func TestMessageSizeLimit_InvalidPolicy(t *testing.T) {
+	_, err := MessageSizeLimit{MaxMessageSize: 10, MessageSizePolicy: "drop"}.enforce("test", strings.Repeat("a", 20), 100)
+	assert.EqualError(t, err, "messageSizePolicy 'drop' is not valid, must be one of: truncate, reject")
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -51,6 +52,10 @@ func NewMattermostService(opts MattermostOptions) NotificationService {
 }
 
 func (m *mattermostService) Send(notification Notification, dest Destination) error {
+	return m.SendContext(context.Background(), notification, dest)
+}
+
+func (m *mattermostService) SendContext(ctx context.Context, notification Notification, dest Destination) error {
 	transport := httputil.NewTransport(m.opts.ApiURL, m.opts.InsecureSkipVerify)
 	client := &http.Client{
 		Transport: httputil.NewLoggingRoundTripper(transport, log.WithField("service", "mattermost")),
@@ -74,7 +79,7 @@ func (m *mattermostService) Send(notification Notification, dest Destination) er
 	}
 	b, _ := json.Marshal(body)
 
-	req, err := http.NewRequest(http.MethodPost, m.opts.ApiURL+"/api/v4/posts", bytes.NewReader(b))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.opts.ApiURL+"/api/v4/posts", bytes.NewReader(b))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %v", err)
 	}
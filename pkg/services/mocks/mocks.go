@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	services "github.com/argoproj/notifications-engine/pkg/services"
@@ -47,3 +48,17 @@ func (mr *MockNotificationServiceMockRecorder) Send(arg0, arg1 interface{}) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockNotificationService)(nil).Send), arg0, arg1)
 }
+
+// SendContext mocks base method.
+func (m *MockNotificationService) SendContext(arg0 context.Context, arg1 services.Notification, arg2 services.Destination) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendContext", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendContext indicates an expected call of SendContext.
+func (mr *MockNotificationServiceMockRecorder) SendContext(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendContext", reflect.TypeOf((*MockNotificationService)(nil).SendContext), arg0, arg1, arg2)
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+)
+
+type NatsNotification struct {
+	// SubjectSuffix is appended to the subject of the recipient, separated by a dot, e.g. the name of the application
+	SubjectSuffix string            `json:"subjectSuffix,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty"`
+}
+
+type NatsOptions struct {
+	// Servers are the URLs of the NATS servers, e.g. nats://nats:4222
+	Servers []string `json:"servers"`
+	// Subjects maps recipients to subjects. Recipients without a mapping are used as subject
+	Subjects map[string]string `json:"subjects,omitempty"`
+	Username string            `json:"username,omitempty"`
+	Password string            `json:"password,omitempty"`
+	Token    string            `json:"token,omitempty"`
+	// CredentialsFile is the path of the file holding the JWT and the NKey seed of the user
+	CredentialsFile string `json:"credentialsFile,omitempty"`
+	// JetStream publishes the messages to the streams of the subjects and waits for the acknowledgement of the stream
+	JetStream bool `json:"jetStream,omitempty"`
+	// Timeout bounds the duration of publishing a message, e.g. "30s". Defaults to 10s
+	Timeout string `json:"timeout,omitempty"`
+}
+
+const defaultNatsTimeout = 10 * time.Second
+
+// natsPublisher publishes the messages either to core NATS or to JetStream
+type natsPublisher interface {
+	Publish(ctx context.Context, msg *nats.Msg) error
+	Close() error
+}
+
+type natsService struct {
+	opts      NatsOptions
+	timeout   time.Duration
+	publisher natsPublisher
+}
+
+func NewNatsService(opts NatsOptions) (NotificationService, error) {
+	if len(opts.Servers) == 0 {
+		return nil, fmt.Errorf("nats servers are not specified")
+	}
+
+	timeout := defaultNatsTimeout
+	if opts.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(opts.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse nats timeout: %v", err)
+		}
+	}
+
+	options := []nats.Option{nats.Name("notifications-engine")}
+	if opts.Username != "" {
+		options = append(options, nats.UserInfo(opts.Username, opts.Password))
+	}
+	if opts.Token != "" {
+		options = append(options, nats.Token(opts.Token))
+	}
+	if opts.CredentialsFile != "" {
+		options = append(options, nats.UserCredentials(opts.CredentialsFile))
+	}
+
+	return &natsService{
+		opts:    opts,
+		timeout: timeout,
+		publisher: &natsConnPublisher{
+			servers:   strings.Join(opts.Servers, ","),
+			options:   options,
+			jetStream: opts.JetStream,
+		},
+	}, nil
+}
+
+func (s *natsService) Send(notification Notification, dest Destination) error {
+	return s.SendContext(context.Background(), notification, dest)
+}
+
+// Close closes the connection to the servers
+func (s *natsService) Close() error {
+	return s.publisher.Close()
+}
+
+func (s *natsService) SendContext(ctx context.Context, notification Notification, dest Destination) error {
+	subject, ok := s.opts.Subjects[dest.Recipient]
+	if !ok {
+		subject = dest.Recipient
+	}
+	if subject == "" {
+		return fmt.Errorf("nats subject is not specified")
+	}
+	if notification.Nats != nil && notification.Nats.SubjectSuffix != "" {
+		subject = subject + "." + notification.Nats.SubjectSuffix
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if err := s.publisher.Publish(ctx, natsMessage(subject, notification)); err != nil {
+		return fmt.Errorf("failed to publish nats message to subject '%s': %w", subject, err)
+	}
+	log.Debugf("NATS message published to subject '%s'", subject)
+	return nil
+}
+
+func natsMessage(subject string, notification Notification) *nats.Msg {
+	msg := nats.NewMsg(subject)
+	msg.Data = []byte(notification.Message)
+	if notification.Nats != nil {
+		for name, value := range notification.Nats.Headers {
+			msg.Header.Set(name, value)
+		}
+	}
+	return msg
+}
+
+// natsConnPublisher connects to the servers once the first message is published and keeps the connection, which
+// reconnects on its own, for the following messages until the publisher is closed
+type natsConnPublisher struct {
+	servers   string
+	options   []nats.Option
+	jetStream bool
+
+	lock   sync.Mutex
+	conn   *nats.Conn
+	js     nats.JetStreamContext
+	closed bool
+}
+
+func (p *natsConnPublisher) connect() (*nats.Conn, nats.JetStreamContext, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.closed {
+		return nil, nil, nats.ErrConnectionClosed
+	}
+	if p.conn != nil && !p.conn.IsClosed() {
+		return p.conn, p.js, nil
+	}
+	conn, err := nats.Connect(p.servers, p.options...)
+	if err != nil {
+		return nil, nil, err
+	}
+	var js nats.JetStreamContext
+	if p.jetStream {
+		if js, err = conn.JetStream(); err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+	}
+	p.conn, p.js = conn, js
+	return conn, js, nil
+}
+
+// Close closes the connection and prevents new connections, so that a connection is not left open once the
+// configuration is reloaded
+func (p *natsConnPublisher) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.closed = true
+	if p.conn != nil {
+		p.conn.Close()
+	}
+	return nil
+}
+
+func (p *natsConnPublisher) Publish(ctx context.Context, msg *nats.Msg) error {
+	conn, js, err := p.connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	if js != nil {
+		_, err := js.PublishMsg(msg, nats.Context(ctx))
+		return err
+	}
+	if err := conn.PublishMsg(msg); err != nil {
+		return err
+	}
+	// the message is buffered by the client, flushing waits until the server received it
+	return conn.FlushWithContext(ctx)
+}
+
+func (n *NatsNotification) GetTemplater(name string, f texttemplate.FuncMap) (Templater, error) {
+	subjectSuffix, err := texttemplate.New(name).Funcs(f).Parse(n.SubjectSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(map[string]*texttemplate.Template, len(n.Headers))
+	for k, v := range n.Headers {
+		if headers[k], err = texttemplate.New(name + k).Funcs(f).Parse(v); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(notification *Notification, vars map[string]interface{}) error {
+		if notification.Nats == nil {
+			notification.Nats = &NatsNotification{}
+		}
+
+		var subjectSuffixData bytes.Buffer
+		if err := subjectSuffix.Execute(&subjectSuffixData, vars); err != nil {
+			return err
+		}
+		notification.Nats.SubjectSuffix = subjectSuffixData.String()
+
+		if len(headers) > 0 {
+			notification.Nats.Headers = make(map[string]string, len(headers))
+			for k, tmpl := range headers {
+				var headerData bytes.Buffer
+				if err := tmpl.Execute(&headerData, vars); err != nil {
+					return err
+				}
+				notification.Nats.Headers[k] = headerData.String()
+			}
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"text/template"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeNatsPublisher struct {
+	messages []*nats.Msg
+	deadline time.Time
+	err      error
+	closed   bool
+}
+
+func (p *fakeNatsPublisher) Close() error {
+	p.closed = true
+	return nil
+}
+
+func (p *fakeNatsPublisher) Publish(ctx context.Context, msg *nats.Msg) error {
+	p.deadline, _ = ctx.Deadline()
+	p.messages = append(p.messages, msg)
+	return p.err
+}
+
+func TestGetTemplater_Nats(t *testing.T) {
+	n := Notification{
+		Message: "{{.app.metadata.name}} is synced",
+		Nats: &NatsNotification{
+			SubjectSuffix: "{{.app.metadata.name}}",
+			Headers:       map[string]string{"revision": "{{.app.status.revision}}"},
+		},
+	}
+
+	templater, err := n.GetTemplater("", template.FuncMap{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var notification Notification
+	err = templater(&notification, map[string]interface{}{
+		"app": map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "guestbook"},
+			"status":   map[string]interface{}{"revision": "abc123"},
+		},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "guestbook is synced", notification.Message)
+	assert.Equal(t, "guestbook", notification.Nats.SubjectSuffix)
+	assert.Equal(t, map[string]string{"revision": "abc123"}, notification.Nats.Headers)
+}
+
+func TestSend_Nats(t *testing.T) {
+	publisher := &fakeNatsPublisher{}
+	service := &natsService{
+		opts:      NatsOptions{Subjects: map[string]string{"deployments": "argocd.deployments"}},
+		timeout:   time.Minute,
+		publisher: publisher,
+	}
+
+	before := time.Now()
+	err := service.Send(Notification{
+		Message: "guestbook is synced",
+		Nats: &NatsNotification{
+			SubjectSuffix: "guestbook",
+			Headers:       map[string]string{"revision": "abc123"},
+		},
+	}, Destination{Service: "nats", Recipient: "deployments"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, service.Send(Notification{Message: "hello"}, Destination{Service: "nats", Recipient: "other.subject"}))
+
+	if assert.Len(t, publisher.messages, 2) {
+		assert.Equal(t, "argocd.deployments.guestbook", publisher.messages[0].Subject)
+		assert.Equal(t, []byte("guestbook is synced"), publisher.messages[0].Data)
+		assert.Equal(t, "abc123", publisher.messages[0].Header.Get("revision"))
+		assert.Equal(t, "other.subject", publisher.messages[1].Subject)
+		assert.Equal(t, []byte("hello"), publisher.messages[1].Data)
+		assert.Empty(t, publisher.messages[1].Header)
+	}
+	assert.WithinDuration(t, before.Add(time.Minute), publisher.deadline, 10*time.Second)
+}
+
+func TestSend_NatsError(t *testing.T) {
+	service := &natsService{timeout: time.Minute, publisher: &fakeNatsPublisher{err: errors.New("no responders available for request")}}
+
+	err := service.Send(Notification{Message: "hello"}, Destination{Service: "nats", Recipient: "subject"})
+	assert.EqualError(t, err, "failed to publish nats message to subject 'subject': no responders available for request")
+
+	err = service.Send(Notification{Message: "hello"}, Destination{Service: "nats"})
+	assert.EqualError(t, err, "nats subject is not specified")
+}
+
+func TestClose_Nats(t *testing.T) {
+	publisher := &fakeNatsPublisher{}
+	service := &natsService{timeout: time.Minute, publisher: publisher}
+
+	assert.Implements(t, (*Closable)(nil), service)
+	assert.NoError(t, service.Close())
+	assert.True(t, publisher.closed)
+}
+
+func TestNatsConnPublisher_Closed(t *testing.T) {
+	publisher := &natsConnPublisher{servers: "nats://localhost:4222"}
+	assert.NoError(t, publisher.Close())
+
+	// a closed publisher does not connect again
+	err := publisher.Publish(context.Background(), nats.NewMsg("subject"))
+	assert.ErrorIs(t, err, nats.ErrConnectionClosed)
+	assert.Nil(t, publisher.conn)
+}
+
+func TestNewNatsService(t *testing.T) {
+	service, err := NewNatsService(NatsOptions{
+		Servers:   []string{"nats://nats-0:4222", "nats://nats-1:4222"},
+		Username:  "user",
+		Password:  "password",
+		JetStream: true,
+		Timeout:   "30s",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 30*time.Second, service.(*natsService).timeout)
+	publisher := service.(*natsService).publisher.(*natsConnPublisher)
+	assert.Equal(t, "nats://nats-0:4222,nats://nats-1:4222", publisher.servers)
+	assert.True(t, publisher.jetStream)
+	assert.Len(t, publisher.options, 2)
+
+	_, err = NewNatsService(NatsOptions{})
+	assert.EqualError(t, err, "nats servers are not specified")
+
+	_, err = NewNatsService(NatsOptions{Servers: []string{"nats://nats:4222"}, Timeout: "soon"})
+	assert.EqualError(t, err, `failed to parse nats timeout: time: invalid duration "soon"`)
+}
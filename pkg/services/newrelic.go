@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -111,6 +112,10 @@ type newrelicDeploymentMarkerRequest struct {
 }
 
 func (s newrelicService) Send(notification Notification, dest Destination) error {
+	return s.SendContext(context.Background(), notification, dest)
+}
+
+func (s newrelicService) SendContext(ctx context.Context, notification Notification, dest Destination) error {
 	if s.opts.ApiKey == "" {
 		return ErrMissingApiKey
 	}
@@ -143,7 +148,7 @@ func (s newrelicService) Send(notification Notification, dest Destination) error
 	}
 
 	markerApi := fmt.Sprintf(s.opts.ApiURL+"/v2/applications/%s/deployments.json", dest.Recipient)
-	req, err := http.NewRequest(http.MethodPost, markerApi, bytes.NewBuffer(jsonValue))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, markerApi, bytes.NewBuffer(jsonValue))
 	if err != nil {
 		log.Errorf("Failed to create deployment marker request: %s", err)
 		return err
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	texttemplate "text/template"
 
 	"github.com/opsgenie/opsgenie-go-sdk-v2/alert"
@@ -15,11 +16,25 @@ import (
 )
 
 type OpsgenieOptions struct {
-	ApiUrl  string            `json:"apiUrl"`
-	ApiKeys map[string]string `json:"apiKeys"`
+	ApiUrl   string            `json:"apiUrl"`
+	ApiKeys  map[string]string `json:"apiKeys"`
+	ProxyURL string            `json:"proxyUrl,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
 }
 
+const (
+	// OpsgenieActionCreate creates a new alert
+	OpsgenieActionCreate = "create"
+	// OpsgenieActionClose closes the alert with the notification alias
+	OpsgenieActionClose = "close"
+	// OpsgenieActionAcknowledge acknowledges the alert with the notification alias
+	OpsgenieActionAcknowledge = "acknowledge"
+)
+
 type OpsgenieNotification struct {
+	// Action is the action performed on the alert: create (default), close or acknowledge.
+	// Close and acknowledge identify the alert by its alias.
+	Action      string            `json:"action,omitempty"`
 	Description string            `json:"description"`
 	Priority    string            `json:"priority,omitempty"`
 	Alias       string            `json:"alias,omitempty"`
@@ -30,6 +45,96 @@ type OpsgenieNotification struct {
 	Entity      string            `json:"entity,omitempty"`
 	VisibleTo   []alert.Responder `json:"visibleTo,omitempty"`
 	User        string            `json:"user,omitempty"`
+	// Responders replace the team responder derived from the recipient. The responder fields are templates, e.g.
+	// {"type": "team", "id": "{{.recipient}}"} keeps the team of the recipient.
+	Responders []alert.Responder `json:"responders,omitempty"`
+}
+
+// opsgenieResponderTypes are the responder types supported by Opsgenie
+var opsgenieResponderTypes = []string{"team", "user", "escalation", "schedule"}
+
+// validateOpsgenieResponders checks that the responders have a supported type and identify the responder
+func validateOpsgenieResponders(responders []alert.Responder) error {
+	for i, responder := range responders {
+		valid := false
+		for _, t := range opsgenieResponderTypes {
+			valid = valid || string(responder.Type) == t
+		}
+		if !valid {
+			return fmt.Errorf("opsgenie responder type '%s' is not valid, must be one of: %s", responder.Type, strings.Join(opsgenieResponderTypes, ", "))
+		}
+		if responder.Id == "" && responder.Name == "" && responder.Username == "" {
+			return fmt.Errorf("opsgenie responder %d requires an id, name or username", i)
+		}
+	}
+	return nil
+}
+
+type opsgenieResponderTemplate struct {
+	Type     *texttemplate.Template
+	Name     *texttemplate.Template
+	Id       *texttemplate.Template
+	Username *texttemplate.Template
+}
+
+// parseOpsgenieResponders parses the fields (Id, Type, Name, Username) of the responders as templates
+func parseOpsgenieResponders(name string, kind string, responders []alert.Responder, f texttemplate.FuncMap) ([]opsgenieResponderTemplate, error) {
+	res := make([]opsgenieResponderTemplate, len(responders))
+	for i, responder := range responders {
+		idTemplate, err := texttemplate.New(fmt.Sprintf("%s_%s_%d_id", name, kind, i)).Funcs(f).Parse(responder.Id)
+		if err != nil {
+			return nil, err
+		}
+		typeTemplate, err := texttemplate.New(fmt.Sprintf("%s_%s_%d_type", name, kind, i)).Funcs(f).Parse(string(responder.Type))
+		if err != nil {
+			return nil, err
+		}
+		nameTemplate, err := texttemplate.New(fmt.Sprintf("%s_%s_%d_name", name, kind, i)).Funcs(f).Parse(responder.Name)
+		if err != nil {
+			return nil, err
+		}
+		usernameTemplate, err := texttemplate.New(fmt.Sprintf("%s_%s_%d_username", name, kind, i)).Funcs(f).Parse(responder.Username)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = opsgenieResponderTemplate{
+			Type:     typeTemplate,
+			Name:     nameTemplate,
+			Id:       idTemplate,
+			Username: usernameTemplate,
+		}
+	}
+	return res, nil
+}
+
+// executeOpsgenieResponders renders the responders parsed by parseOpsgenieResponders
+func executeOpsgenieResponders(templates []opsgenieResponderTemplate, vars map[string]interface{}) ([]alert.Responder, error) {
+	res := make([]alert.Responder, len(templates))
+	for i, template := range templates {
+		var idData, typeData, nameData, usernameData bytes.Buffer
+
+		// Execute each responder field template
+		if err := template.Id.Execute(&idData, vars); err != nil {
+			return nil, err
+		}
+		if err := template.Type.Execute(&typeData, vars); err != nil {
+			return nil, err
+		}
+		if err := template.Name.Execute(&nameData, vars); err != nil {
+			return nil, err
+		}
+		if err := template.Username.Execute(&usernameData, vars); err != nil {
+			return nil, err
+		}
+
+		res[i] = alert.Responder{
+			Id:       idData.String(),
+			Type:     alert.ResponderType(typeData.String()), // Convert the string to the ResponderType
+			Name:     nameData.String(),
+			Username: usernameData.String(),
+		}
+	}
+	return res, nil
 }
 
 func (n *OpsgenieNotification) GetTemplater(name string, f texttemplate.FuncMap) (Templater, error) {
@@ -67,43 +172,13 @@ func (n *OpsgenieNotification) GetTemplater(name string, f texttemplate.FuncMap)
 		details[key] = detailTemplate
 	}
 
-	visibleTo := make([]struct {
-		Type     *texttemplate.Template
-		Name     *texttemplate.Template
-		Id       *texttemplate.Template
-		Username *texttemplate.Template
-	}, len(n.VisibleTo))
-
-	// Templating for VisibleTo Responder fields (Id, Type, Name, Username)
-	for i, responder := range n.VisibleTo {
-		idTemplate, err := texttemplate.New(fmt.Sprintf("%s_responder_%d_id", name, i)).Funcs(f).Parse(responder.Id)
-		if err != nil {
-			return nil, err
-		}
-		typeTemplate, err := texttemplate.New(fmt.Sprintf("%s_responder_%d_type", name, i)).Funcs(f).Parse(string(responder.Type))
-		if err != nil {
-			return nil, err
-		}
-		nameTemplate, err := texttemplate.New(fmt.Sprintf("%s_responder_%d_name", name, i)).Funcs(f).Parse(responder.Name)
-		if err != nil {
-			return nil, err
-		}
-		usernameTemplate, err := texttemplate.New(fmt.Sprintf("%s_responder_%d_username", name, i)).Funcs(f).Parse(responder.Username)
-		if err != nil {
-			return nil, err
-		}
-
-		visibleTo[i] = struct {
-			Type     *texttemplate.Template
-			Name     *texttemplate.Template
-			Id       *texttemplate.Template
-			Username *texttemplate.Template
-		}{
-			Type:     typeTemplate,
-			Name:     nameTemplate,
-			Id:       idTemplate,
-			Username: usernameTemplate,
-		}
+	visibleTo, err := parseOpsgenieResponders(name, "responder", n.VisibleTo, f)
+	if err != nil {
+		return nil, err
+	}
+	responders, err := parseOpsgenieResponders(name, "responders", n.Responders, f)
+	if err != nil {
+		return nil, err
 	}
 
 	var actionsTemplates []*texttemplate.Template
@@ -134,6 +209,7 @@ func (n *OpsgenieNotification) GetTemplater(name string, f texttemplate.FuncMap)
 		if notification.Opsgenie == nil {
 			notification.Opsgenie = &OpsgenieNotification{}
 		}
+		notification.Opsgenie.Action = n.Action
 
 		var descData bytes.Buffer
 		if err := desc.Execute(&descData, vars); err != nil {
@@ -183,31 +259,19 @@ func (n *OpsgenieNotification) GetTemplater(name string, f texttemplate.FuncMap)
 		}
 
 		if n.VisibleTo != nil {
-			notification.Opsgenie.VisibleTo = make([]alert.Responder, len(n.VisibleTo))
-			for i, template := range visibleTo {
-				var idData, typeData, nameData, usernameData bytes.Buffer
-
-				// Execute each responder field template
-				if err := template.Id.Execute(&idData, vars); err != nil {
-					return err
-				}
-				if err := template.Type.Execute(&typeData, vars); err != nil {
-					return err
-				}
-				if err := template.Name.Execute(&nameData, vars); err != nil {
-					return err
-				}
-				if err := template.Username.Execute(&usernameData, vars); err != nil {
-					return err
-				}
+			visibleToData, err := executeOpsgenieResponders(visibleTo, vars)
+			if err != nil {
+				return err
+			}
+			notification.Opsgenie.VisibleTo = visibleToData
+		}
 
-				notification.Opsgenie.VisibleTo[i] = alert.Responder{
-					Id:       idData.String(),
-					Type:     alert.ResponderType(typeData.String()), // Convert the string to the ResponderType
-					Name:     nameData.String(),
-					Username: usernameData.String(),
-				}
+		if n.Responders != nil {
+			respondersData, err := executeOpsgenieResponders(responders, vars)
+			if err != nil {
+				return err
 			}
+			notification.Opsgenie.Responders = respondersData
 		}
 
 		if n.Actions != nil {
@@ -236,33 +300,79 @@ func (n *OpsgenieNotification) GetTemplater(name string, f texttemplate.FuncMap)
 	}, nil
 }
 
+type opsgenieAlertClient interface {
+	Create(ctx context.Context, req *alert.CreateAlertRequest) (*alert.AsyncAlertResult, error)
+	Close(ctx context.Context, req *alert.CloseAlertRequest) (*alert.AsyncAlertResult, error)
+	Acknowledge(ctx context.Context, req *alert.AcknowledgeAlertRequest) (*alert.AsyncAlertResult, error)
+}
+
 type opsgenieService struct {
-	opts OpsgenieOptions
+	opts           OpsgenieOptions
+	newAlertClient func(config *client.Config) (opsgenieAlertClient, error)
 }
 
 func NewOpsgenieService(opts OpsgenieOptions) NotificationService {
-	return &opsgenieService{opts: opts}
+	return &opsgenieService{opts: opts, newAlertClient: func(config *client.Config) (opsgenieAlertClient, error) {
+		return alert.NewClient(config)
+	}}
+}
+
+func (s *opsgenieService) newHTTPClient() (*http.Client, error) {
+	transport := httputil.NewTransport(s.opts.ApiUrl, false)
+	if s.opts.ProxyURL != "" {
+		proxy, err := httputil.NewProxyFunc(s.opts.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid opsgenie proxyUrl '%s': %v", s.opts.ProxyURL, err)
+		}
+		transport.Proxy = proxy
+	}
+
+	var roundTripper http.RoundTripper = transport
+	if len(s.opts.Headers) > 0 {
+		roundTripper = httputil.NewHeadersRoundTripper(roundTripper, s.opts.Headers)
+	}
+	return &http.Client{
+		Transport: httputil.NewLoggingRoundTripper(roundTripper, log.WithField("service", "opsgenie")),
+	}, nil
 }
 
 func (s *opsgenieService) Send(notification Notification, dest Destination) error {
+	return s.SendContext(context.Background(), notification, dest)
+}
+
+func (s *opsgenieService) SendContext(ctx context.Context, notification Notification, dest Destination) error {
 	apiKey, ok := s.opts.ApiKeys[dest.Recipient]
 	if !ok {
 		return fmt.Errorf("no API key configured for recipient %s", dest.Recipient)
 	}
-	alertClient, _ := alert.NewClient(&client.Config{
+	httpClient, err := s.newHTTPClient()
+	if err != nil {
+		return err
+	}
+	alertClient, err := s.newAlertClient(&client.Config{
 		ApiKey:         apiKey,
 		OpsGenieAPIURL: client.ApiUrl(s.opts.ApiUrl),
-		HttpClient: &http.Client{
-			Transport: httputil.NewLoggingRoundTripper(
-				httputil.NewTransport(s.opts.ApiUrl, false), log.WithField("service", "opsgenie")),
-		},
+		HttpClient:     httpClient,
 	})
+	if err != nil {
+		return err
+	}
+
+	if notification.Opsgenie != nil && notification.Opsgenie.Action != "" && notification.Opsgenie.Action != OpsgenieActionCreate {
+		return updateOpsgenieAlert(ctx, alertClient, notification.Opsgenie)
+	}
 
 	var description, alias, note, entity, user string
 	var priority alert.Priority
 	var actions, tags []string
 	var details map[string]string
 	var visibleTo []alert.Responder
+	responders := []alert.Responder{
+		{
+			Type: "team",
+			Id:   dest.Recipient,
+		},
+	}
 
 	if notification.Opsgenie != nil {
 		if notification.Opsgenie.Description == "" {
@@ -306,9 +416,16 @@ func (s *opsgenieService) Send(notification Notification, dest Destination) erro
 		if len(notification.Opsgenie.VisibleTo) > 0 {
 			visibleTo = notification.Opsgenie.VisibleTo
 		}
+
+		if len(notification.Opsgenie.Responders) > 0 {
+			if err := validateOpsgenieResponders(notification.Opsgenie.Responders); err != nil {
+				return err
+			}
+			responders = notification.Opsgenie.Responders
+		}
 	}
 
-	_, err := alertClient.Create(context.TODO(), &alert.CreateAlertRequest{
+	_, err = alertClient.Create(ctx, &alert.CreateAlertRequest{
 		Message:     notification.Message,
 		Description: description,
 		Priority:    priority,
@@ -320,13 +437,43 @@ func (s *opsgenieService) Send(notification Notification, dest Destination) erro
 		Entity:      entity,
 		VisibleTo:   visibleTo,
 		User:        user,
-		Responders: []alert.Responder{
-			{
-				Type: "team",
-				Id:   dest.Recipient,
-			},
-		},
-		Source: "Argo CD",
+		Responders:  responders,
+		Source:      "Argo CD",
 	})
 	return err
 }
+
+// updateOpsgenieAlert closes or acknowledges the alert identified by the alias of the notification
+func updateOpsgenieAlert(ctx context.Context, alertClient opsgenieAlertClient, notification *OpsgenieNotification) error {
+	if notification.Action != OpsgenieActionClose && notification.Action != OpsgenieActionAcknowledge {
+		return fmt.Errorf("unsupported opsgenie action '%s'", notification.Action)
+	}
+	if notification.Alias == "" {
+		return fmt.Errorf("opsgenie notification alias is required to %s an alert", notification.Action)
+	}
+
+	var err error
+	if notification.Action == OpsgenieActionClose {
+		_, err = alertClient.Close(ctx, &alert.CloseAlertRequest{
+			IdentifierType:  alert.ALIAS,
+			IdentifierValue: notification.Alias,
+			User:            notification.User,
+			Note:            notification.Note,
+			Source:          "Argo CD",
+		})
+	} else {
+		_, err = alertClient.Acknowledge(ctx, &alert.AcknowledgeAlertRequest{
+			IdentifierType:  alert.ALIAS,
+			IdentifierValue: notification.Alias,
+			User:            notification.User,
+			Note:            notification.Note,
+			Source:          "Argo CD",
+		})
+	}
+	return err
+}
+
+// Recipients returns the recipients which have an API key configured
+func (s *opsgenieService) Recipients() []string {
+	return sortedRecipients(s.opts.ApiKeys)
+}
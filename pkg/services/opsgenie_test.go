@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -8,6 +9,7 @@ import (
 	texttemplate "text/template"
 
 	"github.com/opsgenie/opsgenie-go-sdk-v2/alert"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/client"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -467,3 +469,194 @@ func TestOpsgenie_SendNotification_WithAllFields(t *testing.T) {
 	// Assert the result for all fields present
 	assert.NoError(t, err) // Expect no error
 }
+
+func TestOpsgenie_HTTPClientWithProxyAndHeaders(t *testing.T) {
+	var receivedHost, receivedHeader string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedHost = r.Host
+		receivedHeader = r.Header.Get("X-Egress-Token")
+	}))
+	defer proxy.Close()
+
+	service := &opsgenieService{opts: OpsgenieOptions{
+		ApiUrl:   "api.opsgenie.com",
+		ProxyURL: proxy.URL,
+		Headers:  map[string]string{"X-Egress-Token": "secret"},
+	}}
+	client, err := service.newHTTPClient()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	resp, err := client.Get("http://api.opsgenie.com/v2/alerts")
+	if !assert.NoError(t, err) {
+		return
+	}
+	_ = resp.Body.Close()
+
+	assert.Equal(t, "api.opsgenie.com", receivedHost)
+	assert.Equal(t, "secret", receivedHeader)
+}
+
+func TestOpsgenie_HTTPClientInvalidProxy(t *testing.T) {
+	service := &opsgenieService{opts: OpsgenieOptions{ProxyURL: "http://[::1"}}
+	_, err := service.newHTTPClient()
+	assert.Error(t, err)
+}
+
+type fakeOpsgenieAlertClient struct {
+	created      []*alert.CreateAlertRequest
+	closed       []*alert.CloseAlertRequest
+	acknowledged []*alert.AcknowledgeAlertRequest
+}
+
+func (c *fakeOpsgenieAlertClient) Create(_ context.Context, req *alert.CreateAlertRequest) (*alert.AsyncAlertResult, error) {
+	c.created = append(c.created, req)
+	return &alert.AsyncAlertResult{}, nil
+}
+
+func (c *fakeOpsgenieAlertClient) Close(_ context.Context, req *alert.CloseAlertRequest) (*alert.AsyncAlertResult, error) {
+	c.closed = append(c.closed, req)
+	return &alert.AsyncAlertResult{}, nil
+}
+
+func (c *fakeOpsgenieAlertClient) Acknowledge(_ context.Context, req *alert.AcknowledgeAlertRequest) (*alert.AsyncAlertResult, error) {
+	c.acknowledged = append(c.acknowledged, req)
+	return &alert.AsyncAlertResult{}, nil
+}
+
+func newFakeOpsgenieService() (*opsgenieService, *fakeOpsgenieAlertClient) {
+	alertClient := &fakeOpsgenieAlertClient{}
+	return &opsgenieService{
+		opts: OpsgenieOptions{ApiUrl: "api.opsgenie.com", ApiKeys: map[string]string{"my-team": "api-key"}},
+		newAlertClient: func(config *client.Config) (opsgenieAlertClient, error) {
+			return alertClient, nil
+		},
+	}, alertClient
+}
+
+func TestOpsgenie_SendNotification_Actions(t *testing.T) {
+	dest := Destination{Service: "opsgenie", Recipient: "my-team"}
+
+	t.Run("Create", func(t *testing.T) {
+		service, alertClient := newFakeOpsgenieService()
+		err := service.Send(Notification{Message: "down", Opsgenie: &OpsgenieNotification{Description: "app is down", Alias: "my-app"}}, dest)
+		if !assert.NoError(t, err) {
+			return
+		}
+		if assert.Len(t, alertClient.created, 1) {
+			assert.Equal(t, "my-app", alertClient.created[0].Alias)
+		}
+		assert.Empty(t, alertClient.closed)
+		assert.Empty(t, alertClient.acknowledged)
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		service, alertClient := newFakeOpsgenieService()
+		err := service.Send(Notification{Opsgenie: &OpsgenieNotification{Action: OpsgenieActionClose, Alias: "my-app", Note: "recovered"}}, dest)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, []*alert.CloseAlertRequest{{
+			IdentifierType:  alert.ALIAS,
+			IdentifierValue: "my-app",
+			Note:            "recovered",
+			Source:          "Argo CD",
+		}}, alertClient.closed)
+		assert.Empty(t, alertClient.created)
+	})
+
+	t.Run("Acknowledge", func(t *testing.T) {
+		service, alertClient := newFakeOpsgenieService()
+		err := service.Send(Notification{Opsgenie: &OpsgenieNotification{Action: OpsgenieActionAcknowledge, Alias: "my-app", User: "argocd"}}, dest)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, []*alert.AcknowledgeAlertRequest{{
+			IdentifierType:  alert.ALIAS,
+			IdentifierValue: "my-app",
+			User:            "argocd",
+			Source:          "Argo CD",
+		}}, alertClient.acknowledged)
+		assert.Empty(t, alertClient.created)
+	})
+
+	t.Run("CloseWithoutAlias", func(t *testing.T) {
+		service, alertClient := newFakeOpsgenieService()
+		err := service.Send(Notification{Opsgenie: &OpsgenieNotification{Action: OpsgenieActionClose}}, dest)
+		assert.EqualError(t, err, "opsgenie notification alias is required to close an alert")
+		assert.Empty(t, alertClient.closed)
+	})
+
+	t.Run("UnsupportedAction", func(t *testing.T) {
+		service, _ := newFakeOpsgenieService()
+		err := service.Send(Notification{Opsgenie: &OpsgenieNotification{Action: "snooze", Alias: "my-app"}}, dest)
+		assert.EqualError(t, err, "unsupported opsgenie action 'snooze'")
+	})
+}
+
+func TestOpsgenieNotification_GetTemplater_Action(t *testing.T) {
+	n := OpsgenieNotification{Action: OpsgenieActionClose, Alias: "{{.app}}"}
+	templater, err := n.GetTemplater("", texttemplate.FuncMap{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	var notification Notification
+	err = templater(&notification, map[string]interface{}{"app": "my-app"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, OpsgenieActionClose, notification.Opsgenie.Action)
+	assert.Equal(t, "my-app", notification.Opsgenie.Alias)
+}
+
+func TestOpsgenie_SendNotification_Responders(t *testing.T) {
+	dest := Destination{Service: "opsgenie", Recipient: "my-team"}
+	n := Notification{Message: "down", Opsgenie: &OpsgenieNotification{
+		Description: "app is down",
+		Responders: []alert.Responder{
+			{Type: "team", Id: "{{.recipient}}"},
+			{Type: "{{.escalationType}}", Name: "{{.app}}-escalation"},
+			{Type: "user", Username: "{{.owner}}"},
+		},
+	}}
+	templater, err := n.GetTemplater("", texttemplate.FuncMap{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	var notification Notification
+	err = templater(&notification, map[string]interface{}{"recipient": "my-team", "escalationType": "escalation", "app": "guestbook", "owner": "jane@example.com"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	service, alertClient := newFakeOpsgenieService()
+	if !assert.NoError(t, service.Send(notification, dest)) {
+		return
+	}
+	if assert.Len(t, alertClient.created, 1) {
+		assert.Equal(t, []alert.Responder{
+			{Type: "team", Id: "my-team"},
+			{Type: "escalation", Name: "guestbook-escalation"},
+			{Type: "user", Username: "jane@example.com"},
+		}, alertClient.created[0].Responders)
+	}
+
+	// the team of the recipient is the responder by default
+	service, alertClient = newFakeOpsgenieService()
+	if assert.NoError(t, service.Send(Notification{Message: "down", Opsgenie: &OpsgenieNotification{Description: "app is down"}}, dest)) && assert.Len(t, alertClient.created, 1) {
+		assert.Equal(t, []alert.Responder{{Type: "team", Id: "my-team"}}, alertClient.created[0].Responders)
+	}
+}
+
+func TestOpsgenie_SendNotification_InvalidResponders(t *testing.T) {
+	dest := Destination{Service: "opsgenie", Recipient: "my-team"}
+
+	service, alertClient := newFakeOpsgenieService()
+	err := service.Send(Notification{Opsgenie: &OpsgenieNotification{Description: "app is down", Responders: []alert.Responder{{Type: "group", Id: "ops"}}}}, dest)
+	assert.EqualError(t, err, "opsgenie responder type 'group' is not valid, must be one of: team, user, escalation, schedule")
+
+	err = service.Send(Notification{Opsgenie: &OpsgenieNotification{Description: "app is down", Responders: []alert.Responder{{Type: "team", Id: "ops"}, {Type: "schedule"}}}}, dest)
+	assert.EqualError(t, err, "opsgenie responder 1 requires an id, name or username")
+	assert.Empty(t, alertClient.created)
+}
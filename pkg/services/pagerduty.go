@@ -81,6 +81,10 @@ type pagerdutyService struct {
 }
 
 func (p pagerdutyService) Send(notification Notification, dest Destination) error {
+	return p.SendContext(context.Background(), notification, dest)
+}
+
+func (p pagerdutyService) SendContext(ctx context.Context, notification Notification, dest Destination) error {
 	title := notification.Pagerduty.Title
 	body := notification.Pagerduty.Body
 	urgency := notification.Pagerduty.Urgency
@@ -95,7 +99,7 @@ func (p pagerdutyService) Send(notification Notification, dest Destination) erro
 		Urgency:  urgency,
 		Body:     &pagerduty.APIDetails{Type: "incident_details	", Details: body},
 	}
-	incident, err := pagerDutyClient.CreateIncidentWithContext(context.TODO(), p.opts.From, input)
+	incident, err := pagerDutyClient.CreateIncidentWithContext(ctx, p.opts.From, input)
 	if err != nil {
 		log.Errorf("Error: %v", err)
 		return err
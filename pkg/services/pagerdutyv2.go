@@ -4,20 +4,33 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"strings"
 	texttemplate "text/template"
 
 	"github.com/PagerDuty/go-pagerduty"
 	log "github.com/sirupsen/logrus"
+
+	"github.com/argoproj/notifications-engine/pkg/util/text"
+)
+
+const (
+	pagerDutyV2ActionTrigger = "trigger"
+	pagerDutyV2ActionResolve = "resolve"
 )
 
+var pagerDutyV2Severities = []string{"critical", "error", "warning", "info"}
+
 type PagerDutyV2Notification struct {
-	Summary   string `json:"summary"`
-	Severity  string `json:"severity"`
-	Source    string `json:"source"`
-	Component string `json:"component,omitempty"`
-	Group     string `json:"group,omitempty"`
-	Class     string `json:"class,omitempty"`
-	URL       string `json:"url"`
+	Summary       string            `json:"summary"`
+	Severity      string            `json:"severity"`
+	Source        string            `json:"source"`
+	Component     string            `json:"component,omitempty"`
+	Group         string            `json:"group,omitempty"`
+	Class         string            `json:"class,omitempty"`
+	URL           string            `json:"url"`
+	DedupKey      string            `json:"dedupKey,omitempty"`
+	EventAction   string            `json:"eventAction,omitempty"`
+	CustomDetails map[string]string `json:"customDetails,omitempty"`
 }
 
 type PagerdutyV2Options struct {
@@ -53,6 +66,23 @@ func (p *PagerDutyV2Notification) GetTemplater(name string, f texttemplate.FuncM
 	if err != nil {
 		return nil, err
 	}
+	dedupKey, err := texttemplate.New(name).Funcs(f).Parse(p.DedupKey)
+	if err != nil {
+		return nil, err
+	}
+	eventAction, err := texttemplate.New(name).Funcs(f).Parse(p.EventAction)
+	if err != nil {
+		return nil, err
+	}
+
+	customDetails := make(map[string]*texttemplate.Template)
+	for key, value := range p.CustomDetails {
+		detailTemplate, err := texttemplate.New(fmt.Sprintf("%s_custom_detail_%s", name, key)).Funcs(f).Parse(value)
+		if err != nil {
+			return nil, err
+		}
+		customDetails[key] = detailTemplate
+	}
 
 	return func(notification *Notification, vars map[string]interface{}) error {
 		if notification.PagerdutyV2 == nil {
@@ -100,6 +130,29 @@ func (p *PagerDutyV2Notification) GetTemplater(name string, f texttemplate.FuncM
 		}
 		notification.PagerdutyV2.URL = urlData.String()
 
+		var dedupKeyData bytes.Buffer
+		if err := dedupKey.Execute(&dedupKeyData, vars); err != nil {
+			return err
+		}
+		notification.PagerdutyV2.DedupKey = dedupKeyData.String()
+
+		var eventActionData bytes.Buffer
+		if err := eventAction.Execute(&eventActionData, vars); err != nil {
+			return err
+		}
+		notification.PagerdutyV2.EventAction = eventActionData.String()
+
+		if p.CustomDetails != nil {
+			notification.PagerdutyV2.CustomDetails = make(map[string]string, len(p.CustomDetails))
+			for key, template := range customDetails {
+				var valueData bytes.Buffer
+				if err := template.Execute(&valueData, vars); err != nil {
+					return err
+				}
+				notification.PagerdutyV2.CustomDetails[key] = valueData.String()
+			}
+		}
+
 		return nil
 	}, nil
 }
@@ -113,6 +166,10 @@ type pagerdutyV2Service struct {
 }
 
 func (p pagerdutyV2Service) Send(notification Notification, dest Destination) error {
+	return p.SendContext(context.Background(), notification, dest)
+}
+
+func (p pagerdutyV2Service) SendContext(ctx context.Context, notification Notification, dest Destination) error {
 	routingKey, ok := p.opts.ServiceKeys[dest.Recipient]
 	if !ok {
 		return fmt.Errorf("no API key configured for recipient %s", dest.Recipient)
@@ -122,18 +179,50 @@ func (p pagerdutyV2Service) Send(notification Notification, dest Destination) er
 		return fmt.Errorf("no config found for pagerdutyv2")
 	}
 
+	if err := validatePagerDutyV2Notification(notification.PagerdutyV2); err != nil {
+		return err
+	}
+
 	event := buildEvent(routingKey, notification)
 
-	response, err := pagerduty.ManageEventWithContext(context.TODO(), event)
+	response, err := pagerduty.ManageEventWithContext(ctx, event)
 	if err != nil {
 		log.Errorf("Error: %v", err)
 		return err
 	}
-	log.Debugf("PagerDuty event triggered successfully. Status: %v, Message: %v", response.Status, response.Message)
+	log.Debugf("PagerDuty event sent successfully. Status: %v, Message: %v", response.Status, response.Message)
 	return nil
 }
 
+func validatePagerDutyV2Notification(n *PagerDutyV2Notification) error {
+	switch n.EventAction {
+	case "", pagerDutyV2ActionTrigger:
+		for _, severity := range pagerDutyV2Severities {
+			if n.Severity == severity {
+				return nil
+			}
+		}
+		return fmt.Errorf("pagerdutyv2 severity '%s' is not valid, must be one of: %s", n.Severity, strings.Join(pagerDutyV2Severities, ", "))
+	case pagerDutyV2ActionResolve:
+		if n.DedupKey == "" {
+			return fmt.Errorf("pagerdutyv2 dedupKey is required to resolve an event")
+		}
+		return nil
+	default:
+		return fmt.Errorf("pagerdutyv2 eventAction '%s' is not valid, must be one of: %s, %s", n.EventAction, pagerDutyV2ActionTrigger, pagerDutyV2ActionResolve)
+	}
+}
+
 func buildEvent(routingKey string, notification Notification) pagerduty.V2Event {
+	if notification.PagerdutyV2.EventAction == pagerDutyV2ActionResolve {
+		return pagerduty.V2Event{
+			RoutingKey: routingKey,
+			Action:     pagerDutyV2ActionResolve,
+			DedupKey:   notification.PagerdutyV2.DedupKey,
+			Client:     "ArgoCD",
+		}
+	}
+
 	payload := pagerduty.V2Payload{
 		Summary:  notification.PagerdutyV2.Summary,
 		Severity: notification.PagerdutyV2.Severity,
@@ -149,10 +238,14 @@ func buildEvent(routingKey string, notification Notification) pagerduty.V2Event
 	if len(notification.PagerdutyV2.Class) > 0 {
 		payload.Class = notification.PagerdutyV2.Class
 	}
+	if len(notification.PagerdutyV2.CustomDetails) > 0 {
+		payload.Details = notification.PagerdutyV2.CustomDetails
+	}
 
 	event := pagerduty.V2Event{
 		RoutingKey: routingKey,
-		Action:     "trigger",
+		Action:     text.Coalesce(notification.PagerdutyV2.EventAction, pagerDutyV2ActionTrigger),
+		DedupKey:   text.Coalesce(notification.PagerdutyV2.DedupKey, notification.IdempotencyKey),
 		Payload:    &payload,
 		Client:     "ArgoCD",
 	}
@@ -163,3 +256,8 @@ func buildEvent(routingKey string, notification Notification) pagerduty.V2Event
 
 	return event
 }
+
+// Recipients returns the recipients which have a service key configured
+func (p pagerdutyV2Service) Recipients() []string {
+	return sortedRecipients(p.opts.ServiceKeys)
+}
@@ -202,6 +202,59 @@ func TestGetTemplater_PagerDutyV2(t *testing.T) {
 		assert.Equal(t, "", notification.PagerdutyV2.Group)
 		assert.Equal(t, "", notification.PagerdutyV2.Class)
 	})
+
+	t.Run("dedup key, event action and custom details", func(t *testing.T) {
+		n := Notification{
+			PagerdutyV2: &PagerDutyV2Notification{
+				Summary:     "{{.summary}}",
+				Severity:    "{{.severity}}",
+				Source:      "{{.source}}",
+				DedupKey:    "{{.app}}-degraded",
+				EventAction: "{{.action}}",
+				CustomDetails: map[string]string{
+					"revision": "{{.revision}}",
+				},
+			},
+		}
+
+		templater, err := n.GetTemplater("", template.FuncMap{})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		var notification Notification
+
+		err = templater(&notification, map[string]interface{}{
+			"summary":  "hello",
+			"severity": "critical",
+			"source":   "my-app",
+			"app":      "my-app",
+			"action":   "resolve",
+			"revision": "abc123",
+		})
+
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, "my-app-degraded", notification.PagerdutyV2.DedupKey)
+		assert.Equal(t, "resolve", notification.PagerdutyV2.EventAction)
+		assert.Equal(t, map[string]string{"revision": "abc123"}, notification.PagerdutyV2.CustomDetails)
+	})
+
+	t.Run("handle error for custom details", func(t *testing.T) {
+		n := Notification{
+			PagerdutyV2: &PagerDutyV2Notification{
+				Summary:       "{{.summary}}",
+				Severity:      "{{.severity}}",
+				Source:        "{{.source}}",
+				CustomDetails: map[string]string{"revision": "{{.revision}"},
+			},
+		}
+
+		_, err := n.GetTemplater("", template.FuncMap{})
+		assert.Error(t, err)
+	})
 }
 
 func TestSend_PagerDuty(t *testing.T) {
@@ -236,6 +289,95 @@ func TestSend_PagerDuty(t *testing.T) {
 		assert.Equal(t, group, event.Payload.Group)
 		assert.Equal(t, class, event.Payload.Class)
 		assert.Equal(t, url, event.ClientURL)
+		assert.Equal(t, "trigger", event.Action)
+	})
+
+	t.Run("builds trigger event with dedup key and custom details", func(t *testing.T) {
+		event := buildEvent("routing-key", Notification{
+			PagerdutyV2: &PagerDutyV2Notification{
+				Summary:       "test-app failed to deploy",
+				Severity:      "error",
+				Source:        "test-app",
+				DedupKey:      "test-app-deploy",
+				CustomDetails: map[string]string{"revision": "abc123"},
+			},
+		})
+
+		assert.Equal(t, "trigger", event.Action)
+		assert.Equal(t, "test-app-deploy", event.DedupKey)
+		assert.Equal(t, map[string]string{"revision": "abc123"}, event.Payload.Details)
+	})
+
+	t.Run("builds trigger event with idempotency key as dedup key", func(t *testing.T) {
+		event := buildEvent("routing-key", Notification{
+			PagerdutyV2: &PagerDutyV2Notification{
+				Summary:  "test-app failed to deploy",
+				Severity: "error",
+				Source:   "test-app",
+			},
+			IdempotencyKey: "def456",
+		})
+
+		assert.Equal(t, "def456", event.DedupKey)
+	})
+
+	t.Run("builds resolve event", func(t *testing.T) {
+		event := buildEvent("routing-key", Notification{
+			PagerdutyV2: &PagerDutyV2Notification{
+				Summary:     "test-app failed to deploy",
+				Severity:    "error",
+				Source:      "test-app",
+				DedupKey:    "test-app-deploy",
+				EventAction: "resolve",
+			},
+		})
+
+		assert.Equal(t, "routing-key", event.RoutingKey)
+		assert.Equal(t, "resolve", event.Action)
+		assert.Equal(t, "test-app-deploy", event.DedupKey)
+		assert.Nil(t, event.Payload)
+	})
+
+	t.Run("invalid severity", func(t *testing.T) {
+		service := NewPagerdutyV2Service(PagerdutyV2Options{
+			ServiceKeys: map[string]string{
+				"test-service": "key",
+			},
+		})
+		err := service.Send(Notification{
+			PagerdutyV2: &PagerDutyV2Notification{
+				Summary:  "test-app failed to deploy",
+				Severity: "urgent",
+				Source:   "test-app",
+			},
+		}, Destination{
+			Service:   "pagerdutyv2",
+			Recipient: "test-service",
+		})
+
+		if assert.Error(t, err) {
+			assert.Equal(t, errors.New("pagerdutyv2 severity 'urgent' is not valid, must be one of: critical, error, warning, info"), err)
+		}
+	})
+
+	t.Run("resolve without dedup key", func(t *testing.T) {
+		service := NewPagerdutyV2Service(PagerdutyV2Options{
+			ServiceKeys: map[string]string{
+				"test-service": "key",
+			},
+		})
+		err := service.Send(Notification{
+			PagerdutyV2: &PagerDutyV2Notification{
+				EventAction: "resolve",
+			},
+		}, Destination{
+			Service:   "pagerdutyv2",
+			Recipient: "test-service",
+		})
+
+		if assert.Error(t, err) {
+			assert.Equal(t, errors.New("pagerdutyv2 dedupKey is required to resolve an event"), err)
+		}
 	})
 
 	t.Run("missing config", func(t *testing.T) {
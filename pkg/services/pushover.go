@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"github.com/gregdel/pushover"
 )
 
@@ -17,6 +18,10 @@ func NewPushoverService(opts PushoverOptions) NotificationService {
 }
 
 func (s *pushoverService) Send(notification Notification, dest Destination) error {
+	return s.SendContext(context.Background(), notification, dest)
+}
+
+func (s *pushoverService) SendContext(_ context.Context, notification Notification, dest Destination) error {
 	app := pushover.New(s.opts.Token)
 
 	recipient := pushover.NewRecipient(dest.Recipient)
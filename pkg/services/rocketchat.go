@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -58,6 +59,10 @@ func NewRocketChatService(opts RocketChatOptions) NotificationService {
 }
 
 func (r *rocketChatService) Send(notification Notification, dest Destination) error {
+	return r.SendContext(context.Background(), notification, dest)
+}
+
+func (r *rocketChatService) SendContext(_ context.Context, notification Notification, dest Destination) error {
 	serverUrl, err := url.Parse(r.opts.ServerUrl)
 	if err != nil {
 		return err
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	envRefPrefix  = "env://"
+	fileRefPrefix = "file://"
+)
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SecretRefs allows the credentials of services to reference an environment variable of the controller or a file,
+// e.g. a mounted Secret, instead of embedding the secret. The environment and the files of the controller hold its own
+// secrets, so references must only be resolved in configurations managed by the operator of the controller.
+type SecretRefs struct {
+	// FilesDir is the directory which holds the files that can be referenced. File references are rejected if empty.
+	FilesDir string
+}
+
+// resolve resolves an option value which references a secret. An "env://VAR" value is replaced with the value of the
+// environment variable and a "file:///path" value with the content of the file, without trailing line breaks. Other
+// values are returned unchanged, as are all values if references are not enabled.
+func (r *SecretRefs) resolve(value string) (string, error) {
+	if r == nil {
+		return value, nil
+	}
+	if strings.HasPrefix(value, envRefPrefix) {
+		name := strings.TrimPrefix(value, envRefPrefix)
+		if !envNamePattern.MatchString(name) {
+			return "", fmt.Errorf("invalid environment variable name '%s'", name)
+		}
+		resolved, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable '%s' is not set", name)
+		}
+		return resolved, nil
+	}
+	if strings.HasPrefix(value, fileRefPrefix) {
+		path, err := r.filePath(strings.TrimPrefix(value, fileRefPrefix))
+		if err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read file '%s': %v", path, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	return value, nil
+}
+
+// filePath returns the path of the referenced file with symbolic links evaluated, or an error if the file is not
+// located in FilesDir
+func (r *SecretRefs) filePath(path string) (string, error) {
+	if r.FilesDir == "" {
+		return "", fmt.Errorf("file references are not enabled")
+	}
+	dir, err := filepath.EvalSymlinks(r.FilesDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the secret files directory '%s': %v", r.FilesDir, err)
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file '%s': %v", path, err)
+	}
+	resolved, err = filepath.Abs(resolved)
+	if err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(dir, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("file '%s' is not located in the secret files directory '%s'", path, r.FilesDir)
+	}
+	return resolved, nil
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretRefs_Resolve(t *testing.T) {
+	t.Setenv("NOTIFICATIONS_TEST_TOKEN", "xoxb-token")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token")
+	assert.NoError(t, os.WriteFile(path, []byte("file-token\n"), 0600))
+	outside := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(outside, []byte("controller-token\n"), 0600))
+	refs := &SecretRefs{FilesDir: dir}
+
+	t.Run("Literal", func(t *testing.T) {
+		value, err := refs.resolve("xoxb-literal")
+		assert.NoError(t, err)
+		assert.Equal(t, "xoxb-literal", value)
+	})
+	t.Run("Env", func(t *testing.T) {
+		value, err := refs.resolve("env://NOTIFICATIONS_TEST_TOKEN")
+		assert.NoError(t, err)
+		assert.Equal(t, "xoxb-token", value)
+	})
+	t.Run("File", func(t *testing.T) {
+		value, err := refs.resolve("file://" + path)
+		assert.NoError(t, err)
+		assert.Equal(t, "file-token", value)
+	})
+	t.Run("MissingEnv", func(t *testing.T) {
+		_, err := refs.resolve("env://NOTIFICATIONS_TEST_MISSING")
+		assert.EqualError(t, err, "environment variable 'NOTIFICATIONS_TEST_MISSING' is not set")
+	})
+	t.Run("MissingFile", func(t *testing.T) {
+		_, err := refs.resolve("file://" + path + "-missing")
+		assert.ErrorContains(t, err, "failed to read file '"+path+"-missing'")
+	})
+	t.Run("FileOutsideDir", func(t *testing.T) {
+		_, err := refs.resolve("file://" + outside)
+		assert.EqualError(t, err, "file '"+outside+"' is not located in the secret files directory '"+dir+"'")
+		_, err = refs.resolve("file://" + dir + "/../" + filepath.Base(filepath.Dir(outside)) + "/token")
+		assert.ErrorContains(t, err, "is not located in the secret files directory")
+	})
+	t.Run("SymlinkOutsideDir", func(t *testing.T) {
+		link := filepath.Join(dir, "link")
+		assert.NoError(t, os.Symlink(outside, link))
+		_, err := refs.resolve("file://" + link)
+		assert.EqualError(t, err, "file '"+link+"' is not located in the secret files directory '"+dir+"'")
+	})
+	t.Run("FilesNotEnabled", func(t *testing.T) {
+		_, err := (&SecretRefs{}).resolve("file://" + path)
+		assert.EqualError(t, err, "file references are not enabled")
+	})
+	t.Run("NotEnabled", func(t *testing.T) {
+		var refs *SecretRefs
+		value, err := refs.resolve("env://NOTIFICATIONS_TEST_TOKEN")
+		assert.NoError(t, err)
+		assert.Equal(t, "env://NOTIFICATIONS_TEST_TOKEN", value)
+	})
+}
+
+func TestNewServiceWithSecretRefs(t *testing.T) {
+	t.Setenv("NOTIFICATIONS_TEST_TOKEN", "xoxb-token")
+	optsData := []byte(`token: env://NOTIFICATIONS_TEST_TOKEN
+recipientTokens:
+  other-workspace: env://NOTIFICATIONS_TEST_TOKEN`)
+
+	service, err := NewServiceWithSecretRefs("slack", optsData, &SecretRefs{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "xoxb-token", service.(*slackService).opts.Token)
+		assert.Equal(t, map[string]string{"other-workspace": "xoxb-token"}, service.(*slackService).opts.RecipientTokens)
+	}
+
+	service, err = NewService("slack", optsData)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "env://NOTIFICATIONS_TEST_TOKEN", service.(*slackService).opts.Token)
+	}
+
+	_, err = NewServiceWithSecretRefs("slack", []byte(`token: env://NOTIFICATIONS_TEST_MISSING`), &SecretRefs{})
+	assert.EqualError(t, err, "failed to resolve slack token: environment variable 'NOTIFICATIONS_TEST_MISSING' is not set")
+
+	_, err = NewServiceWithSecretRefs("github", []byte(`appID: 1
+installationID: 1
+privateKey: file:///notifications-test/missing`), &SecretRefs{FilesDir: "/notifications-test"})
+	assert.ErrorContains(t, err, "failed to resolve GitHub privateKey: failed to resolve the secret files directory '/notifications-test'")
+}
@@ -0,0 +1,25 @@
+package services
+
+import "context"
+
+// ServiceState holds values of notification services which are persisted with the notified state of the resource, so
+// that they survive controller restarts, e.g. the timestamp of a message which is updated by later notifications
+type ServiceState interface {
+	// Get returns the value of the key and true, or false if the key has no value
+	Get(key string) (string, bool)
+	// Set sets the value of the key
+	Set(key string, value string)
+}
+
+type serviceStateKey struct{}
+
+// WithServiceState returns a copy of the context which carries the state to the notification services
+func WithServiceState(ctx context.Context, state ServiceState) context.Context {
+	return context.WithValue(ctx, serviceStateKey{}, state)
+}
+
+// ServiceStateFrom returns the state carried by the context, or nil if the delivery has no persisted state
+func ServiceStateFrom(ctx context.Context) ServiceState {
+	state, _ := ctx.Value(serviceStateKey{}).(ServiceState)
+	return state
+}
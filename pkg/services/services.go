@@ -2,10 +2,13 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	texttemplate "text/template"
+	"time"
 	_ "time/tzdata"
 
 	"sigs.k8s.io/yaml"
@@ -14,6 +17,8 @@ import (
 type Notification struct {
 	Message      string                    `json:"message,omitempty"`
 	AwsSqs       *AwsSqsNotification       `json:"awssqs,omitempty"`
+	AwsSns       *AwsSnsNotification       `json:"awssns,omitempty"`
+	EventBridge  *EventBridgeNotification  `json:"eventbridge,omitempty"`
 	Email        *EmailNotification        `json:"email,omitempty"`
 	Slack        *SlackNotification        `json:"slack,omitempty"`
 	Mattermost   *MattermostNotification   `json:"mattermost,omitempty"`
@@ -27,6 +32,16 @@ type Notification struct {
 	Pagerduty    *PagerDutyNotification    `json:"pagerduty,omitempty"`
 	PagerdutyV2  *PagerDutyV2Notification  `json:"pagerdutyv2,omitempty"`
 	Newrelic     *NewrelicNotification     `json:"newrelic,omitempty"`
+	Telegram     *TelegramNotification     `json:"telegram,omitempty"`
+	Discord      *DiscordNotification      `json:"discord,omitempty"`
+	Kafka        *KafkaNotification        `json:"kafka,omitempty"`
+	Nats         *NatsNotification         `json:"nats,omitempty"`
+	Splunk       *SplunkNotification       `json:"splunk,omitempty"`
+	Grafana      *GrafanaNotification      `json:"grafana,omitempty"`
+	Incident     *IncidentNotification     `json:"incident,omitempty"`
+	// IdempotencyKey identifies the delivery, see DeliveryIdempotencyKey. Services supporting deduplication use it
+	// unless the template configures a deduplication key. Not configurable in templates.
+	IdempotencyKey string `json:"-"`
 }
 
 // Destinations holds notification destinations group by trigger
@@ -57,6 +72,14 @@ func (s Destinations) Dedup() Destinations {
 type Destination struct {
 	Service   string `json:"service"`
 	Recipient string `json:"recipient"`
+	// Template overrides the templates of the trigger, e.g. to render the notifications of a channel differently
+	Template string `json:"template,omitempty"`
+}
+
+// String formats the service and the recipient of the destination, so that log and error messages do not depend on
+// whether the subscription overrides the template
+func (d Destination) String() string {
+	return fmt.Sprintf("{%s %s}", d.Service, d.Recipient)
 }
 
 func (n *Notification) GetTemplater(name string, f texttemplate.FuncMap) (Templater, error) {
@@ -64,6 +87,12 @@ func (n *Notification) GetTemplater(name string, f texttemplate.FuncMap) (Templa
 	if n.AwsSqs != nil {
 		sources = append(sources, n.AwsSqs)
 	}
+	if n.AwsSns != nil {
+		sources = append(sources, n.AwsSns)
+	}
+	if n.EventBridge != nil {
+		sources = append(sources, n.EventBridge)
+	}
 	if n.Slack != nil {
 		sources = append(sources, n.Slack)
 	}
@@ -103,6 +132,27 @@ func (n *Notification) GetTemplater(name string, f texttemplate.FuncMap) (Templa
 	if n.Newrelic != nil {
 		sources = append(sources, n.Newrelic)
 	}
+	if n.Telegram != nil {
+		sources = append(sources, n.Telegram)
+	}
+	if n.Discord != nil {
+		sources = append(sources, n.Discord)
+	}
+	if n.Kafka != nil {
+		sources = append(sources, n.Kafka)
+	}
+	if n.Nats != nil {
+		sources = append(sources, n.Nats)
+	}
+	if n.Splunk != nil {
+		sources = append(sources, n.Splunk)
+	}
+	if n.Grafana != nil {
+		sources = append(sources, n.Grafana)
+	}
+	if n.Incident != nil {
+		sources = append(sources, n.Incident)
+	}
 	return n.getTemplater(name, f, sources)
 }
 
@@ -110,10 +160,57 @@ func (n *Notification) GetTemplater(name string, f texttemplate.FuncMap) (Templa
 
 // NotificationService defines notification service interface
 type NotificationService interface {
+	// Send delivers the notification to the destination, it is equivalent to SendContext with a background context
 	Send(notification Notification, dest Destination) error
+	// SendContext delivers the notification to the destination and aborts the delivery once the context is done
+	SendContext(ctx context.Context, notification Notification, dest Destination) error
+}
+
+// Validatable is implemented by notification services which are able to check their configuration, e.g. that the
+// credentials are valid, without sending a notification
+type Validatable interface {
+	Validate(ctx context.Context) error
+}
+
+// Closable is implemented by notification services which keep connections open between notifications, e.g. to a
+// message broker, so that the connections are released once the service is replaced by a new configuration
+type Closable interface {
+	Close() error
+}
+
+// RecipientLister is implemented by notification services whose recipients are configured statically, e.g. the
+// channels of Slack, so that the recipients can be offered when building subscriptions
+type RecipientLister interface {
+	// Recipients returns the configured recipients in ascending order
+	Recipients() []string
+}
+
+// sortedRecipients returns the keys of the recipient map and the additional recipients in ascending order, without
+// duplicates
+func sortedRecipients[V any](recipients map[string]V, additional ...string) []string {
+	seen := map[string]bool{}
+	res := make([]string, 0, len(recipients)+len(additional))
+	for recipient := range recipients {
+		seen[recipient] = true
+		res = append(res, recipient)
+	}
+	for _, recipient := range additional {
+		if !seen[recipient] {
+			seen[recipient] = true
+			res = append(res, recipient)
+		}
+	}
+	sort.Strings(res)
+	return res
 }
 
 func NewService(serviceType string, optsData []byte) (NotificationService, error) {
+	return NewServiceWithSecretRefs(serviceType, optsData, nil)
+}
+
+// NewServiceWithSecretRefs creates the service like NewService and resolves the credentials of the service which
+// reference an environment variable or a file, see SecretRefs. References are not resolved if refs is nil.
+func NewServiceWithSecretRefs(serviceType string, optsData []byte, refs *SecretRefs) (NotificationService, error) {
 	switch serviceType {
 	case "awssqs":
 		var opts AwsSqsOptions
@@ -121,6 +218,18 @@ func NewService(serviceType string, optsData []byte) (NotificationService, error
 			return nil, err
 		}
 		return NewAwsSqsService(opts), nil
+	case "awssns":
+		var opts AwsSnsOptions
+		if err := yaml.Unmarshal(optsData, &opts); err != nil {
+			return nil, err
+		}
+		return NewAwsSnsService(opts), nil
+	case "eventbridge":
+		var opts EventBridgeOptions
+		if err := yaml.Unmarshal(optsData, &opts); err != nil {
+			return nil, err
+		}
+		return NewEventBridgeService(opts), nil
 	case "email":
 		var opts EmailOptions
 		if err := yaml.Unmarshal(optsData, &opts); err != nil {
@@ -132,6 +241,12 @@ func NewService(serviceType string, optsData []byte) (NotificationService, error
 		if err := yaml.Unmarshal(optsData, &opts); err != nil {
 			return nil, err
 		}
+		if err := resolveSlackSecretRefs(&opts, refs); err != nil {
+			return nil, err
+		}
+		if _, err := parseTimeout("slack", opts.Timeout); err != nil {
+			return nil, err
+		}
 		return NewSlackService(opts), nil
 	case "mattermost":
 		var opts MattermostOptions
@@ -151,6 +266,15 @@ func NewService(serviceType string, optsData []byte) (NotificationService, error
 			return nil, err
 		}
 		return NewGrafanaService(opts), nil
+	case "incident":
+		var opts IncidentOptions
+		if err := yaml.Unmarshal(optsData, &opts); err != nil {
+			return nil, err
+		}
+		if _, err := parseTimeout("incident", opts.Timeout); err != nil {
+			return nil, err
+		}
+		return NewIncidentService(opts), nil
 	case "opsgenie":
 		var opts OpsgenieOptions
 		if err := yaml.Unmarshal(optsData, &opts); err != nil {
@@ -162,6 +286,9 @@ func NewService(serviceType string, optsData []byte) (NotificationService, error
 		if err := yaml.Unmarshal(optsData, &opts); err != nil {
 			return nil, err
 		}
+		if _, err := parseTimeout("webhook", opts.Timeout); err != nil {
+			return nil, err
+		}
 		return NewWebhookService(opts), nil
 	case "telegram":
 		var opts TelegramOptions
@@ -174,12 +301,20 @@ func NewService(serviceType string, optsData []byte) (NotificationService, error
 		if err := yaml.Unmarshal(optsData, &opts); err != nil {
 			return nil, err
 		}
+		privateKey, err := refs.resolve(opts.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve GitHub privateKey: %v", err)
+		}
+		opts.PrivateKey = privateKey
 		return NewGitHubService(opts)
 	case "teams":
 		var opts TeamsOptions
 		if err := yaml.Unmarshal(optsData, &opts); err != nil {
 			return nil, err
 		}
+		if _, err := parseTimeout("teams", opts.Timeout); err != nil {
+			return nil, err
+		}
 		return NewTeamsService(opts), nil
 	case "googlechat":
 		var opts GoogleChatOptions
@@ -223,11 +358,92 @@ func NewService(serviceType string, optsData []byte) (NotificationService, error
 			return nil, err
 		}
 		return NewWebexService(opts), nil
+	case "discord":
+		var opts DiscordOptions
+		if err := yaml.Unmarshal(optsData, &opts); err != nil {
+			return nil, err
+		}
+		return NewDiscordService(opts), nil
+	case "kafka":
+		var opts KafkaOptions
+		if err := yaml.Unmarshal(optsData, &opts); err != nil {
+			return nil, err
+		}
+		return NewKafkaService(opts)
+	case "nats":
+		var opts NatsOptions
+		if err := yaml.Unmarshal(optsData, &opts); err != nil {
+			return nil, err
+		}
+		return NewNatsService(opts)
+	case "splunk":
+		var opts SplunkOptions
+		if err := yaml.Unmarshal(optsData, &opts); err != nil {
+			return nil, err
+		}
+		return NewSplunkService(opts), nil
 	default:
 		return nil, fmt.Errorf("service type '%s' is not supported", serviceType)
 	}
 }
 
+// HasServiceFields returns true if the notification configures the fields of the given service type, e.g. the
+// slack field for the slack service
+func (n *Notification) HasServiceFields(serviceType string) bool {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return false
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return false
+	}
+	_, ok := fields[serviceType]
+	return ok && serviceType != "message"
+}
+
+// IsEmpty returns true if the notification has neither a message nor service specific content, e.g. because the
+// templates rendered only whitespace. Only the fields which hold the content of a message are considered, settings such
+// as the Slack delivery policy are not.
+func (n *Notification) IsEmpty() bool {
+	for _, content := range n.contents() {
+		if strings.TrimSpace(content) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// contents returns the fields of the notification which hold the content of the message
+func (n *Notification) contents() []string {
+	contents := []string{n.Message}
+	if n.Slack != nil {
+		contents = append(contents, n.Slack.Blocks, n.Slack.Attachments)
+	}
+	if n.Teams != nil {
+		contents = append(contents, n.Teams.Title, n.Teams.Text, n.Teams.Sections, n.Teams.Facts)
+	}
+	if n.Mattermost != nil {
+		contents = append(contents, n.Mattermost.Attachments)
+	}
+	if n.RocketChat != nil {
+		contents = append(contents, n.RocketChat.Attachments)
+	}
+	if n.Discord != nil {
+		contents = append(contents, n.Discord.Embeds)
+	}
+	if n.GoogleChat != nil {
+		contents = append(contents, n.GoogleChat.Cards, n.GoogleChat.CardsV2)
+	}
+	if n.Email != nil {
+		contents = append(contents, n.Email.Subject, n.Email.Body)
+	}
+	for _, webhook := range n.Webhook {
+		contents = append(contents, webhook.Body)
+	}
+	return contents
+}
+
 func (n *Notification) Preview() string {
 	preview := ""
 	switch {
@@ -283,6 +499,19 @@ func (n *Notification) getTemplater(name string, f texttemplate.FuncMap, sources
 	}, nil
 }
 
+// parseTimeout parses the timeout option of a service, e.g. "10s". Empty timeout returns zero, which selects the default
+// timeout of the service.
+func parseTimeout(serviceType string, timeout string) (time.Duration, error) {
+	if timeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(timeout)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s timeout: %v", serviceType, err)
+	}
+	return d, nil
+}
+
 type Templater func(notification *Notification, vars map[string]interface{}) error
 
 type TemplaterSource interface {
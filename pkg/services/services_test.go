@@ -1,10 +1,16 @@
 package services
 
 import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"text/template"
 
 	"github.com/stretchr/testify/assert"
+
+	slackutil "github.com/argoproj/notifications-engine/pkg/util/slack"
 )
 
 func TestGetTemplater(t *testing.T) {
@@ -27,3 +33,54 @@ func TestGetTemplater(t *testing.T) {
 
 	assert.Equal(t, "hello", notification.Message)
 }
+
+func TestNotification_IsEmpty(t *testing.T) {
+	assert.True(t, (&Notification{}).IsEmpty())
+	assert.True(t, (&Notification{Message: " \n"}).IsEmpty())
+	assert.True(t, (&Notification{Slack: &SlackNotification{}}).IsEmpty())
+	assert.True(t, (&Notification{Teams: &TeamsNotification{Title: " ", Facts: "\n"}}).IsEmpty())
+	assert.True(t, (&Notification{Slack: &SlackNotification{GroupingKey: "sync", DeliveryPolicy: slackutil.PostAndUpdate, NotifyBroadcast: true}}).IsEmpty())
+	assert.True(t, (&Notification{Webhook: WebhookNotifications{"github": {Method: "POST", Path: "/status"}}}).IsEmpty())
+
+	assert.False(t, (&Notification{Message: "hello"}).IsEmpty())
+	assert.False(t, (&Notification{Slack: &SlackNotification{Blocks: `[{"type": "divider"}]`}}).IsEmpty())
+	assert.False(t, (&Notification{Teams: &TeamsNotification{Title: "Deployed"}}).IsEmpty())
+	assert.False(t, (&Notification{Webhook: WebhookNotifications{"github": {Method: "POST", Body: `{"state": "success"}`}}}).IsEmpty())
+}
+
+func TestDestination_String(t *testing.T) {
+	assert.Equal(t, "{slack my-channel}", fmt.Sprintf("%v", Destination{Service: "slack", Recipient: "my-channel", Template: "compact"}))
+}
+
+func TestNewService_Timeout(t *testing.T) {
+	for _, serviceType := range []string{"slack", "teams", "webhook", "incident"} {
+		t.Run(serviceType, func(t *testing.T) {
+			_, err := NewService(serviceType, []byte(`timeout: 10s`))
+			assert.NoError(t, err)
+
+			_, err = NewService(serviceType, []byte(`timeout: soon`))
+			assert.EqualError(t, err, fmt.Sprintf(`failed to parse %s timeout: time: invalid duration "soon"`, serviceType))
+		})
+	}
+
+	t.Run("Applied", func(t *testing.T) {
+		unblock := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-unblock
+		}))
+		defer server.Close()
+		defer close(unblock)
+
+		service, err := NewService("teams", []byte(fmt.Sprintf(`timeout: 10ms
+recipientUrls:
+  channel: %s`, server.URL)))
+		if !assert.NoError(t, err) {
+			return
+		}
+		err = service.Send(Notification{Message: "hello"}, Destination{Service: "teams", Recipient: "channel"})
+		var netErr net.Error
+		if assert.ErrorAs(t, err, &netErr) {
+			assert.True(t, netErr.Timeout())
+		}
+	})
+}
@@ -3,11 +3,14 @@ package services
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
+	"strings"
 	texttemplate "text/template"
 
 	httputil "github.com/argoproj/notifications-engine/pkg/util/http"
@@ -29,6 +32,33 @@ type SlackNotification struct {
 	GroupingKey     string                   `json:"groupingKey"`
 	NotifyBroadcast bool                     `json:"notifyBroadcast"`
 	DeliveryPolicy  slackutil.DeliveryPolicy `json:"deliveryPolicy"`
+	// BroadcastPolicy controls which replies in the thread are broadcast to the channel: Never, First or Always.
+	// It takes precedence over NotifyBroadcast, which is equivalent to Always.
+	BroadcastPolicy *slackutil.BroadcastPolicy `json:"broadcastPolicy,omitempty"`
+	// UnfurlLinks and UnfurlMedia override the disableUnfurl service option for the message
+	UnfurlLinks *bool `json:"unfurlLinks,omitempty"`
+	UnfurlMedia *bool `json:"unfurlMedia,omitempty"`
+	// FallbackText overrides the message text. Slack displays the text in notifications and to screen readers when
+	// the message consists of blocks or attachments.
+	FallbackText string `json:"fallbackText,omitempty"`
+	// Metadata is the JSON encoded message metadata with the event_type and event_payload fields
+	Metadata string `json:"metadata,omitempty"`
+	// Files are uploaded to the channel after the message is sent
+	Files []SlackFile `json:"files,omitempty"`
+	// Ephemeral posts the message to the channel visible only to the user, which is either a Slack user ID or the
+	// email address of the user. Ephemeral messages can't be grouped or updated.
+	Ephemeral bool   `json:"ephemeral,omitempty"`
+	User      string `json:"user,omitempty"`
+	// PersistentKey identifies a message which is posted once and updated by the later notifications with the same
+	// key, e.g. the status of an application. The message is kept in the notified state of the resource, so it is
+	// updated across controller restarts.
+	PersistentKey string `json:"persistentKey,omitempty"`
+}
+
+type SlackFile struct {
+	Filename string `json:"filename"`
+	Content  string `json:"content"`
+	Title    string `json:"title,omitempty"`
 }
 
 func (n *SlackNotification) GetTemplater(name string, f texttemplate.FuncMap) (Templater, error) {
@@ -54,6 +84,48 @@ func (n *SlackNotification) GetTemplater(name string, f texttemplate.FuncMap) (T
 	if err != nil {
 		return nil, err
 	}
+	slackFallbackText, err := texttemplate.New(name).Funcs(f).Parse(n.FallbackText)
+	if err != nil {
+		return nil, err
+	}
+	slackMetadata, err := texttemplate.New(name).Funcs(f).Parse(n.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	slackUser, err := texttemplate.New(name).Funcs(f).Parse(n.User)
+	if err != nil {
+		return nil, err
+	}
+	persistentKey, err := texttemplate.New(name).Funcs(f).Parse(n.PersistentKey)
+	if err != nil {
+		return nil, err
+	}
+	// payloads without template actions are validated right away, the others once they are rendered
+	if !strings.Contains(n.Blocks, "{{") {
+		if err := validateSlackBlocksJSON(n.Blocks); err != nil {
+			return nil, fmt.Errorf("invalid slack payload of template '%s': %w", name, err)
+		}
+	}
+	if !strings.Contains(n.Attachments, "{{") {
+		if err := validateSlackAttachmentsJSON(n.Attachments); err != nil {
+			return nil, fmt.Errorf("invalid slack payload of template '%s': %w", name, err)
+		}
+	}
+	type slackFileTemplate struct {
+		filename, content, title *texttemplate.Template
+	}
+	slackFiles := make([]slackFileTemplate, len(n.Files))
+	for i, file := range n.Files {
+		if slackFiles[i].filename, err = texttemplate.New(name).Funcs(f).Parse(file.Filename); err != nil {
+			return nil, err
+		}
+		if slackFiles[i].content, err = texttemplate.New(name).Funcs(f).Parse(file.Content); err != nil {
+			return nil, err
+		}
+		if slackFiles[i].title, err = texttemplate.New(name).Funcs(f).Parse(file.Title); err != nil {
+			return nil, err
+		}
+	}
 
 	return func(notification *Notification, vars map[string]interface{}) error {
 		if notification.Slack == nil {
@@ -89,23 +161,81 @@ func (n *SlackNotification) GetTemplater(name string, f texttemplate.FuncMap) (T
 		}
 		notification.Slack.GroupingKey = groupingKeyData.String()
 
+		var slackFallbackTextData bytes.Buffer
+		if err := slackFallbackText.Execute(&slackFallbackTextData, vars); err != nil {
+			return err
+		}
+		notification.Slack.FallbackText = slackFallbackTextData.String()
+
+		var slackMetadataData bytes.Buffer
+		if err := slackMetadata.Execute(&slackMetadataData, vars); err != nil {
+			return err
+		}
+		notification.Slack.Metadata = slackMetadataData.String()
+
+		var slackUserData bytes.Buffer
+		if err := slackUser.Execute(&slackUserData, vars); err != nil {
+			return err
+		}
+		notification.Slack.User = slackUserData.String()
+
+		var persistentKeyData bytes.Buffer
+		if err := persistentKey.Execute(&persistentKeyData, vars); err != nil {
+			return err
+		}
+		notification.Slack.PersistentKey = persistentKeyData.String()
+
+		if len(slackFiles) > 0 {
+			notification.Slack.Files = make([]SlackFile, len(slackFiles))
+			for i, file := range slackFiles {
+				var filenameData, contentData, titleData bytes.Buffer
+				if err := file.filename.Execute(&filenameData, vars); err != nil {
+					return err
+				}
+				if err := file.content.Execute(&contentData, vars); err != nil {
+					return err
+				}
+				if err := file.title.Execute(&titleData, vars); err != nil {
+					return err
+				}
+				notification.Slack.Files[i] = SlackFile{
+					Filename: filenameData.String(),
+					Content:  contentData.String(),
+					Title:    titleData.String(),
+				}
+			}
+		}
+
 		notification.Slack.NotifyBroadcast = n.NotifyBroadcast
 		notification.Slack.DeliveryPolicy = n.DeliveryPolicy
+		notification.Slack.BroadcastPolicy = n.BroadcastPolicy
+		notification.Slack.UnfurlLinks = n.UnfurlLinks
+		notification.Slack.UnfurlMedia = n.UnfurlMedia
+		notification.Slack.Ephemeral = n.Ephemeral
 		return nil
 	}, nil
 }
 
 type SlackOptions struct {
-	Username           string   `json:"username"`
-	Icon               string   `json:"icon"`
-	Token              string   `json:"token"`
-	SigningSecret      string   `json:"signingSecret"`
-	Channels           []string `json:"channels"`
-	InsecureSkipVerify bool     `json:"insecureSkipVerify"`
-	ApiURL             string   `json:"apiURL"`
-	DisableUnfurl      bool     `json:"disableUnfurl"`
+	Username string `json:"username"`
+	Icon     string `json:"icon"`
+	Token    string `json:"token"`
+	// RecipientTokens overrides the token for the given recipients, e.g. to post to channels of other workspaces
+	RecipientTokens    map[string]string `json:"recipientTokens,omitempty"`
+	SigningSecret      string            `json:"signingSecret"`
+	Channels           []string          `json:"channels"`
+	InsecureSkipVerify bool              `json:"insecureSkipVerify"`
+	CABundle           string            `json:"caBundle,omitempty"`
+	ApiURL             string            `json:"apiURL"`
+	DisableUnfurl      bool              `json:"disableUnfurl"`
+	// Timeout bounds the requests to the Slack API, e.g. "10s". Defaults to 30s
+	Timeout string `json:"timeout,omitempty"`
+	MessageSizeLimit
 }
 
+// slackMaxMessageSize is the maximum size of the message text accepted by Slack
+const slackMaxMessageSize = 40000
+
 type slackService struct {
 	opts SlackOptions
 }
@@ -116,8 +246,28 @@ func NewSlackService(opts SlackOptions) NotificationService {
 	return &slackService{opts: opts}
 }
 
+// resolveSlackSecretRefs resolves the tokens which reference environment variables or files
+func resolveSlackSecretRefs(opts *SlackOptions, refs *SecretRefs) error {
+	token, err := refs.resolve(opts.Token)
+	if err != nil {
+		return fmt.Errorf("failed to resolve slack token: %v", err)
+	}
+	opts.Token = token
+	for recipient, recipientToken := range opts.RecipientTokens {
+		if opts.RecipientTokens[recipient], err = refs.resolve(recipientToken); err != nil {
+			return fmt.Errorf("failed to resolve slack token of recipient '%s': %v", recipient, err)
+		}
+	}
+	return nil
+}
+
 func buildMessageOptions(notification Notification, dest Destination, opts SlackOptions) (*SlackNotification, []slack.MsgOption, error) {
-	msgOptions := []slack.MsgOption{slack.MsgOptionText(notification.Message, false)}
+	// the text is sent along with blocks and attachments since Slack uses it in notifications
+	text := notification.Message
+	if notification.Slack != nil && notification.Slack.FallbackText != "" {
+		text = notification.Slack.FallbackText
+	}
+	msgOptions := []slack.MsgOption{slack.MsgOptionText(text, false)}
 	slackNotification := &SlackNotification{}
 
 	if notification.Slack != nil && notification.Slack.Username != "" {
@@ -144,6 +294,9 @@ func buildMessageOptions(notification Notification, dest Destination, opts Slack
 	}
 
 	if notification.Slack != nil {
+		if err := notification.Slack.ValidatePayload(); err != nil {
+			return nil, nil, fmt.Errorf("invalid slack payload: %w", err)
+		}
 		attachments := make([]slack.Attachment, 0)
 		if notification.Slack.Attachments != "" {
 			if err := json.Unmarshal([]byte(notification.Slack.Attachments), &attachments); err != nil {
@@ -158,32 +311,171 @@ func buildMessageOptions(notification Notification, dest Destination, opts Slack
 			}
 		}
 		msgOptions = append(msgOptions, slack.MsgOptionAttachments(attachments...), slack.MsgOptionBlocks(blocks.BlockSet...))
+
+		if notification.Slack.Metadata != "" {
+			var metadata slack.SlackMetadata
+			if err := json.Unmarshal([]byte(notification.Slack.Metadata), &metadata); err != nil {
+				return nil, nil, fmt.Errorf("failed to unmarshal metadata '%s' : %v", notification.Slack.Metadata, err)
+			}
+			msgOptions = append(msgOptions, slack.MsgOptionMetadata(metadata))
+		}
 		slackNotification = notification.Slack
 	}
 
-	if opts.DisableUnfurl {
-		msgOptions = append(msgOptions, slack.MsgOptionDisableLinkUnfurl(), slack.MsgOptionDisableMediaUnfurl())
+	unfurlLinks, unfurlMedia := !opts.DisableUnfurl, !opts.DisableUnfurl
+	if notification.Slack != nil && notification.Slack.UnfurlLinks != nil {
+		unfurlLinks = *notification.Slack.UnfurlLinks
+		if unfurlLinks {
+			msgOptions = append(msgOptions, slack.MsgOptionEnableLinkUnfurl())
+		}
+	}
+	if notification.Slack != nil && notification.Slack.UnfurlMedia != nil {
+		unfurlMedia = *notification.Slack.UnfurlMedia
+	}
+	if !unfurlLinks {
+		msgOptions = append(msgOptions, slack.MsgOptionDisableLinkUnfurl())
+	}
+	// media is unfurled unless disabled
+	if !unfurlMedia {
+		msgOptions = append(msgOptions, slack.MsgOptionDisableMediaUnfurl())
 	}
 
 	return slackNotification, msgOptions, nil
 }
 
 func (s *slackService) Send(notification Notification, dest Destination) error {
+	return s.SendContext(context.Background(), notification, dest)
+}
+
+func (s *slackService) SendContext(ctx context.Context, notification Notification, dest Destination) error {
+	message, err := s.opts.enforce("slack", notification.Message, slackMaxMessageSize)
+	if err != nil {
+		return err
+	}
+	notification.Message = message
 	slackNotification, msgOptions, err := buildMessageOptions(notification, dest, s.opts)
 	if err != nil {
 		return err
 	}
-	return slackutil.NewThreadedClient(
-		newSlackClient(s.opts),
+	token, workspace := s.opts.Token, ""
+	if recipientToken, ok := s.opts.RecipientTokens[dest.Recipient]; ok {
+		token, workspace = recipientToken, slackWorkspaceKey(recipientToken)
+	}
+	client := slackutil.NewWorkspaceThreadedClient(
+		newSlackClient(s.opts, token),
 		slackState,
-	).SendMessage(
-		context.TODO(),
+		workspace,
+	)
+	if slackNotification.Ephemeral {
+		if err := validateEphemeral(slackNotification); err != nil {
+			return err
+		}
+		return client.SendEphemeralMessage(ctx, dest.Recipient, slackNotification.User, msgOptions)
+	}
+	if slackNotification.PersistentKey != "" {
+		if err := validatePersistent(slackNotification); err != nil {
+			return err
+		}
+		// without persisted state, e.g. when sent by the CLI, every notification posts a new message
+		state := ServiceStateFrom(ctx)
+		key := strings.Join([]string{"slack", dest.Service, dest.Recipient, slackNotification.PersistentKey}, "/")
+		var channelID, ts string
+		if state != nil {
+			if value, ok := state.Get(key); ok {
+				channelID, ts, _ = strings.Cut(value, "/")
+			}
+		}
+		channelID, ts, err = client.SendPersistentMessage(ctx, dest.Recipient, channelID, ts, msgOptions)
+		if err != nil {
+			return err
+		}
+		if state != nil {
+			state.Set(key, channelID+"/"+ts)
+		}
+		return nil
+	}
+	err = client.SendMessage(
+		ctx,
 		dest.Recipient,
 		slackNotification.GroupingKey,
-		slackNotification.NotifyBroadcast,
+		slackNotification.broadcastPolicy(),
 		slackNotification.DeliveryPolicy,
 		msgOptions,
 	)
+	if err != nil || len(slackNotification.Files) == 0 || slackNotification.DeliveryPolicy == slackutil.Delete {
+		return err
+	}
+	return client.UploadFiles(ctx, dest.Recipient, slackNotification.GroupingKey, uploadFileParameters(slackNotification.Files))
+}
+
+// broadcastPolicy returns the broadcast policy of the notification, which defaults to Always if notifyBroadcast is set
+func (n *SlackNotification) broadcastPolicy() slackutil.BroadcastPolicy {
+	if n.BroadcastPolicy != nil {
+		return *n.BroadcastPolicy
+	}
+	if n.NotifyBroadcast {
+		return slackutil.BroadcastAlways
+	}
+	return slackutil.BroadcastNever
+}
+
+// validateEphemeral returns an error if the notification uses features which ephemeral messages don't support
+func validateEphemeral(n *SlackNotification) error {
+	switch {
+	case n.User == "":
+		return fmt.Errorf("slack ephemeral message requires a user")
+	case n.GroupingKey != "":
+		return fmt.Errorf("slack ephemeral message can't be grouped, groupingKey must be empty")
+	case n.DeliveryPolicy != slackutil.Post:
+		return fmt.Errorf("slack ephemeral message can't be updated, deliveryPolicy must be Post")
+	case n.PersistentKey != "":
+		return fmt.Errorf("slack ephemeral message can't be updated, persistentKey must be empty")
+	case len(n.Files) > 0:
+		return fmt.Errorf("slack ephemeral message can't have files")
+	}
+	return nil
+}
+
+// validatePersistent returns an error if the notification uses features which persistent messages don't support
+func validatePersistent(n *SlackNotification) error {
+	switch {
+	case n.GroupingKey != "":
+		return fmt.Errorf("slack persistent message can't be grouped, groupingKey must be empty")
+	case len(n.Files) > 0:
+		return fmt.Errorf("slack persistent message can't have files")
+	}
+	return nil
+}
+
+func uploadFileParameters(files []SlackFile) []slack.UploadFileV2Parameters {
+	params := make([]slack.UploadFileV2Parameters, len(files))
+	for i, file := range files {
+		params[i] = slack.UploadFileV2Parameters{
+			Filename: file.Filename,
+			Title:    file.Title,
+			Reader:   strings.NewReader(file.Content),
+			FileSize: len(file.Content),
+		}
+	}
+	return params
+}
+
+// Validate checks that the token and the tokens of recipients are valid
+func (s *slackService) Validate(ctx context.Context) error {
+	if _, err := newSlackClient(s.opts, s.opts.Token).AuthTestContext(ctx); err != nil {
+		return fmt.Errorf("slack token is not valid: %w", err)
+	}
+	recipients := make([]string, 0, len(s.opts.RecipientTokens))
+	for recipient := range s.opts.RecipientTokens {
+		recipients = append(recipients, recipient)
+	}
+	sort.Strings(recipients)
+	for _, recipient := range recipients {
+		if _, err := newSlackClient(s.opts, s.opts.RecipientTokens[recipient]).AuthTestContext(ctx); err != nil {
+			return fmt.Errorf("slack token of recipient '%s' is not valid: %w", recipient, err)
+		}
+	}
+	return nil
 }
 
 // GetSigningSecret exposes signing secret for slack bot
@@ -191,16 +483,25 @@ func (s *slackService) GetSigningSecret() string {
 	return s.opts.SigningSecret
 }
 
-func newSlackClient(opts SlackOptions) *slack.Client {
+// slackWorkspaceKey identifies the workspace of the token in the shared state without keeping the token itself
+func slackWorkspaceKey(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:8])
+}
+
+func newSlackClient(opts SlackOptions, token string) *slack.Client {
 	apiURL := slack.APIURL
 	if opts.ApiURL != "" {
 		apiURL = opts.ApiURL
 	}
-	transport := httputil.NewTransport(apiURL, opts.InsecureSkipVerify)
-	client := &http.Client{
-		Transport: httputil.NewLoggingRoundTripper(transport, log.WithField("service", "slack")),
-	}
-	return slack.New(opts.Token, slack.OptionHTTPClient(client), slack.OptionAPIURL(apiURL))
+	// the timeout is validated once the service is created from its configuration
+	timeout, _ := parseTimeout("slack", opts.Timeout)
+	client := httputil.NewServiceHTTPClient(apiURL, httputil.TransportOptions{
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+		CABundle:           []byte(opts.CABundle),
+		Timeout:            timeout,
+	}, log.WithField("service", "slack"))
+	return slack.New(token, slack.OptionHTTPClient(client), slack.OptionAPIURL(apiURL))
 }
 
 func isValidIconURL(iconURL string) bool {
@@ -216,3 +517,8 @@ func isValidIconURL(iconURL string) bool {
 
 	return true
 }
+
+// Recipients returns the configured channels and the recipients with a dedicated token
+func (s *slackService) Recipients() []string {
+	return sortedRecipients(s.opts.RecipientTokens, s.opts.Channels...)
+}
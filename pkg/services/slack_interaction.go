@@ -0,0 +1,96 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+)
+
+const (
+	// slackRequestTolerance is how far the timestamp of a Slack request may be from the current time, requests outside
+	// of the tolerance are rejected as possible replays
+	slackRequestTolerance = 5 * time.Minute
+	// slackMaxInteractionSize limits the size of the body of interaction requests
+	slackMaxInteractionSize = 1 << 20
+)
+
+// NewSlackInteractionHandler returns a handler of the interaction requests Slack sends once a user clicks a button of a
+// message, e.g. to the request URL configured in the Interactivity settings of the Slack app. The handler verifies the
+// signature of the request with the signing secret of the Slack service and invokes onAction with the interaction.
+func NewSlackInteractionHandler(service NotificationService, onAction func(callback slack.InteractionCallback)) (http.Handler, error) {
+	s, ok := service.(interface{ GetSigningSecret() string })
+	if !ok {
+		return nil, fmt.Errorf("service of type %T is not a slack service", service)
+	}
+	if s.GetSigningSecret() == "" {
+		return nil, fmt.Errorf("slack signing secret is required to verify interaction requests")
+	}
+	return &slackInteractionHandler{signingSecret: s.GetSigningSecret(), onAction: onAction, now: time.Now}, nil
+}
+
+type slackInteractionHandler struct {
+	signingSecret string
+	onAction      func(callback slack.InteractionCallback)
+	now           func() time.Time
+}
+
+func (h *slackInteractionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, slackMaxInteractionSize))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	if err := h.verify(r.Header, body); err != nil {
+		log.Warnf("Rejected slack interaction request: %v", err)
+		http.Error(w, "invalid request signature", http.StatusUnauthorized)
+		return
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	var callback slack.InteractionCallback
+	if err := json.Unmarshal([]byte(values.Get("payload")), &callback); err != nil {
+		http.Error(w, "invalid interaction payload", http.StatusBadRequest)
+		return
+	}
+	h.onAction(callback)
+	w.WriteHeader(http.StatusOK)
+}
+
+// verify checks the timestamp of the request and its signature, the "v0=<hex digest>" HMAC-SHA256 of the version,
+// the timestamp and the body computed with the signing secret
+func (h *slackInteractionHandler) verify(header http.Header, body []byte) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("slack request timestamp '%s' is not valid", timestamp)
+	}
+	age := h.now().Sub(time.Unix(seconds, 0))
+	if age > slackRequestTolerance || age < -slackRequestTolerance {
+		return fmt.Errorf("slack request timestamp '%s' is outside of the tolerance of %s", timestamp, slackRequestTolerance)
+	}
+	mac := hmac.New(sha256.New, []byte(h.signingSecret))
+	_, _ = mac.Write([]byte("v0:" + timestamp + ":"))
+	_, _ = mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return fmt.Errorf("slack request signature does not match")
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func slackInteractionRequest(secret string, timestamp time.Time, body string) *http.Request {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte("v0:" + ts + ":" + body))
+	req := httptest.NewRequest(http.MethodPost, "/slack/interactions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestSlackInteractionHandler(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	body := "payload=" + url.QueryEscape(`{"type": "block_actions", "user": {"id": "U123"}, "actions": [{"block_id": "actions", "action_id": "sync", "value": "guestbook"}]}`)
+
+	newHandler := func(t *testing.T) (http.Handler, *[]slack.InteractionCallback) {
+		var callbacks []slack.InteractionCallback
+		handler, err := NewSlackInteractionHandler(NewSlackService(SlackOptions{SigningSecret: "secret"}), func(callback slack.InteractionCallback) {
+			callbacks = append(callbacks, callback)
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		handler.(*slackInteractionHandler).now = func() time.Time { return now }
+		return handler, &callbacks
+	}
+
+	t.Run("valid signature", func(t *testing.T) {
+		handler, callbacks := newHandler(t)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, slackInteractionRequest("secret", now.Add(-time.Minute), body))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		if assert.Len(t, *callbacks, 1) {
+			callback := (*callbacks)[0]
+			assert.Equal(t, slack.InteractionTypeBlockActions, callback.Type)
+			assert.Equal(t, "U123", callback.User.ID)
+			if assert.Len(t, callback.ActionCallback.BlockActions, 1) {
+				assert.Equal(t, "sync", callback.ActionCallback.BlockActions[0].ActionID)
+				assert.Equal(t, "guestbook", callback.ActionCallback.BlockActions[0].Value)
+			}
+		}
+	})
+
+	t.Run("expired timestamp", func(t *testing.T) {
+		handler, callbacks := newHandler(t)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, slackInteractionRequest("secret", now.Add(-10*time.Minute), body))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Empty(t, *callbacks)
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		handler, callbacks := newHandler(t)
+		req := slackInteractionRequest("secret", now, body)
+		req.Body = http.NoBody
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Empty(t, *callbacks)
+	})
+
+	t.Run("other secret", func(t *testing.T) {
+		handler, callbacks := newHandler(t)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, slackInteractionRequest("other", now, body))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Empty(t, *callbacks)
+	})
+}
+
+func TestNewSlackInteractionHandler_RequiresSigningSecret(t *testing.T) {
+	_, err := NewSlackInteractionHandler(NewSlackService(SlackOptions{}), func(slack.InteractionCallback) {})
+	assert.EqualError(t, err, "slack signing secret is required to verify interaction requests")
+
+	_, err = NewSlackInteractionHandler(NewWebhookService(WebhookOptions{}), func(slack.InteractionCallback) {})
+	assert.EqualError(t, err, "service of type *services.webhookService is not a slack service")
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -11,7 +12,10 @@ import (
 
 	slackutil "github.com/argoproj/notifications-engine/pkg/util/slack"
 
+	"github.com/slack-go/slack"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+	"sigs.k8s.io/yaml"
 )
 
 func TestValidIconEmoji(t *testing.T) {
@@ -37,6 +41,10 @@ func TestGetTemplater_Slack(t *testing.T) {
 			Blocks:          "{{.bar}}",
 			GroupingKey:     "{{.foo}}-{{.bar}}",
 			NotifyBroadcast: true,
+			Files:           []SlackFile{{Filename: "{{.foo}}.diff", Content: "{{.bar}}", Title: "{{.foo}} diff"}},
+			Ephemeral:       true,
+			User:            "{{.foo}}@example.com",
+			FallbackText:    "{{.foo}} {{.bar}}",
 		},
 	}
 	templater, err := n.GetTemplater("", template.FuncMap{})
@@ -61,6 +69,25 @@ func TestGetTemplater_Slack(t *testing.T) {
 	assert.Equal(t, "world", notification.Slack.Blocks)
 	assert.Equal(t, "hello-world", notification.Slack.GroupingKey)
 	assert.Equal(t, true, notification.Slack.NotifyBroadcast)
+	assert.Equal(t, []SlackFile{{Filename: "hello.diff", Content: "world", Title: "hello diff"}}, notification.Slack.Files)
+	assert.Equal(t, true, notification.Slack.Ephemeral)
+	assert.Equal(t, "hello@example.com", notification.Slack.User)
+	assert.Equal(t, "hello world", notification.Slack.FallbackText)
+}
+
+func TestSlackNotification_BroadcastPolicy(t *testing.T) {
+	first := slackutil.BroadcastFirst
+	never := slackutil.BroadcastNever
+	assert.Equal(t, slackutil.BroadcastNever, (&SlackNotification{}).broadcastPolicy())
+	assert.Equal(t, slackutil.BroadcastAlways, (&SlackNotification{NotifyBroadcast: true}).broadcastPolicy())
+	assert.Equal(t, slackutil.BroadcastFirst, (&SlackNotification{BroadcastPolicy: &first}).broadcastPolicy())
+	assert.Equal(t, slackutil.BroadcastNever, (&SlackNotification{NotifyBroadcast: true, BroadcastPolicy: &never}).broadcastPolicy())
+
+	var n SlackNotification
+	err := yaml.Unmarshal([]byte("broadcastPolicy: First"), &n)
+	if assert.NoError(t, err) && assert.NotNil(t, n.BroadcastPolicy) {
+		assert.Equal(t, slackutil.BroadcastFirst, *n.BroadcastPolicy)
+	}
 }
 
 func TestBuildMessageOptionsWithNonExistTemplate(t *testing.T) {
@@ -73,6 +100,75 @@ func TestBuildMessageOptionsWithNonExistTemplate(t *testing.T) {
 	assert.Equal(t, slackutil.Post, sn.DeliveryPolicy)
 }
 
+func TestBuildMessageOptions_Unfurl(t *testing.T) {
+	tr, f := true, false
+	testCases := map[string]struct {
+		disableUnfurl bool
+		slack         *SlackNotification
+		unfurlLinks   string
+		unfurlMedia   string
+	}{
+		"Default":                {slack: &SlackNotification{}},
+		"DisabledByService":      {disableUnfurl: true, slack: &SlackNotification{}, unfurlLinks: "false", unfurlMedia: "false"},
+		"EnabledByNotification":  {disableUnfurl: true, slack: &SlackNotification{UnfurlLinks: &tr, UnfurlMedia: &tr}, unfurlLinks: "true"},
+		"DisabledByNotification": {slack: &SlackNotification{UnfurlLinks: &f, UnfurlMedia: &f}, unfurlLinks: "false", unfurlMedia: "false"},
+		"MediaOnly":              {disableUnfurl: true, slack: &SlackNotification{UnfurlMedia: &tr}, unfurlLinks: "false"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, opts, err := buildMessageOptions(Notification{Message: "hello", Slack: tc.slack}, Destination{}, SlackOptions{DisableUnfurl: tc.disableUnfurl})
+			if !assert.NoError(t, err) {
+				return
+			}
+			_, values, err := slack.UnsafeApplyMsgOptions("token", "channel", "https://slack.com/api/", opts...)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.unfurlLinks, values.Get("unfurl_links"))
+			assert.Equal(t, tc.unfurlMedia, values.Get("unfurl_media"))
+		})
+	}
+}
+
+func TestBuildMessageOptions_Metadata(t *testing.T) {
+	_, opts, err := buildMessageOptions(Notification{Message: "hello", Slack: &SlackNotification{
+		Metadata: `{"event_type": "app_synced", "event_payload": {"app": "guestbook"}}`,
+	}}, Destination{}, SlackOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, values, err := slack.UnsafeApplyMsgOptions("token", "channel", "https://slack.com/api/", opts...)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"event_type": "app_synced", "event_payload": {"app": "guestbook"}}`, values.Get("metadata"))
+
+	_, _, err = buildMessageOptions(Notification{Slack: &SlackNotification{Metadata: "{"}}, Destination{}, SlackOptions{})
+	assert.EqualError(t, err, "failed to unmarshal metadata '{' : unexpected end of JSON input")
+}
+
+func TestBuildMessageOptions_FallbackText(t *testing.T) {
+	blocks := `[{"type": "section", "text": {"type": "mrkdwn", "text": "*guestbook* is synced"}}]`
+	testCases := map[string]struct {
+		notification Notification
+		text         string
+	}{
+		"MessageWithBlocks":      {notification: Notification{Message: "guestbook is synced", Slack: &SlackNotification{Blocks: blocks}}, text: "guestbook is synced"},
+		"FallbackTextWithBlocks": {notification: Notification{Message: "guestbook is synced", Slack: &SlackNotification{Blocks: blocks, FallbackText: "Sync succeeded"}}, text: "Sync succeeded"},
+		"FallbackTextOnly":       {notification: Notification{Slack: &SlackNotification{Attachments: `[{"title": "guestbook"}]`, FallbackText: "Sync succeeded"}}, text: "Sync succeeded"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, opts, err := buildMessageOptions(tc.notification, Destination{}, SlackOptions{})
+			if !assert.NoError(t, err) {
+				return
+			}
+			_, values, err := slack.UnsafeApplyMsgOptions("token", "channel", "https://slack.com/api/", opts...)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.text, values.Get("text"))
+			if tc.notification.Slack.Blocks != "" {
+				assert.Contains(t, values.Get("blocks"), "*guestbook* is synced")
+			}
+		})
+	}
+}
+
 type chatResponseFull struct {
 	Channel          string `json:"channel"`
 	Timestamp        string `json:"ts"`         // Regular message timestamp
@@ -319,3 +415,242 @@ func TestSlack_SetUsernameAndIcon(t *testing.T) {
 		}
 	})
 }
+
+func TestSlack_RecipientTokens(t *testing.T) {
+	var receivedTokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.NoError(t, request.ParseForm())
+		receivedTokens = append(receivedTokens, request.Form.Get("token"))
+		channel := request.Form.Get("channel")
+
+		data, err := json.Marshal(chatResponseFull{
+			Channel:          channel + "-id-for-" + request.Form.Get("token"),
+			Timestamp:        "1503435956.000247",
+			MessageTimeStamp: "1503435956.000247",
+		})
+		assert.NoError(t, err)
+		_, err = writer.Write(data)
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	service := NewSlackService(SlackOptions{
+		ApiURL:          server.URL + "/",
+		Token:           "default-token",
+		RecipientTokens: map[string]string{"other-workspace-channel": "other-token"},
+	})
+	notification := Notification{Message: "test", Slack: &SlackNotification{GroupingKey: "group"}}
+
+	assert.NoError(t, service.Send(notification, Destination{Recipient: "default-workspace-channel", Service: "slack"}))
+	assert.NoError(t, service.Send(notification, Destination{Recipient: "other-workspace-channel", Service: "slack"}))
+	assert.Equal(t, []string{"default-token", "other-token"}, receivedTokens)
+
+	// the state of the recipient which uses its own token is kept separately
+	otherKey := slackWorkspaceKey("other-token") + "/other-workspace-channel"
+	assert.Equal(t, "default-workspace-channel-id-for-default-token", slackState.ChannelIDs["default-workspace-channel"])
+	assert.Equal(t, "other-workspace-channel-id-for-other-token", slackState.ChannelIDs[otherKey])
+	assert.NotContains(t, slackState.ChannelIDs, "other-workspace-channel")
+	assert.Contains(t, slackState.ThreadTSs, otherKey)
+	assert.NotContains(t, slackState.ThreadTSs, "other-workspace-channel")
+}
+
+func TestSlack_SendNotification_Files(t *testing.T) {
+	var uploadedFilename, uploadedContent, completedChannel, completedThread, completedFiles string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		var response interface{}
+		switch request.URL.Path {
+		case "/chat.postMessage":
+			response = chatResponseFull{Channel: "files-channel-id", Timestamp: "1503435956.000247"}
+		case "/files.getUploadURLExternal":
+			assert.NoError(t, request.ParseForm())
+			assert.Equal(t, "sync.diff", request.Form.Get("filename"))
+			assert.Equal(t, "4", request.Form.Get("length"))
+			response = map[string]interface{}{"ok": true, "upload_url": "http://" + request.Host + "/upload", "file_id": "F123"}
+		case "/upload":
+			file, header, err := request.FormFile("file")
+			if !assert.NoError(t, err) {
+				return
+			}
+			data, err := io.ReadAll(file)
+			assert.NoError(t, err)
+			uploadedFilename, uploadedContent = header.Filename, string(data)
+			return
+		case "/files.completeUploadExternal":
+			assert.NoError(t, request.ParseForm())
+			completedChannel = request.Form.Get("channel_id")
+			completedThread = request.Form.Get("thread_ts")
+			completedFiles = request.Form.Get("files")
+			response = map[string]interface{}{"ok": true, "files": []map[string]string{{"id": "F123", "title": "Sync diff"}}}
+		default:
+			t.Errorf("unexpected request to %s", request.URL.Path)
+			return
+		}
+		data, err := json.Marshal(response)
+		assert.NoError(t, err)
+		_, err = writer.Write(data)
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	service := NewSlackService(SlackOptions{ApiURL: server.URL + "/", Token: "something-token"})
+	err := service.Send(Notification{
+		Message: "Application synced",
+		Slack: &SlackNotification{
+			GroupingKey: "files-group",
+			Files:       []SlackFile{{Filename: "sync.diff", Content: "+abc", Title: "Sync diff"}},
+		},
+	}, Destination{Recipient: "files-channel", Service: "slack"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "sync.diff", uploadedFilename)
+	assert.Equal(t, "+abc", uploadedContent)
+	assert.Equal(t, "files-channel-id", completedChannel)
+	assert.Equal(t, "1503435956.000247", completedThread)
+	assert.JSONEq(t, `[{"id": "F123", "title": "Sync diff"}]`, completedFiles)
+}
+
+func TestSlack_SendNotification_Ephemeral(t *testing.T) {
+	var postedChannel, postedUser, postedText string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.NoError(t, request.ParseForm())
+		var response interface{}
+		switch request.URL.Path {
+		case "/users.lookupByEmail":
+			assert.Equal(t, "jane@example.com", request.Form.Get("email"))
+			response = map[string]interface{}{"ok": true, "user": map[string]string{"id": "U123"}}
+		case "/chat.postEphemeral":
+			postedChannel, postedUser, postedText = request.Form.Get("channel"), request.Form.Get("user"), request.Form.Get("text")
+			response = map[string]interface{}{"ok": true, "message_ts": "1503435956.000247"}
+		default:
+			t.Errorf("unexpected request to %s", request.URL.Path)
+			return
+		}
+		data, err := json.Marshal(response)
+		assert.NoError(t, err)
+		_, err = writer.Write(data)
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	service := NewSlackService(SlackOptions{ApiURL: server.URL + "/", Token: "something-token"})
+	err := service.Send(Notification{
+		Message: "Only you can see this",
+		Slack:   &SlackNotification{Ephemeral: true, User: "jane@example.com"},
+	}, Destination{Recipient: "ephemeral-channel", Service: "slack"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ephemeral-channel", postedChannel)
+	assert.Equal(t, "U123", postedUser)
+	assert.Equal(t, "Only you can see this", postedText)
+}
+
+func TestSlack_SendNotification_EphemeralIncompatible(t *testing.T) {
+	service := NewSlackService(SlackOptions{ApiURL: "http://127.0.0.1:0/", Token: "something-token"})
+	for _, tc := range []struct {
+		description string
+		slack       SlackNotification
+		expectedErr string
+	}{
+		{description: "NoUser", slack: SlackNotification{Ephemeral: true}, expectedErr: "slack ephemeral message requires a user"},
+		{description: "GroupingKey", slack: SlackNotification{Ephemeral: true, User: "U123", GroupingKey: "group"}, expectedErr: "slack ephemeral message can't be grouped, groupingKey must be empty"},
+		{description: "UpdatePolicy", slack: SlackNotification{Ephemeral: true, User: "U123", DeliveryPolicy: slackutil.PostAndUpdate}, expectedErr: "slack ephemeral message can't be updated, deliveryPolicy must be Post"},
+		{description: "Files", slack: SlackNotification{Ephemeral: true, User: "U123", Files: []SlackFile{{Filename: "a.txt"}}}, expectedErr: "slack ephemeral message can't have files"},
+		{description: "PersistentKey", slack: SlackNotification{Ephemeral: true, User: "U123", PersistentKey: "status"}, expectedErr: "slack ephemeral message can't be updated, persistentKey must be empty"},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			slackNotification := tc.slack
+			err := service.Send(Notification{Message: "hello", Slack: &slackNotification}, Destination{Recipient: "channel", Service: "slack"})
+			assert.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+type testServiceState map[string]string
+
+func (s testServiceState) Get(key string) (string, bool) {
+	value, ok := s[key]
+	return value, ok
+}
+
+func (s testServiceState) Set(key string, value string) {
+	s[key] = value
+}
+
+func TestSlack_SendNotification_PersistentKey(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.NoError(t, request.ParseForm())
+		requests = append(requests, request.URL.Path+" "+request.Form.Get("channel")+" "+request.Form.Get("ts"))
+		var response interface{}
+		switch {
+		case request.URL.Path == "/chat.postMessage":
+			response = map[string]interface{}{"ok": true, "channel": "C123", "ts": "1503435956.000247"}
+		case request.URL.Path == "/chat.update" && request.Form.Get("ts") == "1503435956.000247":
+			response = map[string]interface{}{"ok": true, "channel": "C123", "ts": "1503435956.000247"}
+		case request.URL.Path == "/chat.update":
+			response = map[string]interface{}{"ok": false, "error": "message_not_found"}
+		default:
+			t.Errorf("unexpected request to %s", request.URL.Path)
+			return
+		}
+		data, err := json.Marshal(response)
+		assert.NoError(t, err)
+		_, err = writer.Write(data)
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	notification := Notification{Message: "guestbook is healthy", Slack: &SlackNotification{PersistentKey: "guestbook-status"}}
+	dest := Destination{Recipient: "status-channel", Service: "slack"}
+	state := testServiceState{}
+
+	err := NewSlackService(SlackOptions{ApiURL: server.URL + "/", Token: "something-token"}).SendContext(WithServiceState(context.Background(), state), notification, dest)
+	assert.NoError(t, err)
+	assert.Equal(t, testServiceState{"slack/slack/status-channel/guestbook-status": "C123/1503435956.000247"}, state)
+
+	// a restarted controller has no cached channel IDs and thread timestamps, but updates the persisted message
+	previousState := slackState
+	defer func() { slackState = previousState }()
+	slackState = slackutil.NewState(rate.NewLimiter(rate.Inf, 1))
+	err = NewSlackService(SlackOptions{ApiURL: server.URL + "/", Token: "something-token"}).SendContext(WithServiceState(context.Background(), state), notification, dest)
+	assert.NoError(t, err)
+
+	// the message was deleted in Slack, so a new one is posted
+	state["slack/slack/status-channel/guestbook-status"] = "C123/1503435956.000100"
+	err = NewSlackService(SlackOptions{ApiURL: server.URL + "/", Token: "something-token"}).SendContext(WithServiceState(context.Background(), state), notification, dest)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"/chat.postMessage status-channel ",
+		"/chat.update C123 1503435956.000247",
+		"/chat.update C123 1503435956.000100",
+		"/chat.postMessage status-channel ",
+	}, requests)
+	assert.Equal(t, testServiceState{"slack/slack/status-channel/guestbook-status": "C123/1503435956.000247"}, state)
+}
+
+func TestSlack_Validate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.Equal(t, "/auth.test", request.URL.Path)
+		assert.NoError(t, request.ParseForm())
+		if request.Form.Get("token") == "valid-token" {
+			_, _ = writer.Write([]byte(`{"ok": true, "team": "my-team", "user": "bot"}`))
+		} else {
+			_, _ = writer.Write([]byte(`{"ok": false, "error": "invalid_auth"}`))
+		}
+	}))
+	defer server.Close()
+
+	service := NewSlackService(SlackOptions{ApiURL: server.URL + "/", Token: "valid-token"})
+	assert.NoError(t, service.(Validatable).Validate(context.Background()))
+
+	service = NewSlackService(SlackOptions{ApiURL: server.URL + "/", Token: "invalid-token"})
+	assert.EqualError(t, service.(Validatable).Validate(context.Background()), "slack token is not valid: invalid_auth")
+
+	service = NewSlackService(SlackOptions{
+		ApiURL:          server.URL + "/",
+		Token:           "valid-token",
+		RecipientTokens: map[string]string{"other-workspace-channel": "invalid-token"},
+	})
+	assert.EqualError(t, service.(Validatable).Validate(context.Background()), "slack token of recipient 'other-workspace-channel' is not valid: invalid_auth")
+}
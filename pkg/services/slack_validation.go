@@ -0,0 +1,199 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ValidatePayload checks that the blocks and attachments of the notification have the structure required by the
+// Slack API. The returned error points at the invalid field, e.g. "blocks[0].text.type". Block types which are not
+// known to the validator are accepted as long as they have a type.
+func (n *SlackNotification) ValidatePayload() error {
+	if err := validateSlackBlocksJSON(n.Blocks); err != nil {
+		return err
+	}
+	return validateSlackAttachmentsJSON(n.Attachments)
+}
+
+var slackTextObjectTypes = []string{"plain_text", "mrkdwn"}
+
+func validateSlackBlocksJSON(raw string) error {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	var blocks interface{}
+	if err := json.Unmarshal([]byte(raw), &blocks); err != nil {
+		return fmt.Errorf("blocks is not valid JSON: %v", err)
+	}
+	return validateSlackBlocks("blocks", blocks)
+}
+
+func validateSlackAttachmentsJSON(raw string) error {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	var attachments interface{}
+	if err := json.Unmarshal([]byte(raw), &attachments); err != nil {
+		return fmt.Errorf("attachments is not valid JSON: %v", err)
+	}
+	items, ok := attachments.([]interface{})
+	if !ok {
+		return fmt.Errorf("attachments must be a list")
+	}
+	for i, item := range items {
+		path := fmt.Sprintf("attachments[%d]", i)
+		attachment, ok := item.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an object", path)
+		}
+		for _, field := range []string{"color", "fallback", "pretext", "title", "title_link", "text", "footer"} {
+			if err := validateOptionalString(path+"."+field, attachment[field]); err != nil {
+				return err
+			}
+		}
+		if fields, ok := attachment["fields"]; ok {
+			list, ok := fields.([]interface{})
+			if !ok {
+				return fmt.Errorf("%s.fields must be a list", path)
+			}
+			for j, f := range list {
+				fieldPath := fmt.Sprintf("%s.fields[%d]", path, j)
+				field, ok := f.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("%s must be an object", fieldPath)
+				}
+				if err := validateOptionalString(fieldPath+".title", field["title"]); err != nil {
+					return err
+				}
+				if err := validateOptionalString(fieldPath+".value", field["value"]); err != nil {
+					return err
+				}
+			}
+		}
+		if blocks, ok := attachment["blocks"]; ok {
+			if err := validateSlackBlocks(path+".blocks", blocks); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateSlackBlocks(path string, blocks interface{}) error {
+	items, ok := blocks.([]interface{})
+	if !ok {
+		return fmt.Errorf("%s must be a list", path)
+	}
+	for i, item := range items {
+		if err := validateSlackBlock(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateSlackBlock(path string, item interface{}) error {
+	block, ok := item.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s must be an object", path)
+	}
+	blockType, ok := block["type"].(string)
+	if !ok || blockType == "" {
+		return fmt.Errorf("%s.type is required", path)
+	}
+	switch blockType {
+	case "section":
+		_, hasText := block["text"]
+		fields, hasFields := block["fields"]
+		if !hasText && !hasFields {
+			return fmt.Errorf("%s requires text or fields", path)
+		}
+		if hasText {
+			if err := validateSlackTextObject(path+".text", block["text"], slackTextObjectTypes...); err != nil {
+				return err
+			}
+		}
+		if hasFields {
+			list, ok := fields.([]interface{})
+			if !ok {
+				return fmt.Errorf("%s.fields must be a list", path)
+			}
+			for j, field := range list {
+				if err := validateSlackTextObject(fmt.Sprintf("%s.fields[%d]", path, j), field, slackTextObjectTypes...); err != nil {
+					return err
+				}
+			}
+		}
+	case "header":
+		return validateSlackTextObject(path+".text", block["text"], "plain_text")
+	case "image":
+		if _, hasURL := block["image_url"]; !hasURL {
+			if _, hasFile := block["slack_file"]; !hasFile {
+				return fmt.Errorf("%s requires image_url or slack_file", path)
+			}
+		}
+		if altText, ok := block["alt_text"].(string); !ok || altText == "" {
+			return fmt.Errorf("%s.alt_text is required", path)
+		}
+	case "context", "actions":
+		elements, ok := block["elements"].([]interface{})
+		if !ok || len(elements) == 0 {
+			return fmt.Errorf("%s.elements is required", path)
+		}
+		for j, e := range elements {
+			elementPath := fmt.Sprintf("%s.elements[%d]", path, j)
+			element, ok := e.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s must be an object", elementPath)
+			}
+			if elementType, ok := element["type"].(string); !ok || elementType == "" {
+				return fmt.Errorf("%s.type is required", elementPath)
+			}
+		}
+	case "input":
+		if err := validateSlackTextObject(path+".label", block["label"], "plain_text"); err != nil {
+			return err
+		}
+		element, ok := block["element"].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s.element is required", path)
+		}
+		if elementType, ok := element["type"].(string); !ok || elementType == "" {
+			return fmt.Errorf("%s.element.type is required", path)
+		}
+	}
+	return nil
+}
+
+func validateSlackTextObject(path string, value interface{}, types ...string) error {
+	if value == nil {
+		return fmt.Errorf("%s is required", path)
+	}
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s must be an object", path)
+	}
+	textType, _ := obj["type"].(string)
+	valid := false
+	for _, t := range types {
+		valid = valid || textType == t
+	}
+	if !valid {
+		return fmt.Errorf("%s.type '%s' is not valid, must be one of: %s", path, textType, strings.Join(types, ", "))
+	}
+	if _, ok := obj["text"].(string); !ok {
+		return fmt.Errorf("%s.text is required", path)
+	}
+	return nil
+}
+
+func validateOptionalString(path string, value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	if _, ok := value.(string); !ok {
+		return fmt.Errorf("%s must be a string", path)
+	}
+	return nil
+}
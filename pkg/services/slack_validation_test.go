@@ -0,0 +1,92 @@
+package services
+
+import (
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlackNotification_ValidatePayload(t *testing.T) {
+	for _, tc := range []struct {
+		description  string
+		notification SlackNotification
+		expectedErr  string
+	}{{
+		description: "ValidBlocks",
+		notification: SlackNotification{Blocks: `[
+			{"type": "header", "text": {"type": "plain_text", "text": "Synced"}},
+			{"type": "section", "text": {"type": "mrkdwn", "text": "*guestbook*"}, "fields": [{"type": "mrkdwn", "text": "a"}]},
+			{"type": "divider"},
+			{"type": "image", "image_url": "https://example.com/a.png", "alt_text": "a"},
+			{"type": "context", "elements": [{"type": "mrkdwn", "text": "b"}]},
+			{"type": "actions", "elements": [{"type": "button", "text": {"type": "plain_text", "text": "Open"}, "url": "https://example.com"}]},
+			{"type": "rich_text", "elements": []}
+		]`},
+	}, {
+		description:  "ValidAttachments",
+		notification: SlackNotification{Attachments: `[{"color": "#18be52", "title": "guestbook", "fields": [{"title": "Sync Status", "value": "Synced", "short": true}]}]`},
+	}, {
+		description:  "InvalidJSON",
+		notification: SlackNotification{Blocks: `[{"type": "section"`},
+		expectedErr:  "blocks is not valid JSON: unexpected end of JSON input",
+	}, {
+		description:  "BlocksNotAList",
+		notification: SlackNotification{Blocks: `{"type": "section"}`},
+		expectedErr:  "blocks must be a list",
+	}, {
+		description:  "MissingBlockType",
+		notification: SlackNotification{Blocks: `[{"type": "divider"}, {"text": {"type": "mrkdwn", "text": "a"}}]`},
+		expectedErr:  "blocks[1].type is required",
+	}, {
+		description:  "InvalidTextType",
+		notification: SlackNotification{Blocks: `[{"type": "section", "text": {"type": "markdown", "text": "a"}}]`},
+		expectedErr:  "blocks[0].text.type 'markdown' is not valid, must be one of: plain_text, mrkdwn",
+	}, {
+		description:  "HeaderRequiresPlainText",
+		notification: SlackNotification{Blocks: `[{"type": "header", "text": {"type": "mrkdwn", "text": "a"}}]`},
+		expectedErr:  "blocks[0].text.type 'mrkdwn' is not valid, must be one of: plain_text",
+	}, {
+		description:  "SectionWithoutText",
+		notification: SlackNotification{Blocks: `[{"type": "section"}]`},
+		expectedErr:  "blocks[0] requires text or fields",
+	}, {
+		description:  "ImageWithoutAltText",
+		notification: SlackNotification{Blocks: `[{"type": "image", "image_url": "https://example.com/a.png"}]`},
+		expectedErr:  "blocks[0].alt_text is required",
+	}, {
+		description:  "InvalidAttachmentField",
+		notification: SlackNotification{Attachments: `[{"fields": [{"title": "Replicas", "value": 3}]}]`},
+		expectedErr:  "attachments[0].fields[0].value must be a string",
+	}, {
+		description:  "InvalidAttachmentBlocks",
+		notification: SlackNotification{Attachments: `[{"blocks": [{"type": "context", "elements": []}]}]`},
+		expectedErr:  "attachments[0].blocks[0].elements is required",
+	}} {
+		t.Run(tc.description, func(t *testing.T) {
+			err := tc.notification.ValidatePayload()
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestGetTemplater_Slack_ValidatesPayload(t *testing.T) {
+	n := Notification{Slack: &SlackNotification{Blocks: `[{"type": "section", "text": {"type": "markdown", "text": "hello"}}]`}}
+	_, err := n.GetTemplater("app-synced", template.FuncMap{})
+	assert.EqualError(t, err, "invalid slack payload of template 'app-synced': blocks[0].text.type 'markdown' is not valid, must be one of: plain_text, mrkdwn")
+
+	// templated payloads are validated once they are rendered
+	n = Notification{Slack: &SlackNotification{Blocks: `[{"type": "section", "text": {"type": "{{.type}}", "text": "hello"}}]`}}
+	templater, err := n.GetTemplater("app-synced", template.FuncMap{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	var notification Notification
+	assert.NoError(t, templater(&notification, map[string]interface{}{"type": "markdown"}))
+	_, _, err = buildMessageOptions(notification, Destination{}, SlackOptions{})
+	assert.EqualError(t, err, "invalid slack payload: blocks[0].text.type 'markdown' is not valid, must be one of: plain_text, mrkdwn")
+}
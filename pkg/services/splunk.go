@@ -0,0 +1,194 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	texttemplate "text/template"
+
+	log "github.com/sirupsen/logrus"
+
+	httputil "github.com/argoproj/notifications-engine/pkg/util/http"
+)
+
+type SplunkOptions struct {
+	// URL is the address of the HTTP Event Collector, e.g. https://splunk.example.com:8088
+	URL   string `json:"url"`
+	Token string `json:"token"`
+	// Host is the host of the events unless the template sets it
+	Host               string `json:"host,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+}
+
+type SplunkNotification struct {
+	Host       string `json:"host,omitempty"`
+	Index      string `json:"index,omitempty"`
+	Source     string `json:"source,omitempty"`
+	Sourcetype string `json:"sourcetype,omitempty"`
+	// Event is the JSON event. Defaults to the message.
+	Event string `json:"event,omitempty"`
+}
+
+// splunkEvent is the envelope of an event sent to the HTTP Event Collector
+type splunkEvent struct {
+	Host       string      `json:"host,omitempty"`
+	Index      string      `json:"index,omitempty"`
+	Source     string      `json:"source,omitempty"`
+	Sourcetype string      `json:"sourcetype,omitempty"`
+	Event      interface{} `json:"event"`
+}
+
+// splunkResponse is the response of the HTTP Event Collector
+type splunkResponse struct {
+	Text string `json:"text"`
+	Code int    `json:"code"`
+}
+
+func (n *SplunkNotification) GetTemplater(name string, f texttemplate.FuncMap) (Templater, error) {
+	host, err := texttemplate.New(name).Funcs(f).Parse(n.Host)
+	if err != nil {
+		return nil, err
+	}
+	index, err := texttemplate.New(name).Funcs(f).Parse(n.Index)
+	if err != nil {
+		return nil, err
+	}
+	source, err := texttemplate.New(name).Funcs(f).Parse(n.Source)
+	if err != nil {
+		return nil, err
+	}
+	sourcetype, err := texttemplate.New(name).Funcs(f).Parse(n.Sourcetype)
+	if err != nil {
+		return nil, err
+	}
+	event, err := texttemplate.New(name).Funcs(f).Parse(n.Event)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(notification *Notification, vars map[string]interface{}) error {
+		if notification.Splunk == nil {
+			notification.Splunk = &SplunkNotification{}
+		}
+
+		var hostData bytes.Buffer
+		if err := host.Execute(&hostData, vars); err != nil {
+			return err
+		}
+		notification.Splunk.Host = hostData.String()
+
+		var indexData bytes.Buffer
+		if err := index.Execute(&indexData, vars); err != nil {
+			return err
+		}
+		notification.Splunk.Index = indexData.String()
+
+		var sourceData bytes.Buffer
+		if err := source.Execute(&sourceData, vars); err != nil {
+			return err
+		}
+		notification.Splunk.Source = sourceData.String()
+
+		var sourcetypeData bytes.Buffer
+		if err := sourcetype.Execute(&sourcetypeData, vars); err != nil {
+			return err
+		}
+		notification.Splunk.Sourcetype = sourcetypeData.String()
+
+		var eventData bytes.Buffer
+		if err := event.Execute(&eventData, vars); err != nil {
+			return err
+		}
+		notification.Splunk.Event = eventData.String()
+
+		return nil
+	}, nil
+}
+
+func NewSplunkService(opts SplunkOptions) NotificationService {
+	opts.URL = strings.TrimSuffix(opts.URL, "/")
+	return &splunkService{opts: opts}
+}
+
+type splunkService struct {
+	opts SplunkOptions
+}
+
+func (s *splunkService) Send(notification Notification, dest Destination) error {
+	return s.SendContext(context.Background(), notification, dest)
+}
+
+func (s *splunkService) SendContext(ctx context.Context, notification Notification, dest Destination) error {
+	event, err := buildSplunkEvent(notification, dest, s.opts)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{
+		Transport: httputil.NewLoggingRoundTripper(
+			httputil.NewTransport(s.opts.URL, s.opts.InsecureSkipVerify), log.WithField("service", dest.Service)),
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.opts.URL+"/services/collector/event", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Splunk "+s.opts.Token)
+
+	response, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read response data: %v", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		var hecResponse splunkResponse
+		if err := json.Unmarshal(body, &hecResponse); err == nil && hecResponse.Text != "" {
+			return fmt.Errorf("splunk HEC request failed with status %d: %s (code %d)", response.StatusCode, hecResponse.Text, hecResponse.Code)
+		}
+		return fmt.Errorf("splunk HEC request failed with status %d: %s", response.StatusCode, string(body))
+	}
+	return nil
+}
+
+// buildSplunkEvent builds the envelope of the event. The recipient is used as index unless the template sets one.
+func buildSplunkEvent(notification Notification, dest Destination, opts SplunkOptions) (*splunkEvent, error) {
+	event := &splunkEvent{
+		Host:  opts.Host,
+		Index: dest.Recipient,
+		Event: notification.Message,
+	}
+	if notification.Splunk == nil {
+		return event, nil
+	}
+
+	if notification.Splunk.Host != "" {
+		event.Host = notification.Splunk.Host
+	}
+	if notification.Splunk.Index != "" {
+		event.Index = notification.Splunk.Index
+	}
+	event.Source = notification.Splunk.Source
+	event.Sourcetype = notification.Splunk.Sourcetype
+	if notification.Splunk.Event != "" {
+		if !json.Valid([]byte(notification.Splunk.Event)) {
+			return nil, fmt.Errorf("splunk event is not valid JSON: %s", notification.Splunk.Event)
+		}
+		event.Event = json.RawMessage(notification.Splunk.Event)
+	}
+	return event, nil
+}
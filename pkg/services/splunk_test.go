@@ -0,0 +1,114 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTemplater_Splunk(t *testing.T) {
+	n := Notification{
+		Splunk: &SplunkNotification{
+			Host:       "{{.context.host}}",
+			Index:      "{{.app.metadata.namespace}}",
+			Source:     "argocd",
+			Sourcetype: "argocd:{{.app.metadata.name}}",
+			Event:      `{"app": "{{.app.metadata.name}}"}`,
+		},
+	}
+	templater, err := n.GetTemplater("", template.FuncMap{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var notification Notification
+	err = templater(&notification, map[string]interface{}{
+		"context": map[string]interface{}{"host": "argocd.example.com"},
+		"app": map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "guestbook", "namespace": "apps"},
+		},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, &SplunkNotification{
+		Host:       "argocd.example.com",
+		Index:      "apps",
+		Source:     "argocd",
+		Sourcetype: "argocd:guestbook",
+		Event:      `{"app": "guestbook"}`,
+	}, notification.Splunk)
+}
+
+func TestSend_Splunk(t *testing.T) {
+	var authorization string
+	var event map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/services/collector/event", r.URL.Path)
+		authorization = r.Header.Get("Authorization")
+		event = nil
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		_, _ = w.Write([]byte(`{"text": "Success", "code": 0}`))
+	}))
+	defer server.Close()
+
+	service := NewSplunkService(SplunkOptions{URL: server.URL + "/", Token: "hec-token", Host: "argocd"})
+
+	t.Run("Event", func(t *testing.T) {
+		err := service.Send(Notification{
+			Message: "message",
+			Splunk: &SplunkNotification{
+				Host:       "argocd.example.com",
+				Index:      "deployments",
+				Sourcetype: "_json",
+				Event:      `{"app": "guestbook", "status": "Synced"}`,
+			},
+		}, Destination{Service: "splunk", Recipient: "main"})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, "Splunk hec-token", authorization)
+		assert.Equal(t, map[string]interface{}{
+			"host":       "argocd.example.com",
+			"index":      "deployments",
+			"sourcetype": "_json",
+			"event":      map[string]interface{}{"app": "guestbook", "status": "Synced"},
+		}, event)
+	})
+
+	t.Run("Message", func(t *testing.T) {
+		err := service.Send(Notification{Message: "message"}, Destination{Service: "splunk", Recipient: "main"})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, map[string]interface{}{
+			"host":  "argocd",
+			"index": "main",
+			"event": "message",
+		}, event)
+	})
+
+	t.Run("InvalidEvent", func(t *testing.T) {
+		err := service.Send(Notification{Splunk: &SplunkNotification{Event: `{"app": }`}}, Destination{Service: "splunk"})
+		assert.EqualError(t, err, `splunk event is not valid JSON: {"app": }`)
+	})
+}
+
+func TestSend_Splunk_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"text": "Invalid token", "code": 4}`))
+	}))
+	defer server.Close()
+
+	service := NewSplunkService(SplunkOptions{URL: server.URL, Token: "invalid"})
+	err := service.Send(Notification{Message: "message"}, Destination{Service: "splunk"})
+	assert.EqualError(t, err, "splunk HEC request failed with status 403: Invalid token (code 4)")
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,6 +12,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	httputil "github.com/argoproj/notifications-engine/pkg/util/http"
+	"github.com/argoproj/notifications-engine/pkg/util/text"
 )
 
 type TeamsNotification struct {
@@ -140,8 +142,24 @@ func (n *TeamsNotification) GetTemplater(name string, f texttemplate.FuncMap) (T
 
 type TeamsOptions struct {
 	RecipientUrls map[string]string `json:"recipientUrls"`
+	// SigningSecret is used to sign the request body with HMAC-SHA256. The requests are not signed if it is empty
+	SigningSecret string `json:"signingSecret,omitempty"`
+	// SignatureHeader is the header which carries the signature. Defaults to X-Hub-Signature-256
+	SignatureHeader string `json:"signatureHeader,omitempty"`
+	// CABundle holds PEM encoded certificates of additional certificate authorities to trust
+	CABundle string `json:"caBundle,omitempty"`
+	// Timeout bounds the requests to the webhooks, e.g. "10s". Defaults to 30s
+	Timeout string `json:"timeout,omitempty"`
+	// ClientCert and ClientKey hold the PEM encoded client certificate and key used to authenticate to webhooks
+	// which require mutual TLS, e.g. behind a gateway
+	ClientCert string `json:"clientCert,omitempty"`
+	ClientKey  string `json:"clientKey,omitempty"`
+	MessageSizeLimit
 }
 
+// teamsMaxMessageSize is the maximum size of the message text accepted by Teams incoming webhooks
+const teamsMaxMessageSize = 28000
+
 type teamsService struct {
 	opts TeamsOptions
 }
@@ -151,13 +169,37 @@ func NewTeamsService(opts TeamsOptions) NotificationService {
 }
 
 func (s teamsService) Send(notification Notification, dest Destination) error {
+	return s.SendContext(context.Background(), notification, dest)
+}
+
+func (s teamsService) SendContext(ctx context.Context, notification Notification, dest Destination) error {
 	webhookUrl, ok := s.opts.RecipientUrls[dest.Recipient]
 	if !ok {
 		return fmt.Errorf("no teams webhook configured for recipient %s", dest.Recipient)
 	}
-	transport := httputil.NewTransport(webhookUrl, false)
-	client := &http.Client{
-		Transport: httputil.NewLoggingRoundTripper(transport, log.WithField("service", "teams")),
+	timeout, err := parseTimeout("teams", s.opts.Timeout)
+	if err != nil {
+		return err
+	}
+	transportOpts := httputil.TransportOptions{
+		CABundle:   []byte(s.opts.CABundle),
+		Timeout:    timeout,
+		ClientCert: []byte(s.opts.ClientCert),
+		ClientKey:  []byte(s.opts.ClientKey),
+	}
+	if err := transportOpts.Validate(); err != nil {
+		return fmt.Errorf("teams is not configured properly: %w", err)
+	}
+	client := httputil.NewServiceHTTPClient(webhookUrl, transportOpts, log.WithField("service", "teams"))
+
+	if notification.Teams != nil && notification.Teams.Text != "" {
+		teams := *notification.Teams
+		if teams.Text, err = s.opts.enforce("teams", teams.Text, teamsMaxMessageSize); err != nil {
+			return err
+		}
+		notification.Teams = &teams
+	} else if notification.Message, err = s.opts.enforce("teams", notification.Message, teamsMaxMessageSize); err != nil {
+		return err
 	}
 
 	message, err := teamsNotificationToReader(notification)
@@ -165,7 +207,16 @@ func (s teamsService) Send(notification Notification, dest Destination) error {
 		return err
 	}
 
-	response, err := client.Post(webhookUrl, "application/json", bytes.NewReader(message))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookUrl, bytes.NewReader(message))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.opts.SigningSecret != "" {
+		req.Header.Set(text.Coalesce(s.opts.SignatureHeader, httputil.DefaultSignatureHeader), httputil.Signature(s.opts.SigningSecret, message))
+	}
+
+	response, err := client.Do(req)
 
 	if err != nil {
 		return err
@@ -279,3 +330,8 @@ type teamsMessage struct {
 
 type teamsSection = map[string]interface{}
 type teamsAction map[string]interface{}
+
+// Recipients returns the recipients which have a webhook configured
+func (s teamsService) Recipients() []string {
+	return sortedRecipients(s.opts.RecipientUrls)
+}
@@ -1,6 +1,9 @@
 package services
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -89,6 +92,63 @@ func TestTeams_DefaultMessage(t *testing.T) {
 	assert.Equal(t, receivedBody.Text, notification.Message)
 }
 
+func TestTeams_SigningSecret(t *testing.T) {
+	var receivedBody []byte
+	var receivedSignature string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		data, err := io.ReadAll(request.Body)
+		assert.NoError(t, err)
+		receivedBody = data
+		receivedSignature = request.Header.Get("X-Signature")
+
+		_, err = writer.Write([]byte("1"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	service := NewTeamsService(TeamsOptions{
+		RecipientUrls:   map[string]string{"test": server.URL},
+		SigningSecret:   "my-secret",
+		SignatureHeader: "X-Signature",
+	})
+
+	err := service.Send(Notification{Message: "simple message"}, Destination{Recipient: "test", Service: "test"})
+	assert.NoError(t, err)
+
+	mac := hmac.New(sha256.New, []byte("my-secret"))
+	mac.Write(receivedBody)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), receivedSignature)
+}
+
+func TestTeams_MessageSizeLimit(t *testing.T) {
+	var receivedBody teamsMessage
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		data, err := io.ReadAll(request.Body)
+		assert.NoError(t, err)
+		assert.NoError(t, json.Unmarshal(data, &receivedBody))
+
+		_, err = writer.Write([]byte("1"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	notification := Notification{Message: "a message exceeding the limit"}
+	destination := Destination{Recipient: "test", Service: "test"}
+
+	service := NewTeamsService(TeamsOptions{
+		RecipientUrls:    map[string]string{"test": server.URL},
+		MessageSizeLimit: MessageSizeLimit{MaxMessageSize: 12},
+	})
+	assert.NoError(t, service.Send(notification, destination))
+	assert.Equal(t, "a message...", receivedBody.Text)
+
+	service = NewTeamsService(TeamsOptions{
+		RecipientUrls:    map[string]string{"test": server.URL},
+		MessageSizeLimit: MessageSizeLimit{MaxMessageSize: 12, MessageSizePolicy: MessageSizePolicyReject},
+	})
+	assert.EqualError(t, service.Send(notification, destination), "teams message size of 29 bytes exceeds the maximum of 12 bytes")
+}
+
 func TestTeams_TemplateMessage(t *testing.T) {
 	var receivedBody string
 	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
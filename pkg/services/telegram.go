@@ -1,12 +1,74 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
 	"strconv"
 	"strings"
+	texttemplate "text/template"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+var (
+	telegramAPIEndpoint = tgbotapi.APIEndpoint
+
+	telegramParseModes = []string{tgbotapi.ModeMarkdown, tgbotapi.ModeMarkdownV2, tgbotapi.ModeHTML}
+)
+
+type TelegramNotification struct {
+	ParseMode             string `json:"parseMode,omitempty"`
+	MessageThreadID       string `json:"messageThreadID,omitempty"`
+	DisableWebPagePreview bool   `json:"disableWebPagePreview,omitempty"`
+	ReplyToMessageID      string `json:"replyToMessageID,omitempty"`
+}
+
+func (n *TelegramNotification) GetTemplater(name string, f texttemplate.FuncMap) (Templater, error) {
+	parseMode, err := texttemplate.New(name).Funcs(f).Parse(n.ParseMode)
+	if err != nil {
+		return nil, err
+	}
+
+	messageThreadID, err := texttemplate.New(name).Funcs(f).Parse(n.MessageThreadID)
+	if err != nil {
+		return nil, err
+	}
+
+	replyToMessageID, err := texttemplate.New(name).Funcs(f).Parse(n.ReplyToMessageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(notification *Notification, vars map[string]interface{}) error {
+		if notification.Telegram == nil {
+			notification.Telegram = &TelegramNotification{}
+		}
+
+		var parseModeData bytes.Buffer
+		if err := parseMode.Execute(&parseModeData, vars); err != nil {
+			return err
+		}
+		notification.Telegram.ParseMode = parseModeData.String()
+
+		var messageThreadIDData bytes.Buffer
+		if err := messageThreadID.Execute(&messageThreadIDData, vars); err != nil {
+			return err
+		}
+		notification.Telegram.MessageThreadID = messageThreadIDData.String()
+
+		var replyToMessageIDData bytes.Buffer
+		if err := replyToMessageID.Execute(&replyToMessageIDData, vars); err != nil {
+			return err
+		}
+		notification.Telegram.ReplyToMessageID = replyToMessageIDData.String()
+
+		notification.Telegram.DisableWebPagePreview = n.DisableWebPagePreview
+		return nil
+	}, nil
+}
+
 type TelegramOptions struct {
 	Token string `json:"token"`
 }
@@ -46,16 +108,61 @@ func buildTelegramMessageOptions(notification Notification, dest Destination) (*
 		msg = tgbotapi.NewMessageToChannel("@"+dest.Recipient, notification.Message)
 		msg.ParseMode = "Markdown"
 	}
+
+	if notification.Telegram != nil {
+		if err := applyTelegramNotification(&msg, notification.Telegram); err != nil {
+			return nil, err
+		}
+	}
 	return &msg, nil
 }
 
+func applyTelegramNotification(msg *tgbotapi.MessageConfig, n *TelegramNotification) error {
+	if n.ParseMode != "" {
+		valid := false
+		for _, mode := range telegramParseModes {
+			if n.ParseMode == mode {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("telegram parseMode '%s' is not valid, must be one of: %s", n.ParseMode, strings.Join(telegramParseModes, ", "))
+		}
+		msg.ParseMode = n.ParseMode
+	}
+
+	if n.MessageThreadID != "" {
+		threadID, err := strconv.Atoi(n.MessageThreadID)
+		if err != nil {
+			return fmt.Errorf("telegram messageThreadID '%s' is not valid: %v", n.MessageThreadID, err)
+		}
+		msg.MessageThreadID = threadID
+	}
+
+	if n.ReplyToMessageID != "" {
+		messageID, err := strconv.Atoi(n.ReplyToMessageID)
+		if err != nil {
+			return fmt.Errorf("telegram replyToMessageID '%s' is not valid: %v", n.ReplyToMessageID, err)
+		}
+		msg.ReplyParameters = tgbotapi.ReplyParameters{MessageID: messageID}
+	}
+
+	msg.LinkPreviewOptions.IsDisabled = n.DisableWebPagePreview
+	return nil
+}
+
 func (s telegramService) Send(notification Notification, dest Destination) error {
-	bot, err := tgbotapi.NewBotAPI(s.opts.Token)
+	return s.SendContext(context.Background(), notification, dest)
+}
+
+func (s telegramService) SendContext(ctx context.Context, notification Notification, dest Destination) error {
+	msg, err := buildTelegramMessageOptions(notification, dest)
 	if err != nil {
 		return err
 	}
 
-	msg, err := buildTelegramMessageOptions(notification, dest)
+	bot, err := tgbotapi.NewBotAPIWithClient(s.opts.Token, telegramAPIEndpoint, telegramContextClient{ctx: ctx})
 	if err != nil {
 		return err
 	}
@@ -67,3 +174,13 @@ func (s telegramService) Send(notification Notification, dest Destination) error
 
 	return nil
 }
+
+// telegramContextClient sends the requests of the bot with the context of the delivery, since the bot API does not
+// accept a context
+type telegramContextClient struct {
+	ctx context.Context
+}
+
+func (c telegramContextClient) Do(req *http.Request) (*http.Response, error) {
+	return http.DefaultClient.Do(req.WithContext(c.ctx))
+}
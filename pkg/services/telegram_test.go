@@ -1,10 +1,18 @@
 package services
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"reflect"
 	"testing"
+	"text/template"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestBuildTelegramMessageOptions(t *testing.T) {
@@ -51,3 +59,106 @@ func TestBuildTelegramMessageOptions(t *testing.T) {
 		})
 	}
 }
+
+func TestGetTemplater_Telegram(t *testing.T) {
+	n := Notification{
+		Telegram: &TelegramNotification{
+			ParseMode:             "{{.parseMode}}",
+			MessageThreadID:       "{{.threadID}}",
+			ReplyToMessageID:      "{{.messageID}}",
+			DisableWebPagePreview: true,
+		},
+	}
+
+	templater, err := n.GetTemplater("", template.FuncMap{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var notification Notification
+	err = templater(&notification, map[string]interface{}{
+		"parseMode": "HTML",
+		"threadID":  "7",
+		"messageID": "42",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, &TelegramNotification{
+		ParseMode:             "HTML",
+		MessageThreadID:       "7",
+		ReplyToMessageID:      "42",
+		DisableWebPagePreview: true,
+	}, notification.Telegram)
+}
+
+func TestSend_Telegram(t *testing.T) {
+	var received url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bottest-token/getMe":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"id":1,"is_bot":true,"first_name":"bot","username":"bot"}}`))
+		case "/bottest-token/sendMessage":
+			assert.NoError(t, r.ParseForm())
+			received = r.PostForm
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":100,"date":0,"chat":{"id":-123456,"type":"supergroup"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	origEndpoint := telegramAPIEndpoint
+	telegramAPIEndpoint = server.URL + "/bot%s/%s"
+	defer func() { telegramAPIEndpoint = origEndpoint }()
+
+	service := NewTelegramService(TelegramOptions{Token: "test-token"})
+	err := service.Send(Notification{
+		Message: "<b>Deployed</b>",
+		Telegram: &TelegramNotification{
+			ParseMode:        "HTML",
+			MessageThreadID:  "7",
+			ReplyToMessageID: "42",
+		},
+	}, Destination{Service: "telegram", Recipient: "-123456"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "-123456", received.Get("chat_id"))
+	assert.Equal(t, "<b>Deployed</b>", received.Get("text"))
+	assert.Equal(t, "HTML", received.Get("parse_mode"))
+	assert.Equal(t, "7", received.Get("message_thread_id"))
+	assert.Contains(t, received.Get("reply_parameters"), `"message_id":42`)
+}
+
+func TestSend_Telegram_Cancelled(t *testing.T) {
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	origEndpoint := telegramAPIEndpoint
+	telegramAPIEndpoint = server.URL + "/bot%s/%s"
+	defer func() { telegramAPIEndpoint = origEndpoint }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	service := NewTelegramService(TelegramOptions{Token: "test-token"})
+	err := service.SendContext(ctx, Notification{Message: "hello"}, Destination{Service: "telegram", Recipient: "-123456"})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSend_Telegram_InvalidParseMode(t *testing.T) {
+	service := NewTelegramService(TelegramOptions{Token: "test-token"})
+	err := service.Send(Notification{
+		Message:  "hello",
+		Telegram: &TelegramNotification{ParseMode: "Markdown2"},
+	}, Destination{Service: "telegram", Recipient: "-123456"})
+
+	assert.EqualError(t, err, fmt.Sprintf("telegram parseMode '%s' is not valid, must be one of: Markdown, MarkdownV2, HTML", "Markdown2"))
+}
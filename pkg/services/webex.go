@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -41,6 +42,10 @@ func NewWebexService(opts WebexOptions) NotificationService {
 var validEmail = regexp.MustCompile(`^\S+@\S+\.\S+$`)
 
 func (w webexService) Send(notification Notification, dest Destination) error {
+	return w.SendContext(context.Background(), notification, dest)
+}
+
+func (w webexService) SendContext(ctx context.Context, notification Notification, dest Destination) error {
 	requestURL := fmt.Sprintf("%s/v1/messages", w.opts.ApiURL)
 
 	client := &http.Client{
@@ -64,7 +69,7 @@ func (w webexService) Send(notification Notification, dest Destination) error {
 		return err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, requestURL, bytes.NewBuffer(jsonValue))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, bytes.NewBuffer(jsonValue))
 	if err != nil {
 		return err
 	}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -18,17 +19,24 @@ import (
 )
 
 type WebhookNotification struct {
-	Method string `json:"method"`
-	Body   string `json:"body"`
-	Path   string `json:"path"`
+	Method  string   `json:"method"`
+	Body    string   `json:"body"`
+	Path    string   `json:"path"`
+	Headers []Header `json:"headers,omitempty"`
 }
 
 type WebhookNotifications map[string]WebhookNotification
 
+type compiledWebhookHeader struct {
+	name  string
+	value *texttemplate.Template
+}
+
 type compiledWebhookTemplate struct {
-	body   *texttemplate.Template
-	path   *texttemplate.Template
-	method string
+	body    *texttemplate.Template
+	path    *texttemplate.Template
+	method  *texttemplate.Template
+	headers []compiledWebhookHeader
 }
 
 func (n WebhookNotifications) GetTemplater(name string, f texttemplate.FuncMap) (Templater, error) {
@@ -42,7 +50,19 @@ func (n WebhookNotifications) GetTemplater(name string, f texttemplate.FuncMap)
 		if err != nil {
 			return nil, err
 		}
-		webhooks[k] = compiledWebhookTemplate{body: body, method: v.Method, path: path}
+		method, err := texttemplate.New(name + k).Funcs(f).Parse(v.Method)
+		if err != nil {
+			return nil, err
+		}
+		var headers []compiledWebhookHeader
+		for _, header := range v.Headers {
+			value, err := texttemplate.New(name + k + header.Name).Funcs(f).Parse(header.Value)
+			if err != nil {
+				return nil, err
+			}
+			headers = append(headers, compiledWebhookHeader{name: header.Name, value: value})
+		}
+		webhooks[k] = compiledWebhookTemplate{body: body, method: method, path: path, headers: headers}
 	}
 	return func(notification *Notification, vars map[string]interface{}) error {
 		for k, v := range webhooks {
@@ -59,10 +79,24 @@ func (n WebhookNotifications) GetTemplater(name string, f texttemplate.FuncMap)
 			if err != nil {
 				return err
 			}
+			var method bytes.Buffer
+			err = webhooks[k].method.Execute(&method, vars)
+			if err != nil {
+				return err
+			}
+			var headers []Header
+			for _, header := range v.headers {
+				var value bytes.Buffer
+				if err := header.value.Execute(&value, vars); err != nil {
+					return err
+				}
+				headers = append(headers, Header{Name: header.name, Value: value.String()})
+			}
 			notification.Webhook[k] = WebhookNotification{
-				Method: v.method,
-				Body:   body.String(),
-				Path:   path.String(),
+				Method:  strings.ToUpper(strings.TrimSpace(method.String())),
+				Body:    body.String(),
+				Path:    path.String(),
+				Headers: headers,
 			}
 		}
 		return nil
@@ -84,9 +118,20 @@ type WebhookOptions struct {
 	Headers            []Header      `json:"headers"`
 	BasicAuth          *BasicAuth    `json:"basicAuth"`
 	InsecureSkipVerify bool          `json:"insecureSkipVerify"`
+	CABundle           string        `json:"caBundle,omitempty"`
 	RetryWaitMin       time.Duration `json:"retryWaitMin"`
 	RetryWaitMax       time.Duration `json:"retryWaitMax"`
 	RetryMax           int           `json:"retryMax"`
+	// Timeout bounds each attempt of the request, e.g. "10s". Defaults to 30s
+	Timeout string `json:"timeout,omitempty"`
+	// SigningSecret is used to sign the request body with HMAC-SHA256. The requests are not signed if it is empty
+	SigningSecret string `json:"signingSecret,omitempty"`
+	// SignatureHeader is the header which carries the signature. Defaults to X-Hub-Signature-256
+	SignatureHeader string `json:"signatureHeader,omitempty"`
+	// ClientCert and ClientKey hold the PEM encoded client certificate and key used to authenticate to receivers
+	// which require mutual TLS
+	ClientCert string `json:"clientCert,omitempty"`
+	ClientKey  string `json:"clientKey,omitempty"`
 }
 
 func NewWebhookService(opts WebhookOptions) NotificationService {
@@ -108,6 +153,10 @@ type webhookService struct {
 }
 
 func (s webhookService) Send(notification Notification, dest Destination) error {
+	return s.SendContext(context.Background(), notification, dest)
+}
+
+func (s webhookService) SendContext(ctx context.Context, notification Notification, dest Destination) error {
 	request := request{
 		body:        notification.Message,
 		method:      http.MethodGet,
@@ -119,7 +168,7 @@ func (s webhookService) Send(notification Notification, dest Destination) error
 		request.applyOverridesFrom(webhookNotification)
 	}
 
-	resp, err := request.execute(&s)
+	resp, err := request.execute(ctx, &s)
 	if err != nil {
 		return err
 	}
@@ -138,45 +187,63 @@ type request struct {
 	body        string
 	method      string
 	url         string
+	headers     []Header
 	destService string
 }
 
 func (r *request) applyOverridesFrom(notification WebhookNotification) {
 	r.body = notification.Body
 	r.method = text.Coalesce(notification.Method, r.method)
+	r.headers = notification.Headers
 	if notification.Path != "" {
 		r.url = strings.TrimRight(r.url, "/") + "/" + strings.TrimLeft(notification.Path, "/")
 	}
 }
 
-func (r *request) intoRetryableHttpRequest(service *webhookService) (*retryablehttp.Request, error) {
+func (r *request) intoRetryableHttpRequest(ctx context.Context, service *webhookService) (*retryablehttp.Request, error) {
 	retryReq, err := retryablehttp.NewRequest(r.method, r.url, bytes.NewBufferString(r.body))
 	if err != nil {
 		return nil, err
 	}
+	retryReq = retryReq.WithContext(ctx)
 	for _, header := range service.opts.Headers {
 		retryReq.Header.Set(header.Name, header.Value)
 	}
+	// headers defined in the template take precedence over the ones configured for the service
+	for _, header := range r.headers {
+		retryReq.Header.Set(header.Name, header.Value)
+	}
+	if service.opts.SigningSecret != "" {
+		retryReq.Header.Set(text.Coalesce(service.opts.SignatureHeader, httputil.DefaultSignatureHeader), httputil.Signature(service.opts.SigningSecret, []byte(r.body)))
+	}
 	if service.opts.BasicAuth != nil {
 		retryReq.SetBasicAuth(service.opts.BasicAuth.Username, service.opts.BasicAuth.Password)
 	}
 	return retryReq, nil
 }
 
-func (r *request) execute(service *webhookService) (*http.Response, error) {
-	req, err := r.intoRetryableHttpRequest(service)
+func (r *request) execute(ctx context.Context, service *webhookService) (*http.Response, error) {
+	req, err := r.intoRetryableHttpRequest(ctx, service)
 	if err != nil {
 		return nil, err
 	}
 
-	transport := httputil.NewLoggingRoundTripper(
-		httputil.NewTransport(r.url, service.opts.InsecureSkipVerify),
-		log.WithField("service", r.destService))
-
-	client := retryablehttp.NewClient()
-	client.HTTPClient = &http.Client{
-		Transport: transport,
+	timeout, err := parseTimeout("webhook", service.opts.Timeout)
+	if err != nil {
+		return nil, err
 	}
+	transportOpts := httputil.TransportOptions{
+		InsecureSkipVerify: service.opts.InsecureSkipVerify,
+		CABundle:           []byte(service.opts.CABundle),
+		Timeout:            timeout,
+		ClientCert:         []byte(service.opts.ClientCert),
+		ClientKey:          []byte(service.opts.ClientKey),
+	}
+	if err := transportOpts.Validate(); err != nil {
+		return nil, fmt.Errorf("webhook %s is not configured properly: %w", r.destService, err)
+	}
+	client := retryablehttp.NewClient()
+	client.HTTPClient = httputil.NewServiceHTTPClient(r.url, transportOpts, log.WithField("service", r.destService))
 	client.RetryWaitMin = service.opts.RetryWaitMin
 	client.RetryWaitMax = service.opts.RetryWaitMax
 	client.RetryMax = service.opts.RetryMax
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -8,6 +9,7 @@ import (
 	"strings"
 	"testing"
 	"text/template"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -94,13 +96,89 @@ func TestWebhook_SubPath(t *testing.T) {
 	assert.Equal(t, "/subpath1/subpath2", receivedPath)
 }
 
+func TestWebhook_TemplateHeadersOverrideServiceHeaders(t *testing.T) {
+	var receivedHeaders http.Header
+	var receivedMethod string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		receivedHeaders = request.Header
+		receivedMethod = request.Method
+	}))
+	defer server.Close()
+
+	service := NewWebhookService(WebhookOptions{
+		URL:     server.URL,
+		Headers: []Header{{Name: "X-Token", Value: "service-token"}, {Name: "X-Source", Value: "argocd"}},
+	})
+	err := service.Send(
+		Notification{
+			Webhook: map[string]WebhookNotification{
+				"test": {Body: "hello world", Method: http.MethodPut, Headers: []Header{{Name: "X-Token", Value: "request-token"}}},
+			},
+		}, Destination{Recipient: "test", Service: "test"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.MethodPut, receivedMethod)
+	assert.Equal(t, "request-token", receivedHeaders.Get("X-Token"))
+	assert.Equal(t, "argocd", receivedHeaders.Get("X-Source"))
+}
+
+func TestWebhook_SigningSecret(t *testing.T) {
+	var receivedHeaders http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		receivedHeaders = request.Header
+	}))
+	defer server.Close()
+
+	service := NewWebhookService(WebhookOptions{URL: server.URL, SigningSecret: "It's a Secret to Everybody"})
+	err := service.Send(Notification{
+		Webhook: map[string]WebhookNotification{"test": {Body: "Hello, World!", Method: http.MethodPost}},
+	}, Destination{Recipient: "test", Service: "test"})
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", receivedHeaders.Get("X-Hub-Signature-256"))
+
+	service = NewWebhookService(WebhookOptions{URL: server.URL, SigningSecret: "It's a Secret to Everybody", SignatureHeader: "X-Signature"})
+	err = service.Send(Notification{
+		Webhook: map[string]WebhookNotification{"test": {Body: "Hello, World!", Method: http.MethodPost}},
+	}, Destination{Recipient: "test", Service: "test"})
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", receivedHeaders.Get("X-Signature"))
+	assert.Empty(t, receivedHeaders.Get("X-Hub-Signature-256"))
+}
+
+func TestWebhook_FailedRequestIncludesResponseBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusBadRequest)
+		_, _ = writer.Write([]byte("invalid payload"))
+	}))
+	defer server.Close()
+
+	service := NewWebhookService(WebhookOptions{URL: server.URL})
+	err := service.Send(Notification{}, Destination{Recipient: "test", Service: "test"})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "failed with error code 400")
+		assert.Contains(t, err.Error(), "invalid payload")
+	}
+}
+
+func TestWebhook_PartialClientCertificate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		t.Error("the request must not be sent")
+	}))
+	defer server.Close()
+
+	service := NewWebhookService(WebhookOptions{URL: server.URL, ClientCert: "-----BEGIN CERTIFICATE-----"})
+	err := service.Send(Notification{}, Destination{Recipient: "test", Service: "test"})
+	assert.EqualError(t, err, "webhook test is not configured properly: client certificate and client key must be specified together")
+}
+
 func TestGetTemplater_Webhook(t *testing.T) {
 	n := Notification{
 		Webhook: WebhookNotifications{
 			"github": {
-				Method: "POST",
-				Body:   "{{.foo}}",
-				Path:   "{{.bar}}",
+				Method:  "{{.method}}",
+				Body:    "{{.foo}}",
+				Path:    "{{.bar}}",
+				Headers: []Header{{Name: "X-Request-Id", Value: "{{.id}}"}},
 			},
 		},
 	}
@@ -112,8 +190,10 @@ func TestGetTemplater_Webhook(t *testing.T) {
 
 	var notification Notification
 	err = templater(&notification, map[string]interface{}{
-		"foo": "hello",
-		"bar": "world",
+		"foo":    "hello",
+		"bar":    "world",
+		"method": "post",
+		"id":     "123",
 	})
 
 	if !assert.NoError(t, err) {
@@ -123,6 +203,7 @@ func TestGetTemplater_Webhook(t *testing.T) {
 	assert.Equal(t, notification.Webhook["github"].Method, "POST")
 	assert.Equal(t, notification.Webhook["github"].Body, "hello")
 	assert.Equal(t, notification.Webhook["github"].Path, "world")
+	assert.Equal(t, notification.Webhook["github"].Headers, []Header{{Name: "X-Request-Id", Value: "123"}})
 }
 
 func TestWebhookService_Send_Retry(t *testing.T) {
@@ -161,3 +242,24 @@ func TestWebhookService_Send_Retry(t *testing.T) {
 		t.Errorf("Expected 4 requests, got %d", count)
 	}
 }
+
+func TestWebhookService_SendContext_Cancelled(t *testing.T) {
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	service := NewWebhookService(WebhookOptions{URL: server.URL})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := service.SendContext(ctx, Notification{
+		Webhook: map[string]WebhookNotification{
+			"test": {Body: "hello world", Method: http.MethodPost},
+		},
+	}, Destination{Recipient: "test", Service: "test"})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -3,6 +3,7 @@ package subscriptions
 import (
 	"fmt"
 	"strings"
+	"unicode"
 
 	log "github.com/sirupsen/logrus"
 	"sigs.k8s.io/yaml"
@@ -16,17 +17,59 @@ var (
 
 // SetAnnotationPrefix sets the annotationPrefix to the provided string.
 // defaults to "notifications.argoproj.io"
+//
+// Deprecated: SetAnnotationPrefix changes the prefix of every controller in the process. Use Options.AnnotationPrefix instead.
 func SetAnnotationPrefix(prefix string) {
 	annotationPrefix = prefix
 }
 
+// Options configure the keys of the annotations used to subscribe to notifications
+type Options struct {
+	// AnnotationPrefix is the prefix of the annotation keys. Defaults to the prefix set by SetAnnotationPrefix
+	AnnotationPrefix string
+}
+
+func (o Options) prefix() string {
+	if o.AnnotationPrefix != "" {
+		return o.AnnotationPrefix
+	}
+	return annotationPrefix
+}
+
+// NotifiedAnnotationKey returns the key of the annotation which holds the notified state
+func (o Options) NotifiedAnnotationKey() string {
+	return fmt.Sprintf("notified.%s", o.prefix())
+}
+
+// NotifiedDataAnnotationKey returns the key of the annotation which holds the data kept along with the notified state,
+// e.g. the failure counts of trigger conditions
+func (o Options) NotifiedDataAnnotationKey() string {
+	return fmt.Sprintf("notified-data.%s", o.prefix())
+}
+
+// SubscribeAnnotationKey returns the key of the annotation which subscribes to the trigger of the service
+func (o Options) SubscribeAnnotationKey(trigger string, service string) string {
+	return fmt.Sprintf("%s/subscribe.%s.%s", o.prefix(), trigger, service)
+}
+
+// IsSubscriptionAnnotation returns true if the annotation key subscribes to notifications
+func (o Options) IsSubscriptionAnnotation(key string) bool {
+	return strings.HasPrefix(key, o.prefix()+"/subscribe.") || strings.HasPrefix(key, o.prefix()+"/subscriptions")
+}
+
+// NewAnnotations returns the annotations using the annotation prefix of the options
+func (o Options) NewAnnotations(annotations map[string]string) PrefixedAnnotations {
+	return PrefixedAnnotations{Annotations: NewAnnotations(annotations), prefix: o.prefix()}
+}
+
 func NotifiedAnnotationKey() string {
-	return fmt.Sprintf("notified.%s", annotationPrefix)
+	return Options{}.NotifiedAnnotationKey()
 }
 
+// parseRecipients splits a list of recipients separated by semicolons or commas
 func parseRecipients(v string) []string {
 	var recipients []string
-	for _, recipient := range strings.Split(v, ";") {
+	for _, recipient := range strings.FieldsFunc(v, func(r rune) bool { return r == ';' || r == ',' }) {
 		if recipient = strings.TrimSpace(recipient); recipient == "" {
 			continue
 		}
@@ -35,8 +78,24 @@ func parseRecipients(v string) []string {
 	return recipients
 }
 
+// parseRecipientTemplate splits the recipient into the recipient and the name of the template which overrides the
+// templates of the trigger, using the "recipient|template" syntax. The recipient is returned as is if the template name
+// is empty or contains whitespace.
+func parseRecipientTemplate(recipient string) (string, string) {
+	i := strings.LastIndex(recipient, "|")
+	if i < 0 {
+		return recipient, ""
+	}
+	template := recipient[i+1:]
+	if template == "" || strings.ContainsFunc(template, unicode.IsSpace) {
+		log.Warnf("Ignoring invalid template name '%s' of recipient '%s'", template, recipient[:i])
+		return recipient, ""
+	}
+	return recipient[:i], template
+}
+
 func SubscribeAnnotationKey(trigger string, service string) string {
-	return fmt.Sprintf("%s/subscribe.%s.%s", annotationPrefix, trigger, service)
+	return Options{}.SubscribeAnnotationKey(trigger, service)
 }
 
 type Annotations map[string]string
@@ -49,6 +108,12 @@ func NewAnnotations(annotations map[string]string) Annotations {
 	return Annotations(annotations)
 }
 
+// PrefixedAnnotations are annotations which use their own annotation prefix instead of the global one
+type PrefixedAnnotations struct {
+	Annotations
+	prefix string
+}
+
 type Subscription struct {
 	Trigger      []string
 	Destinations []Destination
@@ -60,9 +125,9 @@ type Destination struct {
 	Recipients []string `json:"recipients"`
 }
 
-func (a Annotations) iterate(callback func(trigger string, service string, recipients []string, key string)) {
-	prefix := annotationPrefix + "/subscribe."
-	altPrefix := annotationPrefix + "/subscriptions"
+func (a Annotations) iterate(basePrefix string, callback func(trigger string, service string, recipients []string, key string)) {
+	prefix := basePrefix + "/subscribe."
+	altPrefix := basePrefix + "/subscriptions"
 	var recipients []string
 	for k, v := range a {
 		switch {
@@ -135,9 +200,29 @@ func (a Annotations) iterate(callback func(trigger string, service string, recip
 	}
 }
 
+func (a Annotations) withGlobalPrefix() PrefixedAnnotations {
+	return PrefixedAnnotations{Annotations: a, prefix: annotationPrefix}
+}
+
 func (a Annotations) Subscribe(trigger string, service string, recipients ...string) {
-	annotationKey := SubscribeAnnotationKey(trigger, service)
-	r := parseRecipients(a[annotationKey])
+	a.withGlobalPrefix().Subscribe(trigger, service, recipients...)
+}
+
+func (a Annotations) Unsubscribe(trigger string, service string, recipient string) {
+	a.withGlobalPrefix().Unsubscribe(trigger, service, recipient)
+}
+
+func (a Annotations) Has(service string, recipient string) bool {
+	return a.withGlobalPrefix().Has(service, recipient)
+}
+
+func (a Annotations) GetDestinations(defaultTriggers []string, serviceDefaultTriggers map[string][]string) services.Destinations {
+	return a.withGlobalPrefix().GetDestinations(defaultTriggers, serviceDefaultTriggers)
+}
+
+func (a PrefixedAnnotations) Subscribe(trigger string, service string, recipients ...string) {
+	annotationKey := Options{AnnotationPrefix: a.prefix}.SubscribeAnnotationKey(trigger, service)
+	r := parseRecipients(a.Annotations[annotationKey])
 	set := map[string]bool{}
 	for _, recipient := range r {
 		set[recipient] = true
@@ -148,11 +233,11 @@ func (a Annotations) Subscribe(trigger string, service string, recipients ...str
 		}
 	}
 
-	a[annotationKey] = strings.Join(r, ";")
+	a.Annotations[annotationKey] = strings.Join(r, ";")
 }
 
-func (a Annotations) Unsubscribe(trigger string, service string, recipient string) {
-	a.iterate(func(t string, s string, r []string, k string) {
+func (a PrefixedAnnotations) Unsubscribe(trigger string, service string, recipient string) {
+	a.iterate(a.prefix, func(t string, s string, r []string, k string) {
 		if trigger != t || s != service {
 			return
 		}
@@ -160,9 +245,9 @@ func (a Annotations) Unsubscribe(trigger string, service string, recipient strin
 			if r[i] == recipient {
 				updatedRecipients := append(r[:i], r[i+1:]...)
 				if len(updatedRecipients) > 0 {
-					a[k] = strings.Join(updatedRecipients, "")
+					a.Annotations[k] = strings.Join(updatedRecipients, "")
 				} else {
-					delete(a, k)
+					delete(a.Annotations, k)
 				}
 				break
 			}
@@ -170,9 +255,9 @@ func (a Annotations) Unsubscribe(trigger string, service string, recipient strin
 	})
 }
 
-func (a Annotations) Has(service string, recipient string) bool {
+func (a PrefixedAnnotations) Has(service string, recipient string) bool {
 	has := false
-	a.iterate(func(t string, s string, r []string, k string) {
+	a.iterate(a.prefix, func(t string, s string, r []string, k string) {
 		if s != service {
 			return
 		}
@@ -186,10 +271,11 @@ func (a Annotations) Has(service string, recipient string) bool {
 	return has
 }
 
-func (a Annotations) GetDestinations(defaultTriggers []string, serviceDefaultTriggers map[string][]string) services.Destinations {
+func (a PrefixedAnnotations) GetDestinations(defaultTriggers []string, serviceDefaultTriggers map[string][]string) services.Destinations {
 	dests := services.Destinations{}
-	a.iterate(func(trigger string, service string, recipients []string, v string) {
+	a.iterate(a.prefix, func(trigger string, service string, recipients []string, v string) {
 		for _, recipient := range recipients {
+			recipient, template := parseRecipientTemplate(recipient)
 			triggers := defaultTriggers
 			if trigger != "" {
 				triggers = []string{trigger}
@@ -201,6 +287,7 @@ func (a Annotations) GetDestinations(defaultTriggers []string, serviceDefaultTri
 				dests[triggers[i]] = append(dests[triggers[i]], services.Destination{
 					Service:   service,
 					Recipient: recipient,
+					Template:  template,
 				})
 			}
 		}
@@ -1,6 +1,8 @@
 package subscriptions
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/argoproj/notifications-engine/pkg/services"
@@ -121,7 +123,7 @@ func TestIterate(t *testing.T) {
 
 	for _, tt := range tests {
 		a := Annotations(tt.annotations)
-		a.iterate(func(trigger, service string, recipients []string, key string) {
+		a.iterate(annotationPrefix, func(trigger, service string, recipients []string, key string) {
 			for _, v := range tt.triggers {
 				for _, serv := range tt.service {
 					if trigger == v {
@@ -231,6 +233,59 @@ func TestGetDestinations(t *testing.T) {
 	}
 }
 
+func TestGetDestinations_TemplateOverride(t *testing.T) {
+	a := Annotations(map[string]string{
+		"notifications.argoproj.io/subscribe.my-trigger.slack":   "my-channel1|my-template;my-channel2",
+		"notifications.argoproj.io/subscribe.my-trigger.webhook": "|my-template",
+		"notifications.argoproj.io/subscribe.my-trigger.teams":   "https://example.com:8080/hook|compact",
+		"notifications.argoproj.io/subscribe.my-trigger.awssns":  "arn:aws:sns:us-east-1:123456789012:my-topic",
+		"notifications.argoproj.io/subscribe.my-trigger.email":   "alice@example.com|",
+	})
+
+	dests := a.GetDestinations(nil, nil)
+	assert.ElementsMatch(t, []services.Destination{
+		{Service: "slack", Recipient: "my-channel1", Template: "my-template"},
+		{Service: "slack", Recipient: "my-channel2"},
+		{Service: "webhook", Recipient: "", Template: "my-template"},
+		{Service: "teams", Recipient: "https://example.com:8080/hook", Template: "compact"},
+		{Service: "awssns", Recipient: "arn:aws:sns:us-east-1:123456789012:my-topic"},
+		{Service: "email", Recipient: "alice@example.com|"},
+	}, dests["my-trigger"])
+}
+
+func TestGetDestinations_MultipleRecipients(t *testing.T) {
+	a := Annotations(map[string]string{
+		"notifications.argoproj.io/subscribe.my-trigger.slack": " my-channel1, ,my-channel2;my-channel3,, my-channel1 ",
+	})
+
+	dests := a.GetDestinations(nil, nil)
+	assert.Equal(t, services.Destinations{
+		"my-trigger": []services.Destination{
+			{Service: "slack", Recipient: "my-channel1"},
+			{Service: "slack", Recipient: "my-channel2"},
+			{Service: "slack", Recipient: "my-channel3"},
+			{Service: "slack", Recipient: "my-channel1"},
+		},
+	}, dests)
+
+	assert.Equal(t, services.Destinations{
+		"my-trigger": []services.Destination{
+			{Service: "slack", Recipient: "my-channel1"},
+			{Service: "slack", Recipient: "my-channel2"},
+			{Service: "slack", Recipient: "my-channel3"},
+		},
+	}, dests.Dedup())
+}
+
+func TestSubscribe_CommaSeparatedRecipients(t *testing.T) {
+	a := Annotations(map[string]string{
+		"notifications.argoproj.io/subscribe.my-trigger.slack": "my-channel1,my-channel2",
+	})
+	a.Subscribe("my-trigger", "slack", "my-channel2", "my-channel3")
+
+	assert.Equal(t, "my-channel1;my-channel2;my-channel3", a["notifications.argoproj.io/subscribe.my-trigger.slack"])
+}
+
 func TestSubscribe(t *testing.T) {
 	a := Annotations(map[string]string{})
 	a.Subscribe("my-trigger", "slack", "my-channel1")
@@ -269,3 +324,48 @@ func TestSetAnnotationPrefix(t *testing.T) {
 	assert.Equal(t, "test.prefix", annotationPrefix)
 	assert.Equal(t, "notified.test.prefix", NotifiedAnnotationKey())
 }
+
+func TestOptions_AnnotationPrefix(t *testing.T) {
+	opts := Options{AnnotationPrefix: "example.prefix.io"}
+	assert.Equal(t, "notified.example.prefix.io", opts.NotifiedAnnotationKey())
+	assert.Equal(t, "notified-data.example.prefix.io", opts.NotifiedDataAnnotationKey())
+	assert.Equal(t, "example.prefix.io/subscribe.my-trigger.slack", opts.SubscribeAnnotationKey("my-trigger", "slack"))
+
+	assert.Equal(t, "notified.notifications.argoproj.io", Options{}.NotifiedAnnotationKey())
+	assert.Equal(t, "notifications.argoproj.io/subscribe.my-trigger.slack", Options{}.SubscribeAnnotationKey("my-trigger", "slack"))
+
+	assert.True(t, opts.IsSubscriptionAnnotation("example.prefix.io/subscribe.my-trigger.slack"))
+	assert.True(t, opts.IsSubscriptionAnnotation("example.prefix.io/subscriptions"))
+	assert.False(t, opts.IsSubscriptionAnnotation("notifications.argoproj.io/subscribe.my-trigger.slack"))
+	assert.False(t, opts.IsSubscriptionAnnotation("notified.example.prefix.io"))
+}
+
+func TestOptions_ConcurrentPrefixes(t *testing.T) {
+	prefixes := []string{"first.prefix.io", "second.prefix.io"}
+	var wg sync.WaitGroup
+	for _, prefix := range prefixes {
+		wg.Add(1)
+		go func(prefix string) {
+			defer wg.Done()
+			opts := Options{AnnotationPrefix: prefix}
+			for i := 0; i < 100; i++ {
+				a := opts.NewAnnotations(map[string]string{})
+				recipient := fmt.Sprintf("%s-%d", prefix, i)
+				a.Subscribe("my-trigger", "slack", recipient)
+
+				assert.Equal(t, recipient, a.Annotations[prefix+"/subscribe.my-trigger.slack"])
+				assert.True(t, a.Has("slack", recipient))
+				assert.Equal(t, services.Destinations{
+					"my-trigger": {{Service: "slack", Recipient: recipient}},
+				}, a.GetDestinations(nil, nil))
+
+				// annotations of the other prefix are ignored
+				assert.Empty(t, NewAnnotations(a.Annotations).GetDestinations(nil, nil))
+
+				a.Unsubscribe("my-trigger", "slack", recipient)
+				assert.Empty(t, a.Annotations)
+			}
+		}(prefix)
+	}
+	wg.Wait()
+}
@@ -3,6 +3,9 @@ package subscriptions
 import (
 	"encoding/json"
 
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
+	log "github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/labels"
 )
 
@@ -10,6 +13,7 @@ type rawSubscription struct {
 	Recipients []string `json:"recipients"`
 	Triggers   []string `json:"triggers"`
 	Selector   string   `json:"selector"`
+	Expr       string   `json:"expr,omitempty"`
 }
 
 // DefaultSubscription holds recipients that receives notification by default.
@@ -20,6 +24,10 @@ type DefaultSubscription struct {
 	Triggers []string
 	// Options label selector that limits applied applications
 	Selector labels.Selector
+	// Optional expression evaluated against the resource (available as `obj`) that limits applied applications
+	Expr string
+
+	exprProgram *vm.Program
 }
 
 func (s *DefaultSubscription) MatchesTrigger(trigger string) bool {
@@ -46,13 +54,45 @@ func (s *DefaultSubscription) UnmarshalJSON(data []byte) error {
 		return err
 	}
 	s.Selector = selector
+	s.Expr = raw.Expr
+	if s.Expr != "" {
+		if s.exprProgram, err = expr.Compile(s.Expr); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// MatchesExpr returns true if the subscription has no expression or the expression evaluates to true for the resource
+func (s *DefaultSubscription) MatchesExpr(obj map[string]interface{}) bool {
+	if s.Expr == "" {
+		return true
+	}
+	if obj == nil {
+		return false
+	}
+	prog := s.exprProgram
+	if prog == nil {
+		var err error
+		if prog, err = expr.Compile(s.Expr); err != nil {
+			log.Errorf("failed to compile subscription expression '%s': %v", s.Expr, err)
+			return false
+		}
+	}
+	val, err := expr.Run(prog, map[string]interface{}{"obj": obj})
+	if err != nil {
+		log.Errorf("failed to execute subscription expression '%s': %v", s.Expr, err)
+		return false
+	}
+	res, ok := val.(bool)
+	return ok && res
+}
+
 func (s *DefaultSubscription) MarshalJSON() ([]byte, error) {
 	raw := rawSubscription{
 		Triggers:   s.Triggers,
 		Recipients: s.Recipients,
+		Expr:       s.Expr,
 	}
 	if s.Selector != nil {
 		raw.Selector = s.Selector.String()
@@ -2,6 +2,7 @@ package templates
 
 import (
 	"fmt"
+	texttemplate "text/template"
 
 	"github.com/Masterminds/sprig/v3"
 
@@ -17,9 +18,18 @@ type service struct {
 }
 
 func NewService(templates map[string]services.Notification) (*service, error) {
+	return NewServiceWithFuncs(templates, nil)
+}
+
+// NewServiceWithFuncs creates a templates service which templates can use the given functions in addition to the
+// built-in ones. The given functions take precedence over built-in functions with the same name.
+func NewServiceWithFuncs(templates map[string]services.Notification, funcs texttemplate.FuncMap) (*service, error) {
 	f := sprig.TxtFuncMap()
 	delete(f, "env")
 	delete(f, "expandenv")
+	for name, fn := range funcs {
+		f[name] = fn
+	}
 
 	svc := &service{templaters: map[string]services.Templater{}}
 	for name, cfg := range templates {
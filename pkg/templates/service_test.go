@@ -1,7 +1,9 @@
 package templates
 
 import (
+	"strings"
 	"testing"
+	texttemplate "text/template"
 
 	"github.com/stretchr/testify/assert"
 
@@ -29,3 +31,34 @@ func TestFormat_Message(t *testing.T) {
 
 	assert.Equal(t, "hello", notification.Message)
 }
+
+func TestFormat_CustomFuncs(t *testing.T) {
+	svc, err := NewServiceWithFuncs(map[string]services.Notification{
+		"test": {
+			Message: "{{.app | shout}}",
+			Slack: &services.SlackNotification{
+				Attachments: `[{"title": "{{.app | shout}}", "color": "{{upper .color}}"}]`,
+			},
+		},
+	}, texttemplate.FuncMap{
+		"shout": func(s string) string { return strings.ToUpper(s) + "!" },
+		// overrides the built-in function
+		"upper": func(s string) string { return "#" + s },
+	})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	notification, err := svc.FormatNotification(map[string]interface{}{
+		"app":   "guestbook",
+		"color": "18be52",
+	}, "test")
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "GUESTBOOK!", notification.Message)
+	assert.Equal(t, `[{"title": "GUESTBOOK!", "color": "#18be52"}]`, notification.Slack.Attachments)
+}
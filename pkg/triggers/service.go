@@ -18,6 +18,9 @@ type Condition struct {
 	When        string   `json:"when,omitempty"`
 	Description string   `json:"description,omitempty"`
 	Send        []string `json:"send,omitempty"`
+	// Recover is the list of templates sent once the condition is no longer triggered. The recovery notification is
+	// only sent to destinations which were notified while the condition was triggered.
+	Recover []string `json:"recover,omitempty"`
 }
 
 type ConditionResult struct {
@@ -25,6 +28,8 @@ type ConditionResult struct {
 	OncePer   string
 	Templates []string
 	Triggered bool
+	// RecoverTemplates are the templates of the notification sent once the condition is no longer triggered
+	RecoverTemplates []string
 }
 
 type Service interface {
@@ -78,8 +83,9 @@ func (svc *service) Run(triggerName string, vars map[string]interface{}) ([]Cond
 	var res []ConditionResult
 	for i, condition := range t {
 		conditionResult := ConditionResult{
-			Templates: condition.Send,
-			Key:       fmt.Sprintf("[%d].%s", i, hash(condition.When)),
+			Templates:        condition.Send,
+			Key:              fmt.Sprintf("[%d].%s", i, hash(condition.When)),
+			RecoverTemplates: condition.Recover,
 		}
 		var whenResult bool
 		if prog, ok := svc.compiledConditions[condition.When]; !ok {
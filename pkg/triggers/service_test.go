@@ -58,6 +58,30 @@ func TestRun(t *testing.T) {
 	})
 }
 
+func TestRun_Recover(t *testing.T) {
+	svc, err := NewService(map[string][]Condition{
+		"my-trigger": {{
+			When:    "var1 == 'abc'",
+			Send:    []string{"my-template"},
+			Recover: []string{"my-recovery"},
+		}},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	res, err := svc.Run("my-trigger", map[string]interface{}{"var1": "bcd"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []ConditionResult{{
+		Key:              fmt.Sprintf("[0].%s", hash("var1 == 'abc'")),
+		Triggered:        false,
+		Templates:        []string{"my-template"},
+		RecoverTemplates: []string{"my-recovery"},
+	}}, res)
+}
+
 func TestRun_OncePerSet(t *testing.T) {
 	revision := "123"
 	svc, err := NewService(map[string][]Condition{
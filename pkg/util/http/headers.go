@@ -0,0 +1,23 @@
+package http
+
+import (
+	"net/http"
+)
+
+// NewHeadersRoundTripper returns a round tripper which sets the given headers on every request
+func NewHeadersRoundTripper(roundTripper http.RoundTripper, headers map[string]string) http.RoundTripper {
+	return &headersRoundTripper{roundTripper: roundTripper, headers: headers}
+}
+
+type headersRoundTripper struct {
+	roundTripper http.RoundTripper
+	headers      map[string]string
+}
+
+func (rt *headersRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, value := range rt.headers {
+		req.Header.Set(name, value)
+	}
+	return rt.roundTripper.RoundTrip(req)
+}
@@ -0,0 +1,29 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// NewProxyFunc returns a proxy function which sends requests through the given proxy, except for
+// the hosts excluded by the NO_PROXY environment variable.
+func NewProxyFunc(proxyURL string) (func(*http.Request) (*url.URL, error), error) {
+	if _, err := url.Parse(proxyURL); err != nil {
+		return nil, err
+	}
+	noProxy := os.Getenv("NO_PROXY")
+	if noProxy == "" {
+		noProxy = os.Getenv("no_proxy")
+	}
+	proxyFunc := (&httpproxy.Config{
+		HTTPProxy:  proxyURL,
+		HTTPSProxy: proxyURL,
+		NoProxy:    noProxy,
+	}).ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}, nil
+}
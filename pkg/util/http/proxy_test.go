@@ -0,0 +1,52 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProxyFunc(t *testing.T) {
+	t.Setenv("NO_PROXY", "internal.example.com")
+
+	proxyFunc, err := NewProxyFunc("http://proxy.example.com:3128")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "https://api.opsgenie.com/v2/alerts", nil)
+	assert.NoError(t, err)
+	proxyURL, err := proxyFunc(req)
+	if assert.NoError(t, err) && assert.NotNil(t, proxyURL) {
+		assert.Equal(t, "proxy.example.com:3128", proxyURL.Host)
+	}
+
+	req, err = http.NewRequest(http.MethodGet, "https://internal.example.com/v2/alerts", nil)
+	assert.NoError(t, err)
+	proxyURL, err = proxyFunc(req)
+	assert.NoError(t, err)
+	assert.Nil(t, proxyURL)
+}
+
+func TestHeadersRoundTripper(t *testing.T) {
+	var received http.Header
+	rt := NewHeadersRoundTripper(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		received = req.Header
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}), map[string]string{"X-Egress-Token": "secret"})
+
+	req, err := http.NewRequest(http.MethodGet, "https://api.opsgenie.com/v2/alerts", nil)
+	assert.NoError(t, err)
+	_, err = rt.RoundTrip(req)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "secret", received.Get("X-Egress-Token"))
+	assert.Empty(t, req.Header.Get("X-Egress-Token"))
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
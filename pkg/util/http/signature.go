@@ -0,0 +1,17 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// DefaultSignatureHeader is the header which carries the request body signature unless configured otherwise
+const DefaultSignatureHeader = "X-Hub-Signature-256"
+
+// Signature returns the HMAC-SHA256 of the body computed with the secret, in the "sha256=<hex digest>" format
+func Signature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
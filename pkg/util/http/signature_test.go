@@ -0,0 +1,13 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignature(t *testing.T) {
+	// generated with: printf 'Hello, World!' | openssl dgst -sha256 -hmac "It's a Secret to Everybody"
+	assert.Equal(t, "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17",
+		Signature("It's a Secret to Everybody", []byte("Hello, World!")))
+}
@@ -3,8 +3,21 @@ package http
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultTimeout bounds requests to services unless configured otherwise, so that a hung connection does not
+	// block the delivery until the TCP timeout of the OS
+	DefaultTimeout = 30 * time.Second
+	// DefaultIdleConnTimeout is how long idle keep-alive connections are kept open unless configured otherwise
+	DefaultIdleConnTimeout = 90 * time.Second
 )
 
 var certResolver func(serverName string) ([]string, error)
@@ -13,26 +26,108 @@ func SetCertResolver(resolver func(serverName string) ([]string, error)) {
 	certResolver = resolver
 }
 
+// TransportOptions holds the TLS and timeout settings of the transport
+type TransportOptions struct {
+	InsecureSkipVerify bool
+	// CABundle holds PEM encoded certificates of certificate authorities trusted in addition to the system ones,
+	// e.g. to reach services using certificates signed by a private CA
+	CABundle []byte
+	// Timeout bounds the time until the response headers are received, and the whole request for clients created by
+	// NewServiceHTTPClient. Defaults to DefaultTimeout.
+	Timeout time.Duration
+	// IdleConnTimeout is how long idle keep-alive connections are kept open. Defaults to DefaultIdleConnTimeout.
+	IdleConnTimeout time.Duration
+	// ClientCert and ClientKey hold the PEM encoded certificate and private key presented to servers which require
+	// client authentication (mutual TLS). Both must be set.
+	ClientCert []byte
+	ClientKey  []byte
+}
+
+// Validate returns an error if the client certificate is configured partially or can't be loaded. Transports ignore
+// invalid client certificates, so the options should be validated before the transport is created.
+func (opts TransportOptions) Validate() error {
+	_, err := opts.clientCertificate()
+	return err
+}
+
+// clientCertificate returns the client certificate, or nil if no client certificate is configured
+func (opts TransportOptions) clientCertificate() (*tls.Certificate, error) {
+	if len(opts.ClientCert) == 0 && len(opts.ClientKey) == 0 {
+		return nil, nil
+	}
+	if len(opts.ClientCert) == 0 || len(opts.ClientKey) == 0 {
+		return nil, errors.New("client certificate and client key must be specified together")
+	}
+	cert, err := tls.X509KeyPair(opts.ClientCert, opts.ClientKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+	return &cert, nil
+}
+
+func (opts TransportOptions) timeout() time.Duration {
+	if opts.Timeout > 0 {
+		return opts.Timeout
+	}
+	return DefaultTimeout
+}
+
+// NewServiceHTTPClient returns a client of the service at the URL which logs the requests to the log entry and fails
+// the requests which do not complete within the timeout
+func NewServiceHTTPClient(rawURL string, opts TransportOptions, entry *log.Entry) *http.Client {
+	return &http.Client{
+		Transport: NewLoggingRoundTripper(NewTransportWithOptions(rawURL, opts), entry),
+		Timeout:   opts.timeout(),
+	}
+}
+
 func NewTransport(rawURL string, insecureSkipVerify bool) *http.Transport {
+	return NewTransportWithOptions(rawURL, TransportOptions{InsecureSkipVerify: insecureSkipVerify})
+}
+
+func NewTransportWithOptions(rawURL string, opts TransportOptions) *http.Transport {
+	idleConnTimeout := opts.IdleConnTimeout
+	if idleConnTimeout <= 0 {
+		idleConnTimeout = DefaultIdleConnTimeout
+	}
 	transport := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
+		Proxy:                 http.ProxyFromEnvironment,
+		ResponseHeaderTimeout: opts.timeout(),
+		IdleConnTimeout:       idleConnTimeout,
 	}
-	if insecureSkipVerify {
+	var certificates []tls.Certificate
+	if cert, err := opts.clientCertificate(); err == nil && cert != nil {
+		certificates = []tls.Certificate{*cert}
+	}
+	if opts.InsecureSkipVerify {
 		transport.TLSClientConfig = &tls.Config{
 			InsecureSkipVerify: true,
+			Certificates:       certificates,
 		}
-	} else if certResolver != nil {
-		parsedURL, err := url.Parse(rawURL)
-		if err != nil {
-			return transport
+		return transport
+	}
+	var certPool *x509.CertPool
+	if certResolver != nil {
+		if parsedURL, err := url.Parse(rawURL); err == nil {
+			if serverCertificatePem, err := certResolver(parsedURL.Host); err == nil && len(serverCertificatePem) > 0 {
+				certPool = getCertPoolFromPEMData(serverCertificatePem)
+			}
 		}
-		serverCertificatePem, err := certResolver(parsedURL.Host)
-		if err != nil {
-			return transport
-		} else if len(serverCertificatePem) > 0 {
-			transport.TLSClientConfig = &tls.Config{
-				RootCAs: getCertPoolFromPEMData(serverCertificatePem),
+	}
+	if len(opts.CABundle) > 0 {
+		if certPool == nil {
+			systemPool, err := x509.SystemCertPool()
+			if err != nil {
+				systemPool = x509.NewCertPool()
 			}
+			certPool = systemPool
+		}
+		certPool.AppendCertsFromPEM(opts.CABundle)
+	}
+	if certPool != nil || len(certificates) > 0 {
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:      certPool,
+			Certificates: certificates,
 		}
 	}
 	return transport
@@ -0,0 +1,146 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTransportWithOptions_CABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	client := &http.Client{Transport: NewTransportWithOptions(server.URL, TransportOptions{CABundle: caBundle})}
+	resp, err := client.Get(server.URL)
+	if assert.NoError(t, err) {
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	client = &http.Client{Transport: NewTransportWithOptions(server.URL, TransportOptions{})}
+	_, err = client.Get(server.URL)
+	assert.ErrorContains(t, err, "certificate")
+}
+
+func TestNewTransportWithOptions_InsecureSkipVerify(t *testing.T) {
+	transport := NewTransportWithOptions("https://example.com", TransportOptions{InsecureSkipVerify: true, CABundle: []byte("ignored")})
+
+	assert.True(t, transport.TLSClientConfig.InsecureSkipVerify)
+	assert.Nil(t, transport.TLSClientConfig.RootCAs)
+}
+
+func TestNewServiceHTTPClient_Timeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	defer close(done)
+
+	client := NewServiceHTTPClient(server.URL, TransportOptions{Timeout: 50 * time.Millisecond}, log.NewEntry(log.New()))
+	_, err := client.Get(server.URL)
+	if assert.Error(t, err) {
+		assert.True(t, os.IsTimeout(err), "expected a timeout error, got %v", err)
+	}
+}
+
+func TestNewTransportWithOptions_Timeouts(t *testing.T) {
+	transport := NewTransportWithOptions("https://example.com", TransportOptions{})
+	assert.Equal(t, DefaultTimeout, transport.ResponseHeaderTimeout)
+	assert.Equal(t, DefaultIdleConnTimeout, transport.IdleConnTimeout)
+
+	transport = NewTransportWithOptions("https://example.com", TransportOptions{InsecureSkipVerify: true, Timeout: time.Second, IdleConnTimeout: time.Minute})
+	assert.Equal(t, time.Second, transport.ResponseHeaderTimeout)
+	assert.Equal(t, time.Minute, transport.IdleConnTimeout)
+	assert.Equal(t, DefaultTimeout, NewServiceHTTPClient("https://example.com", TransportOptions{}, log.NewEntry(log.New())).Timeout)
+}
+
+// newClientCertificate returns a self-signed client certificate and its key in PEM format
+func newClientCertificate(t *testing.T) (*x509.Certificate, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "notifications-controller"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func TestNewTransportWithOptions_ClientCertificate(t *testing.T) {
+	clientCert, certPEM, keyPEM := newClientCertificate(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	opts := TransportOptions{CABundle: caBundle, ClientCert: certPEM, ClientKey: keyPEM}
+	assert.NoError(t, opts.Validate())
+	client := &http.Client{Transport: NewTransportWithOptions(server.URL, opts)}
+	resp, err := client.Get(server.URL)
+	if assert.NoError(t, err) {
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	// the server rejects the handshake without a client certificate
+	client = &http.Client{Transport: NewTransportWithOptions(server.URL, TransportOptions{CABundle: caBundle})}
+	_, err = client.Get(server.URL)
+	assert.Error(t, err)
+
+	transport := NewTransportWithOptions(server.URL, TransportOptions{InsecureSkipVerify: true, ClientCert: certPEM, ClientKey: keyPEM})
+	assert.Len(t, transport.TLSClientConfig.Certificates, 1)
+}
+
+func TestTransportOptions_Validate(t *testing.T) {
+	_, certPEM, keyPEM := newClientCertificate(t)
+
+	assert.NoError(t, TransportOptions{}.Validate())
+	assert.NoError(t, TransportOptions{ClientCert: certPEM, ClientKey: keyPEM}.Validate())
+	assert.EqualError(t, TransportOptions{ClientCert: certPEM}.Validate(), "client certificate and client key must be specified together")
+	assert.EqualError(t, TransportOptions{ClientKey: keyPEM}.Validate(), "client certificate and client key must be specified together")
+	assert.ErrorContains(t, TransportOptions{ClientCert: keyPEM, ClientKey: certPEM}.Validate(), "failed to load client certificate")
+}
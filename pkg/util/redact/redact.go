@@ -0,0 +1,100 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Mask replaces the sensitive substrings
+const Mask = "******"
+
+// DefaultPatterns match credentials which commonly appear in notification destinations, service URLs and errors.
+// If a pattern has a group named "secret", only the group is masked, otherwise the whole match.
+var DefaultPatterns = []*regexp.Regexp{
+	// Slack bot, user and app tokens
+	regexp.MustCompile(`xox[abposr]-[A-Za-z0-9-]+`),
+	regexp.MustCompile(`xapp-[A-Za-z0-9-]+`),
+	// GitHub tokens
+	regexp.MustCompile(`gh[pousr]_[A-Za-z0-9]{20,}`),
+	regexp.MustCompile(`github_pat_[A-Za-z0-9_]{20,}`),
+	// Telegram bot tokens
+	regexp.MustCompile(`\b\d{6,12}:(?P<secret>[A-Za-z0-9_-]{30,})`),
+	// Slack and Discord webhook URLs
+	regexp.MustCompile(`hooks\.slack\.com/services/(?P<secret>[A-Za-z0-9/]+)`),
+	regexp.MustCompile(`/api/webhooks/\d+/(?P<secret>[A-Za-z0-9_-]+)`),
+	// passwords of URLs
+	regexp.MustCompile(`://[^/\s:@]+:(?P<secret>[^/\s@]+)@`),
+	// bearer tokens
+	regexp.MustCompile(`(?i)\bbearer\s+(?P<secret>[A-Za-z0-9._~+/-]+=*)`),
+	// key-value pairs, e.g. query parameters, with a sensitive key
+	regexp.MustCompile(`(?i)\b(?:[a-z_]*token|secret|password|passwd|api_?key|signature)["']?\s*[:=]\s*["']?(?P<secret>[^\s"'&,;}]+)`),
+}
+
+// String masks the substrings of s matched by the default patterns
+func String(s string) string {
+	return redact(s, DefaultPatterns)
+}
+
+func redact(s string, patterns []*regexp.Regexp) string {
+	for _, pattern := range patterns {
+		group := pattern.SubexpIndex("secret")
+		matches := pattern.FindAllStringSubmatchIndex(s, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		res := ""
+		last := 0
+		for _, match := range matches {
+			start, end := match[0], match[1]
+			if group > 0 {
+				start, end = match[2*group], match[2*group+1]
+			}
+			if start < 0 {
+				continue
+			}
+			res += s[last:start] + Mask
+			last = end
+		}
+		s = res + s[last:]
+	}
+	return s
+}
+
+// Hook masks sensitive substrings in the message and in the string and error fields of log entries
+type Hook struct {
+	patterns []*regexp.Regexp
+}
+
+// NewHook returns a hook which masks the substrings matched by the given patterns, or by the default patterns if none
+// are given. Use AddHook of the logger to install it.
+func NewHook(patterns ...*regexp.Regexp) *Hook {
+	if len(patterns) == 0 {
+		patterns = DefaultPatterns
+	}
+	return &Hook{patterns: patterns}
+}
+
+func (h *Hook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (h *Hook) Fire(entry *log.Entry) error {
+	entry.Message = redact(entry.Message, h.patterns)
+	for k, v := range entry.Data {
+		switch val := v.(type) {
+		case string:
+			entry.Data[k] = redact(val, h.patterns)
+		case error:
+			if s := redact(val.Error(), h.patterns); s != val.Error() {
+				entry.Data[k] = s
+			}
+		case fmt.Stringer:
+			if s := redact(val.String(), h.patterns); s != val.String() {
+				entry.Data[k] = s
+			}
+		}
+	}
+	return nil
+}
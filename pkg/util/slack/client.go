@@ -3,6 +3,11 @@ package slack
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
 
 	sl "github.com/slack-go/slack"
 	"golang.org/x/time/rate"
@@ -14,6 +19,8 @@ const (
 	Post DeliveryPolicy = iota
 	PostAndUpdate
 	Update
+	// Delete deletes the message which started the thread of the grouping key, e.g. once the condition resolved
+	Delete
 )
 
 func (p DeliveryPolicy) String() string {
@@ -24,6 +31,8 @@ func (p DeliveryPolicy) String() string {
 		return "PostAndUpdate"
 	case Update:
 		return "Update"
+	case Delete:
+		return "Delete"
 	}
 	return "Post"
 }
@@ -36,6 +45,8 @@ func (p DeliveryPolicy) FromString(policy string) DeliveryPolicy {
 		return PostAndUpdate
 	case "Update":
 		return Update
+	case "Delete":
+		return Delete
 	}
 	return Post
 }
@@ -53,18 +64,72 @@ func (p *DeliveryPolicy) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// BroadcastPolicy controls whether the replies in a thread are also broadcast to the channel
+type BroadcastPolicy int
+
+const (
+	BroadcastNever BroadcastPolicy = iota
+	// BroadcastFirst broadcasts only the first reply in the thread
+	BroadcastFirst
+	BroadcastAlways
+)
+
+func (p BroadcastPolicy) String() string {
+	switch p {
+	case BroadcastNever:
+		return "Never"
+	case BroadcastFirst:
+		return "First"
+	case BroadcastAlways:
+		return "Always"
+	}
+	return "Never"
+}
+
+func (p BroadcastPolicy) FromString(policy string) BroadcastPolicy {
+	switch policy {
+	case "Never":
+		return BroadcastNever
+	case "First":
+		return BroadcastFirst
+	case "Always":
+		return BroadcastAlways
+	}
+	return BroadcastNever
+}
+
+func (p BroadcastPolicy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+func (p *BroadcastPolicy) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*p = p.FromString(s)
+	return nil
+}
+
 //go:generate mockgen -destination=./mocks/client.go -source=$GOFILE -package=mocks SlackClient
 type SlackClient interface {
 	SendMessageContext(ctx context.Context, channelID string, options ...sl.MsgOption) (string, string, string, error)
+	UploadFileV2Context(ctx context.Context, params sl.UploadFileV2Parameters) (*sl.FileSummary, error)
+	PostEphemeralContext(ctx context.Context, channelID, userID string, options ...sl.MsgOption) (string, error)
+	GetUserByEmailContext(ctx context.Context, email string) (*sl.User, error)
+	DeleteMessageContext(ctx context.Context, channel, messageTimestamp string) (string, string, error)
 }
 
 type timestampMap map[string]map[string]string
 type channelMap map[string]string
+type replyMap map[string]map[string]bool
 
 type state struct {
 	Limiter    *rate.Limiter
 	ThreadTSs  timestampMap
 	ChannelIDs channelMap
+	// Replied holds the threads which already have a reply
+	Replied replyMap
 }
 
 func NewState(limiter *rate.Limiter) *state {
@@ -72,27 +137,44 @@ func NewState(limiter *rate.Limiter) *state {
 		Limiter:    limiter,
 		ThreadTSs:  make(timestampMap),
 		ChannelIDs: make(channelMap),
+		Replied:    make(replyMap),
 	}
 }
 
 type threadedClient struct {
 	Client SlackClient
 	*state
+	// workspace scopes the cached channel IDs and thread timestamps, so that the same recipient name in
+	// different workspaces does not share them
+	workspace string
 }
 
 func NewThreadedClient(client SlackClient, s *state) *threadedClient {
-	return &threadedClient{client, s}
+	return &threadedClient{Client: client, state: s}
+}
+
+// NewWorkspaceThreadedClient returns a client which keeps its channel IDs and thread timestamps in the state
+// separately from the clients of other workspaces
+func NewWorkspaceThreadedClient(client SlackClient, s *state, workspace string) *threadedClient {
+	return &threadedClient{Client: client, state: s, workspace: workspace}
+}
+
+func (c *threadedClient) cacheKey(recipient string) string {
+	if c.workspace == "" {
+		return recipient
+	}
+	return c.workspace + "/" + recipient
 }
 
 func (c *threadedClient) getChannelID(recipient string) string {
-	if id, ok := c.ChannelIDs[recipient]; ok {
+	if id, ok := c.ChannelIDs[c.cacheKey(recipient)]; ok {
 		return id
 	}
 	return recipient
 }
 
 func (c *threadedClient) getThreadTimestamp(recipient string, groupingKey string) string {
-	thread, ok := c.ThreadTSs[recipient]
+	thread, ok := c.ThreadTSs[c.cacheKey(recipient)]
 	if !ok {
 		return ""
 	}
@@ -100,17 +182,41 @@ func (c *threadedClient) getThreadTimestamp(recipient string, groupingKey string
 }
 
 func (c *threadedClient) setThreadTimestamp(recipient string, groupingKey string, ts string) {
-	thread, ok := c.ThreadTSs[recipient]
+	thread, ok := c.ThreadTSs[c.cacheKey(recipient)]
 	if !ok {
 		thread = map[string]string{}
-		c.ThreadTSs[recipient] = thread
+		c.ThreadTSs[c.cacheKey(recipient)] = thread
 	}
 	thread[groupingKey] = ts
 }
 
-func (c *threadedClient) SendMessage(ctx context.Context, recipient string, groupingKey string, broadcast bool, policy DeliveryPolicy, options []sl.MsgOption) error {
+// hasReplied returns true if a reply was already posted to the thread of the grouping key
+func (c *threadedClient) hasReplied(recipient string, groupingKey string) bool {
+	return c.Replied[c.cacheKey(recipient)][groupingKey]
+}
+
+func (c *threadedClient) setReplied(recipient string, groupingKey string) {
+	if c.Replied == nil {
+		c.Replied = make(replyMap)
+	}
+	thread, ok := c.Replied[c.cacheKey(recipient)]
+	if !ok {
+		thread = map[string]bool{}
+		c.Replied[c.cacheKey(recipient)] = thread
+	}
+	thread[groupingKey] = true
+}
+
+// SendMessage posts the message to the thread of the grouping key, or starts the thread if it does not exist yet, and
+// updates the parent message according to the delivery policy. Replies are broadcast to the channel according to the
+// broadcast policy.
+func (c *threadedClient) SendMessage(ctx context.Context, recipient string, groupingKey string, broadcast BroadcastPolicy, policy DeliveryPolicy, options []sl.MsgOption) error {
+	if policy == Delete {
+		return c.deleteThread(ctx, recipient, groupingKey)
+	}
 	ts := c.getThreadTimestamp(recipient, groupingKey)
-	if groupingKey != "" && ts != "" {
+	reply := groupingKey != "" && ts != ""
+	if reply {
 		options = append(options, sl.MsgOptionTS(ts))
 	}
 
@@ -121,7 +227,7 @@ func (c *threadedClient) SendMessage(ctx context.Context, recipient string, grou
 			c.Limiter,
 			recipient,
 			sl.MsgOptionPost(),
-			buildPostOptions(broadcast, options),
+			buildPostOptions(reply && c.shouldBroadcast(recipient, groupingKey, broadcast), options),
 		)
 		if err != nil {
 			return err
@@ -129,7 +235,10 @@ func (c *threadedClient) SendMessage(ctx context.Context, recipient string, grou
 		if groupingKey != "" && ts == "" {
 			c.setThreadTimestamp(recipient, groupingKey, newTs)
 		}
-		c.ChannelIDs[recipient] = channelID
+		if reply {
+			c.setReplied(recipient, groupingKey)
+		}
+		c.ChannelIDs[c.cacheKey(recipient)] = channelID
 	}
 
 	if ts != "" && (policy == Update || policy == PostAndUpdate) {
@@ -150,6 +259,103 @@ func (c *threadedClient) SendMessage(ctx context.Context, recipient string, grou
 	return nil
 }
 
+// SendPersistentMessage updates the message with the timestamp in the channel, or posts a new message to the recipient
+// if the timestamp is empty or the message no longer exists. It returns the channel ID and the timestamp of the
+// message, which the caller keeps to update the message once more.
+func (c *threadedClient) SendPersistentMessage(ctx context.Context, recipient string, channelID string, ts string, options []sl.MsgOption) (string, string, error) {
+	if ts != "" {
+		_, _, err := SendMessageRateLimited(
+			c.Client,
+			ctx,
+			c.Limiter,
+			channelID,
+			sl.MsgOptionUpdate(ts),
+			sl.MsgOptionAsUser(true),
+			sl.MsgOptionCompose(options...),
+		)
+		// the message might have been deleted in Slack, in which case a new one is posted
+		if err == nil || err.Error() != "message_not_found" {
+			return channelID, ts, err
+		}
+	}
+	newTs, newChannelID, err := SendMessageRateLimited(c.Client, ctx, c.Limiter, recipient, sl.MsgOptionPost(), sl.MsgOptionCompose(options...))
+	if err != nil {
+		return "", "", err
+	}
+	c.ChannelIDs[c.cacheKey(recipient)] = newChannelID
+	return newChannelID, newTs, nil
+}
+
+func (c *threadedClient) shouldBroadcast(recipient string, groupingKey string, broadcast BroadcastPolicy) bool {
+	switch broadcast {
+	case BroadcastAlways:
+		return true
+	case BroadcastFirst:
+		return !c.hasReplied(recipient, groupingKey)
+	}
+	return false
+}
+
+// deleteThread deletes the message which started the thread of the grouping key and forgets the thread, so that the
+// next message of the grouping key starts a new thread. Nothing is deleted if the thread is unknown.
+func (c *threadedClient) deleteThread(ctx context.Context, recipient string, groupingKey string) error {
+	if groupingKey == "" {
+		return fmt.Errorf("slack message can only be deleted with a grouping key")
+	}
+	ts := c.getThreadTimestamp(recipient, groupingKey)
+	if ts == "" {
+		return nil
+	}
+	if err := c.Limiter.Wait(ctx); err != nil {
+		return err
+	}
+	// the message might have been deleted in Slack already
+	if _, _, err := c.Client.DeleteMessageContext(ctx, c.getChannelID(recipient), ts); err != nil && err.Error() != "message_not_found" {
+		return fmt.Errorf("failed to delete slack message: %w", err)
+	}
+	delete(c.ThreadTSs[c.cacheKey(recipient)], groupingKey)
+	delete(c.Replied[c.cacheKey(recipient)], groupingKey)
+	return nil
+}
+
+// UploadFiles uploads the files into the channel of the recipient. The files are threaded under the message of the
+// grouping key if the thread exists, so UploadFiles should be called after SendMessage.
+func (c *threadedClient) UploadFiles(ctx context.Context, recipient string, groupingKey string, files []sl.UploadFileV2Parameters) error {
+	for _, file := range files {
+		file.Channel = c.getChannelID(recipient)
+		if groupingKey != "" {
+			file.ThreadTimestamp = c.getThreadTimestamp(recipient, groupingKey)
+		}
+		if err := c.Limiter.Wait(ctx); err != nil {
+			return err
+		}
+		if _, err := c.Client.UploadFileV2Context(ctx, file); err != nil {
+			return fmt.Errorf("failed to upload file '%s': %w", file.Filename, err)
+		}
+	}
+	return nil
+}
+
+// SendEphemeralMessage posts a message to the channel of the recipient which is only visible to the user. The user is
+// either a Slack user ID or the email address of the user. Ephemeral messages are neither threaded nor updated.
+func (c *threadedClient) SendEphemeralMessage(ctx context.Context, recipient string, user string, options []sl.MsgOption) error {
+	userID := user
+	if strings.Contains(user, "@") {
+		u, err := c.Client.GetUserByEmailContext(ctx, user)
+		if err != nil {
+			return fmt.Errorf("failed to find slack user with email '%s': %w", user, err)
+		}
+		userID = u.ID
+	}
+	if err := c.Limiter.Wait(ctx); err != nil {
+		return err
+	}
+	if _, err := c.Client.PostEphemeralContext(ctx, c.getChannelID(recipient), userID, options...); err != nil {
+		return fmt.Errorf("failed to post ephemeral message: %w", err)
+	}
+	return nil
+}
+
 func buildPostOptions(broadcast bool, options []sl.MsgOption) sl.MsgOption {
 	opt := sl.MsgOptionCompose(options...)
 	if broadcast {
@@ -158,30 +364,37 @@ func buildPostOptions(broadcast bool, options []sl.MsgOption) sl.MsgOption {
 	return opt
 }
 
-func SendMessageRateLimited(client SlackClient, ctx context.Context, limiter *rate.Limiter, recipient string, options ...sl.MsgOption) (ts, channelID string, err error) {
-	for {
-		err = limiter.Wait(ctx)
-		if err != nil {
-			break
-		}
+// retryAfterJitter returns the random delay added to the Retry-After duration requested by Slack, so that
+// the deliveries which were rate limited at the same time are not retried at the same time
+var retryAfterJitter = func(retryAfter time.Duration) time.Duration {
+	return time.Duration(rand.Int63n(int64(retryAfter)/4 + 1))
+}
 
-		channelID, ts, _, err = client.SendMessageContext(
-			ctx,
-			recipient,
-			options...,
-		)
+// SendMessageRateLimited sends the message once the limiter allows it. If Slack rate limits the request, the limiter
+// is slowed down to the rate requested by Slack and the message is sent once more after the Retry-After duration.
+// The limiter is restored once a message is sent successfully.
+func SendMessageRateLimited(client SlackClient, ctx context.Context, limiter *rate.Limiter, recipient string, options ...sl.MsgOption) (ts, channelID string, err error) {
+	if err = limiter.Wait(ctx); err != nil {
+		return
+	}
+	channelID, ts, _, err = client.SendMessageContext(ctx, recipient, options...)
 
-		if err != nil {
-			if rateLimitedError, ok := err.(*sl.RateLimitedError); ok {
-				limiter.SetLimit(rate.Every(rateLimitedError.RetryAfter))
-			} else {
-				break
-			}
-		} else {
-			// No error, so remove rate limit
-			limiter.SetLimit(rate.Inf)
-			break
+	var rateLimitedError *sl.RateLimitedError
+	if errors.As(err, &rateLimitedError) {
+		limiter.SetLimit(rate.Every(rateLimitedError.RetryAfter))
+		timer := time.NewTimer(rateLimitedError.RetryAfter + retryAfterJitter(rateLimitedError.RetryAfter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", "", ctx.Err()
+		case <-timer.C:
 		}
+		channelID, ts, _, err = client.SendMessageContext(ctx, recipient, options...)
+	}
+
+	if err == nil {
+		// No error, so remove rate limit
+		limiter.SetLimit(rate.Inf)
 	}
 	return
 }
@@ -3,8 +3,10 @@ package slack
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/argoproj/notifications-engine/pkg/util/slack/mocks"
 
@@ -22,6 +24,7 @@ func TestDeliveryPolicy_MarshalJSON(t *testing.T) {
 		{input: Post, want: `"Post"`},
 		{input: PostAndUpdate, want: `"PostAndUpdate"`},
 		{input: Update, want: `"Update"`},
+		{input: Delete, want: `"Delete"`},
 		{input: 100, want: `"Post"`},
 	}
 
@@ -42,6 +45,7 @@ func TestDeliveryPolicy_UnmarshalJSON(t *testing.T) {
 		{input: `"Post"`, want: Post},
 		{input: `"PostAndUpdate"`, want: PostAndUpdate},
 		{input: `"Update"`, want: Update},
+		{input: `"Delete"`, want: Delete},
 		{input: `"Error"`, want: Post},
 	}
 
@@ -172,10 +176,207 @@ func TestThreadedClient(t *testing.T) {
 					SendMessageContext(gomock.Any(), gomock.Eq(channelID), tc.wantPostType2)
 			}
 
-			client := NewThreadedClient(m, &state{rate.NewLimiter(rate.Inf, 1), tc.threadTSs, channelMap{}})
-			err := client.SendMessage(context.TODO(), channel, tc.groupingKey, false, tc.policy, []slack.MsgOption{})
+			client := NewThreadedClient(m, &state{rate.NewLimiter(rate.Inf, 1), tc.threadTSs, channelMap{}, replyMap{}})
+			err := client.SendMessage(context.TODO(), channel, tc.groupingKey, BroadcastNever, tc.policy, []slack.MsgOption{})
 			assert.NoError(t, err)
 			assert.Equal(t, tc.wantthreadTSs, client.ThreadTSs)
 		})
 	}
 }
+
+// Checks whether a posted message is broadcast to the channel
+type slackBroadcastMatcher struct {
+	wantBroadcast bool
+}
+
+func (m slackBroadcastMatcher) Matches(maybeMsgOption interface{}) bool {
+	msgOption, ok := maybeMsgOption.(slack.MsgOption)
+	if !ok {
+		return false
+	}
+	_, values, err := slack.UnsafeApplyMsgOptions("token", "channel", "", msgOption)
+	if err != nil {
+		return false
+	}
+	return m.wantBroadcast == (values.Get("reply_broadcast") == "true")
+}
+
+func (m slackBroadcastMatcher) String() string {
+	return "MsgOption with reply_broadcast " + strconv.FormatBool(m.wantBroadcast)
+}
+
+func TestThreadedClient_BroadcastPolicy(t *testing.T) {
+	tests := map[string]struct {
+		policy BroadcastPolicy
+		// wantBroadcast holds whether the parent message and the following replies are broadcast
+		wantBroadcast []bool
+	}{
+		"Never":  {policy: BroadcastNever, wantBroadcast: []bool{false, false, false}},
+		"First":  {policy: BroadcastFirst, wantBroadcast: []bool{false, true, false}},
+		"Always": {policy: BroadcastAlways, wantBroadcast: []bool{false, true, true}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			m := mocks.NewMockSlackClient(ctrl)
+			client := NewThreadedClient(m, NewState(rate.NewLimiter(rate.Inf, 1)))
+
+			for i, want := range tc.wantBroadcast {
+				m.EXPECT().
+					SendMessageContext(gomock.Any(), gomock.Eq("channel"), EqChatPost(), slackBroadcastMatcher{want}).
+					Return("channel-ID", strconv.Itoa(i+1), "", nil)
+				err := client.SendMessage(context.TODO(), "channel", "group", tc.policy, Post, []slack.MsgOption{})
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, timestampMap{"channel": {"group": "1"}}, client.ThreadTSs)
+		})
+	}
+}
+
+func TestBroadcastPolicy_UnmarshalJSON(t *testing.T) {
+	for input, want := range map[string]BroadcastPolicy{`"Never"`: BroadcastNever, `"First"`: BroadcastFirst, `"Always"`: BroadcastAlways, `"Error"`: BroadcastNever} {
+		var got BroadcastPolicy
+		err := json.Unmarshal([]byte(input), &got)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+}
+
+func TestThreadedClient_Delete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := mocks.NewMockSlackClient(ctrl)
+	client := NewThreadedClient(m, NewState(rate.NewLimiter(rate.Inf, 1)))
+
+	gomock.InOrder(
+		m.EXPECT().SendMessageContext(gomock.Any(), gomock.Eq("channel"), EqChatPost()).Return("channel-ID", "1", "", nil),
+		m.EXPECT().SendMessageContext(gomock.Any(), gomock.Eq("channel"), EqChatPost()).Return("channel-ID", "2", "", nil),
+		m.EXPECT().DeleteMessageContext(gomock.Any(), "channel-ID", "1").Return("channel-ID", "1", nil),
+		// the thread was deleted, so the next message starts a new one
+		m.EXPECT().SendMessageContext(gomock.Any(), gomock.Eq("channel"), EqChatPost()).Return("channel-ID", "3", "", nil),
+	)
+
+	assert.NoError(t, client.SendMessage(context.TODO(), "channel", "group", BroadcastNever, Post, []slack.MsgOption{}))
+	assert.NoError(t, client.SendMessage(context.TODO(), "channel", "group", BroadcastNever, Post, []slack.MsgOption{}))
+	assert.Equal(t, replyMap{"channel": {"group": true}}, client.Replied)
+
+	assert.NoError(t, client.SendMessage(context.TODO(), "channel", "group", BroadcastNever, Delete, []slack.MsgOption{}))
+	assert.Equal(t, timestampMap{"channel": {}}, client.ThreadTSs)
+	assert.Equal(t, replyMap{"channel": {}}, client.Replied)
+
+	// the thread is unknown, so there is nothing to delete
+	assert.NoError(t, client.SendMessage(context.TODO(), "channel", "other", BroadcastNever, Delete, []slack.MsgOption{}))
+
+	assert.NoError(t, client.SendMessage(context.TODO(), "channel", "group", BroadcastNever, Post, []slack.MsgOption{}))
+	assert.Equal(t, timestampMap{"channel": {"group": "3"}}, client.ThreadTSs)
+
+	err := client.SendMessage(context.TODO(), "channel", "", BroadcastNever, Delete, []slack.MsgOption{})
+	assert.EqualError(t, err, "slack message can only be deleted with a grouping key")
+}
+
+func TestThreadedClient_Workspaces(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := mocks.NewMockSlackClient(ctrl)
+	s := NewState(rate.NewLimiter(rate.Inf, 1))
+
+	// the recipient has no thread in the second workspace, so both messages are posted
+	m.EXPECT().SendMessageContext(gomock.Any(), gomock.Eq("channel"), EqChatPost()).Return("channel-ID-1", "1", "", nil)
+	m.EXPECT().SendMessageContext(gomock.Any(), gomock.Eq("channel"), EqChatPost()).Return("channel-ID-2", "2", "", nil)
+
+	err := NewThreadedClient(m, s).SendMessage(context.TODO(), "channel", "group", BroadcastNever, Update, []slack.MsgOption{})
+	assert.NoError(t, err)
+	err = NewWorkspaceThreadedClient(m, s, "other").SendMessage(context.TODO(), "channel", "group", BroadcastNever, Update, []slack.MsgOption{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, timestampMap{"channel": {"group": "1"}, "other/channel": {"group": "2"}}, s.ThreadTSs)
+	assert.Equal(t, channelMap{"channel": "channel-ID-1", "other/channel": "channel-ID-2"}, s.ChannelIDs)
+}
+
+func TestThreadedClient_SendEphemeralMessage(t *testing.T) {
+	t.Run("UserID", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		m := mocks.NewMockSlackClient(ctrl)
+		s := NewState(rate.NewLimiter(rate.Inf, 1))
+		s.ChannelIDs["channel"] = "channel-ID"
+
+		m.EXPECT().PostEphemeralContext(gomock.Any(), "channel-ID", "U123", gomock.Any()).Return("1", nil)
+
+		err := NewThreadedClient(m, s).SendEphemeralMessage(context.TODO(), "channel", "U123", []slack.MsgOption{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("UserEmail", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		m := mocks.NewMockSlackClient(ctrl)
+		s := NewState(rate.NewLimiter(rate.Inf, 1))
+
+		m.EXPECT().GetUserByEmailContext(gomock.Any(), "jane@example.com").Return(&slack.User{ID: "U123"}, nil)
+		m.EXPECT().PostEphemeralContext(gomock.Any(), "channel", "U123", gomock.Any()).Return("1", nil)
+
+		err := NewThreadedClient(m, s).SendEphemeralMessage(context.TODO(), "channel", "jane@example.com", []slack.MsgOption{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("UnknownEmail", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		m := mocks.NewMockSlackClient(ctrl)
+		s := NewState(rate.NewLimiter(rate.Inf, 1))
+
+		m.EXPECT().GetUserByEmailContext(gomock.Any(), "jane@example.com").Return(nil, errors.New("users_not_found"))
+
+		err := NewThreadedClient(m, s).SendEphemeralMessage(context.TODO(), "channel", "jane@example.com", []slack.MsgOption{})
+		assert.EqualError(t, err, "failed to find slack user with email 'jane@example.com': users_not_found")
+	})
+}
+
+func TestSendMessageRateLimited_RetryAfter(t *testing.T) {
+	jitter := retryAfterJitter
+	retryAfterJitter = func(time.Duration) time.Duration { return 0 }
+	defer func() { retryAfterJitter = jitter }()
+	const retryAfter = 50 * time.Millisecond
+
+	t.Run("RetriedOnce", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		m := mocks.NewMockSlackClient(ctrl)
+		limiter := rate.NewLimiter(rate.Inf, 1)
+
+		var firstCallAt, retriedAt time.Time
+		gomock.InOrder(
+			m.EXPECT().SendMessageContext(gomock.Any(), "channel", gomock.Any()).DoAndReturn(func(_ context.Context, _ string, _ ...slack.MsgOption) (string, string, string, error) {
+				firstCallAt = time.Now()
+				return "", "", "", &slack.RateLimitedError{RetryAfter: retryAfter}
+			}),
+			m.EXPECT().SendMessageContext(gomock.Any(), "channel", gomock.Any()).DoAndReturn(func(_ context.Context, _ string, _ ...slack.MsgOption) (string, string, string, error) {
+				retriedAt = time.Now()
+				assert.Equal(t, rate.Every(retryAfter), limiter.Limit())
+				return "channel-ID", "1", "", nil
+			}),
+		)
+
+		ts, channelID, err := SendMessageRateLimited(m, context.TODO(), limiter, "channel", slack.MsgOptionPost())
+		assert.NoError(t, err)
+		assert.Equal(t, "1", ts)
+		assert.Equal(t, "channel-ID", channelID)
+		assert.GreaterOrEqual(t, retriedAt.Sub(firstCallAt), retryAfter)
+		assert.Equal(t, rate.Inf, limiter.Limit())
+	})
+
+	t.Run("StillRateLimited", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		m := mocks.NewMockSlackClient(ctrl)
+		limiter := rate.NewLimiter(rate.Inf, 1)
+
+		m.EXPECT().SendMessageContext(gomock.Any(), "channel", gomock.Any()).Return("", "", "", &slack.RateLimitedError{RetryAfter: retryAfter}).Times(2)
+
+		_, _, err := SendMessageRateLimited(m, context.TODO(), limiter, "channel", slack.MsgOptionPost())
+		assert.Error(t, err)
+		assert.Equal(t, rate.Every(retryAfter), limiter.Limit())
+	})
+}
@@ -56,3 +56,69 @@ func (mr *MockSlackClientMockRecorder) SendMessageContext(ctx, channelID interfa
 	varargs := append([]interface{}{ctx, channelID}, options...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessageContext", reflect.TypeOf((*MockSlackClient)(nil).SendMessageContext), varargs...)
 }
+
+// UploadFileV2Context mocks base method.
+func (m *MockSlackClient) UploadFileV2Context(ctx context.Context, params slack.UploadFileV2Parameters) (*slack.FileSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UploadFileV2Context", ctx, params)
+	ret0, _ := ret[0].(*slack.FileSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UploadFileV2Context indicates an expected call of UploadFileV2Context.
+func (mr *MockSlackClientMockRecorder) UploadFileV2Context(ctx, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadFileV2Context", reflect.TypeOf((*MockSlackClient)(nil).UploadFileV2Context), ctx, params)
+}
+
+// PostEphemeralContext mocks base method.
+func (m *MockSlackClient) PostEphemeralContext(ctx context.Context, channelID, userID string, options ...slack.MsgOption) (string, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, channelID, userID}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "PostEphemeralContext", varargs...)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PostEphemeralContext indicates an expected call of PostEphemeralContext.
+func (mr *MockSlackClientMockRecorder) PostEphemeralContext(ctx, channelID, userID interface{}, options ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, channelID, userID}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostEphemeralContext", reflect.TypeOf((*MockSlackClient)(nil).PostEphemeralContext), varargs...)
+}
+
+// GetUserByEmailContext mocks base method.
+func (m *MockSlackClient) GetUserByEmailContext(ctx context.Context, email string) (*slack.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByEmailContext", ctx, email)
+	ret0, _ := ret[0].(*slack.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByEmailContext indicates an expected call of GetUserByEmailContext.
+func (mr *MockSlackClientMockRecorder) GetUserByEmailContext(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByEmailContext", reflect.TypeOf((*MockSlackClient)(nil).GetUserByEmailContext), ctx, email)
+}
+
+// DeleteMessageContext mocks base method.
+func (m *MockSlackClient) DeleteMessageContext(ctx context.Context, channel, messageTimestamp string) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteMessageContext", ctx, channel, messageTimestamp)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// DeleteMessageContext indicates an expected call of DeleteMessageContext.
+func (mr *MockSlackClientMockRecorder) DeleteMessageContext(ctx, channel, messageTimestamp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMessageContext", reflect.TypeOf((*MockSlackClient)(nil).DeleteMessageContext), ctx, channel, messageTimestamp)
+}